@@ -2,8 +2,8 @@ package app
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
-	"flag"
 	"fmt"
 	"io"
 	"os"
@@ -25,21 +25,27 @@ type App struct {
 }
 
 type globalOptions struct {
-	mode      output.Mode
-	noInput   bool
-	profile   string
-	dryRun    bool
-	showHelp  bool
-	showVer   bool
-	config    string
-	statePath string
+	mode       output.Mode
+	noInput    bool
+	profile    string
+	dryRun     bool
+	showHelp   bool
+	showVer    bool
+	config     string
+	statePath  string
+	fields     []string
+	jq         string
+	template   string
+	shaper     *output.Shaper
+	fromOutbox bool
 }
 
 type cliError struct {
-	exit int
-	code string
-	msg  string
-	hint string
+	exit       int
+	code       string
+	msg        string
+	hint       string
+	retryAfter int
 }
 
 func (e cliError) Error() string { return e.msg }
@@ -53,8 +59,20 @@ var (
 	runtimeStdout      io.Writer = os.Stdout
 	runtimeStderr      io.Writer = os.Stderr
 	runtimeStdinIsTTY            = func() bool { return isTTY(os.Stdin) }
+	runtimeWarnings    []string
+	runtimeStream      *output.NDJSON
 )
 
+func addWarning(msg string) {
+	runtimeWarnings = append(runtimeWarnings, msg)
+}
+
+func streamRecord(v any) {
+	if runtimeStream != nil {
+		_ = runtimeStream.Record(v)
+	}
+}
+
 var (
 	Version = "dev"
 	Commit  = "none"
@@ -71,6 +89,8 @@ func bindRuntimeIO(a App) func() {
 	prevOut := runtimeStdout
 	prevErr := runtimeStderr
 	prevTTY := runtimeStdinIsTTY
+	prevWarnings := runtimeWarnings
+	prevStream := runtimeStream
 
 	runtimeStdinReader = a.Stdin
 	runtimeStdout = a.Stdout
@@ -81,12 +101,16 @@ func bindRuntimeIO(a App) func() {
 		}
 		return false
 	}
+	runtimeWarnings = nil
+	runtimeStream = nil
 
 	return func() {
 		runtimeStdinReader = prevIn
 		runtimeStdout = prevOut
 		runtimeStderr = prevErr
 		runtimeStdinIsTTY = prevTTY
+		runtimeWarnings = prevWarnings
+		runtimeStream = prevStream
 	}
 }
 
@@ -94,36 +118,39 @@ func (a App) run(args []string) int {
 	restoreIO := bindRuntimeIO(a)
 	defer restoreIO()
 
+	if len(args) > 0 && args[0] == "__complete" {
+		return a.cmdComplete(args[1:])
+	}
+	return a.runCommand(args, globalOptions{})
+}
+
+func (a App) runCommand(args []string, base globalOptions) int {
 	start := time.Now()
 	requestID := fmt.Sprintf("req_%d", time.Now().UnixNano())
-	g, rest, err := parseGlobal(args)
+	g, rest, err := parseCommandLine(args)
+	g = g.inherit(base)
 	if err != nil {
-		mode := g.mode
-		if mode == "" {
-			mode = output.ModeHuman
-		}
-		_ = output.PrintError(a.Stdout, mode, "usage_error", err.Error(), "Use --help for usage", "usage", false, g.profile, requestID, start)
-		return 2
+		return a.exitWithError(err, fallbackMode(g.mode), g.profile, requestID, start)
 	}
 	if g.showVer {
 		fmt.Fprintf(a.Stdout, "protonmailcli %s (%s) %s\n", Version, Commit, Date)
 		return 0
 	}
 	if g.showHelp || len(rest) == 0 {
-		printHelp(a.Stdout)
-		return 0
+		g.mode = fallbackMode(g.mode)
+		return a.printResult(helpFor(rest), g, requestID, start)
 	}
-	if err := validateNoLateGlobalFlags(rest); err != nil {
-		return a.exitWithError(err, fallbackMode(g.mode), g.profile, requestID, start)
+	if g.shaper, err = output.NewShaper(g.fields, g.jq, g.template); err != nil {
+		return a.exitWithError(cliError{exit: 2, code: "usage_error", msg: err.Error()}, fallbackMode(g.mode), g.profile, requestID, start)
 	}
 
-	cfgPath := g.config
-	if cfgPath == "" {
-		cfgPath = config.DefaultConfigPath()
+	if g.config == "" {
+		g.config = config.DefaultConfigPath()
 	}
 	if g.statePath == "" {
 		g.statePath = config.DefaultStatePath()
 	}
+	cfgPath := g.config
 
 	if rest[0] == "completion" {
 		if err := cmdCompletion(a.Stdout, rest[1:]); err != nil {
@@ -140,6 +167,27 @@ func (a App) run(args []string) int {
 		return 0
 	}
 
+	if rest[0] == "schema" || rest[0] == "commands" {
+		g.mode = fallbackMode(g.mode)
+		if g.mode == output.ModeNDJSON {
+			runtimeStream = output.NewNDJSON(a.Stdout, g.shaper)
+		}
+		var data any
+		if rest[0] == "commands" {
+			data, err = cmdCommands(rest[1:])
+		} else {
+			action := ""
+			if len(rest) > 1 {
+				action = rest[1]
+			}
+			data, err = cmdSchema(action, rest[min(2, len(rest)):], g)
+		}
+		if err != nil {
+			return a.exitWithError(err, g.mode, g.profile, requestID, start)
+		}
+		return a.printResult(data, g, requestID, start)
+	}
+
 	cfg, err := config.Load(cfgPath)
 	if err != nil {
 		return a.exitWithError(cliError{exit: 3, code: "config_missing", msg: "configuration not found", hint: "Run protonmailcli setup first"}, fallbackMode(g.mode), g.profile, requestID, start)
@@ -153,28 +201,116 @@ func (a App) run(args []string) int {
 	if g.mode == "" {
 		g.mode = output.ModeHuman
 	}
+	if rest[0] == "shell" || rest[0] == "tui" {
+		if runtimeSession != nil {
+			return a.exitWithError(cliError{exit: 2, code: "usage_error", msg: "already running an interactive session"}, g.mode, g.profile, requestID, start)
+		}
+		if rest[0] == "shell" {
+			return a.cmdShell(g, cfg)
+		}
+		if err := a.cmdTUI(g, cfg); err != nil {
+			return a.exitWithError(err, g.mode, g.profile, requestID, start)
+		}
+		return 0
+	}
+	if g.mode == output.ModeNDJSON {
+		runtimeStream = output.NewNDJSON(a.Stdout, g.shaper)
+	}
 
-	st := store.New(g.statePath)
-	state, err := st.Load()
+	data, err := a.apply(rest, g, cfg)
 	if err != nil {
-		return a.exitWithError(cliError{exit: 1, code: "state_error", msg: err.Error()}, g.mode, g.profile, requestID, start)
+		return a.exitWithError(err, g.mode, g.profile, requestID, start)
+	}
+	exitCode := normalizeExitCode(data)
+	if g.dryRun {
+		fmt.Fprintln(a.Stderr, "dry-run: no changes applied")
+	}
+	if code := a.printResult(data, g, requestID, start); code != 0 {
+		return code
 	}
+	return exitCode
+}
 
+func (a App) apply(rest []string, g globalOptions, cfg config.Config) (any, error) {
+	state, err := store.New(g.statePath).Load()
+	if err != nil {
+		return nil, cliError{exit: 1, code: "state_error", msg: err.Error()}
+	}
+	file, err := openStateFile(g.statePath, state)
+	if err != nil {
+		return nil, cliError{exit: 1, code: "state_error", msg: err.Error()}
+	}
+	prev := runtimeState
+	runtimeState = file
+	defer func() { runtimeState = prev }()
 	data, changed, err := a.dispatch(rest, g, cfg, &state)
 	if err != nil {
-		return a.exitWithError(err, g.mode, g.profile, requestID, start)
+		return nil, err
+	}
+	if !g.dryRun && rememberCompletions(&state.Completion, data) {
+		changed = true
 	}
-	exitCode := normalizeExitCode(data)
 	if changed && !g.dryRun {
-		if err := st.Save(state); err != nil {
-			return a.exitWithError(cliError{exit: 1, code: "state_save_failed", msg: err.Error()}, g.mode, g.profile, requestID, start)
+		if err := file.sync(&state, true, nil); err != nil {
+			return nil, cliError{exit: 1, code: "state_save_failed", msg: err.Error()}
 		}
 	}
-	if g.dryRun {
-		fmt.Fprintln(a.Stderr, "dry-run: no changes applied")
+	return data, nil
+}
+
+func (g globalOptions) inherit(base globalOptions) globalOptions {
+	if g.mode == "" {
+		g.mode = base.mode
 	}
-	_ = output.PrintSuccess(a.Stdout, g.mode, data, g.profile, requestID, start)
-	return exitCode
+	if g.profile == "" {
+		g.profile = base.profile
+	}
+	if g.config == "" {
+		g.config = base.config
+	}
+	if g.statePath == "" {
+		g.statePath = base.statePath
+	}
+	if len(g.fields) == 0 {
+		g.fields = base.fields
+	}
+	if g.jq == "" {
+		g.jq = base.jq
+	}
+	if g.template == "" {
+		g.template = base.template
+	}
+	g.noInput = g.noInput || base.noInput
+	g.dryRun = g.dryRun || base.dryRun
+	return g
+}
+
+func (a App) printResult(data any, g globalOptions, requestID string, start time.Time) int {
+	var buf bytes.Buffer
+	err := output.PrintSuccessWithOptions(&buf, g.mode, data, output.Options{Shaper: g.shaper, Warnings: runtimeWarnings, Stream: runtimeStream}, g.profile, requestID, start)
+	var unknown output.UnknownFieldsError
+	shapeFailed := err != nil && !errors.As(err, &unknown) && g.shaper != nil
+	if shapeFailed && runtimeStream == nil {
+		addWarning("output shaping failed, printing the full result: " + err.Error())
+		buf.Reset()
+		err = output.PrintSuccessWithOptions(&buf, g.mode, data, output.Options{Warnings: runtimeWarnings}, g.profile, requestID, start)
+	}
+	if g.mode != output.ModeJSON && g.mode != output.ModeNDJSON {
+		h := output.NewHuman(a.Stderr, output.HumanOptions{})
+		for _, w := range runtimeWarnings {
+			h.Line("warning: %s", w)
+		}
+	}
+	_, _ = a.Stdout.Write(buf.Bytes())
+	switch {
+	case errors.As(err, &unknown):
+		fmt.Fprintln(a.Stderr, "warning: --fields: "+unknown.Error())
+	case shapeFailed && runtimeStream != nil:
+		fmt.Fprintln(a.Stderr, "warning: output shaping failed: "+err.Error())
+	case err != nil:
+		return a.exitWithError(cliError{exit: 2, code: "usage_error", msg: err.Error()}, g.mode, g.profile, requestID, start)
+	}
+	return 0
 }
 
 func fallbackMode(m output.Mode) output.Mode {
@@ -222,109 +358,16 @@ func (a App) exitWithError(err error, mode output.Mode, profile, requestID strin
 			fmt.Fprintln(a.Stderr, ce.hint)
 		}
 		classified := classifyCLIError(ce.code, ce.exit)
-		_ = output.PrintError(a.Stdout, mode, ce.code, ce.msg, ce.hint, classified.Category, classified.Retryable, profile, requestID, start)
+		body := output.ErrBody{Code: ce.code, Message: ce.msg, Hint: ce.hint, Category: classified.Category, Retryable: classified.Retryable, RetryAfterSeconds: ce.retryAfter}
+		_ = output.PrintErrorBody(a.Stdout, mode, body, profile, requestID, start)
 		return ce.exit
 	}
 	_ = output.PrintError(a.Stdout, mode, "runtime_error", err.Error(), "", "runtime", false, profile, requestID, start)
 	return 1
 }
 
-func parseGlobal(args []string) (globalOptions, []string, error) {
-	g := globalOptions{}
-	i := 0
-	for i < len(args) {
-		a := args[i]
-		if !strings.HasPrefix(a, "-") {
-			break
-		}
-		switch a {
-		case "--json":
-			g.mode = output.ModeJSON
-		case "--plain":
-			g.mode = output.ModePlain
-		case "--no-input":
-			g.noInput = true
-		case "--dry-run", "-n":
-			g.dryRun = true
-		case "--help", "-h":
-			g.showHelp = true
-		case "--version":
-			g.showVer = true
-		case "--profile":
-			i++
-			if i >= len(args) {
-				return g, nil, fmt.Errorf("missing value for --profile")
-			}
-			g.profile = args[i]
-		case "--config":
-			i++
-			if i >= len(args) {
-				return g, nil, fmt.Errorf("missing value for --config")
-			}
-			g.config = args[i]
-		case "--state":
-			i++
-			if i >= len(args) {
-				return g, nil, fmt.Errorf("missing value for --state")
-			}
-			g.statePath = args[i]
-		default:
-			return g, nil, fmt.Errorf("unknown global flag: %s", a)
-		}
-		i++
-	}
-	return g, args[i:], nil
-}
-
-func validateNoLateGlobalFlags(rest []string) error {
-	lateGlobals := map[string]bool{
-		"--json":     true,
-		"--plain":    true,
-		"--no-input": true,
-		"--dry-run":  true,
-		"-n":         true,
-	}
-	for _, a := range rest[1:] {
-		if lateGlobals[a] {
-			return cliError{
-				exit: 2,
-				code: "usage_error",
-				msg:  fmt.Sprintf("global flag %s must appear before the resource", a),
-				hint: "Example: protonmailcli --json draft list",
-			}
-		}
-	}
-	return nil
-}
-
-func printHelp(w io.Writer) {
-	fmt.Fprintln(w, `protonmailcli - Proton Mail Bridge CLI
-
-Usage:
-  protonmailcli [global flags] <resource> <action> [args]
-  protonmailcli setup [flags]
-  protonmailcli doctor
-  protonmailcli completion <bash|zsh|fish>
-
-Resources:
-  setup
-  bridge     account list|use
-  auth       login|status|logout
-  draft      create|create-many|update|get|list|delete
-  message    send|send-many|get|follow-up
-  search     messages|drafts
-  mailbox    list|resolve
-  tag        list|create|add|remove
-  filter     list|create|delete|test|apply
-
-Global flags:
-  --json --plain --no-input --dry-run --profile <name> --config <path> --state <path>
-  -h, --help  --version`)
-}
-
 func (a App) cmdSetup(args []string, g globalOptions, cfgPath string) error {
-	fs := flag.NewFlagSet("setup", flag.ContinueOnError)
-	fs.SetOutput(io.Discard)
+	fs := newFlagSet("setup")
 	interactive := fs.Bool("interactive", false, "interactive prompts")
 	nonInteractive := fs.Bool("non-interactive", false, "disable prompts")
 	host := fs.String("bridge-host", "127.0.0.1", "Bridge host")
@@ -333,8 +376,8 @@ func (a App) cmdSetup(args []string, g globalOptions, cfgPath string) error {
 	username := fs.String("username", "", "Bridge username/email")
 	passwordFile := fs.String("smtp-password-file", "", "path to Bridge SMTP password file")
 	profile := fs.String("profile", "default", "Profile name")
-	if err := fs.Parse(args); err != nil {
-		return cliError{exit: 2, code: "usage_error", msg: err.Error()}
+	if err := parseFlags(fs, args); err != nil {
+		return err
 	}
 	useInteractive := *interactive || (!*nonInteractive && !g.noInput && runtimeStdinIsTTY())
 	cfg := config.Default()
@@ -379,63 +422,18 @@ func (a App) cmdSetup(args []string, g globalOptions, cfgPath string) error {
 }
 
 func (a App) dispatch(rest []string, g globalOptions, cfg config.Config, state *model.State) (any, bool, error) {
-	resource := rest[0]
-	action := ""
-	args := []string{}
-	if len(rest) > 1 {
-		action = rest[1]
-		args = rest[2:]
-	}
-
-	switch resource {
-	case "doctor":
-		if action != "" {
-			return nil, false, cliError{exit: 2, code: "usage_error", msg: "doctor does not take an action"}
-		}
-		return cmdDoctor(cfg, state)
-	case "auth":
-		if action == "" {
-			return nil, false, cliError{exit: 2, code: "usage_error", msg: "auth action required"}
-		}
-		return cmdAuth(action, args, g, cfg, state)
-	case "bridge":
-		if action == "" {
-			return nil, false, cliError{exit: 2, code: "usage_error", msg: "bridge action required"}
-		}
-		return cmdBridge(action, args, cfg, state)
-	case "mailbox":
-		if action == "" {
-			return nil, false, cliError{exit: 2, code: "usage_error", msg: "mailbox action required"}
-		}
-		return dispatchMailbox(action, args, g, cfg, state)
-	case "draft":
-		if action == "" {
-			return nil, false, cliError{exit: 2, code: "usage_error", msg: "draft action required"}
+	c, _, ok := lookupCommand(rest)
+	handler, known := resourceHandlers[c.Resource]
+	if !ok || !known {
+		if len(rest) == 0 || len(commandChildren[rest[0]]) == 0 {
+			return nil, false, unknownCommandError(nil, strings.Join(rest[:min(1, len(rest))], ""))
 		}
-		return dispatchDraft(action, args, g, cfg, state)
-	case "message":
-		if action == "" {
-			return nil, false, cliError{exit: 2, code: "usage_error", msg: "message action required"}
-		}
-		return dispatchMessage(action, args, g, cfg, state)
-	case "search":
-		if action == "" {
-			return nil, false, cliError{exit: 2, code: "usage_error", msg: "search action required"}
-		}
-		return dispatchSearch(action, args, g, cfg, state)
-	case "tag":
-		if action == "" {
-			return nil, false, cliError{exit: 2, code: "usage_error", msg: "tag action required"}
-		}
-		return dispatchTag(action, args, g, cfg, state)
-	case "filter":
-		if action == "" {
-			return nil, false, cliError{exit: 2, code: "usage_error", msg: "filter action required"}
-		}
-		return cmdFilter(action, args, g, state)
-	default:
-		return nil, false, cliError{exit: 2, code: "usage_error", msg: "unknown resource: " + resource}
+		return nil, false, unknownCommandError(rest[:1], strings.Join(rest[1:min(2, len(rest))], ""))
 	}
+	if c.Action == "" {
+		return handler("", rest[1:], g, cfg, state)
+	}
+	return handler(rest[1], rest[2:], g, cfg, state)
 }
 
 func dispatchMailbox(action string, args []string, g globalOptions, cfg config.Config, state *model.State) (any, bool, error) {
@@ -446,6 +444,21 @@ func dispatchMailbox(action string, args []string, g globalOptions, cfg config.C
 }
 
 func dispatchDraft(action string, args []string, g globalOptions, cfg config.Config, state *model.State) (any, bool, error) {
+	if action == "lint" {
+		draftID, err := parseDraftLintFlags(args)
+		if err != nil {
+			return nil, false, err
+		}
+		if useLocalStateMode() {
+			return cmdDraftLintLocal(draftID, cfg, state)
+		}
+		c, _, _, err := bridgeClient(cfg, state, "")
+		if err != nil {
+			return nil, false, err
+		}
+		defer c.Close()
+		return cmdDraftLintIMAP(c, draftID, cfg)
+	}
 	if useLocalStateMode() {
 		return cmdDraft(action, args, g, state)
 	}
@@ -473,6 +486,38 @@ func dispatchTag(action string, args []string, g globalOptions, cfg config.Confi
 	return cmdTagIMAP(action, args, g, cfg, state)
 }
 
+func dispatchThread(action string, args []string, g globalOptions, cfg config.Config, state *model.State) (any, bool, error) {
+	req, err := parseThreadFlags(action, args)
+	if err != nil {
+		return nil, false, err
+	}
+	if useLocalStateMode() {
+		return cmdThreadLocal(req, state)
+	}
+	c, _, _, err := bridgeClient(cfg, state, "")
+	if err != nil {
+		return nil, false, err
+	}
+	defer c.Close()
+	return cmdThreadIMAP(c, req, g)
+}
+
+func dispatchPolicy(action string, args []string, g globalOptions, cfg config.Config, state *model.State) (any, bool, error) {
+	draftID, confirmBulk, err := parsePolicyCheckFlags(action, args)
+	if err != nil {
+		return nil, false, err
+	}
+	if useLocalStateMode() {
+		return cmdPolicyCheckLocal(draftID, confirmBulk, cfg, state)
+	}
+	c, _, _, err := bridgeClient(cfg, state, "")
+	if err != nil {
+		return nil, false, err
+	}
+	defer c.Close()
+	return cmdPolicyCheckIMAP(c, draftID, confirmBulk, cfg)
+}
+
 type sliceFlag []string
 
 func (s *sliceFlag) String() string { return strings.Join(*s, ",") }
@@ -481,7 +526,7 @@ func (s *sliceFlag) Set(v string) error {
 	return nil
 }
 
-func cmdDoctor(cfg config.Config, st *model.State) (any, bool, error) {
+func cmdDoctor(cfg config.Config, st *model.State, g globalOptions) (any, bool, error) {
 	timeout := 3 * time.Second
 	smtp := bridge.CheckTCP(cfg.Bridge.Host, cfg.Bridge.SMTPPort, timeout, "smtp")
 	imap := bridge.CheckTCP(cfg.Bridge.Host, cfg.Bridge.IMAPPort, timeout, "imap")
@@ -501,6 +546,12 @@ func cmdDoctor(cfg config.Config, st *model.State) (any, bool, error) {
 			"bridge": map[string]any{"ok": bridgeOK, "checks": []bridge.HealthStatus{smtp, imap}},
 			"auth":   authDetails,
 			"config": configDetails,
+			"quota": map[string]any{
+				"enabled":      rateLimitEnabled(cfg),
+				"maxPerMinute": cfg.RateLimit.MaxPerMinute,
+				"maxPerDay":    cfg.RateLimit.MaxPerDay,
+				"identities":   sendQuotaReport(cfg, st, g.profile, firstNonEmpty(st.Auth.Username, cfg.Bridge.Username)),
+			},
 		},
 	}
 	if !configOK || !authOK {
@@ -555,22 +606,9 @@ func doctorAuthPrereqs(cfg config.Config, st *model.State) (map[string]any, bool
 
 func cmdCompletion(w io.Writer, args []string) error {
 	if len(args) < 1 {
-		return cliError{exit: 2, code: "usage_error", msg: "completion shell required (bash|zsh|fish)"}
-	}
-	shell := args[0]
-	switch shell {
-	case "bash":
-		_, err := fmt.Fprintln(w, bashCompletion())
-		return err
-	case "zsh":
-		_, err := fmt.Fprintln(w, zshCompletion())
-		return err
-	case "fish":
-		_, err := fmt.Fprintln(w, fishCompletion())
-		return err
-	default:
-		return cliError{exit: 2, code: "usage_error", msg: "unsupported shell: " + shell}
+		return cliError{exit: 2, code: "usage_error", msg: "completion shell required (" + strings.Join(completionShells, "|") + ")"}
 	}
+	return writeCompletionScript(w, args[0])
 }
 
 func loadBody(body, bodyFile string, stdinBody bool) (string, error) {
@@ -633,21 +671,3 @@ func isTTY(r *os.File) bool {
 	}
 	return info.Mode()&os.ModeCharDevice != 0
 }
-
-func bashCompletion() string {
-	return `# protonmailcli bash completion
-_protonmailcli_completions()
-{
-  COMPREPLY=( $(compgen -W "setup doctor completion bridge auth draft message search mailbox tag filter" -- "${COMP_WORDS[1]}") )
-}
-complete -F _protonmailcli_completions protonmailcli`
-}
-
-func zshCompletion() string {
-	return `#compdef protonmailcli
-_arguments "1: :((setup doctor completion bridge auth draft message search mailbox tag filter))"`
-}
-
-func fishCompletion() string {
-	return `complete -c protonmailcli -f -a "setup doctor completion bridge auth draft message search mailbox tag filter"`
-}
@@ -203,7 +203,7 @@ func TestSubcommandHelpWritesUsageToInjectedStdout(t *testing.T) {
 	if exit != 0 {
 		t.Fatalf("expected help exit 0 got %d stderr=%s", exit, stderr.String())
 	}
-	if !strings.Contains(stdout.String(), "Usage of message send:") {
+	if !strings.Contains(stdout.String(), "Usage: protonmailcli [global flags] message send [flags]") {
 		t.Fatalf("missing usage on stdout: %s", stdout.String())
 	}
 }
@@ -314,7 +314,7 @@ func TestDoctorPayloadGroups(t *testing.T) {
 	st := &model.State{Auth: model.AuthState{Username: "me@example.com"}}
 	t.Setenv("PMAIL_SMTP_PASSWORD", "secret")
 
-	data, _, err := cmdDoctor(cfg, st)
+	data, _, err := cmdDoctor(cfg, st, globalOptions{})
 	if err == nil {
 		t.Fatalf("expected error due to unreachable ports")
 	}
@@ -485,7 +485,7 @@ func TestBatchHelpDoesNotRequireBridgeAuth(t *testing.T) {
 	if exit != 0 {
 		t.Fatalf("draft create-many --help failed: exit=%d stdout=%s stderr=%s", exit, stdout.String(), stderr.String())
 	}
-	if !strings.Contains(stdout.String(), "\"usage\":\"Usage of draft create-many:") {
+	if !strings.Contains(stdout.String(), "\"usage\":\"Usage: protonmailcli [global flags] draft create-many [flags]") {
 		t.Fatalf("expected usage field in json stdout: %s", stdout.String())
 	}
 	if strings.Contains(stdout.String(), "auth_missing") || strings.Contains(stderr.String(), "auth_missing") {
@@ -498,7 +498,7 @@ func TestBatchHelpDoesNotRequireBridgeAuth(t *testing.T) {
 	if exit != 0 {
 		t.Fatalf("message send-many --help failed: exit=%d stdout=%s stderr=%s", exit, stdout.String(), stderr.String())
 	}
-	if !strings.Contains(stdout.String(), "\"usage\":\"Usage of message send-many:") {
+	if !strings.Contains(stdout.String(), "\"usage\":\"Usage: protonmailcli [global flags] message send-many [flags]") {
 		t.Fatalf("expected usage field in json stdout: %s", stdout.String())
 	}
 	if strings.Contains(stdout.String(), "auth_missing") || strings.Contains(stderr.String(), "auth_missing") {
@@ -521,7 +521,7 @@ func TestLocalBatchHelpIncludesUsageJSON(t *testing.T) {
 	if exit != 0 {
 		t.Fatalf("draft create-many --help failed: exit=%d stdout=%s stderr=%s", exit, stdout.String(), stderr.String())
 	}
-	if !strings.Contains(stdout.String(), "\"usage\":\"Usage of draft create-many:") {
+	if !strings.Contains(stdout.String(), "\"usage\":\"Usage: protonmailcli [global flags] draft create-many [flags]") {
 		t.Fatalf("expected usage field in json stdout: %s", stdout.String())
 	}
 
@@ -531,7 +531,7 @@ func TestLocalBatchHelpIncludesUsageJSON(t *testing.T) {
 	if exit != 0 {
 		t.Fatalf("message send-many --help failed: exit=%d stdout=%s stderr=%s", exit, stdout.String(), stderr.String())
 	}
-	if !strings.Contains(stdout.String(), "\"usage\":\"Usage of message send-many:") {
+	if !strings.Contains(stdout.String(), "\"usage\":\"Usage: protonmailcli [global flags] message send-many [flags]") {
 		t.Fatalf("expected usage field in json stdout: %s", stdout.String())
 	}
 }
@@ -550,7 +550,7 @@ func TestMessageSendHelpJSON(t *testing.T) {
 	if exit != 0 {
 		t.Fatalf("message send --help failed: exit=%d stdout=%s stderr=%s", exit, stdout.String(), stderr.String())
 	}
-	if !strings.Contains(stdout.String(), "\"help\":\"message send\"") || !strings.Contains(stdout.String(), "\"usage\":\"Usage of message send:") {
+	if !strings.Contains(stdout.String(), "\"help\":\"message send\"") || !strings.Contains(stdout.String(), "\"usage\":\"Usage: protonmailcli [global flags] message send [flags]") {
 		t.Fatalf("unexpected help payload: %s", stdout.String())
 	}
 }
@@ -587,7 +587,7 @@ func TestMailboxResolveAndSearchHelpIMAPWithoutAuth(t *testing.T) {
 	if exit != 0 {
 		t.Fatalf("mailbox resolve --help failed: exit=%d stdout=%s stderr=%s", exit, stdout.String(), stderr.String())
 	}
-	if !strings.Contains(stdout.String(), "\"help\":\"mailbox resolve\"") || !strings.Contains(stdout.String(), "\"usage\":\"Usage of mailbox resolve:") {
+	if !strings.Contains(stdout.String(), "\"help\":\"mailbox resolve\"") || !strings.Contains(stdout.String(), "\"usage\":\"Usage: protonmailcli [global flags] mailbox resolve [flags]") {
 		t.Fatalf("unexpected mailbox help payload: %s", stdout.String())
 	}
 	if strings.Contains(stdout.String(), "auth_missing") || strings.Contains(stderr.String(), "auth_missing") {
@@ -600,7 +600,7 @@ func TestMailboxResolveAndSearchHelpIMAPWithoutAuth(t *testing.T) {
 	if exit != 0 {
 		t.Fatalf("search messages --help failed: exit=%d stdout=%s stderr=%s", exit, stdout.String(), stderr.String())
 	}
-	if !strings.Contains(stdout.String(), "\"help\":\"search messages\"") || !strings.Contains(stdout.String(), "\"usage\":\"Usage of search:") {
+	if !strings.Contains(stdout.String(), "\"help\":\"search messages\"") || !strings.Contains(stdout.String(), "\"usage\":\"Usage: protonmailcli [global flags] search messages [flags]") {
 		t.Fatalf("unexpected search help payload: %s", stdout.String())
 	}
 	if strings.Contains(stdout.String(), "auth_missing") || strings.Contains(stderr.String(), "auth_missing") {
@@ -622,7 +622,7 @@ func TestTagAndFilterHelpWithoutAuthOrBridge(t *testing.T) {
 	if exit != 0 {
 		t.Fatalf("tag create --help failed: exit=%d stdout=%s stderr=%s", exit, stdout.String(), stderr.String())
 	}
-	if !strings.Contains(stdout.String(), "\"help\":\"tag create\"") || !strings.Contains(stdout.String(), "\"usage\":\"Usage of tag create:") {
+	if !strings.Contains(stdout.String(), "\"help\":\"tag create\"") || !strings.Contains(stdout.String(), "\"usage\":\"Usage: protonmailcli [global flags] tag create [flags]") {
 		t.Fatalf("unexpected tag help payload: %s", stdout.String())
 	}
 	if strings.Contains(stdout.String(), "auth_missing") || strings.Contains(stderr.String(), "auth_missing") {
@@ -635,7 +635,7 @@ func TestTagAndFilterHelpWithoutAuthOrBridge(t *testing.T) {
 	if exit != 0 {
 		t.Fatalf("filter create --help failed: exit=%d stdout=%s stderr=%s", exit, stdout.String(), stderr.String())
 	}
-	if !strings.Contains(stdout.String(), "\"help\":\"filter create\"") || !strings.Contains(stdout.String(), "\"usage\":\"Usage of filter create:") {
+	if !strings.Contains(stdout.String(), "\"help\":\"filter create\"") || !strings.Contains(stdout.String(), "\"usage\":\"Usage: protonmailcli [global flags] filter create [flags]") {
 		t.Fatalf("unexpected filter help payload: %s", stdout.String())
 	}
 }
@@ -710,7 +710,7 @@ func TestLocalSendManyAllFailedReturnsNonZero(t *testing.T) {
 	}
 }
 
-func TestLateGlobalFlagFailsFast(t *testing.T) {
+func TestGlobalFlagsAfterCommand(t *testing.T) {
 	t.Setenv("PMAIL_USE_LOCAL_STATE", "1")
 	tmp := t.TempDir()
 	cfg := filepath.Join(tmp, "config.toml")
@@ -720,12 +720,58 @@ func TestLateGlobalFlagFailsFast(t *testing.T) {
 	}
 	stdout := &bytes.Buffer{}
 	stderr := &bytes.Buffer{}
-	exit := Run([]string{"draft", "list", "--json", "--config", cfg, "--state", state}, bytes.NewBuffer(nil), stdout, stderr)
-	if exit != 2 {
-		t.Fatalf("expected usage exit 2, got %d stdout=%s stderr=%s", exit, stdout.String(), stderr.String())
+	exit := Run([]string{"draft", "list", "--json", "--config", cfg, "--state=" + state}, bytes.NewBuffer(nil), stdout, stderr)
+	if exit != 0 {
+		t.Fatalf("expected exit 0, got %d stdout=%s stderr=%s", exit, stdout.String(), stderr.String())
+	}
+	if !strings.HasPrefix(stdout.String(), `{"ok":true,"data":{"drafts":[]`) {
+		t.Fatalf("expected JSON envelope: %s", stdout.String())
+	}
+}
+
+func TestLocalDraftListFiltersAndSendManyReplaysIdempotencyKey(t *testing.T) {
+	t.Setenv("PMAIL_USE_LOCAL_STATE", "1")
+	tmp := t.TempDir()
+	cfg := filepath.Join(tmp, "config.toml")
+	state := filepath.Join(tmp, "state.json")
+	if exit := Run([]string{"--json", "--config", cfg, "--state", state, "setup", "--non-interactive", "--username", "me@example.com"}, bytes.NewBuffer(nil), &bytes.Buffer{}, &bytes.Buffer{}); exit != 0 {
+		t.Fatalf("setup failed: %d", exit)
+	}
+	for _, subject := range []string{"invoice march", "lunch"} {
+		if exit := Run([]string{"--json", "--config", cfg, "--state", state, "draft", "create", "--to", "a@example.com", "--subject", subject, "--body", "body"}, bytes.NewBuffer(nil), &bytes.Buffer{}, &bytes.Buffer{}); exit != 0 {
+			t.Fatalf("create failed: %d", exit)
+		}
+	}
+	stdout := &bytes.Buffer{}
+	if exit := Run([]string{"--json", "--config", cfg, "--state", state, "draft", "list", "--query", "invoice"}, bytes.NewBuffer(nil), stdout, &bytes.Buffer{}); exit != 0 {
+		t.Fatalf("draft list --query failed: %d %s", exit, stdout.String())
+	}
+	var list struct {
+		Data struct {
+			Drafts []model.Draft `json:"drafts"`
+			Total  int           `json:"total"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Data.Drafts) != 1 || list.Data.Drafts[0].Subject != "invoice march" || list.Data.Total != 1 {
+		t.Fatalf("unexpected filtered list: %s", stdout.String())
+	}
+
+	draftID := list.Data.Drafts[0].ID
+	sendManifest := filepath.Join(tmp, "sends.json")
+	if err := os.WriteFile(sendManifest, []byte(`[{"draft_id":"`+draftID+`","confirm_send":"`+draftID+`"}]`), 0o600); err != nil {
+		t.Fatalf("write send manifest: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		stdout.Reset()
+		if exit := Run([]string{"--json", "--no-input", "--config", cfg, "--state", state, "message", "send-many", "--file", sendManifest, "--idempotency-key", "batch-1"}, bytes.NewBuffer(nil), stdout, &bytes.Buffer{}); exit != 0 {
+			t.Fatalf("send-many %d failed: %d %s", i, exit, stdout.String())
+		}
 	}
-	if !strings.Contains(stdout.String(), "global flag --json must appear before the resource") {
-		t.Fatalf("unexpected error message: %s", stdout.String())
+	if st := loadTestState(t, state); len(st.Messages) != 1 {
+		t.Fatalf("replayed send-many must not send again: %d messages", len(st.Messages))
 	}
 }
 
@@ -2,9 +2,7 @@ package app
 
 import (
 	"bufio"
-	"flag"
 	"fmt"
-	"io"
 	"os"
 	"strings"
 	"time"
@@ -22,12 +20,11 @@ func cmdAuth(action string, args []string, g globalOptions, cfg config.Config, s
 			PasswordFile: coalesce(st.Auth.PasswordFile, cfg.Bridge.PasswordFile),
 		}, false, nil
 	case "login":
-		fs := flag.NewFlagSet("auth login", flag.ContinueOnError)
-		fs.SetOutput(io.Discard)
+		fs := newFlagSet("auth login")
 		username := fs.String("username", "", "Bridge username/email")
 		passwordFile := fs.String("password-file", "", "path to Bridge password file")
-		if err := fs.Parse(args); err != nil {
-			return nil, false, cliError{exit: 2, code: "usage_error", msg: err.Error()}
+		if err := parseFlags(fs, args); err != nil {
+			return nil, false, err
 		}
 		user := *username
 		passFile := *passwordFile
@@ -103,11 +100,10 @@ func cmdBridge(action string, args []string, cfg config.Config, st *model.State)
 			Active:   strings.TrimSpace(st.Bridge.ActiveUsername),
 		}, false, nil
 	case "use":
-		fs := flag.NewFlagSet("bridge account use", flag.ContinueOnError)
-		fs.SetOutput(io.Discard)
+		fs := newFlagSet("bridge account use")
 		username := fs.String("username", "", "bridge account username/email")
-		if err := fs.Parse(args[1:]); err != nil {
-			return nil, false, cliError{exit: 2, code: "usage_error", msg: err.Error()}
+		if err := parseFlags(fs, args[1:]); err != nil {
+			return nil, false, err
 		}
 		u := strings.TrimSpace(*username)
 		if u == "" {
@@ -36,11 +36,44 @@ func resolveBridgeCredentials(cfg config.Config, st *model.State, passwordFileOv
 	return username, password, nil
 }
 
+type imapSession struct {
+	client   *bridge.IMAPClient
+	username string
+	password string
+}
+
+var runtimeSession *imapSession
+
+func (s *imapSession) close() {
+	if s.client != nil {
+		_ = s.client.Release()
+		s.client = nil
+	}
+}
+
 func bridgeClient(cfg config.Config, st *model.State, passwordFileOverride string) (*bridge.IMAPClient, string, string, error) {
 	username, password, err := resolveBridgeCredentials(cfg, st, passwordFileOverride)
 	if err != nil {
 		return nil, "", "", err
 	}
+	s := runtimeSession
+	if s == nil || passwordFileOverride != "" {
+		return dialBridgeClient(cfg, username, password)
+	}
+	if s.client != nil && s.username == username && s.password == password && s.client.Noop() == nil {
+		return s.client, username, password, nil
+	}
+	s.close()
+	c, _, _, err := dialBridgeClient(cfg, username, password)
+	if err != nil {
+		return nil, "", "", err
+	}
+	c.Hold()
+	s.client, s.username, s.password = c, username, password
+	return c, username, password, nil
+}
+
+func dialBridgeClient(cfg config.Config, username, password string) (*bridge.IMAPClient, string, string, error) {
 	timeout := 30 * time.Second
 	if strings.TrimSpace(cfg.Timeout) != "" {
 		if d, err := time.ParseDuration(cfg.Timeout); err == nil && d > 0 {
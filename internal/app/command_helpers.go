@@ -1,27 +1,26 @@
 package app
 
 import (
-	"errors"
 	"flag"
-	"fmt"
 	"io"
-
-	"protonmailcli/internal/output"
 )
 
-func parseFlagSetWithHelp(fs *flag.FlagSet, args []string, g globalOptions, helpName string, stdout io.Writer) (any, bool, error) {
+var flagSetCreated func(fs *flag.FlagSet)
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if flagSetCreated != nil {
+		flagSetCreated(fs)
+	}
+	return fs
+}
+
+func parseFlags(fs *flag.FlagSet, args []string) error {
 	if err := fs.Parse(args); err != nil {
-		if errors.Is(err, flag.ErrHelp) {
-			usage := usageForFlagSet(fs)
-			if g.mode == output.ModeJSON || g.mode == output.ModePlain {
-				return map[string]any{"help": helpName, "usage": usage}, true, nil
-			}
-			fmt.Fprintln(stdout, usage)
-			return map[string]any{"help": helpName}, true, nil
-		}
-		return nil, false, cliError{exit: 2, code: "usage_error", msg: err.Error()}
+		return cliError{exit: 2, code: "usage_error", msg: err.Error()}
 	}
-	return nil, false, nil
+	return nil
 }
 
 func parseDraftCreateManifestInput(file string, fromStdin bool) ([]draftCreateItem, error) {
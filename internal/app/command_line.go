@@ -0,0 +1,277 @@
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"protonmailcli/internal/config"
+	"protonmailcli/internal/model"
+	"protonmailcli/internal/output"
+)
+
+type resourceHandler func(action string, args []string, g globalOptions, cfg config.Config, st *model.State) (any, bool, error)
+
+var resourceHandlers = map[string]resourceHandler{
+	"doctor": func(_ string, _ []string, g globalOptions, cfg config.Config, st *model.State) (any, bool, error) {
+		return cmdDoctor(cfg, st, g)
+	},
+	"auth": cmdAuth,
+	"bridge": func(action string, args []string, _ globalOptions, cfg config.Config, st *model.State) (any, bool, error) {
+		return cmdBridge(action, args, cfg, st)
+	},
+	"mailbox": dispatchMailbox,
+	"draft":   dispatchDraft,
+	"message": dispatchMessage,
+	"search":  dispatchSearch,
+	"tag":     dispatchTag,
+	"filter": func(action string, args []string, g globalOptions, _ config.Config, st *model.State) (any, bool, error) {
+		return cmdFilter(action, args, g, st)
+	},
+	"outbox": cmdOutbox,
+	"thread": dispatchThread,
+	"policy": dispatchPolicy,
+}
+
+var flagAliases = map[string]string{
+	"to":         "t",
+	"subject":    "s",
+	"body":       "b",
+	"draft-id":   "d",
+	"message-id": "i",
+	"mailbox":    "m",
+	"file":       "f",
+	"query":      "q",
+	"limit":      "l",
+}
+
+func findFlag(list []flagSpec, name string) (flagSpec, bool) {
+	for _, f := range list {
+		if f.Name == name || (f.Short != "" && f.Short == name) {
+			return f, true
+		}
+	}
+	return flagSpec{}, false
+}
+
+func flagNames(lists ...[]flagSpec) []string {
+	var out []string
+	for _, list := range lists {
+		for _, f := range list {
+			out = append(out, "--"+f.Name)
+		}
+	}
+	return out
+}
+
+func parseCommandLine(args []string) (globalOptions, []string, error) {
+	g := globalOptions{}
+	var path, flagArgs, positional []string
+	var cmd commandSpec
+	found := false
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			positional = append(positional, args[i+1:]...)
+			break
+		}
+		if a == "-" || !strings.HasPrefix(a, "-") {
+			if found {
+				positional = append(positional, a)
+				continue
+			}
+			next := strings.Join(append(append([]string{}, path...), a), " ")
+			c, isCommand := findCommand(next)
+			if !isCommand && len(commandChildren[next]) == 0 {
+				return g, nil, unknownCommandError(path, a)
+			}
+			path = append(path, a)
+			if isCommand {
+				cmd, found = c, true
+			}
+			continue
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(a, "-"), "=")
+		var cmdFlag flagSpec
+		isCmdFlag := false
+		if found {
+			cmdFlag, isCmdFlag = findFlag(cmd.Flags, name)
+		}
+		f, isGlobal := findFlag(globalFlags, name)
+		if isCmdFlag {
+			f = cmdFlag
+		} else if !isGlobal {
+			return g, nil, unknownFlagError(a, name, found, cmd)
+		}
+		if f.Type != "bool" && !hasValue {
+			if i+1 >= len(args) {
+				return g, nil, cliError{exit: 2, code: "usage_error", msg: "flag needs an argument: --" + f.Name}
+			}
+			i++
+			value, hasValue = args[i], true
+		}
+		if isCmdFlag {
+			if hasValue {
+				flagArgs = append(flagArgs, "--"+f.Name+"="+value)
+			} else {
+				flagArgs = append(flagArgs, "--"+f.Name)
+			}
+			continue
+		}
+		if err := applyGlobalFlag(&g, f, value, hasValue); err != nil {
+			return g, nil, err
+		}
+	}
+	if g.showHelp || g.showVer {
+		return g, path, nil
+	}
+	if len(path) > 0 && !found {
+		parent := strings.Join(path, " ")
+		return g, nil, cliError{exit: 2, code: "usage_error",
+			msg:  fmt.Sprintf("%s requires an action: %s", parent, strings.Join(commandChildren[parent], ", ")),
+			hint: "Run protonmailcli " + parent + " --help"}
+	}
+	if found && cmd.Args == "" && len(positional) > 0 {
+		return g, nil, cliError{exit: 2, code: "usage_error",
+			msg:  fmt.Sprintf("unexpected argument %q for %s", positional[0], cmd.Command),
+			hint: "Run protonmailcli " + cmd.Command + " --help"}
+	}
+	rest := append(path, flagArgs...)
+	for _, p := range positional {
+		if strings.HasPrefix(p, "-") && p != "-" {
+			rest = append(rest, "--")
+			break
+		}
+	}
+	return g, append(rest, positional...), nil
+}
+
+func applyGlobalFlag(g *globalOptions, f flagSpec, value string, hasValue bool) error {
+	on := true
+	if f.Type == "bool" && hasValue {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return cliError{exit: 2, code: "usage_error", msg: fmt.Sprintf("invalid boolean value %q for --%s", value, f.Name)}
+		}
+		on = b
+	}
+	setMode := func(m output.Mode) {
+		if on {
+			g.mode = m
+		} else if g.mode == m {
+			g.mode = ""
+		}
+	}
+	switch f.Name {
+	case "json":
+		setMode(output.ModeJSON)
+	case "plain":
+		setMode(output.ModePlain)
+	case "ndjson":
+		setMode(output.ModeNDJSON)
+	case "no-input":
+		g.noInput = on
+	case "dry-run":
+		g.dryRun = on
+	case "help":
+		g.showHelp = on
+	case "version":
+		g.showVer = on
+	case "profile":
+		g.profile = value
+	case "config":
+		g.config = value
+	case "state":
+		g.statePath = value
+	case "jq":
+		g.jq = value
+	case "template":
+		g.template = value
+	case "fields":
+		g.fields = nil
+		for _, field := range strings.Split(value, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				g.fields = append(g.fields, field)
+			}
+		}
+	}
+	return nil
+}
+
+func unknownCommandError(path []string, word string) error {
+	if len(path) == 0 {
+		return cliError{exit: 2, code: "usage_error",
+			msg:  fmt.Sprintf("unknown resource %q", word) + didYouMean(word, resourceNames()),
+			hint: "Run protonmailcli --help"}
+	}
+	parent := strings.Join(path, " ")
+	return cliError{exit: 2, code: "usage_error",
+		msg:  fmt.Sprintf("unknown action %q for %s", word, parent) + didYouMean(word, commandChildren[parent]),
+		hint: "Run protonmailcli " + parent + " --help"}
+}
+
+func unknownFlagError(arg, name string, found bool, cmd commandSpec) error {
+	if !found {
+		return cliError{exit: 2, code: "usage_error",
+			msg:  "unknown global flag: " + arg + didYouMean("--"+name, flagNames(globalFlags)),
+			hint: "Run protonmailcli --help"}
+	}
+	return cliError{exit: 2, code: "usage_error",
+		msg:  fmt.Sprintf("unknown flag %s for %s", arg, cmd.Command) + didYouMean("--"+name, flagNames(cmd.Flags, globalFlags)),
+		hint: "Run protonmailcli " + cmd.Command + " --help"}
+}
+
+func didYouMean(word string, candidates []string) string {
+	if s := suggest(word, candidates); s != "" {
+		return fmt.Sprintf("; did you mean %q?", s)
+	}
+	return ""
+}
+
+func suggest(word string, candidates []string) string {
+	best, bestDist := "", max(1, len(strings.TrimLeft(word, "-"))/4)+1
+	for _, c := range candidates {
+		d := editDistance(word, c)
+		if strings.HasPrefix(c, word) && len(strings.TrimLeft(word, "-")) >= 3 {
+			d = min(d, 1)
+		}
+		if d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+func helpFor(rest []string) helpResponse {
+	if c, _, ok := lookupCommand(rest); ok {
+		return helpResponse{Help: c.Command, Usage: commandUsage(c)}
+	}
+	if len(rest) > 0 && len(commandChildren[rest[0]]) > 0 {
+		return helpResponse{Help: rest[0], Usage: resourceUsage(rest[0])}
+	}
+	return helpResponse{Help: "protonmailcli", Usage: rootUsage()}
+}
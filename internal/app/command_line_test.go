@@ -0,0 +1,143 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"protonmailcli/internal/output"
+)
+
+func TestParseCommandLine(t *testing.T) {
+	cases := []struct {
+		args []string
+		mode output.Mode
+		rest string
+	}{
+		{[]string{"draft", "list", "--json"}, output.ModeJSON, "draft list"},
+		{[]string{"--plain", "draft", "create", "-t", "a@example.com", "--subject=Hi", "-n"}, output.ModePlain, "draft create --to=a@example.com --subject=Hi"},
+		{[]string{"search", "drafts", "--query", "invoice", "-q=x"}, "", "search drafts --query=invoice --query=x"},
+		{[]string{"--jq", ".count", "search", "drafts", "--query", "x"}, "", "search drafts --query=x"},
+		{[]string{"message", "send", "-d", "d_1", "--force", "--json=false"}, "", "message send --draft-id=d_1 --force"},
+		{[]string{"schema", "get", "--ndjson", "draft", "list"}, output.ModeNDJSON, "schema get draft list"},
+		{[]string{"bridge", "account", "use", "--username", "-odd"}, "", "bridge account use --username=-odd"},
+		{[]string{"draft", "create", "--body-file", "-", "--to", "a@example.com"}, "", "draft create --body-file=- --to=a@example.com"},
+	}
+	for _, tc := range cases {
+		g, rest, err := parseCommandLine(tc.args)
+		if err != nil {
+			t.Fatalf("%q: %v", tc.args, err)
+		}
+		if g.mode != tc.mode || strings.Join(rest, " ") != tc.rest {
+			t.Fatalf("%q: mode=%q rest=%q", tc.args, g.mode, rest)
+		}
+	}
+	g, _, _ := parseCommandLine([]string{"draft", "create", "-n", "--fields", "id, subject", "--state", "s.json"})
+	if !g.dryRun || strings.Join(g.fields, ",") != "id,subject" || g.statePath != "s.json" {
+		t.Fatalf("global flags after the command were not applied: %+v", g)
+	}
+	if g, _, _ := parseCommandLine([]string{"setup", "--profile", "work"}); g.profile != "" {
+		t.Fatalf("command flag should win over the global flag of the same name: %+v", g)
+	}
+}
+
+func TestParseCommandLineErrors(t *testing.T) {
+	cases := []struct {
+		args []string
+		msg  string
+	}{
+		{[]string{"drafts", "list"}, `unknown resource "drafts"; did you mean "draft"?`},
+		{[]string{"message", "send-mny"}, `unknown action "send-mny" for message; did you mean "send-many"?`},
+		{[]string{"message", "sned"}, `unknown action "sned" for message; did you mean "send"?`},
+		{[]string{"bridge", "acount", "list"}, `unknown action "acount" for bridge; did you mean "account"?`},
+		{[]string{"draft", "create", "--subjet", "x"}, `unknown flag --subjet for draft create; did you mean "--subject"?`},
+		{[]string{"draft", "list", "--jsn"}, `unknown flag --jsn for draft list; did you mean "--json"?`},
+		{[]string{"--verbose", "draft", "list"}, "unknown global flag: --verbose"},
+		{[]string{"draft"}, "draft requires an action: create, create-many, update, get, list, delete, lint"},
+		{[]string{"draft", "get", "d_1"}, `unexpected argument "d_1" for draft get`},
+		{[]string{"draft", "get", "--draft-id"}, "flag needs an argument: --draft-id"},
+		{[]string{"--json=maybe", "draft", "list"}, `invalid boolean value "maybe" for --json`},
+	}
+	for _, tc := range cases {
+		_, _, err := parseCommandLine(tc.args)
+		ce, ok := err.(cliError)
+		if !ok || ce.code != "usage_error" || ce.exit != 2 || ce.msg != tc.msg {
+			t.Errorf("%q: got %#v, want usage_error %q", tc.args, err, tc.msg)
+		}
+	}
+}
+
+func TestHelpIsConsistentInAnyPosition(t *testing.T) {
+	cfg := filepath.Join(t.TempDir(), "missing.toml")
+	want := commandUsage(mustFindCommand(t, "message send"))
+	for _, args := range [][]string{
+		{"--help", "message", "send"},
+		{"message", "--help", "send"},
+		{"message", "send", "-h"},
+		{"message", "send", "--draft-id", "d_1", "--help"},
+	} {
+		stdout := &bytes.Buffer{}
+		if code := Run(append([]string{"--config", cfg}, args...), bytes.NewBuffer(nil), stdout, &bytes.Buffer{}); code != 0 {
+			t.Fatalf("%q: exit %d", args, code)
+		}
+		if stdout.String() != want+"\n" {
+			t.Fatalf("%q: unexpected help:\n%s", args, stdout.String())
+		}
+	}
+	stdout := &bytes.Buffer{}
+	Run([]string{"--config", cfg, "tag", "--json", "-h"}, bytes.NewBuffer(nil), stdout, &bytes.Buffer{})
+	var env struct {
+		Data helpResponse `json:"data"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &env); err != nil || env.Data.Help != "tag" || env.Data.Usage != resourceUsage("tag") {
+		t.Fatalf("unexpected JSON help: %s", stdout.String())
+	}
+}
+
+func TestUsageErrorsCarrySuggestion(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	code := Run([]string{"--json", "message", "send-mny", "--file", "m.json"}, bytes.NewBuffer(nil), stdout, stderr)
+	if code != 2 {
+		t.Fatalf("expected exit 2, got %d", code)
+	}
+	var env struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+			Hint    string `json:"hint"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Error.Code != "usage_error" || !strings.Contains(env.Error.Message, `did you mean "send-many"?`) || env.Error.Hint != "Run protonmailcli message --help" {
+		t.Fatalf("unexpected error envelope: %s", stdout.String())
+	}
+}
+
+func TestShortAliasesAreUnique(t *testing.T) {
+	for _, c := range commandRegistry {
+		seen := map[string]string{"n": "dry-run", "h": "help"}
+		for _, f := range c.Flags {
+			if f.Short == "" {
+				continue
+			}
+			if other, dup := seen[f.Short]; dup {
+				t.Fatalf("%s: -%s is used by --%s and --%s", c.Command, f.Short, other, f.Name)
+			}
+			seen[f.Short] = f.Name
+		}
+	}
+}
+
+func mustFindCommand(t *testing.T, name string) commandSpec {
+	t.Helper()
+	c, ok := findCommand(name)
+	if !ok {
+		t.Fatalf("unknown command %s", name)
+	}
+	return c
+}
@@ -0,0 +1,648 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type flagSpec struct {
+	Name       string `json:"name"`
+	Short      string `json:"short,omitempty"`
+	Type       string `json:"type"`
+	Default    string `json:"default,omitempty"`
+	Usage      string `json:"usage"`
+	Repeatable bool   `json:"repeatable,omitempty"`
+	Required   bool   `json:"required,omitempty"`
+}
+
+func stringFlag(name, def, usage string) flagSpec {
+	return flagSpec{Name: name, Type: "string", Default: def, Usage: usage}
+}
+
+func boolFlag(name, usage string) flagSpec {
+	return flagSpec{Name: name, Type: "bool", Usage: usage}
+}
+
+func intFlag(name string, def int, usage string) flagSpec {
+	f := flagSpec{Name: name, Type: "int", Usage: usage}
+	if def != 0 {
+		f.Default = fmt.Sprint(def)
+	}
+	return f
+}
+
+func durationFlag(name, def, usage string) flagSpec {
+	return flagSpec{Name: name, Type: "duration", Default: def, Usage: usage}
+}
+
+func (f flagSpec) required() flagSpec {
+	f.Required = true
+	return f
+}
+
+func (f flagSpec) repeatable() flagSpec {
+	f.Repeatable = true
+	return f
+}
+
+const (
+	effectRead   = "read"
+	effectMutate = "mutate"
+	effectSend   = "send"
+)
+
+type commandSpec struct {
+	Command        string     `json:"command"`
+	Resource       string     `json:"resource"`
+	Action         string     `json:"action,omitempty"`
+	Args           string     `json:"args,omitempty"`
+	Summary        string     `json:"summary"`
+	Effect         string     `json:"effect"`
+	IdempotencyKey bool       `json:"idempotencyKey"`
+	Requires       []string   `json:"requires"`
+	Flags          []flagSpec `json:"flags"`
+	ExitCodes      []int      `json:"exitCodes"`
+	ErrorCodes     []string   `json:"errorCodes"`
+	extraExits     []int
+}
+
+var globalFlags = []flagSpec{
+	boolFlag("json", "print one JSON envelope on stdout"),
+	boolFlag("plain", "print tab-separated records"),
+	boolFlag("ndjson", "print one JSON object per line"),
+	boolFlag("no-input", "never prompt; fail instead"),
+	{Name: "dry-run", Short: "n", Type: "bool", Usage: "plan the command without applying changes"},
+	stringFlag("profile", "", "config profile name"),
+	stringFlag("config", "", "config file path"),
+	stringFlag("state", "", "state file path"),
+	stringFlag("fields", "", "project record fields (columns for --plain, see command help)"),
+	stringFlag("jq", "", "extract values from data, e.g. .drafts[].id"),
+	stringFlag("template", "", "Go text/template per record, e.g. '{{.Subject}} <{{.From}}>'"),
+	{Name: "help", Short: "h", Type: "bool", Usage: "show help"},
+	boolFlag("version", "print the version"),
+}
+
+var (
+	bodyFlags = []flagSpec{
+		stringFlag("body", "", "body"),
+		stringFlag("body-file", "", "body from file or -"),
+		boolFlag("stdin", "read body from stdin"),
+	}
+	manifestFlags = []flagSpec{
+		stringFlag("file", "", "manifest json path or -"),
+		boolFlag("stdin", "read manifest json from stdin"),
+	}
+	searchFilterFlags = []flagSpec{
+		stringFlag("query", "", "text query"),
+		stringFlag("from", "", "from filter"),
+		stringFlag("to", "", "to filter"),
+		stringFlag("subject", "", "subject filter"),
+		stringFlag("has-tag", "", "imap keyword/tag"),
+		boolFlag("unread", "only unread messages"),
+		stringFlag("since-id", "", "minimum UID (inclusive)"),
+		stringFlag("after", "", "date filter YYYY-MM-DD"),
+		stringFlag("before", "", "date filter YYYY-MM-DD"),
+	}
+	pageFlags = []flagSpec{
+		intFlag("limit", 50, "max results"),
+		stringFlag("cursor", "", "offset cursor"),
+	}
+	idempotencyFlag = stringFlag("idempotency-key", "", "idempotency key; a retry with the same key replays the first result")
+	postSendFlag    = stringFlag("post-send", "", "draft action after send: delete|move-to-sent|keep (default from config)")
+	waitQuotaFlag   = boolFlag("wait-for-quota", "wait for send quota instead of failing with rate_limit")
+	smtpPassFlag    = stringFlag("smtp-password-file", "", "path to smtp password file")
+	messageIDsFlag  = stringFlag("message-id", "", "message id").repeatable().required()
+	sendGuardCodes  = []string{"confirmation_required", "safety_blocked", "policy_blocked", "lint_failed", "dlp_blocked", "rate_limit", "idempotency_conflict", "send_failed", "not_found", "validation_error"}
+)
+
+func flags(groups ...any) []flagSpec {
+	var out []flagSpec
+	for _, g := range groups {
+		switch v := g.(type) {
+		case flagSpec:
+			out = append(out, v)
+		case []flagSpec:
+			out = append(out, v...)
+		}
+	}
+	return out
+}
+
+func codes(groups ...any) []string {
+	var out []string
+	for _, g := range groups {
+		switch v := g.(type) {
+		case string:
+			out = append(out, v)
+		case []string:
+			out = append(out, v...)
+		}
+	}
+	return out
+}
+
+var commandRegistry = []commandSpec{
+	{Resource: "setup", Summary: "Write the config file for a Bridge account", Effect: effectMutate, Requires: []string{},
+		Flags: flags(
+			boolFlag("interactive", "interactive prompts"),
+			boolFlag("non-interactive", "disable prompts"),
+			stringFlag("bridge-host", "127.0.0.1", "Bridge host"),
+			intFlag("bridge-smtp-port", 1025, "Bridge SMTP port"),
+			intFlag("bridge-imap-port", 1143, "Bridge IMAP port"),
+			stringFlag("username", "", "Bridge username/email"),
+			stringFlag("smtp-password-file", "", "path to Bridge SMTP password file"),
+			stringFlag("profile", "default", "Profile name"),
+		),
+		ErrorCodes: codes("validation_error")},
+	{Resource: "doctor", Summary: "Check config, credentials, Bridge ports and send quota", Effect: effectRead, Requires: []string{"config"},
+		ErrorCodes: codes("doctor_prereq_failed", "bridge_unreachable")},
+	{Resource: "completion", Args: "<bash|zsh|fish|powershell>", Summary: "Print a shell completion script", Effect: effectRead, Requires: []string{}},
+	{Resource: "shell", Summary: "Run commands interactively over one Bridge session", Effect: effectMutate, Requires: []string{"config"}},
+	{Resource: "tui", Summary: "Browse and triage mail in a full-screen terminal interface", Effect: effectMutate, Requires: []string{"config"}},
+	{Resource: "commands", Summary: "Describe every command, flag, effect and error code", Effect: effectRead, Requires: []string{}},
+
+	{Resource: "auth", Action: "login", Summary: "Store Bridge credentials for later commands", Effect: effectMutate, Requires: []string{"config"},
+		Flags: flags(
+			stringFlag("username", "", "Bridge username/email").required(),
+			stringFlag("password-file", "", "path to Bridge password file").required(),
+		),
+		ErrorCodes: codes("validation_error")},
+	{Resource: "auth", Action: "status", Summary: "Show stored credentials", Effect: effectRead, Requires: []string{"config"}},
+	{Resource: "auth", Action: "logout", Summary: "Forget stored credentials", Effect: effectMutate, Requires: []string{"config"}},
+
+	{Resource: "bridge", Action: "account list", Summary: "List known Bridge accounts", Effect: effectRead, Requires: []string{"config"}},
+	{Resource: "bridge", Action: "account use", Summary: "Select the active Bridge account", Effect: effectMutate, Requires: []string{"config"},
+		Flags:      flags(stringFlag("username", "", "bridge account username/email").required()),
+		ErrorCodes: codes("validation_error")},
+
+	{Resource: "draft", Action: "create", Summary: "Create a draft", Effect: effectMutate, IdempotencyKey: true, Requires: []string{"config", "bridge"},
+		Flags: flags(
+			stringFlag("to", "", "recipient").repeatable().required(),
+			stringFlag("subject", "", "subject"),
+			bodyFlags,
+			stringFlag("tag", "", "tag (local state only)").repeatable(),
+			idempotencyFlag,
+		),
+		ErrorCodes: codes("validation_error", "idempotency_conflict", "imap_draft_create_failed")},
+	{Resource: "draft", Action: "create-many", Summary: "Create drafts from a JSON manifest", Effect: effectMutate, IdempotencyKey: true, Requires: []string{"config", "bridge"},
+		Flags:      flags(manifestFlags, idempotencyFlag),
+		ErrorCodes: codes("validation_error", "idempotency_conflict", "imap_draft_create_failed"), extraExits: []int{1, 10}},
+	{Resource: "draft", Action: "update", Summary: "Replace a draft's subject or body", Effect: effectMutate, Requires: []string{"config", "bridge"},
+		Flags:      flags(stringFlag("draft-id", "", "draft id").required(), stringFlag("subject", "", "subject"), bodyFlags),
+		ErrorCodes: codes("validation_error", "not_found", "imap_draft_update_failed")},
+	{Resource: "draft", Action: "get", Summary: "Show one draft", Effect: effectRead, Requires: []string{"config", "bridge"},
+		Flags:      flags(stringFlag("draft-id", "", "draft id").required()),
+		ErrorCodes: codes("validation_error", "not_found")},
+	{Resource: "draft", Action: "list", Summary: "List drafts, newest first", Effect: effectRead, Requires: []string{"config", "bridge"},
+		Flags: flags(
+			stringFlag("query", "", "text query"),
+			stringFlag("from", "", "from filter"),
+			stringFlag("to", "", "to filter"),
+			stringFlag("after", "", "date filter YYYY-MM-DD"),
+			stringFlag("before", "", "date filter YYYY-MM-DD"),
+			pageFlags,
+		),
+		ErrorCodes: codes("validation_error", "imap_draft_list_failed")},
+	{Resource: "draft", Action: "delete", Summary: "Delete a draft", Effect: effectMutate, Requires: []string{"config", "bridge"},
+		Flags:      flags(stringFlag("draft-id", "", "draft id").required()),
+		ErrorCodes: codes("validation_error", "not_found", "imap_draft_delete_failed")},
+	{Resource: "draft", Action: "lint", Summary: "Check a draft for common mistakes before sending", Effect: effectRead, Requires: []string{"config", "bridge"},
+		Flags:      flags(stringFlag("draft-id", "", "draft id").required()),
+		ErrorCodes: codes("validation_error", "not_found"), extraExits: []int{7}},
+
+	{Resource: "message", Action: "send", Summary: "Send a draft now or schedule it via the outbox", Effect: effectSend, IdempotencyKey: true, Requires: []string{"config", "bridge"},
+		Flags: flags(
+			stringFlag("draft-id", "", "draft id").required(),
+			stringFlag("confirm-send", "", "confirmation token (the draft id)"),
+			boolFlag("force", "force send without confirm token"),
+			smtpPassFlag,
+			idempotencyFlag,
+			postSendFlag,
+			stringFlag("at", "", "schedule the send via the outbox (RFC3339)"),
+			stringFlag("missed", "send-late", "policy when the schedule is missed: send-late|skip"),
+			waitQuotaFlag,
+			intFlag("confirm-bulk", 0, "recipient count, required above safety.recipients.bulk_threshold"),
+			stringFlag("allow-finding", "", "DLP finding id to send anyway (audited in state)").repeatable(),
+		),
+		ErrorCodes: codes(sendGuardCodes, "config_error")},
+	{Resource: "message", Action: "send-many", Summary: "Send drafts from a JSON manifest", Effect: effectSend, IdempotencyKey: true, Requires: []string{"config", "bridge"},
+		Flags:      flags(manifestFlags, smtpPassFlag, idempotencyFlag, postSendFlag, waitQuotaFlag),
+		ErrorCodes: codes(sendGuardCodes, "config_error"), extraExits: []int{1, 10}},
+	{Resource: "message", Action: "get", Summary: "Show one message", Effect: effectRead, Requires: []string{"config", "bridge"},
+		Flags: flags(
+			stringFlag("message-id", "", "message id").required(),
+			boolFlag("mark-read", "set \\Seen after fetching (fetches never mark read otherwise)"),
+			stringFlag("headers", "", "include raw headers: all or a comma-separated list of names"),
+		),
+		ErrorCodes: codes("validation_error", "not_found", "imap_flag_update_failed")},
+	{Resource: "message", Action: "follow-up", Summary: "Create a threaded follow-up draft for a sent message", Effect: effectMutate, IdempotencyKey: true, Requires: []string{"config", "bridge"},
+		Flags: flags(
+			stringFlag("message-id", "", "message id").required(),
+			stringFlag("to", "", "recipient (defaults to the original recipients)").repeatable(),
+			stringFlag("subject", "", "subject override"),
+			bodyFlags,
+			idempotencyFlag,
+		),
+		ErrorCodes: codes("validation_error", "not_found", "idempotency_conflict", "imap_draft_create_failed")},
+	{Resource: "message", Action: "mark", Summary: "Set or clear read, flagged and answered flags", Effect: effectMutate, Requires: []string{"config", "bridge"},
+		Flags: flags(
+			messageIDsFlag,
+			boolFlag("read", "set \\Seen"),
+			boolFlag("unread", "clear \\Seen"),
+			boolFlag("flagged", "set \\Flagged (starred)"),
+			boolFlag("unflagged", "clear \\Flagged"),
+			boolFlag("answered", "set \\Answered"),
+		),
+		ErrorCodes: codes("validation_error", "not_found", "imap_flag_update_failed")},
+	{Resource: "message", Action: "bulk", Summary: "Apply one action to every message matching a search", Effect: effectMutate, Requires: []string{"config", "bridge"},
+		Flags: flags(
+			stringFlag("action", "", "tag:<name>|untag:<name>|move:<mailbox>|mark:read|mark:unread|mark:flagged|mark:unflagged|archive|trash").required(),
+			stringFlag("mailbox", "INBOX", "mailbox to select messages from"),
+			searchFilterFlags,
+			boolFlag("plan", "list affected messages and the plan hash without applying"),
+			stringFlag("confirm", "", "plan hash from --plan (required to apply)"),
+		),
+		ErrorCodes: codes("validation_error", "confirmation_required", "imap_search_failed", "imap_list_failed", "imap_flag_update_failed", "imap_move_failed")},
+	{Resource: "message", Action: "move", Summary: "Move messages to another mailbox", Effect: effectMutate, IdempotencyKey: true, Requires: []string{"config", "bridge"},
+		Flags:      flags(messageIDsFlag, stringFlag("to-mailbox", "", "destination mailbox id or name").required(), idempotencyFlag),
+		ErrorCodes: codes("validation_error", "not_found", "idempotency_conflict", "imap_list_failed", "imap_move_failed")},
+	{Resource: "message", Action: "copy", Summary: "Copy messages to another mailbox", Effect: effectMutate, IdempotencyKey: true, Requires: []string{"config", "bridge"},
+		Flags:      flags(messageIDsFlag, stringFlag("to-mailbox", "", "destination mailbox id or name").required(), idempotencyFlag),
+		ErrorCodes: codes("validation_error", "not_found", "idempotency_conflict", "imap_list_failed", "imap_copy_failed")},
+	{Resource: "message", Action: "archive", Summary: "Move messages to Archive", Effect: effectMutate, IdempotencyKey: true, Requires: []string{"config", "bridge"},
+		Flags:      flags(messageIDsFlag, idempotencyFlag),
+		ErrorCodes: codes("validation_error", "not_found", "idempotency_conflict", "imap_list_failed", "imap_move_failed")},
+	{Resource: "message", Action: "trash", Summary: "Move messages to Trash", Effect: effectMutate, IdempotencyKey: true, Requires: []string{"config", "bridge"},
+		Flags:      flags(messageIDsFlag, idempotencyFlag),
+		ErrorCodes: codes("validation_error", "not_found", "idempotency_conflict", "imap_list_failed", "imap_move_failed")},
+	{Resource: "message", Action: "delete", Summary: "Permanently delete messages", Effect: effectMutate, IdempotencyKey: true, Requires: []string{"config", "bridge"},
+		Flags:      flags(messageIDsFlag, stringFlag("confirm-delete", "", "confirmation token (message id, or token from --dry-run)"), idempotencyFlag),
+		ErrorCodes: codes("validation_error", "not_found", "idempotency_conflict", "confirmation_required", "imap_list_failed", "imap_delete_failed")},
+	{Resource: "message", Action: "unsubscribe", Summary: "Unsubscribe using the message's List-Unsubscribe header", Effect: effectSend, Requires: []string{"config", "bridge"},
+		Flags: flags(
+			stringFlag("message-id", "", "message id").required(),
+			stringFlag("mailto", "draft", "mailto fallback: draft|send"),
+			stringFlag("confirm-send", "", "message id, required with --mailto send"),
+		),
+		ErrorCodes: codes(sendGuardCodes, "config_error", "unsubscribe_unavailable", "unsubscribe_failed")},
+	{Resource: "message", Action: "undo-send", Summary: "Cancel a send that is still inside the undo window", Effect: effectMutate, Requires: []string{"config"},
+		Flags:      flags(stringFlag("token", "", "undo token returned by message send").required()),
+		ErrorCodes: codes("validation_error", "not_found", "undo_window_expired")},
+
+	{Resource: "search", Action: "messages", Summary: "Search messages", Effect: effectRead, Requires: []string{"config", "bridge"},
+		Flags: flags(
+			searchFilterFlags,
+			stringFlag("mailbox", "", "mailbox name"),
+			boolFlag("auth-fail", "only messages failing SPF, DKIM or DMARC"),
+			pageFlags,
+		),
+		ErrorCodes: codes("validation_error", "imap_search_failed")},
+	{Resource: "search", Action: "drafts", Summary: "Search drafts", Effect: effectRead, Requires: []string{"config", "bridge"},
+		Flags:      flags(searchFilterFlags, pageFlags),
+		ErrorCodes: codes("validation_error", "imap_search_failed")},
+
+	{Resource: "mailbox", Action: "list", Summary: "List mailboxes with message counts", Effect: effectRead, Requires: []string{"config", "bridge"},
+		Flags:      flags(boolFlag("no-status", "skip per-mailbox STATUS counts")),
+		ErrorCodes: codes("imap_list_failed")},
+	{Resource: "mailbox", Action: "resolve", Summary: "Resolve a mailbox id or name to its canonical mailbox", Effect: effectRead, Requires: []string{"config", "bridge"},
+		Flags:      flags(stringFlag("name", "", "mailbox id or name").required()),
+		ErrorCodes: codes("validation_error", "not_found", "imap_list_failed")},
+	{Resource: "mailbox", Action: "create", Summary: "Create a mailbox", Effect: effectMutate, Requires: []string{"config", "bridge"},
+		Flags:      flags(stringFlag("name", "", "mailbox name (use / between hierarchy levels)").required()),
+		ErrorCodes: codes("validation_error", "mailbox_exists", "imap_list_failed", "imap_mailbox_update_failed")},
+	{Resource: "mailbox", Action: "rename", Summary: "Rename a mailbox", Effect: effectMutate, Requires: []string{"config", "bridge"},
+		Flags: flags(
+			stringFlag("name", "", "mailbox id or name").required(),
+			stringFlag("to", "", "new mailbox name (use / between hierarchy levels)").required(),
+		),
+		ErrorCodes: codes("validation_error", "not_found", "mailbox_exists", "safety_blocked", "imap_list_failed", "imap_mailbox_update_failed")},
+	{Resource: "mailbox", Action: "delete", Summary: "Delete a mailbox", Effect: effectMutate, Requires: []string{"config", "bridge"},
+		Flags: flags(
+			stringFlag("name", "", "mailbox id or name").required(),
+			boolFlag("force", "delete even if the mailbox contains messages or child mailboxes"),
+			stringFlag("confirm-delete", "", "mailbox name (required with --force for non-empty mailboxes)"),
+		),
+		ErrorCodes: codes("validation_error", "not_found", "safety_blocked", "mailbox_not_empty", "confirmation_required", "imap_list_failed", "imap_search_failed", "imap_mailbox_update_failed")},
+	{Resource: "mailbox", Action: "subscribe", Summary: "Subscribe to a mailbox", Effect: effectMutate, Requires: []string{"config", "bridge"},
+		Flags:      flags(stringFlag("name", "", "mailbox id or name").required()),
+		ErrorCodes: codes("validation_error", "not_found", "imap_list_failed", "imap_mailbox_update_failed")},
+	{Resource: "mailbox", Action: "unsubscribe", Summary: "Unsubscribe from a mailbox", Effect: effectMutate, Requires: []string{"config", "bridge"},
+		Flags:      flags(stringFlag("name", "", "mailbox id or name").required()),
+		ErrorCodes: codes("validation_error", "not_found", "imap_list_failed", "imap_mailbox_update_failed")},
+
+	{Resource: "tag", Action: "list", Summary: "List tags (IMAP keywords)", Effect: effectRead, Requires: []string{"config", "bridge"},
+		ErrorCodes: codes("imap_tag_list_failed")},
+	{Resource: "tag", Action: "create", Summary: "Create a tag", Effect: effectMutate, Requires: []string{"config", "bridge"},
+		Flags:      flags(stringFlag("name", "", "tag name").required()),
+		ErrorCodes: codes("validation_error")},
+	{Resource: "tag", Action: "add", Summary: "Add a tag to a message", Effect: effectMutate, Requires: []string{"config", "bridge"},
+		Flags:      flags(stringFlag("message-id", "", "message id").required(), stringFlag("tag", "", "tag name").required()),
+		ErrorCodes: codes("validation_error", "not_found", "imap_tag_update_failed")},
+	{Resource: "tag", Action: "remove", Summary: "Remove a tag from a message", Effect: effectMutate, Requires: []string{"config", "bridge"},
+		Flags:      flags(stringFlag("message-id", "", "message id").required(), stringFlag("tag", "", "tag name").required()),
+		ErrorCodes: codes("validation_error", "not_found", "imap_tag_update_failed")},
+
+	{Resource: "filter", Action: "list", Summary: "List local filters", Effect: effectRead, Requires: []string{"config"}},
+	{Resource: "filter", Action: "create", Summary: "Create a local filter that tags matching messages", Effect: effectMutate, Requires: []string{"config"},
+		Flags: flags(
+			stringFlag("name", "", "name").required(),
+			stringFlag("contains", "", "subject/body contains").required(),
+			stringFlag("add-tag", "", "tag to add").required(),
+		),
+		ErrorCodes: codes("validation_error")},
+	{Resource: "filter", Action: "delete", Summary: "Delete a local filter", Effect: effectMutate, Requires: []string{"config"},
+		Flags:      flags(stringFlag("filter-id", "", "filter id").required()),
+		ErrorCodes: codes("validation_error", "not_found")},
+	{Resource: "filter", Action: "test", Summary: "Show which messages a filter would tag", Effect: effectRead, Requires: []string{"config"},
+		Flags:      flags(stringFlag("filter-id", "", "filter id").required()),
+		ErrorCodes: codes("validation_error", "not_found")},
+	{Resource: "filter", Action: "apply", Summary: "Tag the messages a filter matches", Effect: effectMutate, Requires: []string{"config"},
+		Flags:      flags(stringFlag("filter-id", "", "filter id").required()),
+		ErrorCodes: codes("validation_error", "not_found")},
+
+	{Resource: "thread", Action: "list", Summary: "List conversations", Effect: effectRead, Requires: []string{"config", "bridge"},
+		Flags: flags(
+			stringFlag("mailbox", "", "restrict to one mailbox (uses server THREAD when available)"),
+			stringFlag("after", "", "only messages on/after date (YYYY-MM-DD or RFC3339)"),
+			intFlag("limit", 50, "max threads"),
+			stringFlag("cursor", "", "pagination cursor"),
+		),
+		ErrorCodes: codes("validation_error", "imap_thread_failed")},
+	{Resource: "thread", Action: "get", Summary: "Show every message of one conversation", Effect: effectRead, Requires: []string{"config", "bridge"},
+		Flags: flags(
+			stringFlag("mailbox", "", "restrict to one mailbox (uses server THREAD when available)"),
+			stringFlag("after", "", "only messages on/after date (YYYY-MM-DD or RFC3339)"),
+			stringFlag("message-id", "", "any message id in the thread (imap:<mailbox>:<uid>, local id or Message-ID header)"),
+			stringFlag("thread-id", "", "thread id from thread list"),
+		),
+		ErrorCodes: codes("validation_error", "not_found", "imap_thread_failed")},
+
+	{Resource: "outbox", Action: "list", Summary: "List scheduled sends", Effect: effectRead, Requires: []string{"config"},
+		Flags: flags(stringFlag("status", "", "filter by status: queued|sending|sent|failed|skipped|canceled"))},
+	{Resource: "outbox", Action: "cancel", Summary: "Cancel a queued send", Effect: effectMutate, Requires: []string{"config"},
+		Flags:      flags(stringFlag("id", "", "outbox item id").required()),
+		ErrorCodes: codes("validation_error", "not_found", "outbox_item_not_pending")},
+	{Resource: "outbox", Action: "requeue", Summary: "Queue a failed send again", Effect: effectMutate, Requires: []string{"config"},
+		Flags:      flags(stringFlag("id", "", "outbox item id").required()),
+		ErrorCodes: codes("validation_error", "not_found", "outbox_item_not_pending")},
+	{Resource: "outbox", Action: "run", Summary: "Send outbox items that are due", Effect: effectSend, Requires: []string{"config", "bridge"},
+		Flags: flags(
+			boolFlag("loop", "keep running and process items as they become due"),
+			durationFlag("interval", "30s", "poll interval with --loop"),
+			intFlag("iterations", 0, "stop --loop after n polls (0 = until interrupted)"),
+			durationFlag("grace", "15m0s", "how late an item may run before its missed policy applies (default: outbox.grace)"),
+			stringFlag("missed-policy", "", "override missed policy for this run: send-late|skip"),
+		),
+		ErrorCodes: codes("validation_error", "config_error", "outbox_busy")},
+	{Resource: "outbox", Action: "flush", Summary: "Send every due outbox item once", Effect: effectSend, Requires: []string{"config", "bridge"},
+		ErrorCodes: codes("config_error", "outbox_busy")},
+
+	{Resource: "policy", Action: "check", Summary: "Evaluate a draft's recipients against the recipient policy", Effect: effectRead, Requires: []string{"config", "bridge"},
+		Flags: flags(
+			stringFlag("draft-id", "", "draft id").required(),
+			intFlag("confirm-bulk", 0, "recipient count confirmation for bulk sends"),
+		),
+		ErrorCodes: codes("validation_error", "not_found"), extraExits: []int{7}},
+
+	{Resource: "schema", Action: "list", Summary: "List the published response schemas", Effect: effectRead, Requires: []string{}},
+	{Resource: "schema", Action: "get", Args: "<command>", Summary: "Print the JSON Schema of a command's response", Effect: effectRead, Requires: []string{},
+		ErrorCodes: codes("not_found")},
+}
+
+var commandChildren = map[string][]string{}
+
+func init() {
+	for i := range commandRegistry {
+		commandRegistry[i] = completeSpec(commandRegistry[i])
+		parts := strings.Fields(commandRegistry[i].Command)
+		for j := 1; j < len(parts); j++ {
+			parent := strings.Join(parts[:j], " ")
+			if !contains(commandChildren[parent], parts[j]) {
+				commandChildren[parent] = append(commandChildren[parent], parts[j])
+			}
+		}
+	}
+}
+
+func completeSpec(c commandSpec) commandSpec {
+	c.Command = strings.TrimSpace(c.Resource + " " + c.Action)
+	if c.Flags == nil {
+		c.Flags = []flagSpec{}
+	}
+	for i, f := range c.Flags {
+		if short, ok := flagAliases[f.Name]; ok {
+			c.Flags[i].Short = short
+		}
+	}
+	seen := map[string]bool{}
+	var errs []string
+	add := func(list ...string) {
+		for _, code := range list {
+			if !seen[code] {
+				seen[code] = true
+				errs = append(errs, code)
+			}
+		}
+	}
+	add("usage_error")
+	if contains(c.Requires, "config") {
+		add("config_missing", "state_error")
+		if c.Effect != effectRead {
+			add("state_save_failed")
+		}
+	}
+	if contains(c.Requires, "bridge") {
+		add("auth_missing", "config_error", "imap_connect_failed")
+	}
+	add(c.ErrorCodes...)
+	sort.Strings(errs)
+	c.ErrorCodes = errs
+
+	exits := map[int]bool{0: true}
+	for _, code := range errs {
+		exits[errorCodeClasses[code].Exit] = true
+	}
+	for _, e := range c.extraExits {
+		exits[e] = true
+	}
+	c.ExitCodes = nil
+	for e := range exits {
+		c.ExitCodes = append(c.ExitCodes, e)
+	}
+	sort.Ints(c.ExitCodes)
+	return c
+}
+
+func lookupCommand(rest []string) (commandSpec, []string, bool) {
+	var best commandSpec
+	n := 0
+	for _, c := range commandRegistry {
+		words := strings.Fields(c.Command)
+		if len(words) <= n || len(words) > len(rest) {
+			continue
+		}
+		match := true
+		for i, w := range words {
+			if rest[i] != w {
+				match = false
+				break
+			}
+		}
+		if match {
+			best, n = c, len(words)
+		}
+	}
+	if n == 0 {
+		return commandSpec{}, nil, false
+	}
+	return best, rest[n:], true
+}
+
+func findCommand(name string) (commandSpec, bool) {
+	for _, c := range commandRegistry {
+		if c.Command == name {
+			return c, true
+		}
+	}
+	return commandSpec{}, false
+}
+
+func resourceActions(resource string) []string {
+	var out []string
+	for _, c := range commandRegistry {
+		if c.Resource == resource && c.Action != "" {
+			out = append(out, c.Action)
+		}
+	}
+	return out
+}
+
+func resourceNames() []string {
+	var out []string
+	for _, c := range commandRegistry {
+		if !contains(out, c.Resource) {
+			out = append(out, c.Resource)
+		}
+	}
+	return out
+}
+
+func (f flagSpec) placeholder() string {
+	if f.Type == "bool" {
+		return ""
+	}
+	return " " + f.Type
+}
+
+func writeFlagTable(b *strings.Builder, list []flagSpec, withNotes bool) {
+	left := make([]string, len(list))
+	width := 0
+	for i, f := range list {
+		left[i] = "--" + f.Name + f.placeholder()
+		if f.Short != "" {
+			left[i] = "-" + f.Short + ", " + left[i]
+		}
+		width = max(width, len(left[i]))
+	}
+	for i, f := range list {
+		usage := f.Usage
+		if withNotes {
+			var notes []string
+			if f.Default != "" {
+				notes = append(notes, "default "+f.Default)
+			}
+			if f.Repeatable {
+				notes = append(notes, "repeatable")
+			}
+			if f.Required {
+				notes = append(notes, "required")
+			}
+			if len(notes) > 0 {
+				usage += " (" + strings.Join(notes, ", ") + ")"
+			}
+		}
+		fmt.Fprintf(b, "  %-*s  %s\n", width, left[i], usage)
+	}
+}
+
+func commandUsage(c commandSpec) string {
+	var b strings.Builder
+	line := "protonmailcli [global flags] " + c.Command
+	if c.Args != "" {
+		line += " " + c.Args
+	}
+	if len(c.Flags) > 0 {
+		line += " [flags]"
+	}
+	fmt.Fprintf(&b, "Usage: %s\n\n%s\n", line, c.Summary)
+	if len(c.Flags) > 0 {
+		b.WriteString("\nFlags:\n")
+		writeFlagTable(&b, c.Flags, true)
+	}
+	exits := make([]string, len(c.ExitCodes))
+	for i, e := range c.ExitCodes {
+		exits[i] = fmt.Sprint(e)
+	}
+	fmt.Fprintf(&b, "\nEffect: %s\nExit codes: %s", c.Effect, strings.Join(exits, ", "))
+	if c.IdempotencyKey {
+		b.WriteString("\nRetries: safe with --idempotency-key")
+	}
+	return b.String() + plainColumnsHelp(c.Command)
+}
+
+func resourceUsage(resource string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Usage: protonmailcli [global flags] %s <action> [flags]\n\nActions:\n", resource)
+	width := 0
+	for _, a := range resourceActions(resource) {
+		width = max(width, len(a))
+	}
+	for _, c := range commandRegistry {
+		if c.Resource == resource && c.Action != "" {
+			fmt.Fprintf(&b, "  %-*s  %s\n", width, c.Action, c.Summary)
+		}
+	}
+	fmt.Fprintf(&b, "\nRun protonmailcli %s <action> --help for flags.", resource)
+	return b.String()
+}
+
+func rootUsage() string {
+	var b strings.Builder
+	b.WriteString(`protonmailcli - Proton Mail Bridge CLI
+
+Usage:
+  protonmailcli [global flags] <resource> <action> [args]
+  protonmailcli setup [flags]
+  protonmailcli doctor
+  protonmailcli completion <bash|zsh|fish|powershell>
+  protonmailcli shell
+  protonmailcli tui
+
+Resources:
+`)
+	for _, r := range resourceNames() {
+		actions := strings.Join(resourceActions(r), "|")
+		if r == "bridge" {
+			actions = "account " + strings.ReplaceAll(actions, "account ", "")
+		}
+		fmt.Fprintf(&b, "  %s\n", strings.TrimRight(fmt.Sprintf("%-10s %s", r, actions), " "))
+	}
+	b.WriteString("\nGlobal flags (accepted before or after the command):\n")
+	writeFlagTable(&b, globalFlags, false)
+	b.WriteString("\nRun protonmailcli <resource> --help for actions, and protonmailcli commands --json for the full catalog.")
+	return b.String()
+}
+
+type commandsResponse struct {
+	GlobalFlags []flagSpec    `json:"globalFlags"`
+	Commands    []commandSpec `json:"commands"`
+	Count       int           `json:"count"`
+}
+
+func cmdCommands(args []string) (any, error) {
+	if len(args) > 0 {
+		return nil, cliError{exit: 2, code: "usage_error", msg: "commands does not take arguments"}
+	}
+	return commandsResponse{GlobalFlags: globalFlags, Commands: commandRegistry, Count: len(commandRegistry)}, nil
+}
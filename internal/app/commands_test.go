@@ -0,0 +1,184 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"protonmailcli/internal/config"
+	"protonmailcli/internal/model"
+	"protonmailcli/internal/output"
+)
+
+func probeFlagSets(t *testing.T, c commandSpec, cfgPath, local string) map[string]*flag.Flag {
+	t.Helper()
+	var sets []*flag.FlagSet
+	flagSetCreated = func(fs *flag.FlagSet) { sets = append(sets, fs) }
+	defer func() { flagSetCreated = nil }()
+	probe := []string{"--registry-probe"}
+	t.Setenv("PMAIL_USE_LOCAL_STATE", local)
+	state := model.State{}
+	switch c.Resource {
+	case "doctor", "completion", "commands", "shell", "tui":
+	case "setup":
+		_ = App{}.cmdSetup(probe, globalOptions{}, cfgPath)
+	case "schema":
+		_, _ = cmdSchema(c.Action, probe, globalOptions{})
+	default:
+		_, _, _ = App{}.dispatch(append(strings.Fields(c.Command), probe...), globalOptions{mode: output.ModeJSON}, config.Default(), &state)
+	}
+	if len(sets) == 0 {
+		return nil
+	}
+	defined := map[string]*flag.Flag{}
+	for _, fs := range sets {
+		fs.VisitAll(func(f *flag.Flag) { defined[f.Name] = f })
+	}
+	return defined
+}
+
+func flagValueType(f *flag.Flag) (string, bool) {
+	switch fmt.Sprintf("%T", f.Value) {
+	case "*flag.boolValue":
+		return "bool", false
+	case "*flag.intValue":
+		return "int", false
+	case "*flag.durationValue":
+		return "duration", false
+	case "*app.sliceFlag":
+		return "string", true
+	}
+	return "string", false
+}
+
+func TestCommandRegistryMatchesFlagSets(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.toml")
+	rejected := map[string][]string{"search drafts": {"mailbox", "auth-fail"}}
+	for _, c := range commandRegistry {
+		for mode, local := range map[string]string{"imap": "", "local": "1"} {
+			defined := probeFlagSets(t, c, cfgPath, local)
+			if defined == nil {
+				continue
+			}
+			checkFlagSet(t, c, mode, defined, rejected[c.Command])
+		}
+	}
+}
+
+func checkFlagSet(t *testing.T, c commandSpec, mode string, defined map[string]*flag.Flag, rejected []string) {
+	t.Helper()
+	for _, spec := range c.Flags {
+		f, ok := defined[spec.Name]
+		if !ok {
+			t.Errorf("%s (%s): registry flag --%s is not defined by the handler", c.Command, mode, spec.Name)
+			continue
+		}
+		typ, repeat := flagValueType(f)
+		if typ != spec.Type || repeat != spec.Repeatable {
+			t.Errorf("%s (%s) --%s: handler is %s (repeatable=%t), registry says %s (repeatable=%t)", c.Command, mode, spec.Name, typ, repeat, spec.Type, spec.Repeatable)
+		}
+		def := f.DefValue
+		if def == "false" || def == "0" {
+			def = ""
+		}
+		if def != spec.Default {
+			t.Errorf("%s (%s) --%s: handler default %q, registry default %q", c.Command, mode, spec.Name, def, spec.Default)
+		}
+	}
+	for name := range defined {
+		known := contains(rejected, name)
+		for _, spec := range c.Flags {
+			known = known || spec.Name == name
+		}
+		if !known {
+			t.Errorf("%s (%s): handler flag --%s is missing from the registry", c.Command, mode, name)
+		}
+	}
+}
+
+func TestCommandRegistryCodes(t *testing.T) {
+	seen := map[string]bool{}
+	for _, c := range commandRegistry {
+		if seen[c.Command] {
+			t.Fatalf("duplicate command %s", c.Command)
+		}
+		seen[c.Command] = true
+		if c.Effect != effectRead && c.Effect != effectMutate && c.Effect != effectSend {
+			t.Fatalf("%s: unknown effect %q", c.Command, c.Effect)
+		}
+		for _, code := range c.ErrorCodes {
+			if _, ok := errorCodeClasses[code]; !ok {
+				t.Fatalf("%s: error code %s is not classified", c.Command, code)
+			}
+		}
+		hasKey := false
+		for _, f := range c.Flags {
+			hasKey = hasKey || f.Name == "idempotency-key"
+		}
+		if hasKey != c.IdempotencyKey {
+			t.Fatalf("%s: idempotencyKey=%t but --idempotency-key defined=%t", c.Command, c.IdempotencyKey, hasKey)
+		}
+		if _, ok := findResponseSchema(c.Command); !ok && c.Resource != "completion" && c.Resource != "shell" && c.Resource != "tui" {
+			t.Fatalf("%s has no response schema", c.Command)
+		}
+	}
+}
+
+func TestCommandsCatalog(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	if code := Run([]string{"--json", "--config", filepath.Join(t.TempDir(), "missing.toml"), "commands"}, bytes.NewBuffer(nil), stdout, &bytes.Buffer{}); code != 0 {
+		t.Fatalf("commands should work without config: exit=%d %s", code, stdout.String())
+	}
+	var env struct {
+		Data commandsResponse `json:"data"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Data.Count != len(commandRegistry) || len(env.Data.GlobalFlags) == 0 {
+		t.Fatalf("unexpected catalog size: %d commands, %d global flags", env.Data.Count, len(env.Data.GlobalFlags))
+	}
+	var send commandSpec
+	for _, c := range env.Data.Commands {
+		if c.Command == "message send" {
+			send = c
+		}
+	}
+	if send.Effect != "send" || !send.IdempotencyKey || !contains(send.ErrorCodes, "confirmation_required") || !contains(send.Requires, "bridge") {
+		t.Fatalf("unexpected message send entry: %+v", send)
+	}
+	if fmt.Sprint(send.ExitCodes) != "[0 1 2 3 4 5 6 7 8]" {
+		t.Fatalf("unexpected exit codes: %v", send.ExitCodes)
+	}
+	draftID := send.Flags[0]
+	if draftID.Name != "draft-id" || !draftID.Required || draftID.Type != "string" {
+		t.Fatalf("unexpected first flag: %+v", draftID)
+	}
+}
+
+func TestHelpFromRegistry(t *testing.T) {
+	run := func(args ...string) string {
+		stdout := &bytes.Buffer{}
+		Run(append([]string{"--config", filepath.Join(t.TempDir(), "missing.toml")}, args...), bytes.NewBuffer(nil), stdout, &bytes.Buffer{})
+		return stdout.String()
+	}
+	root := run("--help")
+	for _, r := range resourceNames() {
+		if !strings.Contains(root, "\n  "+r) {
+			t.Fatalf("root help misses %s:\n%s", r, root)
+		}
+	}
+	if help := run("filter", "list", "--help"); !strings.HasPrefix(help, "Usage: protonmailcli [global flags] filter list\n") {
+		t.Fatalf("help should not need config:\n%s", help)
+	}
+	if help := run("draft", "--help"); !strings.Contains(help, "  create-many  Create drafts from a JSON manifest\n") {
+		t.Fatalf("resource help should list actions:\n%s", help)
+	}
+	if help := run("bridge", "account", "use", "-h"); !strings.Contains(help, "--username string  bridge account username/email (required)") {
+		t.Fatalf("unexpected bridge help:\n%s", help)
+	}
+}
@@ -0,0 +1,396 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"net/mail"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"protonmailcli/internal/config"
+	"protonmailcli/internal/model"
+	"protonmailcli/internal/store"
+)
+
+const completionCacheLimit = 200
+
+var completionShells = []string{"bash", "zsh", "fish", "powershell"}
+
+type completionEntry struct {
+	path  string
+	words []string
+	leaf  bool
+}
+
+func completionTable() []completionEntry {
+	root := append([]string{}, resourceNames()...)
+	for _, f := range globalFlags {
+		root = append(root, "--"+f.Name)
+	}
+	table := []completionEntry{{path: "", words: root}}
+	index := map[string]int{"": 0}
+	add := func(path, word string) {
+		i, ok := index[path]
+		if !ok {
+			index[path] = len(table)
+			table = append(table, completionEntry{path: path})
+			i = len(table) - 1
+		}
+		if !contains(table[i].words, word) {
+			table[i].words = append(table[i].words, word)
+		}
+	}
+	for _, c := range commandRegistry {
+		parts := strings.Fields(c.Command)
+		for i := 1; i < len(parts); i++ {
+			add(strings.Join(parts[:i], " "), parts[i])
+		}
+		words := []string{}
+		if c.Resource == "completion" {
+			words = append(words, completionShells...)
+		}
+		for _, f := range c.Flags {
+			words = append(words, "--"+f.Name)
+		}
+		words = append(words, "--help")
+		table = append(table, completionEntry{path: c.Command, words: words, leaf: true})
+	}
+	return table
+}
+
+func flagTakesValue(cmdpath, arg string) bool {
+	name := strings.TrimLeft(arg, "-")
+	if name == "" || !strings.HasPrefix(arg, "-") {
+		return false
+	}
+	for _, f := range globalFlags {
+		if f.Name == name || f.Short == name {
+			return f.Type != "bool"
+		}
+	}
+	if c, ok := findCommand(cmdpath); ok {
+		for _, f := range c.Flags {
+			if f.Name == name || f.Short == name {
+				return f.Type != "bool"
+			}
+		}
+	}
+	return false
+}
+
+func (a App) cmdComplete(args []string) int {
+	statePath := config.DefaultStatePath()
+	for i, w := range args {
+		if v, ok := strings.CutPrefix(w, "--state="); ok {
+			statePath = v
+		} else if w == "--state" && i+1 < len(args)-1 {
+			statePath = args[i+1]
+		}
+	}
+	state := model.State{}
+	if _, err := os.Stat(statePath); err == nil {
+		state, _ = store.New(statePath).Load()
+	}
+	for _, c := range completeWords(args, &state) {
+		fmt.Fprintln(a.Stdout, c)
+	}
+	return 0
+}
+
+func completeWords(args []string, st *model.State) []string {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+	words, cur := args[:len(args)-1], args[len(args)-1]
+	entries := map[string]completionEntry{}
+	for _, e := range completionTable() {
+		entries[e.path] = e
+	}
+	cmdpath := ""
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		if strings.HasPrefix(w, "-") || w == "=" {
+			if !strings.Contains(w, "=") && flagTakesValue(cmdpath, w) {
+				i++
+				if i < len(words) && words[i] == "=" {
+					i++
+				}
+			}
+			continue
+		}
+		if e := entries[cmdpath]; !e.leaf && contains(e.words, w) {
+			cmdpath = strings.TrimSpace(cmdpath + " " + w)
+		}
+	}
+	prev := ""
+	if n := len(words); n > 0 {
+		prev = words[n-1]
+		if prev == "=" && n > 1 {
+			prev = words[n-2]
+		}
+	}
+	if name, value, ok := strings.Cut(cur, "="); ok && strings.HasPrefix(name, "-") {
+		var out []string
+		for _, v := range matchPrefix(flagValues(name, st), value) {
+			out = append(out, name+"="+v)
+		}
+		return out
+	}
+	if flagTakesValue(cmdpath, prev) {
+		return matchPrefix(flagValues(prev, st), cur)
+	}
+	e := entries[cmdpath]
+	var candidates []string
+	for _, w := range e.words {
+		if strings.HasPrefix(w, "-") == strings.HasPrefix(cur, "-") {
+			candidates = append(candidates, w)
+		}
+	}
+	if strings.HasPrefix(cur, "-") && cmdpath != "" {
+		candidates = append(candidates, entries[""].words[len(resourceNames()):]...)
+	}
+	return matchPrefix(candidates, cur)
+}
+
+func matchPrefix(values []string, prefix string) []string {
+	var out []string
+	for _, v := range values {
+		if strings.HasPrefix(v, prefix) && !contains(out, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func flagValues(flagName string, st *model.State) []string {
+	cache := st.Completion
+	var out []string
+	switch strings.TrimLeft(flagName, "-") {
+	case "mailbox", "to-mailbox":
+		for _, b := range localMailboxes(st) {
+			out = append(out, b.Name)
+		}
+		out = append(out, cache.Mailboxes...)
+	case "draft-id":
+		for id, d := range st.Drafts {
+			if d.SentAt == nil {
+				out = append(out, id)
+			}
+		}
+		out = append(out, cache.Drafts...)
+	case "tag", "has-tag", "add-tag":
+		for name := range st.Tags {
+			out = append(out, name)
+		}
+		out = append(out, cache.Tags...)
+	case "to", "from":
+		for _, d := range st.Drafts {
+			out = append(out, contactAddresses(d.To...)...)
+		}
+		for _, m := range st.Messages {
+			out = append(out, contactAddresses(m.To...)...)
+			if strings.TrimLeft(flagName, "-") == "from" {
+				out = append(out, contactAddresses(m.From)...)
+			}
+		}
+		out = append(out, cache.Contacts...)
+	case "post-send":
+		out = []string{"delete", "move-to-sent", "keep"}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func contactAddresses(values ...string) []string {
+	var out []string
+	for _, v := range values {
+		if addr, err := mail.ParseAddress(v); err == nil {
+			out = append(out, addr.Address)
+		} else if strings.Contains(v, "@") {
+			out = append(out, strings.TrimSpace(v))
+		}
+	}
+	return out
+}
+
+func rememberValues(list []string, values ...string) ([]string, bool) {
+	changed := false
+	for _, v := range values {
+		if v == "" {
+			continue
+		}
+		if indexOf(list, v) >= 0 {
+			continue
+		}
+		list = append(list, v)
+		changed = true
+	}
+	if len(list) > completionCacheLimit {
+		list = list[len(list)-completionCacheLimit:]
+	}
+	return list, changed
+}
+
+func forgetValues(list []string, values ...string) ([]string, bool) {
+	changed := false
+	for _, v := range values {
+		if i := indexOf(list, v); i >= 0 {
+			list = append(list[:i], list[i+1:]...)
+			changed = true
+		}
+	}
+	return list, changed
+}
+
+func replaceValues(list []string, values []string) ([]string, bool) {
+	if strings.Join(list, "\x00") == strings.Join(values, "\x00") {
+		return list, false
+	}
+	return append([]string{}, values...), true
+}
+
+func indexOf(list []string, v string) int {
+	for i, x := range list {
+		if x == v {
+			return i
+		}
+	}
+	return -1
+}
+
+func rememberCompletions(cache *model.CompletionCache, data any) bool {
+	changed := false
+	apply := func(list *[]string, f func([]string) ([]string, bool)) {
+		var ok bool
+		*list, ok = f(*list)
+		changed = changed || ok
+	}
+	remember := func(list *[]string, values ...string) {
+		apply(list, func(l []string) ([]string, bool) { return rememberValues(l, values...) })
+	}
+	forget := func(list *[]string, values ...string) {
+		apply(list, func(l []string) ([]string, bool) { return forgetValues(l, values...) })
+	}
+	switch d := data.(type) {
+	case mailboxListResponse:
+		names := make([]string, 0, len(d.Mailboxes))
+		for _, b := range d.Mailboxes {
+			names = append(names, b.Name)
+		}
+		apply(&cache.Mailboxes, func(l []string) ([]string, bool) { return replaceValues(l, names) })
+	case mailboxResolveResponse:
+		remember(&cache.Mailboxes, d.Mailbox.Name)
+	case mailboxChangeResponse:
+		if d.DryRun || !d.Changed {
+			break
+		}
+		switch d.Action {
+		case "delete":
+			forget(&cache.Mailboxes, append([]string{d.Mailbox.Name}, d.DeletedChildren...)...)
+		case "rename":
+			forget(&cache.Mailboxes, d.PreviousName)
+			remember(&cache.Mailboxes, d.Mailbox.Name)
+		case "create":
+			remember(&cache.Mailboxes, d.Mailbox.Name)
+		}
+	case draftListResponse:
+		for _, r := range d.Drafts {
+			remember(&cache.Drafts, r.ID)
+			remember(&cache.Contacts, contactAddresses(r.To...)...)
+		}
+	case draftResponse:
+		remember(&cache.Drafts, d.Draft.ID)
+		remember(&cache.Contacts, contactAddresses(d.Draft.To...)...)
+	case draftDeleteResponse:
+		if d.Deleted {
+			forget(&cache.Drafts, d.DraftID)
+		}
+	case imapMessageSendResponse:
+		if d.Sent {
+			forget(&cache.Drafts, d.DraftID)
+		}
+	case messageListResponse:
+		for _, m := range d.Messages {
+			remember(&cache.Contacts, contactAddresses(m.To...)...)
+		}
+	case messageGetResponse:
+		remember(&cache.Contacts, contactAddresses(d.Message.To...)...)
+	case tagListResponse:
+		apply(&cache.Tags, func(l []string) ([]string, bool) { return replaceValues(l, d.Tags) })
+	case tagCreateResponse:
+		remember(&cache.Tags, d.Tag.Name)
+	case tagUpdateResponse:
+		remember(&cache.Tags, d.Tag)
+	}
+	if changed {
+		cache.UpdatedAt = time.Now().UTC()
+	}
+	return changed
+}
+
+func writeCompletionScript(w io.Writer, shell string) error {
+	var script string
+	switch shell {
+	case "bash":
+		script = bashCompletion()
+	case "zsh":
+		script = zshCompletion()
+	case "fish":
+		script = fishCompletion()
+	case "powershell":
+		script = powershellCompletion()
+	default:
+		return cliError{exit: 2, code: "usage_error", msg: "unsupported shell: " + shell, hint: "Use one of: " + strings.Join(completionShells, ", ")}
+	}
+	_, err := fmt.Fprintln(w, script)
+	return err
+}
+
+func bashCompletion() string {
+	return `# protonmailcli bash completion
+_protonmailcli_completions()
+{
+  local IFS=$'\n'
+  COMPREPLY=( $("${COMP_WORDS[0]}" __complete "${COMP_WORDS[@]:1:COMP_CWORD-1}" "${COMP_WORDS[COMP_CWORD]}" 2>/dev/null) )
+}
+complete -F _protonmailcli_completions protonmailcli`
+}
+
+func zshCompletion() string {
+	return `#compdef protonmailcli
+_protonmailcli() {
+  local -a candidates
+  candidates=(${(f)"$(${words[1]} __complete "${(@)words[2,CURRENT-1]}" "${words[CURRENT]}" 2>/dev/null)"})
+  compadd -- "${candidates[@]}"
+}
+if [ "$funcstack[1]" = "_protonmailcli" ]; then
+  _protonmailcli "$@"
+else
+  compdef _protonmailcli protonmailcli
+fi`
+}
+
+func fishCompletion() string {
+	return `function __protonmailcli_complete
+    set -l tokens (commandline -opc)
+    set -l cur (commandline -ct)
+    $tokens[1] __complete $tokens[2..-1] "$cur" 2>/dev/null
+end
+complete -c protonmailcli -f -a '(__protonmailcli_complete)'`
+}
+
+func powershellCompletion() string {
+	return `Register-ArgumentCompleter -Native -CommandName protonmailcli -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+    $words = @($commandAst.CommandElements | Where-Object { $_.Extent.StartOffset -lt $cursorPosition } | ForEach-Object { $_.ToString() })
+    if ($wordToComplete -ne '') {
+        $words = @($words | Select-Object -SkipLast 1)
+    }
+    $rest = @($words | Select-Object -Skip 1)
+    & $words[0] __complete @rest "$wordToComplete" 2>$null | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}`
+}
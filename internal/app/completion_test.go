@@ -0,0 +1,150 @@
+package app
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"protonmailcli/internal/model"
+	"protonmailcli/internal/store"
+)
+
+func complete(t *testing.T, args ...string) []string {
+	t.Helper()
+	stdout := &bytes.Buffer{}
+	if code := Run(append([]string{"__complete"}, args...), bytes.NewBuffer(nil), stdout, &bytes.Buffer{}); code != 0 {
+		t.Fatalf("__complete exit=%d", code)
+	}
+	return strings.Fields(stdout.String())
+}
+
+func TestCompleteCommandsAndFlags(t *testing.T) {
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"dr"}, "draft"},
+		{[]string{"draft", "cr"}, "create create-many"},
+		{[]string{"--json", "bridge", ""}, "account"},
+		{[]string{"bridge", "account", ""}, "list use"},
+		{[]string{"draft", "create", "--su"}, "--subject"},
+		{[]string{"draft", "create", "--no"}, "--no-input"},
+		{[]string{"--profile", "draft", "d"}, "doctor draft"},
+		{[]string{"completion", "p"}, "powershell"},
+		{[]string{"message", "send", "--post-send", "m"}, "move-to-sent"},
+		{[]string{"message", "send", "--post-send=k"}, "--post-send=keep"},
+	}
+	for _, tc := range cases {
+		if got := strings.Join(complete(t, tc.args...), " "); got != tc.want {
+			t.Errorf("__complete %q = %q, want %q", tc.args, got, tc.want)
+		}
+	}
+}
+
+func TestCompleteValuesFromStateAndCache(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	st := store.New(statePath)
+	state, err := st.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.Drafts["d_local"] = model.Draft{ID: "d_local", To: []string{"Alice <alice@example.com>"}}
+	state.Folders["Projects"] = model.Folder{Name: "Projects"}
+	state.Tags["urgent"] = "t_1"
+	state.Messages["m_1"] = model.Message{ID: "m_1", From: "spam@example.net", To: []string{"alice@example.com"}}
+	state.Completion = model.CompletionCache{
+		Mailboxes: []string{"Folders/Receipts"},
+		Drafts:    []string{"Drafts:42"},
+		Tags:      []string{"followup"},
+		Contacts:  []string{"bob@example.com"},
+	}
+	if err := st.Save(state); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"message", "send", "--draft-id", ""}, "Drafts:42 d_local"},
+		{[]string{"message", "move", "--to-mailbox", "P"}, "Projects"},
+		{[]string{"search", "messages", "--mailbox=F"}, "--mailbox=Folders/Receipts"},
+		{[]string{"message", "bulk", "--mailbox", "=", "IN"}, "INBOX"},
+		{[]string{"tag", "add", "--tag", ""}, "followup urgent"},
+		{[]string{"draft", "create", "--to", ""}, "alice@example.com bob@example.com"},
+		{[]string{"search", "messages", "--from", ""}, "alice@example.com bob@example.com spam@example.net"},
+	}
+	for _, tc := range cases {
+		args := append([]string{"--state", statePath}, tc.args...)
+		if got := strings.Join(complete(t, args...), " "); got != tc.want {
+			t.Errorf("__complete %q = %q, want %q", tc.args, got, tc.want)
+		}
+	}
+}
+
+func TestCompleteDoesNotNeedConfigOrState(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "missing", "state.json")
+	if got := complete(t, "--state", statePath, "message", "send", "--draft-id", ""); len(got) != 0 {
+		t.Fatalf("expected no draft ids, got %v", got)
+	}
+	if _, err := os.Stat(statePath); !os.IsNotExist(err) {
+		t.Fatalf("completion must not create state: %v", err)
+	}
+}
+
+func TestRememberCompletions(t *testing.T) {
+	cache := model.CompletionCache{}
+	if !rememberCompletions(&cache, mailboxListResponse{Mailboxes: []mailboxInfo{{Name: "INBOX"}, {Name: "Old"}}}) {
+		t.Fatal("mailbox list should fill the cache")
+	}
+	if rememberCompletions(&cache, mailboxListResponse{Mailboxes: []mailboxInfo{{Name: "INBOX"}, {Name: "Old"}}}) {
+		t.Fatal("unchanged mailbox list should not dirty the cache")
+	}
+	rememberCompletions(&cache, mailboxChangeResponse{Action: "rename", PreviousName: "Old", Mailbox: mailboxInfo{Name: "New"}, Changed: true})
+	rememberCompletions(&cache, mailboxChangeResponse{Action: "create", Mailbox: mailboxInfo{Name: "Planned"}, Changed: true, DryRun: true})
+	if strings.Join(cache.Mailboxes, ",") != "INBOX,New" {
+		t.Fatalf("unexpected mailboxes: %v", cache.Mailboxes)
+	}
+	rememberCompletions(&cache, draftListResponse{Drafts: []draftRecord{{ID: "Drafts:1", To: []string{"Bob <bob@example.com>"}}, {ID: "Drafts:2"}}})
+	rememberCompletions(&cache, draftDeleteResponse{Deleted: true, DraftID: "Drafts:1"})
+	rememberCompletions(&cache, imapMessageSendResponse{Sent: true, DraftID: "Drafts:2"})
+	rememberCompletions(&cache, messageListResponse{Messages: []messageRecord{{From: "carol@example.com", To: []string{"bob@example.com"}}}})
+	rememberCompletions(&cache, tagUpdateResponse{Tag: "later", Changed: true})
+	if len(cache.Drafts) != 0 || strings.Join(cache.Contacts, ",") != "bob@example.com" || strings.Join(cache.Tags, ",") != "later" {
+		t.Fatalf("unexpected cache: %+v", cache)
+	}
+	if rememberCompletions(&cache, draftListResponse{Drafts: []draftRecord{{ID: "Drafts:3"}, {ID: "Drafts:4"}}}) != true {
+		t.Fatal("new draft ids should dirty the cache")
+	}
+	if rememberCompletions(&cache, draftListResponse{Drafts: []draftRecord{{ID: "Drafts:4"}, {ID: "Drafts:3", To: []string{"bob@example.com"}}}}) {
+		t.Fatal("reading values that are already cached must not rewrite state")
+	}
+	for i := 0; i < completionCacheLimit+10; i++ {
+		rememberCompletions(&cache, draftResponse{Draft: draftRecord{ID: "Drafts:" + strings.Repeat("x", i+1)}})
+	}
+	if len(cache.Drafts) != completionCacheLimit {
+		t.Fatalf("cache should be capped at %d, got %d", completionCacheLimit, len(cache.Drafts))
+	}
+}
+
+func TestCompletionScripts(t *testing.T) {
+	for shell, want := range map[string]string{
+		"bash":       "complete -F _protonmailcli_completions protonmailcli",
+		"zsh":        "compdef _protonmailcli protonmailcli",
+		"fish":       "complete -c protonmailcli",
+		"powershell": "Register-ArgumentCompleter -Native -CommandName protonmailcli",
+	} {
+		stdout := &bytes.Buffer{}
+		if code := Run([]string{"completion", shell}, bytes.NewBuffer(nil), stdout, &bytes.Buffer{}); code != 0 {
+			t.Fatalf("completion %s exit=%d", shell, code)
+		}
+		if !strings.Contains(stdout.String(), want) || !strings.Contains(stdout.String(), "__complete") {
+			t.Fatalf("unexpected %s script:\n%s", shell, stdout.String())
+		}
+	}
+	stdout := &bytes.Buffer{}
+	if code := Run([]string{"--json", "completion", "tcsh"}, bytes.NewBuffer(nil), stdout, &bytes.Buffer{}); code != 2 || !strings.Contains(stdout.String(), "powershell") {
+		t.Fatalf("unsupported shell should be a usage error: %d %s", code, stdout.String())
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"protonmailcli/internal/jsonschema"
 	"protonmailcli/internal/model"
 	"protonmailcli/internal/store"
 )
@@ -91,6 +92,10 @@ func TestContractFixtures(t *testing.T) {
 				if ok, path, reason := subsetMatch(expected, got, "$"); !ok {
 					t.Fatalf("stdout json mismatch at %s: %s\nstdout=%s", path, reason, stdout.String())
 				}
+				schema := loadPublishedSchema(t, root, parseFixtureArgs(fx.Command))
+				if errs := jsonschema.Validate(schema, got); len(errs) > 0 {
+					t.Fatalf("stdout does not match published schema:\n%s\nstdout=%s", strings.Join(errs, "\n"), stdout.String())
+				}
 			}
 
 			for _, s := range fx.Expected.StderrContains {
@@ -130,15 +135,55 @@ func seedContractState(t *testing.T, statePath string) {
 				Body:    "fixture",
 				SentAt:  now,
 			},
+			"m_456": {
+				ID:      "m_456",
+				Mailbox: "Projects",
+				From:    "sender@example.com",
+				To:      []string{"a@example.com"},
+				Subject: "filed fixture msg",
+				Body:    "fixture",
+				SentAt:  now,
+			},
 		},
 		Tags:    map[string]string{},
 		Filters: map[string]model.Filter{},
+		Folders: map[string]model.Folder{
+			"Projects": {Name: "Projects", Subscribed: true, CreatedAt: now},
+		},
 	}
 	if err := store.New(statePath).Save(st); err != nil {
 		t.Fatal(err)
 	}
 }
 
+func loadPublishedSchema(t *testing.T, root string, args []string) map[string]any {
+	t.Helper()
+	var words []string
+	for _, a := range args {
+		if strings.HasPrefix(a, "-") {
+			break
+		}
+		words = append(words, a)
+	}
+	for n := len(words); n > 0; n-- {
+		rs, ok := findResponseSchema(strings.Join(words[:n], " "))
+		if !ok {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(root, "docs", "schemas", "responses", schemaFileName(rs.command)))
+		if err != nil {
+			t.Fatalf("published schema missing, run scripts/update-schemas.sh: %v", err)
+		}
+		var schema map[string]any
+		if err := json.Unmarshal(b, &schema); err != nil {
+			t.Fatalf("invalid published schema %s: %v", schemaFileName(rs.command), err)
+		}
+		return schema
+	}
+	t.Fatalf("no response schema for command %q", strings.Join(words, " "))
+	return nil
+}
+
 func parseFixtureArgs(cmd string) []string {
 	parts := strings.Fields(cmd)
 	if len(parts) == 0 {
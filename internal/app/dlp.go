@@ -0,0 +1,204 @@
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"protonmailcli/internal/bridge"
+	"protonmailcli/internal/config"
+	"protonmailcli/internal/model"
+)
+
+type dlpDetector struct {
+	kind  string
+	re    *regexp.Regexp
+	valid func(string) bool
+}
+
+var dlpDetectors = []dlpDetector{
+	{kind: "aws_access_key", re: regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{kind: "aws_secret_key", re: regexp.MustCompile(`(?i)aws_?secret_?access_?key\s*[:=]\s*["']?[A-Za-z0-9/+=]{40}`)},
+	{kind: "private_key", re: regexp.MustCompile(`-----BEGIN (?:[A-Z0-9]+ )*PRIVATE KEY( BLOCK)?-----`)},
+	{kind: "jwt", re: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{5,}\.eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]{10,}`)},
+	{kind: "github_token", re: regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36}\b`)},
+	{kind: "credit_card", re: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`), valid: luhnValid},
+}
+
+type dlpContent struct {
+	subject     string
+	body        string
+	attachments []bridge.Attachment
+}
+
+type dlpFinding struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Location string `json:"location"`
+	Redacted string `json:"redacted"`
+}
+
+func dlpContentFromDraft(d model.Draft) dlpContent {
+	return dlpContent{subject: d.Subject, body: d.Body}
+}
+
+func dlpContentFromBridge(d bridge.DraftMessage) dlpContent {
+	return dlpContent{subject: d.Subject, body: d.Body, attachments: d.Attachments}
+}
+
+func luhnValid(s string) bool {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		n := int(digits[i] - '0')
+		if (len(digits)-i)%2 == 0 {
+			if n *= 2; n > 9 {
+				n -= 9
+			}
+		}
+		sum += n
+	}
+	return sum%10 == 0
+}
+
+func redactSecret(s string) string {
+	if len(s) <= 8 {
+		return strings.Repeat("*", len(s))
+	}
+	return s[:4] + strings.Repeat("*", min(len(s)-8, 16)) + s[len(s)-4:]
+}
+
+func dlpFindingID(key []byte, kind, match string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(kind + "\x00" + match))
+	return "f_" + hex.EncodeToString(mac.Sum(nil)[:6])
+}
+
+func dlpStateKey(st *model.State, g globalOptions) ([]byte, error) {
+	if st.DLPKey == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		key := hex.EncodeToString(b)
+		set := func(s *model.State) error {
+			if s.DLPKey == "" {
+				s.DLPKey = key
+			}
+			return nil
+		}
+		if file := stateFileFor(g, st); file != nil {
+			if err := file.commit(st, set); err != nil {
+				return nil, err
+			}
+		} else {
+			_ = set(st)
+		}
+	}
+	return hex.DecodeString(st.DLPKey)
+}
+
+func dlpDetectorsFor(cfg config.Config) ([]dlpDetector, error) {
+	detectors := append([]dlpDetector{}, dlpDetectors...)
+	names := make([]string, 0, len(cfg.DLP.Patterns))
+	for name := range cfg.DLP.Patterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		re, err := regexp.Compile(cfg.DLP.Patterns[name])
+		if err != nil {
+			return nil, cliError{exit: 3, code: "config_error", msg: "invalid [dlp.patterns] " + name + ": " + err.Error()}
+		}
+		detectors = append(detectors, dlpDetector{kind: "custom:" + name, re: re})
+	}
+	return detectors, nil
+}
+
+func scanDLP(cfg config.Config, key []byte, content dlpContent) ([]dlpFinding, error) {
+	if !cfg.DLP.Enabled {
+		return nil, nil
+	}
+	detectors, err := dlpDetectorsFor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	type part struct{ location, text string }
+	parts := []part{{"subject", content.subject}, {"body", content.body}}
+	for i, a := range content.attachments {
+		parts = append(parts, part{"attachment:" + firstNonEmpty(a.Filename, "#"+strconv.Itoa(i+1)), string(a.Data)})
+	}
+	findings := []dlpFinding{}
+	seen := map[string]bool{}
+	for _, p := range parts {
+		for _, d := range detectors {
+			for _, m := range d.re.FindAllString(p.text, -1) {
+				if d.valid != nil && !d.valid(m) {
+					continue
+				}
+				id := dlpFindingID(key, d.kind, m)
+				if seen[id+p.location] {
+					continue
+				}
+				seen[id+p.location] = true
+				findings = append(findings, dlpFinding{ID: id, Type: d.kind, Location: p.location, Redacted: redactSecret(m)})
+			}
+		}
+	}
+	return findings, nil
+}
+
+func enforceDLP(cfg config.Config, st *model.State, g globalOptions, content dlpContent, allow []string) ([]dlpFinding, error) {
+	if !cfg.DLP.Enabled {
+		return nil, nil
+	}
+	key, err := dlpStateKey(st, g)
+	if err != nil {
+		return nil, err
+	}
+	findings, err := scanDLP(cfg, key, content)
+	if err != nil {
+		return nil, err
+	}
+	allowed := map[string]bool{}
+	for _, id := range allow {
+		allowed[strings.TrimSpace(id)] = true
+	}
+	var blocked []string
+	var overridden []dlpFinding
+	for _, f := range findings {
+		if allowed[f.ID] {
+			overridden = append(overridden, f)
+			continue
+		}
+		blocked = append(blocked, f.Type+" in "+f.Location+" ("+f.Redacted+") ["+f.ID+"]")
+	}
+	if len(blocked) > 0 {
+		return nil, cliError{exit: 7, code: "dlp_blocked", msg: "outbound content scan: " + strings.Join(blocked, "; "), hint: "Remove the sensitive content, or pass --allow-finding <id> per finding to send anyway (recorded in state)"}
+	}
+	return overridden, nil
+}
+
+func recordDLPOverrides(st *model.State, g globalOptions, draftID string, overridden []dlpFinding) {
+	if g.dryRun {
+		return
+	}
+	now := time.Now().UTC()
+	for _, f := range overridden {
+		st.DLPOverrides = append(st.DLPOverrides, model.DLPOverride{FindingID: f.ID, Type: f.Type, Location: f.Location, Redacted: f.Redacted, DraftID: draftID, Profile: firstNonEmpty(g.profile, "default"), AllowedAt: now})
+	}
+}
@@ -0,0 +1,175 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"protonmailcli/internal/bridge"
+	"protonmailcli/internal/config"
+	"protonmailcli/internal/model"
+)
+
+var (
+	lintPlaceholderRe = regexp.MustCompile(`\{\{[^{}]*\}\}`)
+	lintPossibleRe    = regexp.MustCompile(`\$\{[^{}]+\}|\[\[[^\[\]]+\]\]`)
+	lintAttachmentRe  = regexp.MustCompile(`(?i)\b(attach(ed|ment|ments|ing)?|enclosed)\b`)
+	lintReplyRe       = regexp.MustCompile(`(?i)^\s*(re|aw|sv)\s*:`)
+	lintGreetingRe    = regexp.MustCompile(`(?i)^(hi|hello|hey|dear|good (morning|afternoon|evening)|greetings|to whom)\b`)
+	lintSignOffRe     = regexp.MustCompile(`(?i)^(--\s*$|(best|kind|warm)?\s*regards\b|best\b|thanks\b|thank you\b|cheers\b|sincerely\b|all the best\b)`)
+)
+
+type lintInput struct {
+	subject       string
+	body          string
+	inReplyTo     string
+	references    string
+	hasAttachment bool
+}
+
+type lintFinding struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+func lintInputFromDraft(d model.Draft) lintInput {
+	return lintInput{subject: d.Subject, body: d.Body, inReplyTo: d.InReplyTo}
+}
+
+func lintInputFromBridge(d bridge.DraftMessage) lintInput {
+	in := lintInput{subject: d.Subject, body: d.Body, inReplyTo: d.InReplyTo, references: d.References, hasAttachment: len(d.Attachments) > 0}
+	for _, h := range d.Headers {
+		v := strings.ToLower(h.Value)
+		if (strings.EqualFold(h.Name, "Content-Type") && strings.HasPrefix(v, "multipart/mixed")) || (strings.EqualFold(h.Name, "Content-Disposition") && strings.HasPrefix(v, "attachment")) {
+			in.hasAttachment = true
+		}
+	}
+	return in
+}
+
+func lintSeverity(cfg config.Config, rule string) string {
+	switch s := strings.ToLower(strings.TrimSpace(cfg.Lint.Rules[rule])); s {
+	case "error", "warning":
+		return s
+	default:
+		return "off"
+	}
+}
+
+func bodyLines(body string) []string {
+	lines := []string{}
+	for _, line := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, ">") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func lintDraft(cfg config.Config, in lintInput) []lintFinding {
+	findings := []lintFinding{}
+	add := func(rule, msg string) {
+		if sev := lintSeverity(cfg, rule); sev != "off" {
+			findings = append(findings, lintFinding{Rule: rule, Severity: sev, Message: msg})
+		}
+	}
+	lines := bodyLines(in.body)
+	if strings.TrimSpace(in.subject) == "" {
+		add("empty_subject", "subject is empty")
+	}
+	if !in.hasAttachment && lintAttachmentRe.MatchString(strings.Join(lines, "\n")) {
+		add("attachment_mentioned", "body mentions an attachment but none is attached")
+	}
+	if m := lintPlaceholderRe.FindAllString(in.subject+"\n"+in.body, 3); len(m) > 0 {
+		add("unresolved_placeholder", "unresolved template placeholder "+strings.Join(m, ", "))
+	}
+	if m := lintPossibleRe.FindAllString(in.subject+"\n"+in.body, 3); len(m) > 0 {
+		add("possible_placeholder", "text looks like a template placeholder "+strings.Join(m, ", "))
+	}
+	if len(lines) > 0 && !lintGreetingRe.MatchString(lines[0]) && !(strings.HasSuffix(lines[0], ",") && len(lines[0]) <= 40) {
+		add("missing_greeting", "body does not start with a greeting")
+	}
+	if len(lines) > 0 {
+		signed := false
+		for _, line := range lines[max(0, len(lines)-5):] {
+			if lintSignOffRe.MatchString(line) {
+				signed = true
+			}
+		}
+		if !signed {
+			add("missing_signature", "body has no sign-off or signature")
+		}
+	}
+	if lintReplyRe.MatchString(in.subject) && strings.TrimSpace(in.inReplyTo) == "" && strings.TrimSpace(in.references) == "" {
+		add("reply_without_thread", "reply subject without In-Reply-To/References headers; the reply will not thread")
+	}
+	if cfg.Lint.MaxBodyBytes > 0 && len(in.body) > cfg.Lint.MaxBodyBytes {
+		add("oversized_body", fmt.Sprintf("body is %d bytes (limit %d)", len(in.body), cfg.Lint.MaxBodyBytes))
+	}
+	return findings
+}
+
+func enforceDraftLint(cfg config.Config, in lintInput, draftID string) error {
+	var errs []string
+	for _, f := range lintDraft(cfg, in) {
+		if f.Severity == "error" {
+			errs = append(errs, f.Rule+": "+f.Message)
+			continue
+		}
+		addWarning(draftID + ": " + f.Rule + ": " + f.Message)
+	}
+	if len(errs) > 0 {
+		return cliError{exit: 7, code: "lint_failed", msg: "draft lint failed: " + strings.Join(errs, "; "), hint: "Fix the draft, or lower the rule severity in [lint]; see draft lint --draft-id " + draftID}
+	}
+	return nil
+}
+
+func parseDraftLintFlags(args []string) (string, error) {
+	fs := newFlagSet("draft lint")
+	draftID := fs.String("draft-id", "", "draft id")
+	if err := parseFlags(fs, args); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(*draftID) == "" {
+		return "", cliError{exit: 2, code: "validation_error", msg: "--draft-id required"}
+	}
+	return strings.TrimSpace(*draftID), nil
+}
+
+func draftLintResult(cfg config.Config, draftID string, in lintInput, source string) draftLintResponse {
+	resp := draftLintResponse{DraftID: draftID, Findings: lintDraft(cfg, in), Source: source}
+	for _, f := range resp.Findings {
+		if f.Severity == "error" {
+			resp.Errors++
+		} else {
+			resp.Warnings++
+		}
+	}
+	resp.OK = resp.Errors == 0
+	return resp
+}
+
+func cmdDraftLintLocal(draftID string, cfg config.Config, st *model.State) (any, bool, error) {
+	uid, err := parseRequiredUID(draftID, "--draft-id")
+	if err != nil {
+		return nil, false, cliError{exit: 2, code: "validation_error", msg: err.Error()}
+	}
+	d, ok := st.Drafts[uid]
+	if !ok {
+		return nil, false, cliError{exit: 5, code: "not_found", msg: "draft not found"}
+	}
+	return draftLintResult(cfg, d.ID, lintInputFromDraft(d), "local"), false, nil
+}
+
+func cmdDraftLintIMAP(c imapPolicyClient, draftID string, cfg config.Config) (any, bool, error) {
+	uid, err := parseRequiredUID(draftID, "--draft-id")
+	if err != nil {
+		return nil, false, cliError{exit: 2, code: "validation_error", msg: err.Error()}
+	}
+	d, err := c.GetDraft(uid)
+	if err != nil {
+		return nil, false, cliError{exit: 5, code: "not_found", msg: "draft not found"}
+	}
+	return draftLintResult(cfg, imapDraftID(uid), lintInputFromBridge(d), "imap"), false, nil
+}
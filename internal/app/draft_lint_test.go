@@ -0,0 +1,141 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"protonmailcli/internal/bridge"
+	"protonmailcli/internal/config"
+	"protonmailcli/internal/model"
+)
+
+func lintRules(findings []lintFinding) string {
+	rules := make([]string, 0, len(findings))
+	for _, f := range findings {
+		rules = append(rules, f.Rule+"="+f.Severity)
+	}
+	return strings.Join(rules, ",")
+}
+
+func TestLintDraftRules(t *testing.T) {
+	cfg := config.Default()
+	cfg.Lint.Rules["missing_greeting"] = "warning"
+	cfg.Lint.Rules["missing_signature"] = "warning"
+	cfg.Lint.MaxBodyBytes = 80
+	got := lintDraft(cfg, lintInput{subject: "Re: plan", body: "See the attached plan for {{name}}.\n" + strings.Repeat("x", 60)})
+	want := "attachment_mentioned=warning,unresolved_placeholder=error,missing_greeting=warning,missing_signature=warning,reply_without_thread=warning,oversized_body=warning"
+	if lintRules(got) != want {
+		t.Fatalf("unexpected findings:\n got %s\nwant %s", lintRules(got), want)
+	}
+	clean := lintInput{subject: "Re: plan", body: "Hi Anna,\n\nPlan attached.\n\n> quoted attachment\n\nBest,\nBob", inReplyTo: "<m1@example.com>", hasAttachment: true}
+	if got := lintDraft(cfg, clean); len(got) != 0 {
+		t.Fatalf("expected clean draft, got %+v", got)
+	}
+	if got := lintDraft(config.Default(), lintInput{body: "hello"}); lintRules(got) != "empty_subject=warning" {
+		t.Fatalf("greeting/signature rules should be off by default: %+v", got)
+	}
+	if got := lintDraft(config.Default(), lintInput{subject: "s", body: "run echo ${HOME}, see [[Runbook]]"}); lintRules(got) != "possible_placeholder=warning" {
+		t.Fatalf("${...} and [[...]] should only warn: %+v", got)
+	}
+}
+
+func TestLintInputFromBridgeDetectsAttachments(t *testing.T) {
+	in := lintInputFromBridge(bridge.DraftMessage{Subject: "s", References: "<a@b>", Headers: []bridge.HeaderField{{Name: "Content-Type", Value: "multipart/mixed; boundary=x"}}})
+	if !in.hasAttachment || in.references != "<a@b>" {
+		t.Fatalf("unexpected lint input: %+v", in)
+	}
+}
+
+func TestSendBlockedByLintErrors(t *testing.T) {
+	calls := stubOutboxSend(t, nil)
+	st := outboxState()
+	st.Drafts["d_1"] = model.Draft{ID: "d_1", To: []string{"a@example.com"}, Subject: "Hello {{name}}", Body: "see attachment"}
+	cfg := config.Default()
+	_, _, err := cmdMessage("send", []string{"--draft-id", "d_1", "--confirm-send", "d_1"}, globalOptions{}, cfg, st)
+	if errorCodeFromErr(err, "") != "lint_failed" || exitCodeFromErr(err) != 7 || !strings.Contains(err.Error(), "{{name}}") || *calls != 0 {
+		t.Fatalf("expected lint_failed, got %v calls=%d", err, *calls)
+	}
+	cfg.Lint.Rules["unresolved_placeholder"] = "off"
+	runtimeWarnings = nil
+	if _, _, err := cmdMessage("send", []string{"--draft-id", "d_1", "--confirm-send", "d_1"}, globalOptions{}, cfg, st); err != nil || *calls != 1 {
+		t.Fatalf("send with rule off failed: %v calls=%d", err, *calls)
+	}
+	if len(runtimeWarnings) != 1 || !strings.Contains(runtimeWarnings[0], "attachment_mentioned") {
+		t.Fatalf("expected attachment warning, got %v", runtimeWarnings)
+	}
+	runtimeWarnings = nil
+}
+
+func TestSendManyReportsLintFailurePerItem(t *testing.T) {
+	stubOutboxSend(t, nil)
+	st := outboxState()
+	st.Drafts["d_1"] = model.Draft{ID: "d_1", To: []string{"a@example.com"}, Subject: "Ok", Body: "fine"}
+	st.Drafts["d_2"] = model.Draft{ID: "d_2", To: []string{"b@example.com"}, Subject: "Hi {{first}}", Body: "fine"}
+	manifest := filepath.Join(t.TempDir(), "send.json")
+	if err := os.WriteFile(manifest, []byte(`[{"draft_id":"d_1","confirm_send":"d_1"},{"draft_id":"d_2","confirm_send":"d_2"}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	data, _, err := cmdMessage("send-many", []string{"--file", manifest}, globalOptions{}, config.Default(), st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := data.(batchResultResponse)
+	if resp.Success != 1 || resp.Results[1].ErrorCode != "lint_failed" || !strings.Contains(resp.Results[1].Error, "{{first}}") {
+		t.Fatalf("unexpected batch result: %+v", resp)
+	}
+}
+
+func TestDraftLintCommandAndWarningsEnvelope(t *testing.T) {
+	st := outboxState()
+	st.Drafts["d_1"] = model.Draft{ID: "d_1", To: []string{"a@example.com"}, Body: "{{TODO}}"}
+	data, changed, err := cmdDraftLintLocal("d_1", config.Default(), st)
+	if err != nil || changed {
+		t.Fatalf("lint: changed=%v err=%v", changed, err)
+	}
+	resp := data.(draftLintResponse)
+	if resp.OK || resp.ExitCode() != 7 || resp.Errors != 1 || resp.Warnings != 1 || lintRules(resp.Findings) != "empty_subject=warning,unresolved_placeholder=error" {
+		t.Fatalf("unexpected lint response: %+v", resp)
+	}
+	if _, _, err := cmdDraftLintLocal("d_9", config.Default(), st); errorCodeFromErr(err, "") != "not_found" {
+		t.Fatalf("expected not_found, got %v", err)
+	}
+
+	stubOutboxSend(t, nil)
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.toml")
+	statePath := filepath.Join(dir, "state.json")
+	base := []string{"--json", "--no-input", "--config", cfgPath, "--state", statePath}
+	if exit := Run(append(base, "setup", "--non-interactive", "--username", "me@example.com"), bytes.NewBuffer(nil), &bytes.Buffer{}, &bytes.Buffer{}); exit != 0 {
+		t.Fatalf("setup failed: %d", exit)
+	}
+	stdout := &bytes.Buffer{}
+	if exit := Run(append(base, "draft", "create", "--to", "a@example.com", "--body", "no subject"), bytes.NewBuffer(nil), stdout, &bytes.Buffer{}); exit != 0 {
+		t.Fatalf("draft create failed: %d", exit)
+	}
+	var created struct {
+		Data struct {
+			Draft model.Draft `json:"draft"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &created); err != nil || created.Data.Draft.ID == "" {
+		t.Fatalf("decode draft create: %v %s", err, stdout.String())
+	}
+	id := created.Data.Draft.ID
+	stdout.Reset()
+	if exit := Run(append(base, "message", "send", "--draft-id", id, "--confirm-send", id), bytes.NewBuffer(nil), stdout, &bytes.Buffer{}); exit != 0 {
+		t.Fatalf("send exit %d: %s", exit, stdout.String())
+	}
+	var env struct {
+		Warnings []string `json:"warnings"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &env); err != nil {
+		t.Fatal(err)
+	}
+	if len(env.Warnings) != 1 || !strings.Contains(env.Warnings[0], "empty_subject") {
+		t.Fatalf("expected empty_subject warning in envelope, got %s", stdout.String())
+	}
+}
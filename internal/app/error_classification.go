@@ -3,32 +3,50 @@ package app
 type classifiedError struct {
 	Category  string
 	Retryable bool
+	Exit      int
 }
 
 var errorCodeClasses = map[string]classifiedError{
-	"usage_error":            {Category: "usage", Retryable: false},
-	"validation_error":       {Category: "usage", Retryable: false},
-	"config_missing":         {Category: "config", Retryable: false},
-	"config_error":           {Category: "config", Retryable: false},
-	"state_error":            {Category: "runtime", Retryable: false},
-	"state_save_failed":      {Category: "runtime", Retryable: false},
-	"auth_missing":           {Category: "auth", Retryable: false},
-	"not_found":              {Category: "not_found", Retryable: false},
-	"idempotency_conflict":   {Category: "conflict", Retryable: false},
-	"confirmation_required":  {Category: "safety", Retryable: false},
-	"safety_blocked":         {Category: "safety", Retryable: false},
-	"doctor_prereq_failed":   {Category: "config", Retryable: false},
-	"rate_limit":             {Category: "rate_limit", Retryable: true},
-	"bridge_unreachable":     {Category: "transient", Retryable: true},
-	"send_failed":            {Category: "transient", Retryable: true},
-	"imap_connect_failed":    {Category: "transient", Retryable: true},
-	"imap_search_failed":     {Category: "transient", Retryable: true},
-	"imap_list_failed":       {Category: "transient", Retryable: true},
-	"imap_tag_update_failed": {Category: "transient", Retryable: true},
-	"imap_draft_create_failed": {
-		Category:  "transient",
-		Retryable: true,
-	},
+	"usage_error":                {Category: "usage", Retryable: false, Exit: 2},
+	"validation_error":           {Category: "usage", Retryable: false, Exit: 2},
+	"config_missing":             {Category: "config", Retryable: false, Exit: 3},
+	"config_error":               {Category: "config", Retryable: false, Exit: 3},
+	"state_error":                {Category: "runtime", Retryable: false, Exit: 1},
+	"state_save_failed":          {Category: "runtime", Retryable: false, Exit: 1},
+	"auth_missing":               {Category: "auth", Retryable: false, Exit: 3},
+	"not_found":                  {Category: "not_found", Retryable: false, Exit: 5},
+	"idempotency_conflict":       {Category: "conflict", Retryable: false, Exit: 6},
+	"confirmation_required":      {Category: "safety", Retryable: false, Exit: 7},
+	"safety_blocked":             {Category: "safety", Retryable: false, Exit: 7},
+	"policy_blocked":             {Category: "safety", Retryable: false, Exit: 7},
+	"lint_failed":                {Category: "safety", Retryable: false, Exit: 7},
+	"dlp_blocked":                {Category: "safety", Retryable: false, Exit: 7},
+	"mailbox_not_empty":          {Category: "safety", Retryable: false, Exit: 7},
+	"outbox_item_not_pending":    {Category: "conflict", Retryable: false, Exit: 6},
+	"outbox_busy":                {Category: "conflict", Retryable: true, Exit: 6},
+	"undo_window_expired":        {Category: "conflict", Retryable: false, Exit: 6},
+	"mailbox_exists":             {Category: "conflict", Retryable: false, Exit: 6},
+	"doctor_prereq_failed":       {Category: "config", Retryable: false, Exit: 3},
+	"rate_limit":                 {Category: "rate_limit", Retryable: true, Exit: 8},
+	"bridge_unreachable":         {Category: "transient", Retryable: true, Exit: 4},
+	"send_failed":                {Category: "transient", Retryable: true, Exit: 4},
+	"imap_connect_failed":        {Category: "transient", Retryable: true, Exit: 4},
+	"imap_search_failed":         {Category: "transient", Retryable: true, Exit: 4},
+	"imap_list_failed":           {Category: "transient", Retryable: true, Exit: 4},
+	"imap_tag_update_failed":     {Category: "transient", Retryable: true, Exit: 4},
+	"imap_move_failed":           {Category: "transient", Retryable: true, Exit: 4},
+	"imap_copy_failed":           {Category: "transient", Retryable: true, Exit: 4},
+	"imap_delete_failed":         {Category: "transient", Retryable: true, Exit: 4},
+	"imap_flag_update_failed":    {Category: "transient", Retryable: true, Exit: 4},
+	"imap_mailbox_update_failed": {Category: "transient", Retryable: true, Exit: 4},
+	"unsubscribe_unavailable":    {Category: "not_found", Retryable: false, Exit: 5},
+	"unsubscribe_failed":         {Category: "transient", Retryable: true, Exit: 4},
+	"imap_thread_failed":         {Category: "transient", Retryable: true, Exit: 4},
+	"imap_draft_create_failed":   {Category: "transient", Retryable: true, Exit: 4},
+	"imap_draft_update_failed":   {Category: "transient", Retryable: true, Exit: 4},
+	"imap_draft_delete_failed":   {Category: "transient", Retryable: true, Exit: 4},
+	"imap_draft_list_failed":     {Category: "transient", Retryable: true, Exit: 4},
+	"imap_tag_list_failed":       {Category: "transient", Retryable: true, Exit: 4},
 }
 
 func classifyCLIError(code string, exit int) classifiedError {
@@ -37,20 +55,20 @@ func classifyCLIError(code string, exit int) classifiedError {
 	}
 	switch exit {
 	case 2:
-		return classifiedError{Category: "usage", Retryable: false}
+		return classifiedError{Category: "usage", Retryable: false, Exit: exit}
 	case 3:
-		return classifiedError{Category: "config", Retryable: false}
+		return classifiedError{Category: "config", Retryable: false, Exit: exit}
 	case 4:
-		return classifiedError{Category: "transient", Retryable: true}
+		return classifiedError{Category: "transient", Retryable: true, Exit: exit}
 	case 5:
-		return classifiedError{Category: "not_found", Retryable: false}
+		return classifiedError{Category: "not_found", Retryable: false, Exit: exit}
 	case 6:
-		return classifiedError{Category: "conflict", Retryable: false}
+		return classifiedError{Category: "conflict", Retryable: false, Exit: exit}
 	case 7:
-		return classifiedError{Category: "safety", Retryable: false}
+		return classifiedError{Category: "safety", Retryable: false, Exit: exit}
 	case 8:
-		return classifiedError{Category: "rate_limit", Retryable: true}
+		return classifiedError{Category: "rate_limit", Retryable: true, Exit: exit}
 	default:
-		return classifiedError{Category: "runtime", Retryable: false}
+		return classifiedError{Category: "runtime", Retryable: false, Exit: exit}
 	}
 }
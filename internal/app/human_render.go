@@ -0,0 +1,345 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"protonmailcli/internal/model"
+	"protonmailcli/internal/output"
+)
+
+type helpResponse struct {
+	Help  string `json:"help"`
+	Usage string `json:"usage,omitempty"`
+}
+
+func (r helpResponse) RenderHuman(h *output.Human) {
+	if r.Usage != "" {
+		h.Text(r.Usage)
+	}
+}
+
+func humanDate(s string) string {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return humanTime(t)
+	}
+	return s
+}
+
+func humanTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+func unreadMark(flags []string) string {
+	for _, f := range flags {
+		if strings.EqualFold(f, `\Seen`) {
+			return ""
+		}
+	}
+	return "*"
+}
+
+func countLine(h *output.Human, count, total int, noun, cursor string) {
+	if count == 1 {
+		noun = strings.TrimSuffix(noun, "s")
+	}
+	line := fmt.Sprintf("%d %s", count, noun)
+	if total > count {
+		line = fmt.Sprintf("%d of %d %s", count, total, noun)
+	}
+	if cursor != "" {
+		line += " (next: --cursor " + cursor + ")"
+	}
+	h.Line("%s", line)
+}
+
+func renderDraftDetail(h *output.Human, fields []output.Field, body string) {
+	h.Fields(fields...)
+	if body != "" {
+		h.Blank()
+		h.Text(body)
+	}
+}
+
+func (r setupResponse) RenderHuman(h *output.Human) {
+	h.Line("configured %s", r.ConfigPath)
+}
+
+func renderSent(h *output.Human, draftID, sendPath, messageID string, post *postSendResult) {
+	h.Line("%s %s via %s", h.OK("sent"), draftID, firstNonEmpty(sendPath, "smtp"))
+	fields := []output.Field{{Label: "Message-ID", Value: messageID}}
+	if post != nil {
+		fields = append(fields, output.Field{Label: "Draft", Value: post.Action}, output.Field{Label: "Sent copy", Value: post.SentCopy}, output.Field{Label: "Post-send error", Value: post.Error})
+	}
+	h.Fields(fields...)
+}
+
+func (r messageSendResponse) RenderHuman(h *output.Human) {
+	renderSent(h, firstNonEmpty(r.Message.DraftID, r.Message.ID), r.SendPath, r.SentMessageID, r.PostSend)
+}
+
+func (r imapMessageSendResponse) RenderHuman(h *output.Human) {
+	renderSent(h, r.DraftID, r.SendPath, r.SentMessageID, r.PostSend)
+}
+
+func (r sendPlanResponse) RenderHuman(h *output.Human) {
+	h.Line("dry-run: would send %s via %s (post-send: %s)", r.DraftID, firstNonEmpty(r.SendPath, "smtp"), firstNonEmpty(r.PostSendAction, "default"))
+}
+
+func (r messagePendingSendResponse) RenderHuman(h *output.Human) {
+	h.Line("%s %s until %s", h.Warn("pending"), r.DraftID, humanDate(r.PendingUntil))
+	h.Line("undo: protonmailcli message undo-send --token %s", r.UndoToken)
+	h.Line("deliver: %s (after %s)", r.FlushWith, humanDate(r.PendingUntil))
+}
+
+func (r messageScheduleResponse) RenderHuman(h *output.Human) {
+	verb := "scheduled"
+	if r.DryRun {
+		verb = "dry-run: would schedule"
+	}
+	h.Line("%s %s for %s as %s (missed: %s)", verb, r.Item.DraftID, humanTime(r.Item.At), r.Item.ID, r.Item.MissedPolicy)
+}
+
+func (r draftListResponse) RenderHuman(h *output.Human) {
+	rows := make([][]string, 0, len(r.Drafts))
+	for _, d := range r.Drafts {
+		rows = append(rows, []string{d.ID, strings.Join(d.To, ", "), d.Subject, humanDate(d.Date)})
+	}
+	h.Table([]string{"ID", "TO", "SUBJECT", "DATE"}, rows)
+	countLine(h, r.Count, r.Total, "drafts", r.NextCursor)
+}
+
+func renderLocalDrafts(h *output.Human, drafts []model.Draft, count, total int, next string) {
+	rows := make([][]string, 0, len(drafts))
+	for _, d := range drafts {
+		rows = append(rows, []string{d.ID, strings.Join(d.To, ", "), d.Subject, humanTime(d.UpdatedAt)})
+	}
+	h.Table([]string{"ID", "TO", "SUBJECT", "UPDATED"}, rows)
+	countLine(h, count, total, "drafts", next)
+}
+
+func (r localDraftListResponse) RenderHuman(h *output.Human) {
+	renderLocalDrafts(h, r.Drafts, r.Count, r.Total, r.NextCursor)
+}
+
+func (r localSearchDraftsResponse) RenderHuman(h *output.Human) {
+	renderLocalDrafts(h, r.Drafts, r.Count, r.Total, r.NextCursor)
+}
+
+func (r draftResponse) RenderHuman(h *output.Human) {
+	d := r.Draft
+	renderDraftDetail(h, []output.Field{{Label: "Draft", Value: d.ID}, {Label: "From", Value: d.From}, {Label: "To", Value: strings.Join(d.To, ", ")}, {Label: "Subject", Value: d.Subject}, {Label: "Date", Value: humanDate(d.Date)}}, d.Body)
+}
+
+func (r localDraftResponse) RenderHuman(h *output.Human) {
+	d := r.Draft
+	renderDraftDetail(h, []output.Field{{Label: "Draft", Value: d.ID}, {Label: "To", Value: strings.Join(d.To, ", ")}, {Label: "Cc", Value: strings.Join(d.CC, ", ")}, {Label: "Bcc", Value: strings.Join(d.BCC, ", ")}, {Label: "Subject", Value: d.Subject}, {Label: "Updated", Value: humanTime(d.UpdatedAt)}}, d.Body)
+}
+
+func (r messageFollowUpResponse) RenderHuman(h *output.Human) {
+	draftResponse{Draft: r.Draft}.RenderHuman(h)
+}
+
+func (r localMessageFollowUpResponse) RenderHuman(h *output.Human) {
+	localDraftResponse{Draft: r.Draft}.RenderHuman(h)
+}
+
+func (r messageListResponse) RenderHuman(h *output.Human) {
+	rows := make([][]string, 0, len(r.Messages))
+	for _, m := range r.Messages {
+		rows = append(rows, []string{unreadMark(m.Flags), m.ID, m.From, m.Subject, humanDate(m.Date)})
+	}
+	h.Table([]string{"", "ID", "FROM", "SUBJECT", "DATE"}, rows)
+	countLine(h, r.Count, r.Total, "messages", r.NextCursor)
+}
+
+func (r localSearchMessagesResponse) RenderHuman(h *output.Human) {
+	rows := make([][]string, 0, len(r.Messages))
+	for _, m := range r.Messages {
+		rows = append(rows, []string{m.ID, m.From, m.Subject, humanTime(m.SentAt)})
+	}
+	h.Table([]string{"ID", "FROM", "SUBJECT", "DATE"}, rows)
+	countLine(h, r.Count, r.Total, "messages", r.NextCursor)
+}
+
+func renderAuthentication(auth *authenticationResult) string {
+	if auth == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s (spf=%s dkim=%s dmarc=%s)", auth.Verdict, auth.SPF, auth.DKIM, auth.DMARC)
+}
+
+func (r messageGetResponse) RenderHuman(h *output.Human) {
+	m := r.Message
+	renderDraftDetail(h, []output.Field{{Label: "Message", Value: m.ID}, {Label: "From", Value: m.From}, {Label: "To", Value: strings.Join(m.To, ", ")}, {Label: "Date", Value: humanDate(m.Date)}, {Label: "Subject", Value: m.Subject}, {Label: "Flags", Value: strings.Join(m.Flags, " ")}, {Label: "Auth", Value: renderAuthentication(m.Authentication)}}, m.Body)
+}
+
+func (r localMessageGetResponse) RenderHuman(h *output.Human) {
+	m := r.Message
+	renderDraftDetail(h, []output.Field{{Label: "Message", Value: m.ID}, {Label: "From", Value: m.From}, {Label: "To", Value: strings.Join(m.To, ", ")}, {Label: "Date", Value: humanTime(m.SentAt)}, {Label: "Subject", Value: m.Subject}, {Label: "Mailbox", Value: m.Mailbox}, {Label: "Flags", Value: strings.Join(m.Flags, " ")}, {Label: "Tags", Value: strings.Join(m.Tags, ", ")}, {Label: "Auth", Value: renderAuthentication(m.Authentication)}}, m.Body)
+}
+
+func (r mailboxListResponse) RenderHuman(h *output.Human) {
+	optional := func(n *int) string {
+		if n == nil {
+			return ""
+		}
+		return strconv.Itoa(*n)
+	}
+	rows := make([][]string, 0, len(r.Mailboxes))
+	for _, m := range r.Mailboxes {
+		rows = append(rows, []string{m.Name, m.Kind, optional(m.Total), optional(m.Unread), m.SpecialUse})
+	}
+	h.Table([]string{"NAME", "KIND", "TOTAL", "UNREAD", "SPECIAL"}, rows)
+}
+
+func (r batchResultResponse) RenderHuman(h *output.Human) {
+	rows := make([][]string, 0, len(r.Results))
+	for _, it := range r.Results {
+		status, detail := h.OK("ok"), firstNonEmpty(it.SendPath, it.CreatePath)
+		switch {
+		case !it.OK:
+			status, detail = h.Bad(it.ErrorCode), it.Error
+		case it.DryRun:
+			status = "dry-run"
+		case it.Pending:
+			status, detail = "pending", "until "+humanDate(it.PendingUntil)+", undo token "+it.UndoToken
+		}
+		rows = append(rows, []string{strconv.Itoa(it.Index), status, firstNonEmpty(it.DraftID, it.Subject), detail})
+	}
+	h.Table([]string{"#", "STATUS", "ITEM", "DETAIL"}, rows)
+	summary := fmt.Sprintf("%d of %d succeeded", r.Success, r.Count)
+	if r.Failed > 0 {
+		summary += ", " + h.Bad(fmt.Sprintf("%d failed", r.Failed))
+	}
+	h.Line("%s", summary)
+}
+
+func (r threadListResponse) RenderHuman(h *output.Human) {
+	rows := make([][]string, 0, len(r.Threads))
+	for _, t := range r.Threads {
+		rows = append(rows, []string{t.ID, strconv.Itoa(t.Count), strconv.Itoa(t.Unread), t.Subject, humanDate(t.LastActivity)})
+	}
+	h.Table([]string{"ID", "MSGS", "UNREAD", "SUBJECT", "LAST ACTIVITY"}, rows)
+	countLine(h, r.Count, r.Total, "threads", r.NextCursor)
+}
+
+func (r threadGetResponse) RenderHuman(h *output.Human) {
+	h.Title(r.Thread.Subject)
+	h.Fields(output.Field{Label: "Thread", Value: r.Thread.ID}, output.Field{Label: "Participants", Value: strings.Join(r.Thread.Participants, ", ")})
+	h.Blank()
+	rows := make([][]string, 0, len(r.Messages))
+	for _, m := range r.Messages {
+		rows = append(rows, []string{unreadMark(m.Flags), m.ID, strings.Repeat("  ", m.Depth) + m.From, humanDate(m.Date), m.Mailbox})
+	}
+	h.Table([]string{"", "ID", "FROM", "DATE", "MAILBOX"}, rows)
+}
+
+func (r tagListResponse) RenderHuman(h *output.Human) {
+	for _, t := range r.Tags {
+		h.Line("%s", t)
+	}
+	countLine(h, r.Count, r.Count, "tags", "")
+}
+
+func (r filterListResponse) RenderHuman(h *output.Human) {
+	rows := make([][]string, 0, len(r.Filters))
+	for _, f := range r.Filters {
+		rows = append(rows, []string{f.ID, f.Name, f.Contains, f.AddTag})
+	}
+	h.Table([]string{"ID", "NAME", "CONTAINS", "TAG"}, rows)
+}
+
+func (r bridgeAccountListResponse) RenderHuman(h *output.Human) {
+	for _, a := range r.Accounts {
+		mark := " "
+		if a.Active {
+			mark = "*"
+		}
+		h.Line("%s %s", mark, a.Username)
+	}
+}
+
+func (r outboxListResponse) RenderHuman(h *output.Human) {
+	rows := make([][]string, 0, len(r.Items))
+	for _, it := range r.Items {
+		rows = append(rows, []string{it.ID, it.DraftID, humanTime(it.At), it.Status, strconv.Itoa(it.Attempts), it.LastError})
+	}
+	h.Table([]string{"ID", "DRAFT", "AT", "STATUS", "TRIES", "LAST ERROR"}, rows)
+}
+
+func (r outboxRunResponse) RenderHuman(h *output.Human) {
+	if len(r.Processed) > 0 {
+		rows := make([][]string, 0, len(r.Processed))
+		for _, p := range r.Processed {
+			status := p.Status
+			if p.Status == "failed" {
+				status = h.Bad(status)
+			}
+			rows = append(rows, []string{p.ID, p.DraftID, status, p.Error})
+		}
+		h.Table([]string{"ID", "DRAFT", "STATUS", "ERROR"}, rows)
+	}
+	h.Line("sent %d, skipped %d, failed %d, pending %d", r.Sent, r.Skipped, r.Failed, r.Pending)
+}
+
+func (r policyCheckResponse) RenderHuman(h *output.Human) {
+	verdict := h.OK("allowed")
+	if !r.Allowed {
+		verdict = h.Bad("blocked")
+	}
+	h.Line("%s: %s (%d recipients, domains %s)", r.DraftID, verdict, r.Recipients, strings.Join(r.Domains, ", "))
+	if len(r.Violations) > 0 {
+		rows := make([][]string, 0, len(r.Violations))
+		for _, v := range r.Violations {
+			rows = append(rows, []string{v.Rule, v.Message, strings.Join(v.Addresses, ", ")})
+		}
+		h.Table([]string{"RULE", "MESSAGE", "ADDRESSES"}, rows)
+	}
+}
+
+func (r draftLintResponse) RenderHuman(h *output.Human) {
+	rows := make([][]string, 0, len(r.Findings))
+	for _, f := range r.Findings {
+		sev := h.Warn(f.Severity)
+		if f.Severity == "error" {
+			sev = h.Bad(f.Severity)
+		}
+		rows = append(rows, []string{sev, f.Rule, f.Message})
+	}
+	if len(rows) > 0 {
+		h.Table([]string{"SEVERITY", "RULE", "MESSAGE"}, rows)
+	}
+	h.Line("%s: %d errors, %d warnings", r.DraftID, r.Errors, r.Warnings)
+}
+
+func (r schemaListResponse) RenderHuman(h *output.Human) {
+	rows := make([][]string, 0, len(r.Schemas))
+	for _, s := range r.Schemas {
+		rows = append(rows, []string{s.Command, s.File})
+	}
+	h.Table([]string{"COMMAND", "FILE"}, rows)
+}
+
+func (r schemaGetResponse) RenderHuman(h *output.Human) {
+	b, err := json.MarshalIndent(r.Schema, "", "  ")
+	if err != nil {
+		h.Line("%s: %v", r.Command, err)
+		return
+	}
+	h.Text(string(b))
+}
+
+func (r commandsResponse) RenderHuman(h *output.Human) {
+	rows := make([][]string, 0, len(r.Commands))
+	for _, c := range r.Commands {
+		rows = append(rows, []string{c.Command, c.Effect, c.Summary})
+	}
+	h.Table([]string{"COMMAND", "EFFECT", "SUMMARY"}, rows)
+}
@@ -0,0 +1,117 @@
+package app
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"protonmailcli/internal/output"
+)
+
+func TestHumanModeRendersListsAndDetails(t *testing.T) {
+	t.Setenv("PMAIL_USE_LOCAL_STATE", "1")
+	tmp := t.TempDir()
+	base := []string{"--config", filepath.Join(tmp, "config.toml"), "--state", filepath.Join(tmp, "state.json")}
+	run := func(args ...string) string {
+		stdout := &bytes.Buffer{}
+		if exit := Run(append(append([]string{}, base...), args...), bytes.NewBuffer(nil), stdout, &bytes.Buffer{}); exit != 0 {
+			t.Fatalf("%v exit %d: %s", args, exit, stdout.String())
+		}
+		return stdout.String()
+	}
+	run("setup", "--non-interactive", "--username", "me@example.com")
+	created := run("draft", "create", "--to", "a@example.com", "--subject", "Status", "--body", "Hi,\nall good")
+	if !strings.Contains(created, "Subject: Status\n") || !strings.HasSuffix(created, "\nHi,\nall good\n") {
+		t.Fatalf("unexpected draft detail view:\n%s", created)
+	}
+	list := run("draft", "list")
+	lines := strings.Split(strings.TrimRight(list, "\n"), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "ID ") || !strings.Contains(lines[1], "a@example.com  Status") || lines[2] != "1 draft" {
+		t.Fatalf("unexpected draft table:\n%s", list)
+	}
+	if help := run("message", "send", "--help"); strings.HasSuffix(help, "ok\n") || !strings.Contains(help, "Usage: protonmailcli [global flags] message send [flags]") {
+		t.Fatalf("help should only print usage:\n%s", help)
+	}
+}
+
+func TestBatchResultHumanSummary(t *testing.T) {
+	var out bytes.Buffer
+	resp := batchResultResponse{Results: []batchItemResponse{{Index: 0, OK: true, DraftID: "d_1", SendPath: "smtp"}, {Index: 1, ErrorCode: "policy_blocked", Error: "recipient policy", DraftID: "d_2"}}, Count: 2, Success: 1, Failed: 1}
+	if err := output.PrintSuccess(&out, output.ModeHuman, resp, "", "req", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	want := "#  STATUS          ITEM  DETAIL\n0  ok              d_1   smtp\n1  policy_blocked  d_2   recipient policy\n1 of 2 succeeded, 1 failed\n"
+	if out.String() != want {
+		t.Fatalf("unexpected batch output:\n%s", out.String())
+	}
+}
+
+func TestPlainModeRecordsAndFields(t *testing.T) {
+	t.Setenv("PMAIL_USE_LOCAL_STATE", "1")
+	tmp := t.TempDir()
+	base := []string{"--config", filepath.Join(tmp, "config.toml"), "--state", filepath.Join(tmp, "state.json")}
+	run := func(args ...string) (string, string) {
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		if exit := Run(append(append([]string{}, base...), args...), bytes.NewBuffer(nil), stdout, stderr); exit != 0 {
+			t.Fatalf("%v exit %d: %s", args, exit, stdout.String())
+		}
+		return stdout.String(), stderr.String()
+	}
+	run("setup", "--non-interactive", "--username", "me@example.com")
+	run("draft", "create", "--to", "a@example.com", "--to", "b@example.com", "--subject", "Q\t3", "--body", "x")
+	list, _ := run("--plain", "draft", "list")
+	lines := strings.Split(strings.TrimRight(list, "\n"), "\n")
+	if len(lines) != 2 || lines[0] != "id\tto\tsubject\tdate" || !strings.Contains(lines[1], "\ta@example.com,b@example.com\tQ\\t3\t") {
+		t.Fatalf("unexpected plain draft list:\n%s", list)
+	}
+	picked, stderr := run("--plain", "--fields", "subject,bogus", "draft", "list")
+	if picked != "subject\tbogus\nQ\\t3\t\n" || !strings.Contains(stderr, "warning: --fields: unknown field(s) bogus") {
+		t.Fatalf("unexpected --fields output %q stderr %q", picked, stderr)
+	}
+	if help, _ := run("--plain", "draft", "list", "--help"); !strings.Contains(help, "Plain columns (--plain, select with --fields): id, to, subject, date") {
+		t.Fatalf("help should document plain columns:\n%s", help)
+	}
+	stdout := &bytes.Buffer{}
+	Run(append(append([]string{}, base...), "--plain", "draft", "get", "--draft-id", "missing"), bytes.NewBuffer(nil), stdout, &bytes.Buffer{})
+	if !strings.HasPrefix(stdout.String(), "error\t") {
+		t.Fatalf("plain errors need a distinct prefix: %q", stdout.String())
+	}
+}
+
+func TestOutputShapingFlags(t *testing.T) {
+	t.Setenv("PMAIL_USE_LOCAL_STATE", "1")
+	tmp := t.TempDir()
+	base := []string{"--config", filepath.Join(tmp, "config.toml"), "--state", filepath.Join(tmp, "state.json")}
+	run := func(args ...string) (int, string) {
+		stdout := &bytes.Buffer{}
+		exit := Run(append(append([]string{}, base...), args...), bytes.NewBuffer(nil), stdout, &bytes.Buffer{})
+		return exit, stdout.String()
+	}
+	run("setup", "--non-interactive", "--username", "me@example.com")
+	run("draft", "create", "--to", "a@example.com", "--subject", "Plan", "--body", "x")
+	if _, out := run("--jq", ".drafts[].subject", "draft", "list"); out != "Plan\n" {
+		t.Fatalf("unexpected query output: %q", out)
+	}
+	if _, out := run("--template", "{{.Subject}} -> {{join .To \",\"}}", "draft", "list"); out != "Plan -> a@example.com\n" {
+		t.Fatalf("unexpected template output: %q", out)
+	}
+	if _, out := run("--json", "--fields", "subject", "draft", "list"); !strings.Contains(out, `"drafts":[{"subject":"Plan"}]`) {
+		t.Fatalf("unexpected projected json: %s", out)
+	}
+	exit, out := run("--template", "{{.Subject", "draft", "create", "--to", "b@example.com", "--subject", "Never", "--body", "x")
+	if exit != 2 || !strings.Contains(out, "invalid --template") {
+		t.Fatalf("bad template must fail before dispatch: exit=%d %s", exit, out)
+	}
+	if _, out := run("--jq", ".count", "draft", "list"); out != "1\n" {
+		t.Fatalf("invalid template must not have created a draft: %q", out)
+	}
+	exit, out = run("--json", "--template", "{{.Draft.ID.X}}", "draft", "create", "--to", "c@example.com", "--subject", "Once", "--body", "x")
+	if exit != 0 || !strings.Contains(out, `"ok":true`) || !strings.Contains(out, `"subject":"Once"`) || !strings.Contains(out, "output shaping failed") {
+		t.Fatalf("a template failure after a successful create must not fail the command: exit=%d %s", exit, out)
+	}
+	if exit, out := run("--json", "--jq", ".drafts[].to[0]", "search", "drafts", "--query", "Once"); exit != 0 || !strings.Contains(out, `"data":["c@example.com"]`) {
+		t.Fatalf("--jq and the command's --query must not collide: exit=%d %s", exit, out)
+	}
+}
@@ -1,11 +1,8 @@
 package app
 
 import (
-	"bytes"
 	"encoding/json"
-	"flag"
 	"fmt"
-	"io"
 	"os"
 	"sort"
 	"strconv"
@@ -34,9 +31,11 @@ type draftCreateItem struct {
 }
 
 type sendManyItem struct {
-	DraftID        string `json:"draft_id"`
-	ConfirmSend    string `json:"confirm_send"`
-	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	DraftID        string   `json:"draft_id"`
+	ConfirmSend    string   `json:"confirm_send"`
+	ConfirmBulk    int      `json:"confirm_bulk,omitempty"`
+	AllowFindings  []string `json:"allow_findings,omitempty"`
+	IdempotencyKey string   `json:"idempotency_key,omitempty"`
 }
 
 var smtpSendFn = bridge.Send
@@ -45,39 +44,54 @@ var openBridgeClientFn = func(cfg config.Config, st *model.State, passwordFile s
 }
 
 func cmdMailboxIMAP(action string, args []string, g globalOptions, cfg config.Config, st *model.State) (any, bool, error) {
+	if isMailboxAdminAction(action) {
+		req, err := parseMailboxAdminFlags(action, args)
+		if err != nil {
+			return nil, false, err
+		}
+		c, _, _, err := bridgeClient(cfg, st, "")
+		if err != nil {
+			return nil, false, err
+		}
+		defer c.Close()
+		return cmdMailboxAdminIMAP(c, req, g)
+	}
 	if action != "list" && action != "resolve" {
 		return nil, false, cliError{exit: 2, code: "usage_error", msg: "unknown mailbox action: " + action}
 	}
+	withStatus := false
 	if action == "resolve" {
-		fs := flag.NewFlagSet("mailbox resolve", flag.ContinueOnError)
-		fs.SetOutput(io.Discard)
+		fs := newFlagSet("mailbox resolve")
 		_ = fs.String("name", "", "mailbox id or name")
-		if helpData, handled, err := parseFlagSetWithHelp(fs, args, g, "mailbox resolve", runtimeStdout); err != nil {
+		if err := parseFlags(fs, args); err != nil {
 			return nil, false, err
-		} else if handled {
-			return helpData, false, nil
 		}
+	} else {
+		status, err := parseMailboxListFlags(args)
+		if err != nil {
+			return nil, false, err
+		}
+		withStatus = status
 	}
 	c, _, _, err := bridgeClient(cfg, st, "")
 	if err != nil {
 		return nil, false, err
 	}
 	defer c.Close()
-	boxes, err := c.ListMailboxes()
+	var entries []bridge.MailboxEntry
+	if withStatus {
+		entries, err = c.ListMailboxesWithStatus()
+	} else {
+		entries, err = c.ListMailboxEntries()
+	}
 	if err != nil {
 		return nil, false, cliError{exit: 4, code: "imap_list_failed", msg: err.Error()}
 	}
-	res := make([]mailboxInfo, 0, len(boxes))
-	for _, b := range boxes {
-		id, kind := classifyMailbox(b)
-		res = append(res, mailboxInfo{ID: id, Name: b, Kind: kind})
-	}
-	return mailboxAction(action, args, res, "imap")
+	return mailboxAction(action, args, mailboxInfosFromEntries(entries), "imap")
 }
 
 func cmdSearchIMAP(action string, args []string, g globalOptions, cfg config.Config, st *model.State) (any, bool, error) {
-	fs := flag.NewFlagSet("search", flag.ContinueOnError)
-	fs.SetOutput(io.Discard)
+	fs := newFlagSet("search")
 	query := fs.String("query", "", "query")
 	mailbox := fs.String("mailbox", "", "mailbox name (messages only)")
 	from := fs.String("from", "", "from filter")
@@ -88,12 +102,14 @@ func cmdSearchIMAP(action string, args []string, g globalOptions, cfg config.Con
 	sinceID := fs.String("since-id", "", "minimum UID (inclusive)")
 	after := fs.String("after", "", "date filter YYYY-MM-DD")
 	before := fs.String("before", "", "date filter YYYY-MM-DD")
+	authFail := fs.Bool("auth-fail", false, "only messages failing SPF, DKIM or DMARC (messages only)")
 	limit := fs.Int("limit", 50, "max results")
 	cursor := fs.String("cursor", "", "offset cursor")
-	if helpData, handled, err := parseFlagSetWithHelp(fs, args, g, "search "+action, runtimeStdout); err != nil {
+	if err := parseFlags(fs, args); err != nil {
 		return nil, false, err
-	} else if handled {
-		return helpData, false, nil
+	}
+	if *authFail && action == "drafts" {
+		return nil, false, cliError{exit: 2, code: "validation_error", msg: "--auth-fail is only supported for search messages"}
 	}
 	c, _, _, err := bridgeClient(cfg, st, "")
 	if err != nil {
@@ -108,6 +124,21 @@ func cmdSearchIMAP(action string, args []string, g globalOptions, cfg config.Con
 		if strings.TrimSpace(*mailbox) != "" {
 			return nil, false, cliError{exit: 2, code: "validation_error", msg: "--mailbox is only supported for search messages"}
 		}
+		record := func(m bridge.DraftMessage) draftRecord {
+			return draftRecord{ID: imapDraftID(m.UID), UID: m.UID, To: m.To, From: m.From, Subject: m.Subject, Date: m.Date.UTC().Format(time.RFC3339)}
+		}
+		if runtimeStream != nil {
+			cur, err := c.OpenMessages("Drafts", criteria)
+			if err != nil {
+				return nil, false, cliError{exit: 4, code: "imap_search_failed", msg: err.Error()}
+			}
+			start, _ := parsePage(*cursor, *limit)
+			count, next, err := streamMessages(cur, start, *limit, nil, func(m bridge.DraftMessage) { streamRecord(record(m)) })
+			if err != nil {
+				return nil, false, cliError{exit: 4, code: "imap_search_failed", msg: err.Error()}
+			}
+			return draftListResponse{Drafts: []draftRecord{}, Count: count, Total: cur.Total(), NextCursor: next, Source: "imap"}, false, nil
+		}
 		items, err := c.ListMessages("Drafts", criteria)
 		if err != nil {
 			return nil, false, cliError{exit: 4, code: "imap_search_failed", msg: err.Error()}
@@ -117,7 +148,7 @@ func cmdSearchIMAP(action string, args []string, g globalOptions, cfg config.Con
 		paged, next := paginateMessages(items, start, lim)
 		out := make([]draftRecord, 0, len(paged))
 		for _, m := range paged {
-			out = append(out, draftRecord{ID: imapDraftID(m.UID), UID: m.UID, To: m.To, From: m.From, Subject: m.Subject, Date: m.Date.UTC().Format(time.RFC3339)})
+			out = append(out, record(m))
 		}
 		return draftListResponse{Drafts: out, Count: len(out), Total: len(items), NextCursor: next, Source: "imap"}, false, nil
 	}
@@ -128,16 +159,51 @@ func cmdSearchIMAP(action string, args []string, g globalOptions, cfg config.Con
 	if strings.TrimSpace(*mailbox) != "" {
 		targetMailbox = strings.TrimSpace(*mailbox)
 	}
+	auth := map[string]*authenticationResult{}
+	var keep func(bridge.DraftMessage) bool
+	if *authFail {
+		keep = func(m bridge.DraftMessage) bool {
+			a := parseAuthentication(headersFromBridge(m.Headers))
+			if authFailed(a) {
+				auth[m.UID] = a
+			}
+			return authFailed(a)
+		}
+	}
+	record := func(m bridge.DraftMessage) messageRecord {
+		return messageRecord{ID: imapMessageIDForMailbox(targetMailbox, m.UID), UID: m.UID, From: m.From, To: m.To, Subject: m.Subject, Flags: m.Flags, Date: m.Date.UTC().Format(time.RFC3339), Authentication: auth[m.UID]}
+	}
+	if runtimeStream != nil {
+		cur, err := c.OpenMessages(targetMailbox, criteria)
+		if err != nil {
+			return nil, false, cliError{exit: 4, code: "imap_search_failed", msg: err.Error()}
+		}
+		start, _ := parsePage(*cursor, *limit)
+		count, next, err := streamMessages(cur, start, *limit, keep, func(m bridge.DraftMessage) { streamRecord(record(m)) })
+		if err != nil {
+			return nil, false, cliError{exit: 4, code: "imap_search_failed", msg: err.Error()}
+		}
+		return messageListResponse{Messages: []messageRecord{}, Count: count, Total: cur.Total(), NextCursor: next, Mailbox: targetMailbox, Source: "imap"}, false, nil
+	}
 	items, err := c.ListMessages(targetMailbox, criteria)
 	if err != nil {
 		return nil, false, cliError{exit: 4, code: "imap_search_failed", msg: err.Error()}
 	}
+	if keep != nil {
+		failed := items[:0]
+		for _, m := range items {
+			if keep(m) {
+				failed = append(failed, m)
+			}
+		}
+		items = failed
+	}
 	sortByUIDDesc(items)
 	start, lim := parsePage(*cursor, *limit)
 	paged, next := paginateMessages(items, start, lim)
 	out := make([]messageRecord, 0, len(paged))
 	for _, m := range paged {
-		out = append(out, messageRecord{ID: imapMessageIDForMailbox(targetMailbox, m.UID), UID: m.UID, From: m.From, To: m.To, Subject: m.Subject, Date: m.Date.UTC().Format(time.RFC3339)})
+		out = append(out, record(m))
 	}
 	return messageListResponse{Messages: out, Count: len(out), Total: len(items), NextCursor: next, Mailbox: targetMailbox, Source: "imap"}, false, nil
 }
@@ -163,12 +229,9 @@ func cmdTagIMAP(action string, args []string, g globalOptions, cfg config.Config
 	switch action {
 	case "list":
 		if len(args) > 0 {
-			fs := flag.NewFlagSet("tag list", flag.ContinueOnError)
-			fs.SetOutput(io.Discard)
-			if helpData, handled, err := parseFlagSetWithHelp(fs, args, g, "tag list", runtimeStdout); err != nil {
+			fs := newFlagSet("tag list")
+			if err := parseFlags(fs, args); err != nil {
 				return nil, false, err
-			} else if handled {
-				return helpData, false, nil
 			}
 		}
 		if err := ensureClient(); err != nil {
@@ -181,47 +244,52 @@ func cmdTagIMAP(action string, args []string, g globalOptions, cfg config.Config
 		out := sortedUserKeywords(msgs)
 		return tagListResponse{Tags: out, Count: len(out), Source: "imap"}, false, nil
 	case "create":
-		fs := flag.NewFlagSet("tag create", flag.ContinueOnError)
-		fs.SetOutput(io.Discard)
+		fs := newFlagSet("tag create")
 		name := fs.String("name", "", "tag name")
-		if helpData, handled, err := parseFlagSetWithHelp(fs, args, g, "tag create", runtimeStdout); err != nil {
+		if err := parseFlags(fs, args); err != nil {
 			return nil, false, err
-		} else if handled {
-			return helpData, false, nil
 		}
 		if strings.TrimSpace(*name) == "" {
 			return nil, false, cliError{exit: 2, code: "validation_error", msg: "--name required"}
 		}
 		return tagCreateResponse{Tag: tagInfo{Name: *name}, Changed: false, Source: "imap"}, false, nil
 	case "add", "remove":
-		fs := flag.NewFlagSet("tag add/remove", flag.ContinueOnError)
-		fs.SetOutput(io.Discard)
+		fs := newFlagSet("tag add/remove")
 		msgID := fs.String("message-id", "", "message id")
 		tag := fs.String("tag", "", "tag name")
-		if helpData, handled, err := parseFlagSetWithHelp(fs, args, g, "tag "+action, runtimeStdout); err != nil {
-			return nil, false, err
-		} else if handled {
-			return helpData, false, nil
-		}
-		if err := ensureClient(); err != nil {
+		if err := parseFlags(fs, args); err != nil {
 			return nil, false, err
 		}
-		uid, err := parseRequiredUID(*msgID, "--message-id")
-		if err != nil {
-			return nil, false, cliError{exit: 2, code: "validation_error", msg: err.Error()}
+		if strings.TrimSpace(*msgID) == "" {
+			return nil, false, cliError{exit: 2, code: "validation_error", msg: "--message-id required"}
 		}
 		if strings.TrimSpace(*tag) == "" {
 			return nil, false, cliError{exit: 2, code: "validation_error", msg: "--tag required"}
 		}
-		if err := c.SetKeyword("INBOX", uid, *tag, action == "add"); err != nil {
-			return nil, false, cliError{exit: 4, code: "imap_tag_update_failed", msg: err.Error()}
+		if err := ensureClient(); err != nil {
+			return nil, false, err
 		}
-		return tagUpdateResponse{MessageID: imapMessageID(uid), Tag: *tag, Changed: true, Source: "imap"}, true, nil
+		return updateTagIMAP(c, *msgID, *tag, action == "add")
 	default:
 		return nil, false, cliError{exit: 2, code: "usage_error", msg: "unknown tag action: " + action}
 	}
 }
 
+type imapKeywordClient interface {
+	SetKeyword(mailbox, uid, keyword string, add bool) error
+}
+
+func updateTagIMAP(c imapKeywordClient, msgID, tag string, add bool) (any, bool, error) {
+	mailbox, uid, err := parseMailboxUID(msgID, "INBOX")
+	if err != nil {
+		return nil, false, cliError{exit: 2, code: "validation_error", msg: "invalid --message-id: " + err.Error()}
+	}
+	if err := c.SetKeyword(mailbox, uid, tag, add); err != nil {
+		return nil, false, cliError{exit: 4, code: "imap_tag_update_failed", msg: err.Error()}
+	}
+	return tagUpdateResponse{MessageID: imapMessageIDForMailbox(mailbox, uid), Tag: tag, Changed: true, Source: "imap"}, true, nil
+}
+
 func sortedUserKeywords(msgs []bridge.DraftMessage) []string {
 	set := map[string]struct{}{}
 	for _, m := range msgs {
@@ -317,6 +385,42 @@ func parsePage(cursor string, limit int) (int, int) {
 	return start, limit
 }
 
+func streamMessages(cur *bridge.MessageCursor, start, limit int, keep func(bridge.DraftMessage) bool, emit func(bridge.DraftMessage)) (int, string, error) {
+	if keep == nil {
+		cur.Skip(start)
+		if limit > 0 {
+			cur.SetBatch(limit)
+		}
+	}
+	count, skipped := 0, 0
+	for limit <= 0 || count < limit {
+		m, ok := cur.Next()
+		if !ok {
+			break
+		}
+		if keep != nil && !keep(m) {
+			continue
+		}
+		if keep != nil && skipped < start {
+			skipped++
+			continue
+		}
+		emit(m)
+		count++
+	}
+	if err := cur.Err(); err != nil {
+		return count, "", fmt.Errorf("%w (%d records streamed; resume with --cursor %d)", err, count, start+count)
+	}
+	if skipped := cur.Skipped(); len(skipped) > 0 {
+		addWarning(fmt.Sprintf("skipped %d unreadable messages (uid %s)", len(skipped), strings.Join(skipped, ", ")))
+	}
+	next := ""
+	if cur.Remaining() > 0 {
+		next = strconv.Itoa(start + count)
+	}
+	return count, next, nil
+}
+
 func paginateMessages(all []bridge.DraftMessage, start, limit int) ([]bridge.DraftMessage, string) {
 	if start >= len(all) {
 		return []bridge.DraftMessage{}, ""
@@ -362,12 +466,3 @@ func readManifest(path string, fromStdin bool) ([]byte, error) {
 	}
 	return os.ReadFile(path)
 }
-
-func usageForFlagSet(fs *flag.FlagSet) string {
-	var b bytes.Buffer
-	prev := fs.Output()
-	fs.SetOutput(&b)
-	fs.PrintDefaults()
-	fs.SetOutput(prev)
-	return strings.TrimRight("Usage of "+fs.Name()+":\n"+b.String(), "\n")
-}
@@ -2,7 +2,6 @@ package app
 
 import (
 	"bytes"
-	"flag"
 	"fmt"
 	"io"
 	"strings"
@@ -106,19 +105,6 @@ func TestReadManifestFromDashUsesStdin(t *testing.T) {
 	}
 }
 
-func TestUsageForFlagSet(t *testing.T) {
-	fs := flag.NewFlagSet("draft create-many", flag.ContinueOnError)
-	fs.String("file", "", "manifest json path or -")
-	fs.Bool("stdin", false, "read manifest json from stdin")
-	usage := usageForFlagSet(fs)
-	if !strings.Contains(usage, "Usage of draft create-many:") {
-		t.Fatalf("missing usage heading: %s", usage)
-	}
-	if !strings.Contains(usage, "-file") || !strings.Contains(usage, "-stdin") {
-		t.Fatalf("missing expected flags: %s", usage)
-	}
-}
-
 func TestLoadDraftCreateManifestAllowsPerItemValidation(t *testing.T) {
 	prev := readAllStdinFn
 	defer func() { readAllStdinFn = prev }()
@@ -247,6 +233,32 @@ func TestSortedUserKeywords(t *testing.T) {
 	}
 }
 
+type fakeKeywordClient struct {
+	calls []string
+}
+
+func (f *fakeKeywordClient) SetKeyword(mailbox, uid, keyword string, add bool) error {
+	f.calls = append(f.calls, fmt.Sprintf("%s:%s:%s:%v", mailbox, uid, keyword, add))
+	return nil
+}
+
+func TestUpdateTagIMAPUsesTheMessageMailbox(t *testing.T) {
+	c := &fakeKeywordClient{}
+	data, changed, err := updateTagIMAP(c, "imap:Archive:7", "urgent", true)
+	if err != nil || !changed {
+		t.Fatalf("tag add: changed=%v err=%v", changed, err)
+	}
+	if resp := data.(tagUpdateResponse); resp.MessageID != "imap:Archive:7" {
+		t.Fatalf("unexpected message id: %+v", resp)
+	}
+	if _, _, err := updateTagIMAP(c, "9", "urgent", false); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(c.calls, "|") != "Archive:7:urgent:true|INBOX:9:urgent:false" {
+		t.Fatalf("tags must be stored in the message's own mailbox: %v", c.calls)
+	}
+}
+
 type fakeIMAPDraftClient struct {
 	appendErr    error
 	appendUID    string
@@ -1,9 +1,7 @@
 package app
 
 import (
-	"flag"
 	"fmt"
-	"io"
 	"os"
 	"strings"
 	"time"
@@ -36,8 +34,7 @@ func cmdDraftIMAP(action string, args []string, g globalOptions, cfg config.Conf
 
 	switch action {
 	case "list":
-		fs := flag.NewFlagSet("draft list", flag.ContinueOnError)
-		fs.SetOutput(io.Discard)
+		fs := newFlagSet("draft list")
 		query := fs.String("query", "", "text query")
 		from := fs.String("from", "", "from filter")
 		to := fs.String("to", "", "to filter")
@@ -45,8 +42,8 @@ func cmdDraftIMAP(action string, args []string, g globalOptions, cfg config.Conf
 		before := fs.String("before", "", "date filter YYYY-MM-DD")
 		limit := fs.Int("limit", 50, "max results")
 		cursor := fs.String("cursor", "", "offset cursor")
-		if err := fs.Parse(args); err != nil {
-			return nil, false, cliError{exit: 2, code: "usage_error", msg: err.Error()}
+		if err := parseFlags(fs, args); err != nil {
+			return nil, false, err
 		}
 		criteria, err := buildIMAPCriteria(*query, "", *from, *to, "", false, "", *after, *before)
 		if err != nil {
@@ -55,6 +52,30 @@ func cmdDraftIMAP(action string, args []string, g globalOptions, cfg config.Conf
 		if err := ensureClient(); err != nil {
 			return nil, false, err
 		}
+		record := func(d bridge.DraftMessage) draftRecord {
+			return draftRecord{
+				ID:      imapDraftID(d.UID),
+				UID:     d.UID,
+				To:      d.To,
+				From:    d.From,
+				Subject: d.Subject,
+				Body:    d.Body,
+				Date:    d.Date.UTC().Format(time.RFC3339),
+				Flags:   d.Flags,
+			}
+		}
+		if runtimeStream != nil {
+			cur, err := c.OpenMessages("Drafts", criteria)
+			if err != nil {
+				return nil, false, cliError{exit: 4, code: "imap_draft_list_failed", msg: err.Error()}
+			}
+			start, _ := parsePage(*cursor, *limit)
+			count, next, err := streamMessages(cur, start, *limit, nil, func(d bridge.DraftMessage) { streamRecord(record(d)) })
+			if err != nil {
+				return nil, false, cliError{exit: 4, code: "imap_draft_list_failed", msg: err.Error()}
+			}
+			return draftListResponse{Drafts: []draftRecord{}, Count: count, Total: cur.Total(), NextCursor: next, Source: "imap"}, false, nil
+		}
 		drafts, err := c.ListMessages("Drafts", criteria)
 		if err != nil {
 			return nil, false, cliError{exit: 4, code: "imap_draft_list_failed", msg: err.Error()}
@@ -64,24 +85,14 @@ func cmdDraftIMAP(action string, args []string, g globalOptions, cfg config.Conf
 		paged, next := paginateMessages(drafts, start, lim)
 		out := make([]draftRecord, 0, len(drafts))
 		for _, d := range paged {
-			out = append(out, draftRecord{
-				ID:      imapDraftID(d.UID),
-				UID:     d.UID,
-				To:      d.To,
-				From:    d.From,
-				Subject: d.Subject,
-				Body:    d.Body,
-				Date:    d.Date.UTC().Format(time.RFC3339),
-				Flags:   d.Flags,
-			})
+			out = append(out, record(d))
 		}
 		return draftListResponse{Drafts: out, Count: len(out), Total: len(drafts), NextCursor: next, Source: "imap"}, false, nil
 	case "get":
-		fs := flag.NewFlagSet("draft get", flag.ContinueOnError)
-		fs.SetOutput(io.Discard)
+		fs := newFlagSet("draft get")
 		id := fs.String("draft-id", "", "draft id")
-		if err := fs.Parse(args); err != nil {
-			return nil, false, cliError{exit: 2, code: "usage_error", msg: err.Error()}
+		if err := parseFlags(fs, args); err != nil {
+			return nil, false, err
 		}
 		uid, err := parseRequiredUID(*id, "--draft-id")
 		if err != nil {
@@ -99,17 +110,21 @@ func cmdDraftIMAP(action string, args []string, g globalOptions, cfg config.Conf
 			Source: "imap",
 		}, false, nil
 	case "create":
-		fs := flag.NewFlagSet("draft create", flag.ContinueOnError)
-		fs.SetOutput(io.Discard)
+		fs := newFlagSet("draft create")
 		var to sliceFlag
 		subject := fs.String("subject", "", "subject")
 		body := fs.String("body", "", "body")
 		bodyFile := fs.String("body-file", "", "body from file or -")
 		stdinBody := fs.Bool("stdin", false, "read body from stdin")
 		idempotencyKey := fs.String("idempotency-key", "", "idempotency key")
+		var tags sliceFlag
 		fs.Var(&to, "to", "recipient (repeat)")
-		if err := fs.Parse(args); err != nil {
-			return nil, false, cliError{exit: 2, code: "usage_error", msg: err.Error()}
+		fs.Var(&tags, "tag", "tag (local state only)")
+		if err := parseFlags(fs, args); err != nil {
+			return nil, false, err
+		}
+		if len(tags) > 0 {
+			return nil, false, cliError{exit: 2, code: "validation_error", msg: "--tag is only supported with local state"}
 		}
 		if len(to) == 0 {
 			return nil, false, cliError{exit: 2, code: "validation_error", msg: "at least one --to is required"}
@@ -129,7 +144,7 @@ func cmdDraftIMAP(action string, args []string, g globalOptions, cfg config.Conf
 		}
 		raw := bridge.BuildRawMessage(username, to, *subject, b)
 		if g.dryRun {
-			return map[string]any{"action": "draft.create", "wouldCreate": true, "source": "imap"}, true, nil
+			return draftPlanResponse{Action: "draft.create", Source: "imap", WouldCreate: true}, true, nil
 		}
 		uid, createPath, err := saveDraftWithFallback(c, cfg, st, username, to, *subject, b, raw, nil)
 		if err != nil {
@@ -143,15 +158,12 @@ func cmdDraftIMAP(action string, args []string, g globalOptions, cfg config.Conf
 		_ = idempotencyStore(st, *idempotencyKey, "draft.create", payload, resp)
 		return resp, true, nil
 	case "create-many":
-		fs := flag.NewFlagSet("draft create-many", flag.ContinueOnError)
-		fs.SetOutput(io.Discard)
+		fs := newFlagSet("draft create-many")
 		file := fs.String("file", "", "manifest json path or -")
 		fromStdin := fs.Bool("stdin", false, "read manifest json from stdin")
 		idempotencyKey := fs.String("idempotency-key", "", "idempotency key")
-		if helpData, handled, err := parseFlagSetWithHelp(fs, args, g, "draft create-many", runtimeStdout); err != nil {
+		if err := parseFlags(fs, args); err != nil {
 			return nil, false, err
-		} else if handled {
-			return helpData, false, nil
 		}
 		items, err := parseDraftCreateManifestInput(*file, *fromStdin)
 		if err != nil {
@@ -169,26 +181,26 @@ func cmdDraftIMAP(action string, args []string, g globalOptions, cfg config.Conf
 		success := 0
 		for i, it := range items {
 			if len(it.To) == 0 {
-				results = append(results, batchItemResponse{Index: i, OK: false, ErrorCode: "validation_error", Error: "missing to"})
+				results = appendBatchResult(results, batchItemResponse{Index: i, OK: false, ErrorCode: "validation_error", Error: "missing to"})
 				continue
 			}
 			b, err := loadBody(it.Body, it.BodyFile, false)
 			if err != nil {
-				results = append(results, batchItemResponse{Index: i, OK: false, ErrorCode: "validation_error", Error: err.Error()})
+				results = appendBatchResult(results, batchItemResponse{Index: i, OK: false, ErrorCode: "validation_error", Error: err.Error()})
 				continue
 			}
 			raw := bridge.BuildRawMessage(username, it.To, it.Subject, b)
 			if g.dryRun {
-				results = append(results, batchItemResponse{Index: i, OK: true, DryRun: true, To: it.To, Subject: it.Subject})
+				results = appendBatchResult(results, batchItemResponse{Index: i, OK: true, DryRun: true, To: it.To, Subject: it.Subject})
 				success++
 				continue
 			}
 			uid, createPath, err := saveDraftWithFallback(c, cfg, st, username, it.To, it.Subject, b, raw, nil)
 			if err != nil {
-				results = append(results, batchItemResponse{Index: i, OK: false, ErrorCode: "imap_draft_create_failed", Error: err.Error()})
+				results = appendBatchResult(results, batchItemResponse{Index: i, OK: false, ErrorCode: "imap_draft_create_failed", Error: err.Error()})
 				continue
 			}
-			results = append(results, batchItemResponse{Index: i, OK: true, DraftID: imapDraftID(uid), UID: uid, CreatePath: createPath})
+			results = appendBatchResult(results, batchItemResponse{Index: i, OK: true, DraftID: imapDraftID(uid), UID: uid, CreatePath: createPath})
 			success++
 		}
 		resp := batchResultResponse{Results: results, Count: len(results), Success: success, Failed: len(results) - success, Source: "imap"}
@@ -198,15 +210,14 @@ func cmdDraftIMAP(action string, args []string, g globalOptions, cfg config.Conf
 		_ = idempotencyStore(st, *idempotencyKey, "draft.create-many", items, resp)
 		return resp, success > 0, nil
 	case "update":
-		fs := flag.NewFlagSet("draft update", flag.ContinueOnError)
-		fs.SetOutput(io.Discard)
+		fs := newFlagSet("draft update")
 		id := fs.String("draft-id", "", "draft id")
 		subject := fs.String("subject", "", "subject")
 		body := fs.String("body", "", "body")
 		bodyFile := fs.String("body-file", "", "body from file or -")
 		stdinBody := fs.Bool("stdin", false, "read body from stdin")
-		if err := fs.Parse(args); err != nil {
-			return nil, false, cliError{exit: 2, code: "usage_error", msg: err.Error()}
+		if err := parseFlags(fs, args); err != nil {
+			return nil, false, err
 		}
 		uid, err := parseRequiredUID(*id, "--draft-id")
 		if err != nil {
@@ -230,7 +241,7 @@ func cmdDraftIMAP(action string, args []string, g globalOptions, cfg config.Conf
 			d.Body = nextBody
 		}
 		if g.dryRun {
-			return map[string]any{"action": "draft.update", "draftId": imapDraftID(uid), "wouldUpdate": true, "source": "imap"}, true, nil
+			return draftPlanResponse{Action: "draft.update", DraftID: imapDraftID(uid), Source: "imap", WouldUpdate: true}, true, nil
 		}
 		if err := c.DeleteDraft(uid); err != nil {
 			return nil, false, cliError{exit: 4, code: "imap_draft_update_failed", msg: err.Error()}
@@ -244,11 +255,10 @@ func cmdDraftIMAP(action string, args []string, g globalOptions, cfg config.Conf
 			Source: "imap",
 		}, true, nil
 	case "delete":
-		fs := flag.NewFlagSet("draft delete", flag.ContinueOnError)
-		fs.SetOutput(io.Discard)
+		fs := newFlagSet("draft delete")
 		id := fs.String("draft-id", "", "draft id")
-		if err := fs.Parse(args); err != nil {
-			return nil, false, cliError{exit: 2, code: "usage_error", msg: err.Error()}
+		if err := parseFlags(fs, args); err != nil {
+			return nil, false, err
 		}
 		uid, err := parseRequiredUID(*id, "--draft-id")
 		if err != nil {
@@ -258,16 +268,12 @@ func cmdDraftIMAP(action string, args []string, g globalOptions, cfg config.Conf
 			return nil, false, err
 		}
 		if g.dryRun {
-			return map[string]any{"action": "draft.delete", "draftId": imapDraftID(uid), "wouldDelete": true, "source": "imap"}, true, nil
+			return draftPlanResponse{Action: "draft.delete", DraftID: imapDraftID(uid), Source: "imap", WouldDelete: true}, true, nil
 		}
 		if err := c.DeleteDraft(uid); err != nil {
 			return nil, false, cliError{exit: 4, code: "imap_draft_delete_failed", msg: err.Error()}
 		}
-		return struct {
-			Deleted bool   `json:"deleted"`
-			DraftID string `json:"draftId"`
-			Source  string `json:"source"`
-		}{Deleted: true, DraftID: imapDraftID(uid), Source: "imap"}, true, nil
+		return draftDeleteResponse{Deleted: true, DraftID: imapDraftID(uid), Source: "imap"}, true, nil
 	default:
 		return nil, false, cliError{exit: 2, code: "usage_error", msg: "unknown draft action: " + action}
 	}
@@ -361,15 +367,61 @@ func cmdMessageIMAP(action string, args []string, g globalOptions, cfg config.Co
 		}
 	}()
 
+	if isMessageFileAction(action) {
+		req, err := parseMessageFileFlags(action, args)
+		if err != nil {
+			return nil, false, err
+		}
+		if err := ensureClient(); err != nil {
+			return nil, false, err
+		}
+		return cmdMessageFileIMAP(c, req, g, cfg, st)
+	}
+	if action == "mark" {
+		req, err := parseMessageMarkFlags(args)
+		if err != nil {
+			return nil, false, err
+		}
+		if err := ensureClient(); err != nil {
+			return nil, false, err
+		}
+		return cmdMessageMarkIMAP(c, req, g)
+	}
+	if action == "bulk" {
+		req, err := parseMessageBulkFlags(args, g)
+		if err != nil {
+			return nil, false, err
+		}
+		if err := ensureClient(); err != nil {
+			return nil, false, err
+		}
+		return cmdMessageBulkIMAP(c, req)
+	}
+	if action == "undo-send" {
+		token, err := parseMessageUndoSendFlags(args)
+		if err != nil {
+			return nil, false, err
+		}
+		return cmdMessageUndoSend(token, g, st)
+	}
+	if action == "unsubscribe" {
+		req, err := parseMessageUnsubscribeFlags(args)
+		if err != nil {
+			return nil, false, err
+		}
+		if err := ensureClient(); err != nil {
+			return nil, false, err
+		}
+		return cmdMessageUnsubscribeIMAP(c, req, g, cfg, username, password, st)
+	}
+
 	switch action {
 	case "get":
-		fs := flag.NewFlagSet("message get", flag.ContinueOnError)
-		fs.SetOutput(io.Discard)
-		id := fs.String("message-id", "", "message id")
-		if err := fs.Parse(args); err != nil {
-			return nil, false, cliError{exit: 2, code: "usage_error", msg: err.Error()}
+		req, err := parseMessageGetFlags(args)
+		if err != nil {
+			return nil, false, err
 		}
-		mailbox, uid, err := parseMailboxUID(*id, "INBOX")
+		mailbox, uid, err := parseMailboxUID(req.id, "INBOX")
 		if err != nil {
 			return nil, false, cliError{exit: 2, code: "validation_error", msg: err.Error()}
 		}
@@ -381,35 +433,54 @@ func cmdMessageIMAP(action string, args []string, g globalOptions, cfg config.Co
 			return nil, false, cliError{exit: 5, code: "not_found", msg: "message not found"}
 		}
 		m := msgs[0]
+		changed := false
+		if req.markRead && !g.dryRun {
+			if err := c.StoreFlags(mailbox, []string{m.UID}, []string{flagSeen}, true); err != nil {
+				return nil, false, cliError{exit: 4, code: "imap_flag_update_failed", msg: err.Error()}
+			}
+			m.Flags = applyFlagChanges(m.Flags, []string{flagSeen}, nil)
+			changed = true
+		}
+		headers := headersFromBridge(m.Headers)
 		return messageGetResponse{
 			Message: messageRecord{
-				ID:      imapMessageIDForMailbox(mailbox, m.UID),
-				UID:     m.UID,
-				From:    m.From,
-				To:      m.To,
-				Subject: m.Subject,
-				Body:    m.Body,
-				Flags:   m.Flags,
+				ID:             imapMessageIDForMailbox(mailbox, m.UID),
+				UID:            m.UID,
+				From:           m.From,
+				To:             m.To,
+				Subject:        m.Subject,
+				Body:           m.Body,
+				Flags:          m.Flags,
+				Headers:        req.selectHeaders(headers),
+				Authentication: parseAuthentication(headers),
 			},
 			Source: "imap",
-		}, false, nil
+		}, changed, nil
 	case "send":
-		fs := flag.NewFlagSet("message send", flag.ContinueOnError)
-		fs.SetOutput(io.Discard)
+		fs := newFlagSet("message send")
 		draftID := fs.String("draft-id", "", "draft id")
 		confirm := fs.String("confirm-send", "", "confirmation token")
 		force := fs.Bool("force", false, "force send without confirm token")
 		passwordFile := fs.String("smtp-password-file", "", "path to smtp password file")
 		idempotencyKey := fs.String("idempotency-key", "", "idempotency key")
-		if helpData, handled, err := parseFlagSetWithHelp(fs, args, g, "message send", runtimeStdout); err != nil {
+		postSend := fs.String("post-send", "", "draft action after send: delete|move-to-sent|keep (default from config)")
+		at := fs.String("at", "", "schedule the send via the outbox (RFC3339)")
+		missed := fs.String("missed", "send-late", "policy when the schedule is missed: send-late|skip")
+		waitQuota := fs.Bool("wait-for-quota", false, "wait for send quota instead of failing with rate_limit")
+		confirmBulk := fs.Int("confirm-bulk", 0, "recipient count, required above safety.recipients.bulk_threshold")
+		var allowFindings sliceFlag
+		fs.Var(&allowFindings, "allow-finding", "DLP finding id to send anyway (repeatable, audited in state)")
+		if err := parseFlags(fs, args); err != nil {
 			return nil, false, err
-		} else if handled {
-			return helpData, false, nil
 		}
 		uid, err := parseRequiredUID(*draftID, "--draft-id")
 		if err != nil {
 			return nil, false, cliError{exit: 2, code: "validation_error", msg: err.Error()}
 		}
+		postSendAction, err := resolvePostSendAction(*postSend, cfg)
+		if err != nil {
+			return nil, false, cliError{exit: 2, code: "validation_error", msg: err.Error()}
+		}
 		if err := ensureClient(); err != nil {
 			return nil, false, err
 		}
@@ -417,17 +488,42 @@ func cmdMessageIMAP(action string, args []string, g globalOptions, cfg config.Co
 		if err != nil {
 			return nil, false, cliError{exit: 5, code: "not_found", msg: "draft not found"}
 		}
+		if strings.TrimSpace(*at) != "" {
+			if err := validateSendSafety(cfg, isNonInteractiveSend(g, runtimeStdinIsTTY()), *confirm, *draftID, uid, *force, recipientCheck{addresses: bridgeRecipients(d), confirmBulk: *confirmBulk}); err != nil {
+				return nil, false, err
+			}
+			if err := enforceDraftLint(cfg, lintInputFromBridge(d), imapDraftID(uid)); err != nil {
+				return nil, false, err
+			}
+			if _, err := enforceDLP(cfg, st, g, dlpContentFromBridge(d), allowFindings); err != nil {
+				return nil, false, err
+			}
+			return enqueueScheduledSend(st, g, scheduleRequest{draftID: *draftID, at: *at, missedPolicy: *missed, confirm: *confirm, force: *force, confirmBulk: *confirmBulk, allowFindings: allowFindings, postSend: *postSend, passwordFile: *passwordFile, idempotencyKey: *idempotencyKey}, "imap")
+		}
 		payload := map[string]any{"draftId": *draftID, "confirm": *confirm, "force": *force, "to": d.To, "subject": d.Subject, "body": d.Body}
 		if found, cached, err := idempotencyLookup(st, *idempotencyKey, "message.send", payload); err != nil {
 			return nil, false, err
 		} else if found {
 			return cached, false, nil
 		}
-		if err := validateSendSafety(cfg, isNonInteractiveSend(g, runtimeStdinIsTTY()), *confirm, *draftID, uid, *force); err != nil {
+		if err := validateSendSafety(cfg, isNonInteractiveSend(g, runtimeStdinIsTTY()), *confirm, *draftID, uid, *force, recipientCheck{addresses: bridgeRecipients(d), confirmBulk: *confirmBulk}); err != nil {
+			return nil, false, err
+		}
+		if err := enforceDraftLint(cfg, lintInputFromBridge(d), imapDraftID(uid)); err != nil {
+			return nil, false, err
+		}
+		overridden, err := enforceDLP(cfg, st, g, dlpContentFromBridge(d), allowFindings)
+		if err != nil {
 			return nil, false, err
 		}
 		if g.dryRun {
-			return sendPlanResponse{Action: "send", DraftID: imapDraftID(uid), WouldSend: true, DryRun: true, SendPath: "smtp", Source: "imap"}, true, nil
+			return sendPlanResponse{Action: "send", DraftID: imapDraftID(uid), WouldSend: true, DryRun: true, SendPath: "smtp", PostSendAction: postSendAction, Source: "imap"}, true, nil
+		}
+		if window := undoSendWindow(cfg, g); window > 0 {
+			item := enqueueUndoSend(st, scheduleRequest{draftID: *draftID, confirm: *confirm, force: *force, confirmBulk: *confirmBulk, allowFindings: allowFindings, postSend: *postSend, passwordFile: *passwordFile}, window)
+			resp := pendingSendResponse(item, imapDraftID(uid), "imap")
+			_ = idempotencyStore(st, *idempotencyKey, "message.send", payload, resp)
+			return resp, true, nil
 		}
 		pass := strings.TrimSpace(password)
 		if *passwordFile != "" {
@@ -437,35 +533,51 @@ func cmdMessageIMAP(action string, args []string, g globalOptions, cfg config.Co
 			}
 			pass = p
 		}
-		err = bridge.Send(bridge.SMTPConfig{Host: cfg.Bridge.Host, Port: cfg.Bridge.SMTPPort, Username: username, Password: pass}, bridge.SendInput{From: username, To: d.To, Subject: d.Subject, Body: d.Body})
+		if err := consumeSendQuota(st, cfg, g, username, *waitQuota); err != nil {
+			return nil, false, err
+		}
+		headers := sendHeadersForDraft(d, username)
+		err = smtpSendFn(bridge.SMTPConfig{Host: cfg.Bridge.Host, Port: cfg.Bridge.SMTPPort, Username: username, Password: pass}, bridge.SendInput{From: username, To: d.To, Subject: d.Subject, Body: d.Body, ExtraHeaders: headers})
 		if err != nil {
 			return nil, false, cliError{exit: 4, code: "send_failed", msg: err.Error()}
 		}
-		resp := struct {
-			Sent     bool   `json:"sent"`
-			DraftID  string `json:"draftId"`
-			SendPath string `json:"sendPath,omitempty"`
-			Source   string `json:"source"`
-			SentAt   string `json:"sentAt"`
-		}{Sent: true, DraftID: imapDraftID(uid), SendPath: "smtp", Source: "imap", SentAt: time.Now().UTC().Format(time.RFC3339)}
+		sentAt := time.Now().UTC()
+		recordDLPOverrides(st, g, imapDraftID(uid), overridden)
+		post := finalizeSentDraft(c, d.Mailbox, uid, headers["Message-ID"], bridge.BuildRawMessageWithHeaders(username, d.To, d.Subject, d.Body, headers), postSendAction, sentAt, sentCopyPollBudget)
+		post.AnsweredMessageID = markOriginalAnswered(c, headers["In-Reply-To"])
+		if post.Error != "" {
+			addWarning("post-send cleanup incomplete: " + post.Error)
+		}
+		resp := imapMessageSendResponse{
+			Sent:          true,
+			DraftID:       imapDraftID(uid),
+			SendPath:      "smtp",
+			Source:        "imap",
+			SentAt:        post.SentAt,
+			SentMessageID: post.SentMessageID,
+			PostSend:      &post,
+		}
 		_ = idempotencyStore(st, *idempotencyKey, "message.send", payload, resp)
 		return resp, true, nil
 	case "send-many":
-		fs := flag.NewFlagSet("message send-many", flag.ContinueOnError)
-		fs.SetOutput(io.Discard)
+		fs := newFlagSet("message send-many")
 		file := fs.String("file", "", "manifest json path or -")
 		fromStdin := fs.Bool("stdin", false, "read manifest json from stdin")
 		passwordFile := fs.String("smtp-password-file", "", "path to smtp password file")
 		idempotencyKey := fs.String("idempotency-key", "", "idempotency key")
-		if helpData, handled, err := parseFlagSetWithHelp(fs, args, g, "message send-many", runtimeStdout); err != nil {
+		postSend := fs.String("post-send", "", "draft action after send: delete|move-to-sent|keep (default from config)")
+		waitQuota := fs.Bool("wait-for-quota", false, "wait for send quota instead of failing with rate_limit")
+		if err := parseFlags(fs, args); err != nil {
 			return nil, false, err
-		} else if handled {
-			return helpData, false, nil
 		}
 		items, err := parseSendManyManifestInput(*file, *fromStdin)
 		if err != nil {
 			return nil, false, cliError{exit: 2, code: "validation_error", msg: err.Error()}
 		}
+		postSendAction, err := resolvePostSendAction(*postSend, cfg)
+		if err != nil {
+			return nil, false, cliError{exit: 2, code: "validation_error", msg: err.Error()}
+		}
 		if found, cached, err := idempotencyLookup(st, *idempotencyKey, "message.send-many", items); err != nil {
 			return nil, false, err
 		} else if found {
@@ -482,38 +594,70 @@ func cmdMessageIMAP(action string, args []string, g globalOptions, cfg config.Co
 			}
 			pass = p
 		}
+		window := undoSendWindow(cfg, g)
 		results := make([]batchItemResponse, 0, len(items))
 		success := 0
 		for i, it := range items {
 			if strings.TrimSpace(it.ConfirmSend) == "" {
-				results = append(results, batchItemResponse{Index: i, OK: false, ErrorCode: "validation_error", Error: "missing confirm_send", DraftID: it.DraftID})
+				results = appendBatchResult(results, batchItemResponse{Index: i, OK: false, ErrorCode: "validation_error", Error: "missing confirm_send", DraftID: it.DraftID})
 				continue
 			}
 			uid, err := parseUID(it.DraftID)
 			if err != nil {
-				results = append(results, batchItemResponse{Index: i, OK: false, ErrorCode: "validation_error", Error: "invalid draft_id"})
+				results = appendBatchResult(results, batchItemResponse{Index: i, OK: false, ErrorCode: "validation_error", Error: "invalid draft_id"})
 				continue
 			}
 			d, err := c.GetDraft(uid)
 			if err != nil {
-				results = append(results, batchItemResponse{Index: i, OK: false, ErrorCode: "not_found", Error: "draft not found", DraftID: it.DraftID})
+				results = appendBatchResult(results, batchItemResponse{Index: i, OK: false, ErrorCode: "not_found", Error: "draft not found", DraftID: it.DraftID})
 				continue
 			}
-			if err := validateSendSafety(cfg, isNonInteractiveSend(g, runtimeStdinIsTTY()), it.ConfirmSend, it.DraftID, uid, false); err != nil {
+			if err := validateSendSafety(cfg, isNonInteractiveSend(g, runtimeStdinIsTTY()), it.ConfirmSend, it.DraftID, uid, false, recipientCheck{addresses: bridgeRecipients(d), confirmBulk: it.ConfirmBulk}); err != nil {
 				code := errorCodeFromErr(err, "confirmation_required")
-				results = append(results, batchItemResponse{Index: i, OK: false, ErrorCode: code, Error: code, DraftID: it.DraftID})
+				msg := code
+				if code == "policy_blocked" {
+					msg = err.Error()
+				}
+				results = appendBatchResult(results, batchItemResponse{Index: i, OK: false, ErrorCode: code, Error: msg, DraftID: it.DraftID})
+				continue
+			}
+			if err := enforceDraftLint(cfg, lintInputFromBridge(d), it.DraftID); err != nil {
+				results = appendBatchResult(results, batchItemResponse{Index: i, OK: false, ErrorCode: "lint_failed", Error: err.Error(), DraftID: it.DraftID})
+				continue
+			}
+			overridden, err := enforceDLP(cfg, st, g, dlpContentFromBridge(d), it.AllowFindings)
+			if err != nil {
+				results = appendBatchResult(results, batchItemResponse{Index: i, OK: false, ErrorCode: errorCodeFromErr(err, "dlp_blocked"), Error: err.Error(), DraftID: it.DraftID})
 				continue
 			}
 			if g.dryRun {
-				results = append(results, batchItemResponse{Index: i, OK: true, DraftID: it.DraftID, DryRun: true, SendPath: "smtp"})
+				results = appendBatchResult(results, batchItemResponse{Index: i, OK: true, DraftID: it.DraftID, DryRun: true, SendPath: "smtp"})
 				success++
 				continue
 			}
-			if err := smtpSendFn(bridge.SMTPConfig{Host: cfg.Bridge.Host, Port: cfg.Bridge.SMTPPort, Username: username, Password: pass}, bridge.SendInput{From: username, To: d.To, Subject: d.Subject, Body: d.Body}); err != nil {
-				results = append(results, batchItemResponse{Index: i, OK: false, ErrorCode: "send_failed", Error: err.Error(), DraftID: it.DraftID})
+			if window > 0 {
+				item := enqueueUndoSend(st, scheduleRequest{draftID: it.DraftID, confirm: it.ConfirmSend, confirmBulk: it.ConfirmBulk, allowFindings: it.AllowFindings, postSend: *postSend, passwordFile: *passwordFile}, window)
+				results = appendBatchResult(results, batchItemResponse{Index: i, OK: true, DraftID: it.DraftID, SendPath: "outbox", Pending: true, PendingUntil: item.At.Format(time.RFC3339), UndoToken: item.UndoToken, FlushWith: undoSendFlushCommand})
+				success++
+				continue
+			}
+			if err := consumeSendQuota(st, cfg, g, username, *waitQuota); err != nil {
+				results = appendBatchResult(results, batchItemResponse{Index: i, OK: false, ErrorCode: "rate_limit", Error: err.Error(), DraftID: it.DraftID, RetryAfterSeconds: retryAfterFromErr(err)})
 				continue
 			}
-			results = append(results, batchItemResponse{Index: i, OK: true, DraftID: it.DraftID, SendPath: "smtp", SentAt: time.Now().UTC().Format(time.RFC3339)})
+			headers := sendHeadersForDraft(d, username)
+			if err := smtpSendFn(bridge.SMTPConfig{Host: cfg.Bridge.Host, Port: cfg.Bridge.SMTPPort, Username: username, Password: pass}, bridge.SendInput{From: username, To: d.To, Subject: d.Subject, Body: d.Body, ExtraHeaders: headers}); err != nil {
+				results = appendBatchResult(results, batchItemResponse{Index: i, OK: false, ErrorCode: "send_failed", Error: err.Error(), DraftID: it.DraftID})
+				continue
+			}
+			sentAt := time.Now().UTC()
+			recordDLPOverrides(st, g, it.DraftID, overridden)
+			post := finalizeSentDraft(c, d.Mailbox, uid, headers["Message-ID"], bridge.BuildRawMessageWithHeaders(username, d.To, d.Subject, d.Body, headers), postSendAction, sentAt, sentCopyPollBudget)
+			post.AnsweredMessageID = markOriginalAnswered(c, headers["In-Reply-To"])
+			if post.Error != "" {
+				addWarning(it.DraftID + ": post-send cleanup incomplete: " + post.Error)
+			}
+			results = appendBatchResult(results, batchItemResponse{Index: i, OK: true, DraftID: it.DraftID, SendPath: "smtp", SentAt: post.SentAt, SentMessageID: post.SentMessageID, PostSend: &post})
 			success++
 		}
 		resp := batchResultResponse{Results: results, Count: len(results), Success: success, Failed: len(results) - success, Source: "imap"}
@@ -525,8 +669,7 @@ func cmdMessageIMAP(action string, args []string, g globalOptions, cfg config.Co
 		_ = idempotencyStore(st, *idempotencyKey, "message.send-many", items, resp)
 		return resp, success > 0, nil
 	case "follow-up":
-		fs := flag.NewFlagSet("message follow-up", flag.ContinueOnError)
-		fs.SetOutput(io.Discard)
+		fs := newFlagSet("message follow-up")
 		msgID := fs.String("message-id", "", "message id")
 		var to sliceFlag
 		subject := fs.String("subject", "", "subject override")
@@ -535,10 +678,8 @@ func cmdMessageIMAP(action string, args []string, g globalOptions, cfg config.Co
 		stdinBody := fs.Bool("stdin", false, "read body from stdin")
 		idempotencyKey := fs.String("idempotency-key", "", "idempotency key")
 		fs.Var(&to, "to", "recipient (repeat)")
-		if helpData, handled, err := parseFlagSetWithHelp(fs, args, g, "message follow-up", runtimeStdout); err != nil {
+		if err := parseFlags(fs, args); err != nil {
 			return nil, false, err
-		} else if handled {
-			return helpData, false, nil
 		}
 		mailbox, uid, err := parseMailboxUID(*msgID, "INBOX")
 		if err != nil {
@@ -0,0 +1,264 @@
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode"
+)
+
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyBackspace = 8
+	keyTab       = 9
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+type lineEditor struct {
+	in       *bufio.Reader
+	out      io.Writer
+	raw      func() (func(), error)
+	history  []string
+	complete func(line string) []string
+}
+
+func (e *lineEditor) readLine(prompt string) (string, error) {
+	if e.raw != nil {
+		restore, err := e.raw()
+		if err == nil {
+			defer restore()
+			return e.edit(prompt)
+		}
+		e.raw = nil
+	}
+	fmt.Fprint(e.out, prompt)
+	line, err := e.in.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (e *lineEditor) edit(prompt string) (string, error) {
+	var buf []rune
+	pos, hist, pending := 0, len(e.history), ""
+	redraw := func() {
+		fmt.Fprintf(e.out, "\r\x1b[K%s%s", prompt, string(buf))
+		if n := len(buf) - pos; n > 0 {
+			fmt.Fprintf(e.out, "\x1b[%dD", n)
+		}
+	}
+	setLine := func(s string) {
+		buf = []rune(s)
+		pos = len(buf)
+	}
+	recall := func(to int) {
+		if to < 0 || to > len(e.history) || to == hist {
+			return
+		}
+		if hist == len(e.history) {
+			pending = string(buf)
+		}
+		hist = to
+		if hist == len(e.history) {
+			setLine(pending)
+		} else {
+			setLine(e.history[hist])
+		}
+	}
+	redraw()
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			if err == io.EOF && len(buf) > 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return string(buf), nil
+			}
+			return "", err
+		}
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(e.out, "\r\n")
+			return string(buf), nil
+		case keyCtrlD:
+			if len(buf) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+			if pos < len(buf) {
+				buf = append(buf[:pos], buf[pos+1:]...)
+			}
+		case keyCtrlC:
+			fmt.Fprint(e.out, "^C\r\n")
+			buf, pos, hist = nil, 0, len(e.history)
+		case keyDelete, keyBackspace:
+			if pos > 0 {
+				buf = append(buf[:pos-1], buf[pos:]...)
+				pos--
+			}
+		case keyCtrlA:
+			pos = 0
+		case keyCtrlE:
+			pos = len(buf)
+		case keyCtrlB:
+			pos = max(0, pos-1)
+		case keyCtrlF:
+			pos = min(len(buf), pos+1)
+		case keyCtrlK:
+			buf = buf[:pos]
+		case keyCtrlU:
+			buf, pos = append([]rune{}, buf[pos:]...), 0
+		case keyCtrlW:
+			start := pos
+			for start > 0 && buf[start-1] == ' ' {
+				start--
+			}
+			for start > 0 && buf[start-1] != ' ' {
+				start--
+			}
+			buf, pos = append(buf[:start], buf[pos:]...), start
+		case keyCtrlL:
+			fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+		case keyCtrlP:
+			recall(hist - 1)
+		case keyCtrlN:
+			recall(hist + 1)
+		case keyTab:
+			buf, pos = e.completeAt(buf, pos)
+		case keyEscape:
+			switch readEscapeKey(e.in) {
+			case "up":
+				recall(hist - 1)
+			case "down":
+				recall(hist + 1)
+			case "right":
+				pos = min(len(buf), pos+1)
+			case "left":
+				pos = max(0, pos-1)
+			case "home":
+				pos = 0
+			case "end":
+				pos = len(buf)
+			case "delete":
+				if pos < len(buf) {
+					buf = append(buf[:pos], buf[pos+1:]...)
+				}
+			}
+		default:
+			if unicode.IsPrint(r) {
+				buf = append(buf[:pos], append([]rune{r}, buf[pos:]...)...)
+				pos++
+			}
+		}
+		redraw()
+	}
+}
+
+var escapeKeys = map[string]string{
+	"A": "up", "B": "down", "C": "right", "D": "left", "H": "home", "F": "end",
+	"1~": "home", "7~": "home", "4~": "end", "8~": "end", "3~": "delete", "5~": "pgup", "6~": "pgdn",
+}
+
+func readEscapeKey(in *bufio.Reader) string {
+	r, _, err := in.ReadRune()
+	if err != nil || (r != '[' && r != 'O') {
+		return "esc"
+	}
+	var seq strings.Builder
+	for {
+		r, _, err = in.ReadRune()
+		if err != nil {
+			return "esc"
+		}
+		seq.WriteRune(r)
+		if r < '0' || r > '9' {
+			break
+		}
+	}
+	if key, ok := escapeKeys[seq.String()]; ok {
+		return key
+	}
+	return "esc"
+}
+
+func (e *lineEditor) completeAt(buf []rune, pos int) ([]rune, int) {
+	if e.complete == nil {
+		return buf, pos
+	}
+	head := string(buf[:pos])
+	start := strings.LastIndex(head, " ") + 1
+	word := head[start:]
+	candidates := e.complete(head)
+	switch len(candidates) {
+	case 0:
+		return buf, pos
+	case 1:
+		word = candidates[0]
+		if !strings.HasSuffix(word, "=") {
+			word += " "
+		}
+	default:
+		prefix := commonPrefix(candidates)
+		if len(prefix) <= len(word) {
+			fmt.Fprintf(e.out, "\r\n%s\r\n", strings.Join(candidates, "  "))
+			return buf, pos
+		}
+		word = prefix
+	}
+	out := append([]rune(head[:start]+word), buf[pos:]...)
+	return out, len([]rune(head[:start] + word))
+}
+
+func commonPrefix(values []string) string {
+	prefix := values[0]
+	for _, v := range values[1:] {
+		for !strings.HasPrefix(v, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+func (e *lineEditor) remember(line string) bool {
+	line = strings.TrimSpace(line)
+	if line == "" || (len(e.history) > 0 && e.history[len(e.history)-1] == line) {
+		return false
+	}
+	e.history = append(e.history, line)
+	return true
+}
+
+func sttyRaw(f *os.File) func() (func(), error) {
+	return func() (func(), error) {
+		saved, err := stty(f, "-g")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := stty(f, "-icanon", "-echo", "-isig", "min", "1"); err != nil {
+			return nil, err
+		}
+		return func() { _, _ = stty(f, strings.TrimSpace(saved)) }, nil
+	}
+}
+
+func stty(f *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = f
+	out, err := cmd.Output()
+	return string(out), err
+}
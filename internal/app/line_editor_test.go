@@ -0,0 +1,89 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestEditor(input string, history ...string) (*lineEditor, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	e := &lineEditor{
+		in:      bufio.NewReader(strings.NewReader(input)),
+		out:     out,
+		raw:     func() (func(), error) { return func() {}, nil },
+		history: history,
+		complete: func(head string) []string {
+			word := head[strings.LastIndex(head, " ")+1:]
+			return matchPrefix([]string{"draft", "doctor", "--draft-id=", "--draft-id=imap:Drafts:7"}, word)
+		},
+	}
+	return e, out
+}
+
+func readLines(t *testing.T, e *lineEditor) []string {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := e.readLine("> ")
+		if err == io.EOF {
+			return lines
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestLineEditorEditingKeys(t *testing.T) {
+	input := "helo\x1b[D\x1b[Dl\x01x\x05!\r" +
+		"draft list --json\x17\x17\r" +
+		"abc\x1b[D\x0b\x1b[D\x15\r" +
+		"ab\x1b[D\x1b[3~\x7f\r" +
+		"typo\x03ok\r" +
+		"\x04"
+	e, _ := newTestEditor(input)
+	got := readLines(t, e)
+	want := []string{"xhello!", "draft ", "b", "", "ok"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q want %q", got, want)
+	}
+}
+
+func TestLineEditorHistory(t *testing.T) {
+	e, _ := newTestEditor("\x1b[A\x1b[A\r"+"draft\x1b[A\x1b[B\r"+"\x10\x10\x10\x0e\r", "tag list", "draft list")
+	got := readLines(t, e)
+	want := []string{"tag list", "draft", "draft list"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q want %q", got, want)
+	}
+	for _, line := range []string{" tag list ", "tag list", ""} {
+		e.remember(line)
+	}
+	if want := []string{"tag list", "draft list", "tag list"}; !reflect.DeepEqual(e.history, want) {
+		t.Fatalf("history %q", e.history)
+	}
+}
+
+func TestLineEditorCompletion(t *testing.T) {
+	e, out := newTestEditor("d\t\tr\tlist\r" + "get --dr\t\t\r")
+	got := readLines(t, e)
+	want := []string{"draft list", "get --draft-id="}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q want %q", got, want)
+	}
+	if !strings.Contains(out.String(), "\r\ndraft  doctor\r\n") {
+		t.Fatalf("ambiguous completion should list candidates: %q", out.String())
+	}
+}
+
+func TestLineEditorWithoutTerminal(t *testing.T) {
+	e := &lineEditor{in: bufio.NewReader(strings.NewReader("draft list\r\nlast")), out: &bytes.Buffer{}}
+	if got := readLines(t, e); !reflect.DeepEqual(got, []string{"draft list", "last"}) {
+		t.Fatalf("got %q", got)
+	}
+}
@@ -1,12 +1,7 @@
 package app
 
 import (
-	"flag"
 	"fmt"
-	"io"
-	"os"
-	"path/filepath"
-	"sort"
 	"strings"
 	"time"
 
@@ -18,18 +13,18 @@ import (
 func cmdDraft(action string, args []string, g globalOptions, st *model.State) (any, bool, error) {
 	switch action {
 	case "create":
-		fs := flag.NewFlagSet("draft create", flag.ContinueOnError)
-		fs.SetOutput(io.Discard)
+		fs := newFlagSet("draft create")
 		var to sliceFlag
 		var tags sliceFlag
 		subject := fs.String("subject", "", "subject")
 		body := fs.String("body", "", "body")
 		bodyFile := fs.String("body-file", "", "body from file or -")
 		stdinBody := fs.Bool("stdin", false, "read body from stdin")
+		idempotencyKey := fs.String("idempotency-key", "", "idempotency key")
 		fs.Var(&to, "to", "recipient (repeat)")
 		fs.Var(&tags, "tag", "tag (repeat)")
-		if err := fs.Parse(args); err != nil {
-			return nil, false, cliError{exit: 2, code: "usage_error", msg: err.Error()}
+		if err := parseFlags(fs, args); err != nil {
+			return nil, false, err
 		}
 		if len(to) == 0 {
 			return nil, false, cliError{exit: 2, code: "validation_error", msg: "at least one --to is required"}
@@ -38,23 +33,30 @@ func cmdDraft(action string, args []string, g globalOptions, st *model.State) (a
 		if err != nil {
 			return nil, false, cliError{exit: 2, code: "validation_error", msg: err.Error()}
 		}
+		payload := map[string]any{"to": []string(to), "subject": *subject, "body": b, "tags": []string(tags)}
+		if found, cached, err := idempotencyLookup(st, *idempotencyKey, "draft.create", payload); err != nil {
+			return nil, false, err
+		} else if found {
+			return cached, false, nil
+		}
 		now := time.Now().UTC()
 		id := fmt.Sprintf("d_%d", now.UnixNano())
 		d := model.Draft{ID: id, To: to, Subject: *subject, Body: b, Tags: tags, CreatedAt: now, UpdatedAt: now}
+		resp := localDraftResponse{Draft: d, CreatePath: "local_state", Source: "local"}
 		if !g.dryRun {
 			st.Drafts[id] = d
+			_ = idempotencyStore(st, *idempotencyKey, "draft.create", payload, resp)
 		}
-		return localDraftResponse{Draft: d, CreatePath: "local_state", Source: "local"}, true, nil
+		return resp, true, nil
 	case "update":
-		fs := flag.NewFlagSet("draft update", flag.ContinueOnError)
-		fs.SetOutput(io.Discard)
+		fs := newFlagSet("draft update")
 		id := fs.String("draft-id", "", "draft id")
 		subject := fs.String("subject", "", "subject")
 		body := fs.String("body", "", "body")
 		bodyFile := fs.String("body-file", "", "body from file or -")
 		stdinBody := fs.Bool("stdin", false, "read body from stdin")
-		if err := fs.Parse(args); err != nil {
-			return nil, false, cliError{exit: 2, code: "usage_error", msg: err.Error()}
+		if err := parseFlags(fs, args); err != nil {
+			return nil, false, err
 		}
 		uid, err := parseRequiredUID(*id, "--draft-id")
 		if err != nil {
@@ -80,11 +82,10 @@ func cmdDraft(action string, args []string, g globalOptions, st *model.State) (a
 		}
 		return localDraftResponse{Draft: d}, true, nil
 	case "get":
-		fs := flag.NewFlagSet("draft get", flag.ContinueOnError)
-		fs.SetOutput(io.Discard)
+		fs := newFlagSet("draft get")
 		id := fs.String("draft-id", "", "draft id")
-		if err := fs.Parse(args); err != nil {
-			return nil, false, cliError{exit: 2, code: "usage_error", msg: err.Error()}
+		if err := parseFlags(fs, args); err != nil {
+			return nil, false, err
 		}
 		uid, err := parseRequiredUID(*id, "--draft-id")
 		if err != nil {
@@ -96,22 +97,22 @@ func cmdDraft(action string, args []string, g globalOptions, st *model.State) (a
 		}
 		return localDraftResponse{Draft: d}, false, nil
 	case "list":
-		ids := make([]string, 0, len(st.Drafts))
-		for id := range st.Drafts {
-			ids = append(ids, id)
+		fs := newFlagSet("draft list")
+		var filter localFilter
+		filter.register(fs, false)
+		if err := parseFlags(fs, args); err != nil {
+			return nil, false, err
 		}
-		sort.Strings(ids)
-		out := make([]model.Draft, 0, len(ids))
-		for _, id := range ids {
-			out = append(out, st.Drafts[id])
+		if err := filter.validate(); err != nil {
+			return nil, false, err
 		}
-		return localDraftListResponse{Drafts: out, Count: len(out)}, false, nil
+		out, total, next := filterLocalDrafts(st, &filter)
+		return localDraftListResponse{Drafts: out, Count: len(out), Total: total, NextCursor: next}, false, nil
 	case "delete":
-		fs := flag.NewFlagSet("draft delete", flag.ContinueOnError)
-		fs.SetOutput(io.Discard)
+		fs := newFlagSet("draft delete")
 		id := fs.String("draft-id", "", "draft id")
-		if err := fs.Parse(args); err != nil {
-			return nil, false, cliError{exit: 2, code: "usage_error", msg: err.Error()}
+		if err := parseFlags(fs, args); err != nil {
+			return nil, false, err
 		}
 		uid, err := parseRequiredUID(*id, "--draft-id")
 		if err != nil {
@@ -125,33 +126,36 @@ func cmdDraft(action string, args []string, g globalOptions, st *model.State) (a
 		}
 		return draftDeleteResponse{Deleted: true, DraftID: uid}, true, nil
 	case "create-many":
-		fs := flag.NewFlagSet("draft create-many", flag.ContinueOnError)
-		fs.SetOutput(io.Discard)
+		fs := newFlagSet("draft create-many")
 		file := fs.String("file", "", "manifest json path or -")
 		fromStdin := fs.Bool("stdin", false, "read manifest json from stdin")
-		if helpData, handled, err := parseFlagSetWithHelp(fs, args, g, "draft create-many", runtimeStdout); err != nil {
+		idempotencyKey := fs.String("idempotency-key", "", "idempotency key")
+		if err := parseFlags(fs, args); err != nil {
 			return nil, false, err
-		} else if handled {
-			return helpData, false, nil
 		}
 		items, err := parseDraftCreateManifestInput(*file, *fromStdin)
 		if err != nil {
 			return nil, false, cliError{exit: 2, code: "validation_error", msg: err.Error()}
 		}
+		if found, cached, err := idempotencyLookup(st, *idempotencyKey, "draft.create-many", items); err != nil {
+			return nil, false, err
+		} else if found {
+			return cached, false, nil
+		}
 		results := make([]batchItemResponse, 0, len(items))
 		success := 0
 		for i, it := range items {
 			if len(it.To) == 0 {
-				results = append(results, batchItemResponse{Index: i, OK: false, ErrorCode: "validation_error", Error: "missing to"})
+				results = appendBatchResult(results, batchItemResponse{Index: i, OK: false, ErrorCode: "validation_error", Error: "missing to"})
 				continue
 			}
 			b, err := loadBody(it.Body, it.BodyFile, false)
 			if err != nil {
-				results = append(results, batchItemResponse{Index: i, OK: false, ErrorCode: "validation_error", Error: err.Error()})
+				results = appendBatchResult(results, batchItemResponse{Index: i, OK: false, ErrorCode: "validation_error", Error: err.Error()})
 				continue
 			}
 			if g.dryRun {
-				results = append(results, batchItemResponse{Index: i, OK: true, DryRun: true, To: it.To, Subject: it.Subject})
+				results = appendBatchResult(results, batchItemResponse{Index: i, OK: true, DryRun: true, To: it.To, Subject: it.Subject})
 				success++
 				continue
 			}
@@ -159,7 +163,7 @@ func cmdDraft(action string, args []string, g globalOptions, st *model.State) (a
 			id := fmt.Sprintf("d_%d", now.UnixNano())
 			d := model.Draft{ID: id, To: it.To, Subject: it.Subject, Body: b, CreatedAt: now, UpdatedAt: now}
 			st.Drafts[id] = d
-			results = append(results, batchItemResponse{Index: i, OK: true, DraftID: id, CreatePath: "local_state"})
+			results = appendBatchResult(results, batchItemResponse{Index: i, OK: true, DraftID: id, CreatePath: "local_state"})
 			success++
 		}
 		resp := batchResultResponse{Results: results, Count: len(results), Success: success, Failed: len(results) - success, Source: "local"}
@@ -168,6 +172,7 @@ func cmdDraft(action string, args []string, g globalOptions, st *model.State) (a
 		} else if success > 0 && (len(results)-success) > 0 {
 			resp.exitCode = 10
 		}
+		_ = idempotencyStore(st, *idempotencyKey, "draft.create-many", items, resp)
 		return resp, success > 0, nil
 	default:
 		return nil, false, cliError{exit: 2, code: "usage_error", msg: "unknown draft action: " + action}
@@ -175,15 +180,48 @@ func cmdDraft(action string, args []string, g globalOptions, st *model.State) (a
 }
 
 func cmdMessage(action string, args []string, g globalOptions, cfg config.Config, st *model.State) (any, bool, error) {
+	if isMessageFileAction(action) {
+		req, err := parseMessageFileFlags(action, args)
+		if err != nil {
+			return nil, false, err
+		}
+		return cmdMessageFileLocal(req, g, cfg, st)
+	}
+	if action == "mark" {
+		req, err := parseMessageMarkFlags(args)
+		if err != nil {
+			return nil, false, err
+		}
+		return cmdMessageMarkLocal(req, g, st)
+	}
+	if action == "bulk" {
+		req, err := parseMessageBulkFlags(args, g)
+		if err != nil {
+			return nil, false, err
+		}
+		return cmdMessageBulkLocal(req, st)
+	}
+	if action == "undo-send" {
+		token, err := parseMessageUndoSendFlags(args)
+		if err != nil {
+			return nil, false, err
+		}
+		return cmdMessageUndoSend(token, g, st)
+	}
+	if action == "unsubscribe" {
+		req, err := parseMessageUnsubscribeFlags(args)
+		if err != nil {
+			return nil, false, err
+		}
+		return cmdMessageUnsubscribeLocal(req, g, cfg, st)
+	}
 	switch action {
 	case "get":
-		fs := flag.NewFlagSet("message get", flag.ContinueOnError)
-		fs.SetOutput(io.Discard)
-		id := fs.String("message-id", "", "message id")
-		if err := fs.Parse(args); err != nil {
-			return nil, false, cliError{exit: 2, code: "usage_error", msg: err.Error()}
+		req, err := parseMessageGetFlags(args)
+		if err != nil {
+			return nil, false, err
 		}
-		uid, err := parseRequiredUID(*id, "--message-id")
+		uid, err := parseRequiredUID(req.id, "--message-id")
 		if err != nil {
 			return nil, false, cliError{exit: 2, code: "validation_error", msg: err.Error()}
 		}
@@ -191,111 +229,190 @@ func cmdMessage(action string, args []string, g globalOptions, cfg config.Config
 		if !ok {
 			return nil, false, cliError{exit: 5, code: "not_found", msg: "message not found"}
 		}
-		return localMessageGetResponse{Message: m}, false, nil
+		changed := false
+		if req.markRead && !g.dryRun {
+			m.Flags = applyFlagChanges(m.Flags, []string{flagSeen}, nil)
+			st.Messages[uid] = m
+			changed = true
+		}
+		headers := localMessageHeaders(m)
+		return localMessageGetResponse{Message: localMessageRecord{Message: m, Headers: req.selectHeaders(headers), Authentication: parseAuthentication(headers)}}, changed, nil
 	case "send":
-		fs := flag.NewFlagSet("message send", flag.ContinueOnError)
-		fs.SetOutput(io.Discard)
+		fs := newFlagSet("message send")
 		draftID := fs.String("draft-id", "", "draft id")
 		confirm := fs.String("confirm-send", "", "confirmation token")
 		force := fs.Bool("force", false, "force send without confirm token")
 		passwordFile := fs.String("smtp-password-file", "", "path to smtp password file")
-		if helpData, handled, err := parseFlagSetWithHelp(fs, args, g, "message send", runtimeStdout); err != nil {
+		idempotencyKey := fs.String("idempotency-key", "", "idempotency key")
+		postSend := fs.String("post-send", "", "draft action after send: delete|move-to-sent|keep (default from config)")
+		at := fs.String("at", "", "schedule the send via the outbox (RFC3339)")
+		missed := fs.String("missed", "send-late", "policy when the schedule is missed: send-late|skip")
+		waitQuota := fs.Bool("wait-for-quota", false, "wait for send quota instead of failing with rate_limit")
+		confirmBulk := fs.Int("confirm-bulk", 0, "recipient count, required above safety.recipients.bulk_threshold")
+		var allowFindings sliceFlag
+		fs.Var(&allowFindings, "allow-finding", "DLP finding id to send anyway (repeatable, audited in state)")
+		if err := parseFlags(fs, args); err != nil {
 			return nil, false, err
-		} else if handled {
-			return helpData, false, nil
 		}
 		uid, err := parseRequiredUID(*draftID, "--draft-id")
 		if err != nil {
 			return nil, false, cliError{exit: 2, code: "validation_error", msg: err.Error()}
 		}
+		postSendAction, err := resolvePostSendAction(*postSend, cfg)
+		if err != nil {
+			return nil, false, cliError{exit: 2, code: "validation_error", msg: err.Error()}
+		}
 		d, ok := st.Drafts[uid]
 		if !ok {
 			return nil, false, cliError{exit: 5, code: "not_found", msg: "draft not found"}
 		}
-		if err := validateSendSafety(cfg, isNonInteractiveSend(g, runtimeStdinIsTTY()), *confirm, d.ID, "", *force); err != nil {
+		payload := map[string]any{"draftId": d.ID, "confirm": *confirm, "force": *force, "to": d.To, "subject": d.Subject, "body": d.Body}
+		if strings.TrimSpace(*at) == "" {
+			if found, cached, err := idempotencyLookup(st, *idempotencyKey, "message.send", payload); err != nil {
+				return nil, false, err
+			} else if found {
+				return cached, false, nil
+			}
+		}
+		if err := validateSendSafety(cfg, isNonInteractiveSend(g, runtimeStdinIsTTY()), *confirm, d.ID, "", *force, recipientCheck{addresses: draftRecipients(d), confirmBulk: *confirmBulk}); err != nil {
 			return nil, false, err
 		}
+		if err := enforceDraftLint(cfg, lintInputFromDraft(d), d.ID); err != nil {
+			return nil, false, err
+		}
+		overridden, err := enforceDLP(cfg, st, g, dlpContentFromDraft(d), allowFindings)
+		if err != nil {
+			return nil, false, err
+		}
+		if strings.TrimSpace(*at) != "" {
+			return enqueueScheduledSend(st, g, scheduleRequest{draftID: d.ID, at: *at, missedPolicy: *missed, confirm: *confirm, force: *force, confirmBulk: *confirmBulk, allowFindings: allowFindings, postSend: *postSend, passwordFile: *passwordFile, idempotencyKey: *idempotencyKey}, "local")
+		}
 		if *force {
 			fmt.Fprintln(runtimeStderr, "warning: forcing send by policy override")
 		}
-		password := strings.TrimSpace(os.Getenv("PMAIL_SMTP_PASSWORD"))
-		candidatePasswordFile := firstNonEmpty(*passwordFile, st.Auth.PasswordFile, cfg.Bridge.PasswordFile)
-		if password == "" && candidatePasswordFile != "" {
-			b, err := os.ReadFile(filepath.Clean(config.Expand(candidatePasswordFile)))
-			if err != nil {
-				return nil, false, cliError{exit: 2, code: "validation_error", msg: "cannot read smtp password file"}
-			}
-			password = strings.TrimSpace(string(b))
+		password, err := localSMTPPassword(cfg, st, *passwordFile)
+		if err != nil {
+			return nil, false, err
 		}
 		if g.dryRun {
-			return sendPlanResponse{Action: "send", DraftID: d.ID, WouldSend: true, DryRun: true, SendPath: "local_state", Source: "local"}, true, nil
+			return sendPlanResponse{Action: "send", DraftID: d.ID, WouldSend: true, DryRun: true, SendPath: "local_state", PostSendAction: postSendAction, Source: "local"}, true, nil
+		}
+		if window := undoSendWindow(cfg, g); window > 0 {
+			item := enqueueUndoSend(st, scheduleRequest{draftID: d.ID, confirm: *confirm, force: *force, confirmBulk: *confirmBulk, allowFindings: allowFindings, postSend: *postSend, passwordFile: *passwordFile}, window)
+			resp := pendingSendResponse(item, d.ID, "local")
+			_ = idempotencyStore(st, *idempotencyKey, "message.send", payload, resp)
+			return resp, true, nil
 		}
 		from := firstNonEmpty(st.Auth.Username, cfg.Bridge.Username)
 		if from == "" {
 			return nil, false, cliError{exit: 3, code: "config_error", msg: "bridge username is missing", hint: "Run setup or auth login and set username"}
 		}
-		if err := bridge.Send(bridge.SMTPConfig{Host: cfg.Bridge.Host, Port: cfg.Bridge.SMTPPort, Username: from, Password: password}, bridge.SendInput{From: from, To: d.To, Subject: d.Subject, Body: d.Body}); err != nil {
+		if err := consumeSendQuota(st, cfg, g, from, *waitQuota); err != nil {
+			return nil, false, err
+		}
+		if err := smtpSendFn(bridge.SMTPConfig{Host: cfg.Bridge.Host, Port: cfg.Bridge.SMTPPort, Username: from, Password: password}, bridge.SendInput{From: from, To: d.To, Subject: d.Subject, Body: d.Body}); err != nil {
 			return nil, false, cliError{exit: 4, code: "send_failed", msg: err.Error()}
 		}
+		recordDLPOverrides(st, g, d.ID, overridden)
 		now := time.Now().UTC()
-		d.SentAt = &now
 		msgID := fmt.Sprintf("m_%d", now.UnixNano())
-		m := model.Message{ID: msgID, DraftID: d.ID, From: from, To: d.To, Subject: d.Subject, Body: d.Body, Tags: d.Tags, SentAt: now}
+		m := model.Message{ID: msgID, DraftID: d.ID, From: from, To: d.To, Subject: d.Subject, Body: d.Body, Tags: d.Tags, InReplyTo: d.InReplyTo, SentAt: now}
 		st.Messages[msgID] = m
-		st.Drafts[d.ID] = d
-		return messageSendResponse{Sent: true, Message: m, SendPath: "local_state", Source: "local"}, true, nil
+		post := finalizeLocalSentDraft(st, d, msgID, postSendAction, now)
+		post.AnsweredMessageID = markLocalOriginalAnswered(st, d)
+		resp := messageSendResponse{Sent: true, Message: m, SendPath: "local_state", Source: "local", SentMessageID: msgID, PostSend: &post}
+		_ = idempotencyStore(st, *idempotencyKey, "message.send", payload, resp)
+		return resp, true, nil
 	case "send-many":
-		fs := flag.NewFlagSet("message send-many", flag.ContinueOnError)
-		fs.SetOutput(io.Discard)
+		fs := newFlagSet("message send-many")
 		file := fs.String("file", "", "manifest json path or -")
 		fromStdin := fs.Bool("stdin", false, "read manifest json from stdin")
-		if helpData, handled, err := parseFlagSetWithHelp(fs, args, g, "message send-many", runtimeStdout); err != nil {
+		passwordFile := fs.String("smtp-password-file", "", "path to smtp password file")
+		idempotencyKey := fs.String("idempotency-key", "", "idempotency key")
+		postSend := fs.String("post-send", "", "draft action after send: delete|move-to-sent|keep (default from config)")
+		waitQuota := fs.Bool("wait-for-quota", false, "wait for send quota instead of failing with rate_limit")
+		if err := parseFlags(fs, args); err != nil {
 			return nil, false, err
-		} else if handled {
-			return helpData, false, nil
 		}
 		items, err := parseSendManyManifestInput(*file, *fromStdin)
 		if err != nil {
 			return nil, false, cliError{exit: 2, code: "validation_error", msg: err.Error()}
 		}
+		postSendAction, err := resolvePostSendAction(*postSend, cfg)
+		if err != nil {
+			return nil, false, cliError{exit: 2, code: "validation_error", msg: err.Error()}
+		}
+		if found, cached, err := idempotencyLookup(st, *idempotencyKey, "message.send-many", items); err != nil {
+			return nil, false, err
+		} else if found {
+			return cached, false, nil
+		}
+		if _, err := localSMTPPassword(cfg, st, *passwordFile); err != nil {
+			return nil, false, err
+		}
+		window := undoSendWindow(cfg, g)
 		results := make([]batchItemResponse, 0, len(items))
 		success := 0
 		for i, it := range items {
 			if strings.TrimSpace(it.ConfirmSend) == "" {
-				results = append(results, batchItemResponse{Index: i, OK: false, ErrorCode: "validation_error", Error: "missing confirm_send", DraftID: it.DraftID})
+				results = appendBatchResult(results, batchItemResponse{Index: i, OK: false, ErrorCode: "validation_error", Error: "missing confirm_send", DraftID: it.DraftID})
 				continue
 			}
 			uid, err := parseRequiredUID(it.DraftID, "--draft-id")
 			if err != nil {
-				results = append(results, batchItemResponse{Index: i, OK: false, ErrorCode: "validation_error", Error: "invalid draft_id"})
+				results = appendBatchResult(results, batchItemResponse{Index: i, OK: false, ErrorCode: "validation_error", Error: "invalid draft_id"})
 				continue
 			}
 			d, ok := st.Drafts[uid]
 			if !ok {
-				results = append(results, batchItemResponse{Index: i, OK: false, ErrorCode: "not_found", Error: "draft not found", DraftID: it.DraftID})
+				results = appendBatchResult(results, batchItemResponse{Index: i, OK: false, ErrorCode: "not_found", Error: "draft not found", DraftID: it.DraftID})
 				continue
 			}
-			if err := validateSendSafety(cfg, isNonInteractiveSend(g, runtimeStdinIsTTY()), it.ConfirmSend, it.DraftID, uid, false); err != nil {
+			if err := validateSendSafety(cfg, isNonInteractiveSend(g, runtimeStdinIsTTY()), it.ConfirmSend, it.DraftID, uid, false, recipientCheck{addresses: draftRecipients(d), confirmBulk: it.ConfirmBulk}); err != nil {
 				code := errorCodeFromErr(err, "confirmation_required")
-				results = append(results, batchItemResponse{Index: i, OK: false, ErrorCode: code, Error: code, DraftID: it.DraftID})
+				msg := code
+				if code == "policy_blocked" {
+					msg = err.Error()
+				}
+				results = appendBatchResult(results, batchItemResponse{Index: i, OK: false, ErrorCode: code, Error: msg, DraftID: it.DraftID})
+				continue
+			}
+			if err := enforceDraftLint(cfg, lintInputFromDraft(d), it.DraftID); err != nil {
+				results = appendBatchResult(results, batchItemResponse{Index: i, OK: false, ErrorCode: "lint_failed", Error: err.Error(), DraftID: it.DraftID})
+				continue
+			}
+			overridden, err := enforceDLP(cfg, st, g, dlpContentFromDraft(d), it.AllowFindings)
+			if err != nil {
+				results = appendBatchResult(results, batchItemResponse{Index: i, OK: false, ErrorCode: errorCodeFromErr(err, "dlp_blocked"), Error: err.Error(), DraftID: it.DraftID})
 				continue
 			}
 			if g.dryRun {
-				results = append(results, batchItemResponse{Index: i, OK: true, DraftID: it.DraftID, DryRun: true, SendPath: "local_state"})
+				results = appendBatchResult(results, batchItemResponse{Index: i, OK: true, DraftID: it.DraftID, DryRun: true, SendPath: "local_state"})
+				success++
+				continue
+			}
+			if window > 0 {
+				item := enqueueUndoSend(st, scheduleRequest{draftID: it.DraftID, confirm: it.ConfirmSend, confirmBulk: it.ConfirmBulk, allowFindings: it.AllowFindings, postSend: *postSend, passwordFile: *passwordFile}, window)
+				results = appendBatchResult(results, batchItemResponse{Index: i, OK: true, DraftID: it.DraftID, SendPath: "outbox", Pending: true, PendingUntil: item.At.Format(time.RFC3339), UndoToken: item.UndoToken, FlushWith: undoSendFlushCommand})
 				success++
 				continue
 			}
-			now := time.Now().UTC()
-			d.SentAt = &now
-			msgID := fmt.Sprintf("m_%d", now.UnixNano())
 			from := firstNonEmpty(st.Auth.Username, cfg.Bridge.Username)
 			if from == "" {
 				from = "local@example.com"
 			}
-			m := model.Message{ID: msgID, DraftID: d.ID, From: from, To: d.To, Subject: d.Subject, Body: d.Body, Tags: d.Tags, SentAt: now}
+			if err := consumeSendQuota(st, cfg, g, from, *waitQuota); err != nil {
+				results = appendBatchResult(results, batchItemResponse{Index: i, OK: false, ErrorCode: "rate_limit", Error: err.Error(), DraftID: it.DraftID, RetryAfterSeconds: retryAfterFromErr(err)})
+				continue
+			}
+			recordDLPOverrides(st, g, it.DraftID, overridden)
+			now := time.Now().UTC()
+			msgID := fmt.Sprintf("m_%d", now.UnixNano())
+			m := model.Message{ID: msgID, DraftID: d.ID, From: from, To: d.To, Subject: d.Subject, Body: d.Body, Tags: d.Tags, InReplyTo: d.InReplyTo, SentAt: now}
 			st.Messages[msgID] = m
-			st.Drafts[d.ID] = d
-			results = append(results, batchItemResponse{Index: i, OK: true, DraftID: it.DraftID, SendPath: "local_state", SentAt: now.Format(time.RFC3339)})
+			post := finalizeLocalSentDraft(st, d, msgID, postSendAction, now)
+			post.AnsweredMessageID = markLocalOriginalAnswered(st, d)
+			results = appendBatchResult(results, batchItemResponse{Index: i, OK: true, DraftID: it.DraftID, SendPath: "local_state", SentAt: now.Format(time.RFC3339), SentMessageID: msgID, PostSend: &post})
 			success++
 		}
 		resp := batchResultResponse{Results: results, Count: len(results), Success: success, Failed: len(results) - success, Source: "local"}
@@ -304,10 +421,10 @@ func cmdMessage(action string, args []string, g globalOptions, cfg config.Config
 		} else if success > 0 && (len(results)-success) > 0 {
 			resp.exitCode = 10
 		}
+		_ = idempotencyStore(st, *idempotencyKey, "message.send-many", items, resp)
 		return resp, success > 0, nil
 	case "follow-up":
-		fs := flag.NewFlagSet("message follow-up", flag.ContinueOnError)
-		fs.SetOutput(io.Discard)
+		fs := newFlagSet("message follow-up")
 		msgID := fs.String("message-id", "", "message id")
 		var to sliceFlag
 		subject := fs.String("subject", "", "subject override")
@@ -316,10 +433,8 @@ func cmdMessage(action string, args []string, g globalOptions, cfg config.Config
 		stdinBody := fs.Bool("stdin", false, "read body from stdin")
 		idempotencyKey := fs.String("idempotency-key", "", "idempotency key")
 		fs.Var(&to, "to", "recipient (repeat)")
-		if helpData, handled, err := parseFlagSetWithHelp(fs, args, g, "message follow-up", runtimeStdout); err != nil {
+		if err := parseFlags(fs, args); err != nil {
 			return nil, false, err
-		} else if handled {
-			return helpData, false, nil
 		}
 		uid, err := parseRequiredUID(*msgID, "--message-id")
 		if err != nil {
@@ -370,6 +485,7 @@ func cmdMessage(action string, args []string, g globalOptions, cfg config.Config
 			To:        recipients,
 			Subject:   followUpSubject,
 			Body:      bodyText,
+			InReplyTo: orig.ID,
 			CreatedAt: now,
 			UpdatedAt: now,
 		}
@@ -3,8 +3,8 @@ package app
 import (
 	"flag"
 	"fmt"
-	"io"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,57 +12,226 @@ import (
 )
 
 func cmdMailbox(action string, args []string, g globalOptions, st *model.State) (any, bool, error) {
-	boxes := []mailboxInfo{
-		{ID: "inbox", Name: "INBOX", Kind: "system", Count: len(st.Messages)},
-		{ID: "drafts", Name: "Drafts", Kind: "system", Count: len(st.Drafts)},
-		{ID: "sent", Name: "Sent", Kind: "system", Count: countSent(st.Messages)},
-	}
-	if action == "resolve" {
-		fs := flag.NewFlagSet("mailbox resolve", flag.ContinueOnError)
-		fs.SetOutput(io.Discard)
-		_ = fs.String("name", "", "mailbox id or name")
-		if helpData, handled, err := parseFlagSetWithHelp(fs, args, g, "mailbox resolve", runtimeStdout); err != nil {
+	if isMailboxAdminAction(action) {
+		req, err := parseMailboxAdminFlags(action, args)
+		if err != nil {
+			return nil, false, err
+		}
+		return cmdMailboxAdminLocal(req, g, st)
+	}
+	boxes := localMailboxes(st)
+	if action == "list" {
+		if _, err := parseMailboxListFlags(args); err != nil {
 			return nil, false, err
-		} else if handled {
-			return helpData, false, nil
 		}
 	}
 	return mailboxAction(action, args, boxes, "local")
 }
 
+func localMailboxes(st *model.State) []mailboxInfo {
+	boxes := []mailboxInfo{
+		{ID: "inbox", Name: "INBOX", Kind: "system", Count: countInMailbox(st.Messages, "INBOX")},
+		{ID: "drafts", Name: "Drafts", Kind: "system", Count: len(st.Drafts), SpecialUse: `\Drafts`},
+		{ID: "sent", Name: "Sent", Kind: "system", Count: countSent(st.Messages), SpecialUse: `\Sent`},
+		{ID: "archive", Name: "Archive", Kind: "system", Count: countInMailbox(st.Messages, "Archive"), SpecialUse: `\Archive`},
+		{ID: "trash", Name: "Trash", Kind: "system", Count: countInMailbox(st.Messages, "Trash"), SpecialUse: `\Trash`},
+	}
+	names := make([]string, 0, len(st.Folders))
+	for name := range st.Folders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		id, _ := classifyMailbox(name)
+		info := mailboxInfo{ID: id, Name: name, Kind: "custom", Count: countInMailbox(st.Messages, name)}
+		if i := strings.LastIndex(name, localMailboxDelimiter); i > 0 {
+			info.Parent = name[:i]
+		}
+		boxes = append(boxes, info)
+	}
+	for i := range boxes {
+		total, unread := boxes[i].Count, 0
+		if boxes[i].ID == "inbox" || boxes[i].ID == "archive" || boxes[i].ID == "trash" || boxes[i].Kind == "custom" {
+			unread = countUnreadInMailbox(st.Messages, boxes[i].Name)
+		}
+		boxes[i].Total = &total
+		boxes[i].Unread = &unread
+		for _, b := range boxes {
+			if b.Parent != "" && b.Parent == boxes[i].Name {
+				boxes[i].Children = append(boxes[i].Children, b.Name)
+			}
+		}
+	}
+	return boxes
+}
+
+func localMessageMailbox(m model.Message) string {
+	if strings.TrimSpace(m.Mailbox) == "" {
+		return "INBOX"
+	}
+	return m.Mailbox
+}
+
+func countInMailbox(msgs map[string]model.Message, mailbox string) int {
+	n := 0
+	for _, m := range msgs {
+		if localMessageMailbox(m) == mailbox {
+			n++
+		}
+	}
+	return n
+}
+
+func countUnreadInMailbox(msgs map[string]model.Message, mailbox string) int {
+	n := 0
+	for _, m := range msgs {
+		if localMessageMailbox(m) == mailbox && !contains(m.Flags, flagSeen) {
+			n++
+		}
+	}
+	return n
+}
+
 func countSent(msgs map[string]model.Message) int {
 	return len(msgs)
 }
 
+type localFilter struct {
+	query, from, to, subject, hasTag string
+	sinceID, after, before, cursor   string
+	unread                           bool
+	limit                            int
+
+	afterTime, beforeTime time.Time
+	minID                 int
+}
+
+func (f *localFilter) register(fs *flag.FlagSet, search bool) {
+	fs.StringVar(&f.query, "query", "", "text query")
+	fs.StringVar(&f.from, "from", "", "from filter")
+	fs.StringVar(&f.to, "to", "", "to filter")
+	if search {
+		fs.StringVar(&f.subject, "subject", "", "subject filter")
+		fs.StringVar(&f.hasTag, "has-tag", "", "tag")
+		fs.BoolVar(&f.unread, "unread", false, "only unread messages")
+		fs.StringVar(&f.sinceID, "since-id", "", "minimum id number (inclusive)")
+	}
+	fs.StringVar(&f.after, "after", "", "date filter YYYY-MM-DD")
+	fs.StringVar(&f.before, "before", "", "date filter YYYY-MM-DD")
+	fs.IntVar(&f.limit, "limit", 50, "max results")
+	fs.StringVar(&f.cursor, "cursor", "", "offset cursor")
+}
+
+func (f *localFilter) validate() error {
+	if _, err := buildIMAPCriteria(f.query, f.subject, f.from, f.to, f.hasTag, f.unread, f.sinceID, f.after, f.before); err != nil {
+		return cliError{exit: 2, code: "validation_error", msg: err.Error()}
+	}
+	f.afterTime, _, _ = parseDateInput(f.after)
+	f.beforeTime, _, _ = parseDateInput(f.before)
+	f.minID, _ = strconv.Atoi(strings.TrimSpace(f.sinceID))
+	return nil
+}
+
+func (f *localFilter) matches(id, from string, to []string, subject, body string, tags, flags []string, date time.Time) bool {
+	has := func(field, want string) bool {
+		return strings.Contains(strings.ToLower(field), strings.ToLower(strings.TrimSpace(want)))
+	}
+	switch {
+	case f.query != "" && !has(subject+" "+body+" "+from+" "+strings.Join(to, " "), f.query),
+		f.from != "" && !has(from, f.from),
+		f.to != "" && !has(strings.Join(to, " "), f.to),
+		f.subject != "" && !has(subject, f.subject),
+		f.hasTag != "" && !contains(tags, f.hasTag) && !contains(flags, f.hasTag),
+		f.unread && contains(flags, flagSeen),
+		f.minID > 0 && uidAsInt(id[strings.LastIndex(id, "_")+1:]) < f.minID,
+		!f.afterTime.IsZero() && date.Before(f.afterTime),
+		!f.beforeTime.IsZero() && !date.Before(f.beforeTime):
+		return false
+	}
+	return true
+}
+
+func (f *localFilter) page(n int) (int, int, string) {
+	start, limit := parsePage(f.cursor, f.limit)
+	start = min(start, n)
+	end := min(start+limit, n)
+	next := ""
+	if end < n {
+		next = strconv.Itoa(end)
+	}
+	return start, end, next
+}
+
+func filterLocalDrafts(st *model.State, f *localFilter) ([]model.Draft, int, string) {
+	out := []model.Draft{}
+	for _, d := range st.Drafts {
+		if f.matches(d.ID, st.Auth.Username, d.To, d.Subject, d.Body, d.Tags, []string{flagSeen}, d.UpdatedAt) {
+			out = append(out, d)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].UpdatedAt.Equal(out[j].UpdatedAt) {
+			return out[i].UpdatedAt.After(out[j].UpdatedAt)
+		}
+		return out[i].ID > out[j].ID
+	})
+	start, end, next := f.page(len(out))
+	return out[start:end], len(out), next
+}
+
 func cmdSearch(action string, args []string, g globalOptions, st *model.State) (any, bool, error) {
 	if action != "messages" && action != "drafts" {
 		return nil, false, cliError{exit: 2, code: "usage_error", msg: "search supports messages|drafts"}
 	}
-	fs := flag.NewFlagSet("search", flag.ContinueOnError)
-	fs.SetOutput(io.Discard)
-	query := fs.String("query", "", "query")
-	if helpData, handled, err := parseFlagSetWithHelp(fs, args, g, "search "+action, runtimeStdout); err != nil {
+	fs := newFlagSet("search")
+	var filter localFilter
+	filter.register(fs, true)
+	mailbox := fs.String("mailbox", "", "mailbox name (messages only)")
+	authFail := fs.Bool("auth-fail", false, "only messages failing SPF, DKIM or DMARC (messages only)")
+	if err := parseFlags(fs, args); err != nil {
 		return nil, false, err
-	} else if handled {
-		return helpData, false, nil
 	}
-	q := strings.ToLower(*query)
-	if action == "drafts" {
-		out := []model.Draft{}
-		for _, d := range st.Drafts {
-			if q == "" || strings.Contains(strings.ToLower(d.Subject+" "+d.Body+" "+strings.Join(d.To, " ")), q) {
-				out = append(out, d)
-			}
+	if *authFail && action == "drafts" {
+		return nil, false, cliError{exit: 2, code: "validation_error", msg: "--auth-fail is only supported for search messages"}
+	}
+	if strings.TrimSpace(*mailbox) != "" && action == "drafts" {
+		return nil, false, cliError{exit: 2, code: "validation_error", msg: "--mailbox is only supported for search messages"}
+	}
+	if err := filter.validate(); err != nil {
+		return nil, false, err
+	}
+	box := ""
+	if strings.TrimSpace(*mailbox) != "" {
+		info, err := resolveMailboxArg(localMailboxes(st), *mailbox, "--mailbox")
+		if err != nil {
+			return nil, false, err
 		}
-		return localSearchDraftsResponse{Drafts: out, Count: len(out)}, false, nil
+		box = info.Name
+	}
+	if action == "drafts" {
+		out, total, next := filterLocalDrafts(st, &filter)
+		return localSearchDraftsResponse{Drafts: out, Count: len(out), Total: total, NextCursor: next}, false, nil
 	}
 	out := []model.Message{}
 	for _, m := range st.Messages {
-		if q == "" || strings.Contains(strings.ToLower(m.Subject+" "+m.Body+" "+strings.Join(m.To, " ")), q) {
+		if *authFail && !authFailed(parseAuthentication(localMessageHeaders(m))) {
+			continue
+		}
+		if box != "" && box != "Sent" && localMessageMailbox(m) != box {
+			continue
+		}
+		if filter.matches(m.ID, m.From, m.To, m.Subject, m.Body, m.Tags, m.Flags, m.SentAt) {
 			out = append(out, m)
 		}
 	}
-	return localSearchMessagesResponse{Messages: out, Count: len(out)}, false, nil
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].SentAt.Equal(out[j].SentAt) {
+			return out[i].SentAt.After(out[j].SentAt)
+		}
+		return out[i].ID > out[j].ID
+	})
+	start, end, next := filter.page(len(out))
+	return localSearchMessagesResponse{Messages: out[start:end], Count: end - start, Total: len(out), NextCursor: next}, false, nil
 }
 
 func cmdTag(action string, args []string, g globalOptions, st *model.State) (any, bool, error) {
@@ -75,13 +244,10 @@ func cmdTag(action string, args []string, g globalOptions, st *model.State) (any
 		sort.Strings(list)
 		return tagListResponse{Tags: list, Count: len(list)}, false, nil
 	case "create":
-		fs := flag.NewFlagSet("tag create", flag.ContinueOnError)
-		fs.SetOutput(io.Discard)
+		fs := newFlagSet("tag create")
 		name := fs.String("name", "", "tag name")
-		if helpData, handled, err := parseFlagSetWithHelp(fs, args, g, "tag create", runtimeStdout); err != nil {
+		if err := parseFlags(fs, args); err != nil {
 			return nil, false, err
-		} else if handled {
-			return helpData, false, nil
 		}
 		if *name == "" {
 			return nil, false, cliError{exit: 2, code: "validation_error", msg: "--name required"}
@@ -95,14 +261,11 @@ func cmdTag(action string, args []string, g globalOptions, st *model.State) (any
 		}
 		return tagCreateResponse{Tag: tagInfo{ID: id, Name: *name}, Changed: changed}, changed, nil
 	case "add", "remove":
-		fs := flag.NewFlagSet("tag add/remove", flag.ContinueOnError)
-		fs.SetOutput(io.Discard)
+		fs := newFlagSet("tag add/remove")
 		msgID := fs.String("message-id", "", "message id")
 		tag := fs.String("tag", "", "tag name")
-		if helpData, handled, err := parseFlagSetWithHelp(fs, args, g, "tag "+action, runtimeStdout); err != nil {
+		if err := parseFlags(fs, args); err != nil {
 			return nil, false, err
-		} else if handled {
-			return helpData, false, nil
 		}
 		uid, err := parseRequiredUID(*msgID, "--message-id")
 		if err != nil {
@@ -148,15 +311,12 @@ func cmdFilter(action string, args []string, g globalOptions, st *model.State) (
 		}
 		return filterListResponse{Filters: filters, Count: len(filters)}, false, nil
 	case "create":
-		fs := flag.NewFlagSet("filter create", flag.ContinueOnError)
-		fs.SetOutput(io.Discard)
+		fs := newFlagSet("filter create")
 		name := fs.String("name", "", "name")
 		containsQ := fs.String("contains", "", "subject/body contains")
 		addTag := fs.String("add-tag", "", "tag to add")
-		if helpData, handled, err := parseFlagSetWithHelp(fs, args, g, "filter create", runtimeStdout); err != nil {
+		if err := parseFlags(fs, args); err != nil {
 			return nil, false, err
-		} else if handled {
-			return helpData, false, nil
 		}
 		if *name == "" || *containsQ == "" || *addTag == "" {
 			return nil, false, cliError{exit: 2, code: "validation_error", msg: "--name, --contains and --add-tag are required"}
@@ -168,13 +328,10 @@ func cmdFilter(action string, args []string, g globalOptions, st *model.State) (
 		}
 		return filterCreateResponse{Filter: f}, true, nil
 	case "delete":
-		fs := flag.NewFlagSet("filter delete", flag.ContinueOnError)
-		fs.SetOutput(io.Discard)
+		fs := newFlagSet("filter delete")
 		id := fs.String("filter-id", "", "filter id")
-		if helpData, handled, err := parseFlagSetWithHelp(fs, args, g, "filter delete", runtimeStdout); err != nil {
+		if err := parseFlags(fs, args); err != nil {
 			return nil, false, err
-		} else if handled {
-			return helpData, false, nil
 		}
 		if _, ok := st.Filters[*id]; !ok {
 			return nil, false, cliError{exit: 5, code: "not_found", msg: "filter not found"}
@@ -184,13 +341,10 @@ func cmdFilter(action string, args []string, g globalOptions, st *model.State) (
 		}
 		return filterDeleteResponse{Deleted: true, FilterID: *id}, true, nil
 	case "test", "apply":
-		fs := flag.NewFlagSet("filter test/apply", flag.ContinueOnError)
-		fs.SetOutput(io.Discard)
+		fs := newFlagSet("filter test/apply")
 		id := fs.String("filter-id", "", "filter id")
-		if helpData, handled, err := parseFlagSetWithHelp(fs, args, g, "filter "+action, runtimeStdout); err != nil {
+		if err := parseFlags(fs, args); err != nil {
 			return nil, false, err
-		} else if handled {
-			return helpData, false, nil
 		}
 		f, ok := st.Filters[*id]
 		if !ok {
@@ -1,8 +1,6 @@
 package app
 
 import (
-	"flag"
-	"io"
 	"strings"
 )
 
@@ -11,11 +9,10 @@ func mailboxAction(action string, args []string, boxes []mailboxInfo, source str
 	case "list":
 		return mailboxListResponse{Mailboxes: boxes, Count: len(boxes)}, false, nil
 	case "resolve":
-		fs := flag.NewFlagSet("mailbox resolve", flag.ContinueOnError)
-		fs.SetOutput(io.Discard)
+		fs := newFlagSet("mailbox resolve")
 		name := fs.String("name", "", "mailbox id or name")
-		if err := fs.Parse(args); err != nil {
-			return nil, false, cliError{exit: 2, code: "usage_error", msg: err.Error()}
+		if err := parseFlags(fs, args); err != nil {
+			return nil, false, err
 		}
 		mailbox, matchedBy, ambiguous, err := resolveMailboxQuery(boxes, *name)
 		if err != nil {
@@ -33,3 +30,12 @@ func mailboxAction(action string, args []string, boxes []mailboxInfo, source str
 		return nil, false, cliError{exit: 2, code: "usage_error", msg: "unknown mailbox action: " + action}
 	}
 }
+
+func parseMailboxListFlags(args []string) (bool, error) {
+	fs := newFlagSet("mailbox list")
+	noStatus := fs.Bool("no-status", false, "skip per-mailbox STATUS counts")
+	if err := parseFlags(fs, args); err != nil {
+		return false, err
+	}
+	return !*noStatus, nil
+}
@@ -0,0 +1,347 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"protonmailcli/internal/bridge"
+	"protonmailcli/internal/model"
+)
+
+const localMailboxDelimiter = "/"
+
+type imapMailboxAdminClient interface {
+	ListMailboxEntries() ([]bridge.MailboxEntry, error)
+	HierarchyDelimiter() (string, error)
+	SearchUIDs(mailbox, criteria string) ([]string, error)
+	CreateMailbox(name string) error
+	RenameMailbox(oldName, newName string) error
+	DeleteMailbox(name string) error
+	SubscribeMailbox(name string, subscribe bool) error
+}
+
+type mailboxAdminRequest struct {
+	action        string
+	name          string
+	to            string
+	force         bool
+	confirmDelete string
+}
+
+func isMailboxAdminAction(action string) bool {
+	switch action {
+	case "create", "rename", "delete", "subscribe", "unsubscribe":
+		return true
+	}
+	return false
+}
+
+func parseMailboxAdminFlags(action string, args []string) (mailboxAdminRequest, error) {
+	fs := newFlagSet("mailbox " + action)
+	nameHelp := "mailbox id or name"
+	if action == "create" {
+		nameHelp = "mailbox name (use / between hierarchy levels)"
+	}
+	name := fs.String("name", "", nameHelp)
+	to := new(string)
+	if action == "rename" {
+		to = fs.String("to", "", "new mailbox name (use / between hierarchy levels)")
+	}
+	force := new(bool)
+	confirm := new(string)
+	if action == "delete" {
+		force = fs.Bool("force", false, "delete even if the mailbox contains messages or child mailboxes")
+		confirm = fs.String("confirm-delete", "", "mailbox name (required with --force for non-empty mailboxes)")
+	}
+	if err := parseFlags(fs, args); err != nil {
+		return mailboxAdminRequest{}, err
+	}
+	req := mailboxAdminRequest{action: action, name: strings.TrimSpace(*name), to: strings.TrimSpace(*to), force: *force, confirmDelete: strings.TrimSpace(*confirm)}
+	if req.name == "" {
+		return req, cliError{exit: 2, code: "validation_error", msg: "--name is required"}
+	}
+	if action == "rename" && req.to == "" {
+		return req, cliError{exit: 2, code: "validation_error", msg: "--to is required"}
+	}
+	return req, nil
+}
+
+func mailboxPath(name, delim string) (string, error) {
+	segments := strings.Split(strings.TrimSpace(name), "/")
+	for i, seg := range segments {
+		segments[i] = strings.TrimSpace(seg)
+		if segments[i] == "" {
+			return "", fmt.Errorf("invalid mailbox name %q: empty hierarchy level", name)
+		}
+		if delim != "" && delim != "/" && strings.Contains(segments[i], delim) {
+			return "", fmt.Errorf("invalid mailbox name %q: %q is the server hierarchy delimiter", name, delim)
+		}
+	}
+	if len(segments) > 1 && delim == "" {
+		return "", fmt.Errorf("server does not support mailbox hierarchy; %q cannot be nested", name)
+	}
+	return strings.Join(segments, delim), nil
+}
+
+func mailboxExists(boxes []mailboxInfo, name string) (mailboxInfo, bool) {
+	for _, b := range boxes {
+		if b.Name == name || (strings.EqualFold(name, "INBOX") && strings.EqualFold(b.Name, "INBOX")) {
+			return b, true
+		}
+	}
+	return mailboxInfo{}, false
+}
+
+func childMailboxes(boxes []mailboxInfo, parent, delim string) []string {
+	if delim == "" {
+		return nil
+	}
+	var out []string
+	for _, b := range boxes {
+		if strings.HasPrefix(b.Name, parent+delim) {
+			out = append(out, b.Name)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return len(out[i]) > len(out[j]) })
+	return out
+}
+
+func protectSystemMailbox(m mailboxInfo, action string) error {
+	if m.Kind != "system" {
+		return nil
+	}
+	return cliError{exit: 7, code: "safety_blocked", msg: fmt.Sprintf("system mailbox %q cannot be %s", m.Name, map[string]string{"rename": "renamed", "delete": "deleted"}[action])}
+}
+
+func validateMailboxDelete(req mailboxAdminRequest, m mailboxInfo, messages int, children []string) error {
+	if messages == 0 && len(children) == 0 {
+		return nil
+	}
+	if !req.force {
+		return cliError{exit: 7, code: "mailbox_not_empty", msg: fmt.Sprintf("mailbox %q contains %d messages and %d child mailboxes", m.Name, messages, len(children)), hint: "Pass --force --confirm-delete " + m.Name}
+	}
+	if req.confirmDelete != m.Name {
+		return cliError{exit: 7, code: "confirmation_required", msg: "--confirm-delete is required to delete a non-empty mailbox", hint: "Pass --confirm-delete " + m.Name}
+	}
+	return nil
+}
+
+func cmdMailboxAdminIMAP(c imapMailboxAdminClient, req mailboxAdminRequest, g globalOptions) (any, bool, error) {
+	entries, err := c.ListMailboxEntries()
+	if err != nil {
+		return nil, false, cliError{exit: 4, code: "imap_list_failed", msg: err.Error()}
+	}
+	boxes := mailboxInfosFromEntries(entries)
+	delim, err := c.HierarchyDelimiter()
+	if err != nil {
+		return nil, false, cliError{exit: 4, code: "imap_list_failed", msg: err.Error()}
+	}
+	resp := mailboxChangeResponse{Action: req.action, Delimiter: delim, DryRun: g.dryRun, Source: "imap"}
+	switch req.action {
+	case "create":
+		path, err := mailboxPath(req.name, delim)
+		if err != nil {
+			return nil, false, cliError{exit: 2, code: "validation_error", msg: err.Error()}
+		}
+		if existing, ok := mailboxExists(boxes, path); ok {
+			resp.Mailbox = existing
+			return resp, false, nil
+		}
+		resp.Mailbox = mailboxInfosFromNames([]string{path})[0]
+		if g.dryRun {
+			return resp, false, nil
+		}
+		if err := c.CreateMailbox(path); err != nil {
+			return nil, false, cliError{exit: 4, code: "imap_mailbox_update_failed", msg: err.Error()}
+		}
+	case "rename":
+		src, err := resolveMailboxArg(boxes, req.name, "--name")
+		if err != nil {
+			return nil, false, err
+		}
+		if err := protectSystemMailbox(src, req.action); err != nil {
+			return nil, false, err
+		}
+		path, err := mailboxPath(req.to, delim)
+		if err != nil {
+			return nil, false, cliError{exit: 2, code: "validation_error", msg: err.Error()}
+		}
+		if _, ok := mailboxExists(boxes, path); ok {
+			return nil, false, cliError{exit: 6, code: "mailbox_exists", msg: "mailbox already exists: " + path}
+		}
+		resp.PreviousName = src.Name
+		resp.Mailbox = mailboxInfosFromNames([]string{path})[0]
+		if g.dryRun {
+			return resp, false, nil
+		}
+		if err := c.RenameMailbox(src.Name, path); err != nil {
+			return nil, false, cliError{exit: 4, code: "imap_mailbox_update_failed", msg: err.Error()}
+		}
+	case "delete":
+		m, err := resolveMailboxArg(boxes, req.name, "--name")
+		if err != nil {
+			return nil, false, err
+		}
+		if err := protectSystemMailbox(m, req.action); err != nil {
+			return nil, false, err
+		}
+		uids, err := c.SearchUIDs(m.Name, "ALL")
+		if err != nil {
+			return nil, false, cliError{exit: 4, code: "imap_search_failed", msg: err.Error()}
+		}
+		children := childMailboxes(boxes, m.Name, delim)
+		resp.Mailbox = m
+		resp.MessageCount = len(uids)
+		resp.DeletedChildren = children
+		if g.dryRun {
+			if len(uids) > 0 || len(children) > 0 {
+				resp.ConfirmDelete = m.Name
+			}
+			return resp, false, nil
+		}
+		if err := validateMailboxDelete(req, m, len(uids), children); err != nil {
+			return nil, false, err
+		}
+		for _, child := range append(children, m.Name) {
+			if err := c.DeleteMailbox(child); err != nil {
+				return nil, false, cliError{exit: 4, code: "imap_mailbox_update_failed", msg: err.Error()}
+			}
+		}
+	case "subscribe", "unsubscribe":
+		m, err := resolveMailboxArg(boxes, req.name, "--name")
+		if err != nil {
+			return nil, false, err
+		}
+		subscribed := req.action == "subscribe"
+		resp.Mailbox = m
+		resp.Subscribed = &subscribed
+		if g.dryRun {
+			return resp, false, nil
+		}
+		if err := c.SubscribeMailbox(m.Name, subscribed); err != nil {
+			return nil, false, cliError{exit: 4, code: "imap_mailbox_update_failed", msg: err.Error()}
+		}
+	}
+	resp.Changed = true
+	return resp, true, nil
+}
+
+func cmdMailboxAdminLocal(req mailboxAdminRequest, g globalOptions, st *model.State) (any, bool, error) {
+	if st.Folders == nil {
+		st.Folders = map[string]model.Folder{}
+	}
+	boxes := localMailboxes(st)
+	resp := mailboxChangeResponse{Action: req.action, Delimiter: localMailboxDelimiter, DryRun: g.dryRun, Source: "local"}
+	switch req.action {
+	case "create":
+		path, err := mailboxPath(req.name, localMailboxDelimiter)
+		if err != nil {
+			return nil, false, cliError{exit: 2, code: "validation_error", msg: err.Error()}
+		}
+		if existing, ok := mailboxExists(boxes, path); ok {
+			resp.Mailbox = existing
+			return resp, false, nil
+		}
+		resp.Mailbox = mailboxInfosFromNames([]string{path})[0]
+		if g.dryRun {
+			return resp, false, nil
+		}
+		st.Folders[path] = model.Folder{Name: path, Subscribed: true, CreatedAt: time.Now().UTC()}
+	case "rename":
+		src, err := resolveMailboxArg(boxes, req.name, "--name")
+		if err != nil {
+			return nil, false, err
+		}
+		if err := protectSystemMailbox(src, req.action); err != nil {
+			return nil, false, err
+		}
+		path, err := mailboxPath(req.to, localMailboxDelimiter)
+		if err != nil {
+			return nil, false, cliError{exit: 2, code: "validation_error", msg: err.Error()}
+		}
+		if _, ok := mailboxExists(boxes, path); ok {
+			return nil, false, cliError{exit: 6, code: "mailbox_exists", msg: "mailbox already exists: " + path}
+		}
+		resp.PreviousName = src.Name
+		resp.Mailbox = mailboxInfosFromNames([]string{path})[0]
+		resp.Mailbox.Count = src.Count
+		if g.dryRun {
+			return resp, false, nil
+		}
+		renamed := func(name string) (string, bool) {
+			if name == src.Name {
+				return path, true
+			}
+			if strings.HasPrefix(name, src.Name+localMailboxDelimiter) {
+				return path + strings.TrimPrefix(name, src.Name), true
+			}
+			return "", false
+		}
+		for name, f := range st.Folders {
+			if next, ok := renamed(name); ok {
+				delete(st.Folders, name)
+				f.Name = next
+				st.Folders[next] = f
+			}
+		}
+		for id, m := range st.Messages {
+			if next, ok := renamed(m.Mailbox); ok {
+				m.Mailbox = next
+				st.Messages[id] = m
+			}
+		}
+	case "delete":
+		m, err := resolveMailboxArg(boxes, req.name, "--name")
+		if err != nil {
+			return nil, false, err
+		}
+		if err := protectSystemMailbox(m, req.action); err != nil {
+			return nil, false, err
+		}
+		children := childMailboxes(boxes, m.Name, localMailboxDelimiter)
+		resp.Mailbox = m
+		resp.MessageCount = m.Count
+		resp.DeletedChildren = children
+		if g.dryRun {
+			if m.Count > 0 || len(children) > 0 {
+				resp.ConfirmDelete = m.Name
+			}
+			return resp, false, nil
+		}
+		if err := validateMailboxDelete(req, m, m.Count, children); err != nil {
+			return nil, false, err
+		}
+		for _, name := range append(children, m.Name) {
+			delete(st.Folders, name)
+			for id, msg := range st.Messages {
+				if msg.Mailbox == name {
+					delete(st.Messages, id)
+				}
+			}
+		}
+	case "subscribe", "unsubscribe":
+		m, err := resolveMailboxArg(boxes, req.name, "--name")
+		if err != nil {
+			return nil, false, err
+		}
+		subscribed := req.action == "subscribe"
+		resp.Mailbox = m
+		resp.Subscribed = &subscribed
+		f, custom := st.Folders[m.Name]
+		if !custom {
+			if !subscribed {
+				return nil, false, cliError{exit: 7, code: "safety_blocked", msg: fmt.Sprintf("system mailbox %q cannot be unsubscribed", m.Name)}
+			}
+			return resp, false, nil
+		}
+		if f.Subscribed == subscribed || g.dryRun {
+			return resp, false, nil
+		}
+		f.Subscribed = subscribed
+		st.Folders[m.Name] = f
+	}
+	resp.Changed = true
+	return resp, true, nil
+}
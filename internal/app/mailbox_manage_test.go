@@ -0,0 +1,159 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"protonmailcli/internal/bridge"
+	"protonmailcli/internal/model"
+)
+
+type fakeMailboxAdminClient struct {
+	mailboxes []string
+	delim     string
+	uids      map[string][]string
+	calls     []string
+}
+
+func (f *fakeMailboxAdminClient) ListMailboxEntries() ([]bridge.MailboxEntry, error) {
+	return fakeMailboxEntries(f.mailboxes), nil
+}
+
+func (f *fakeMailboxAdminClient) HierarchyDelimiter() (string, error) { return f.delim, nil }
+
+func (f *fakeMailboxAdminClient) SearchUIDs(mailbox, criteria string) ([]string, error) {
+	return f.uids[mailbox], nil
+}
+
+func (f *fakeMailboxAdminClient) CreateMailbox(name string) error {
+	f.calls = append(f.calls, "CREATE "+name)
+	return nil
+}
+
+func (f *fakeMailboxAdminClient) RenameMailbox(oldName, newName string) error {
+	f.calls = append(f.calls, "RENAME "+oldName+" "+newName)
+	return nil
+}
+
+func (f *fakeMailboxAdminClient) DeleteMailbox(name string) error {
+	f.calls = append(f.calls, "DELETE "+name)
+	return nil
+}
+
+func (f *fakeMailboxAdminClient) SubscribeMailbox(name string, subscribe bool) error {
+	if subscribe {
+		f.calls = append(f.calls, "SUBSCRIBE "+name)
+	} else {
+		f.calls = append(f.calls, "UNSUBSCRIBE "+name)
+	}
+	return nil
+}
+
+func TestMailboxPathTranslatesDelimiter(t *testing.T) {
+	if got, err := mailboxPath("Projects / Alpha", "."); err != nil || got != "Projects.Alpha" {
+		t.Fatalf("unexpected path: %q %v", got, err)
+	}
+	if _, err := mailboxPath("Projects//Alpha", "/"); err == nil {
+		t.Fatal("empty hierarchy level should fail")
+	}
+	if _, err := mailboxPath("v1.2", "."); err == nil {
+		t.Fatal("delimiter inside a level should fail")
+	}
+	if _, err := mailboxPath("a/b", ""); err == nil {
+		t.Fatal("nesting without a delimiter should fail")
+	}
+}
+
+func TestMailboxCreateIMAPIsIdempotent(t *testing.T) {
+	c := &fakeMailboxAdminClient{mailboxes: []string{"INBOX", "Folders"}, delim: "/"}
+	data, changed, err := cmdMailboxAdminIMAP(c, mailboxAdminRequest{action: "create", name: "Folders/Work"}, globalOptions{})
+	if err != nil || !changed {
+		t.Fatalf("create: changed=%v err=%v", changed, err)
+	}
+	if resp := data.(mailboxChangeResponse); resp.Mailbox.Name != "Folders/Work" || resp.Mailbox.Kind != "custom" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	c.mailboxes = append(c.mailboxes, "Folders/Work")
+	if _, changed, err := cmdMailboxAdminIMAP(c, mailboxAdminRequest{action: "create", name: "Folders/Work"}, globalOptions{}); err != nil || changed {
+		t.Fatalf("second create: changed=%v err=%v", changed, err)
+	}
+	if strings.Join(c.calls, "|") != "CREATE Folders/Work" {
+		t.Fatalf("unexpected calls: %v", c.calls)
+	}
+}
+
+func TestMailboxRenameDeleteProtectSystemMailboxes(t *testing.T) {
+	c := &fakeMailboxAdminClient{mailboxes: []string{"INBOX", "Sent", "Trash"}, delim: "/"}
+	for _, req := range []mailboxAdminRequest{
+		{action: "rename", name: "sent", to: "Outbox"},
+		{action: "delete", name: "trash", force: true, confirmDelete: "Trash"},
+	} {
+		if _, _, err := cmdMailboxAdminIMAP(c, req, globalOptions{}); errorCodeFromErr(err, "") != "safety_blocked" {
+			t.Fatalf("%s: expected safety_blocked, got %v", req.action, err)
+		}
+	}
+	if len(c.calls) != 0 {
+		t.Fatalf("no mutation expected: %v", c.calls)
+	}
+}
+
+func TestMailboxDeleteIMAPNonEmptyNeedsForceAndConfirm(t *testing.T) {
+	c := &fakeMailboxAdminClient{
+		mailboxes: []string{"INBOX", "Folders/Work", "Folders/Work/Old"},
+		delim:     "/",
+		uids:      map[string][]string{"Folders/Work": {"1", "2"}},
+	}
+	req := mailboxAdminRequest{action: "delete", name: "Folders/Work"}
+	if _, _, err := cmdMailboxAdminIMAP(c, req, globalOptions{}); errorCodeFromErr(err, "") != "mailbox_not_empty" {
+		t.Fatalf("expected mailbox_not_empty, got %v", err)
+	}
+	req.force = true
+	if _, _, err := cmdMailboxAdminIMAP(c, req, globalOptions{}); errorCodeFromErr(err, "") != "confirmation_required" {
+		t.Fatalf("expected confirmation_required, got %v", err)
+	}
+	data, _, err := cmdMailboxAdminIMAP(c, req, globalOptions{dryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := data.(mailboxChangeResponse)
+	if plan.MessageCount != 2 || plan.ConfirmDelete != "Folders/Work" || len(c.calls) != 0 {
+		t.Fatalf("unexpected dry-run: %+v calls=%v", plan, c.calls)
+	}
+	req.confirmDelete = plan.ConfirmDelete
+	if _, _, err := cmdMailboxAdminIMAP(c, req, globalOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(c.calls, "|") != "DELETE Folders/Work/Old|DELETE Folders/Work" {
+		t.Fatalf("children must be deleted first: %v", c.calls)
+	}
+}
+
+func TestMailboxRenameLocalMovesChildrenAndMessages(t *testing.T) {
+	now := time.Now().UTC()
+	st := &model.State{
+		Folders: map[string]model.Folder{
+			"Projects":       {Name: "Projects", Subscribed: true, CreatedAt: now},
+			"Projects/Alpha": {Name: "Projects/Alpha", Subscribed: true, CreatedAt: now},
+		},
+		Messages: map[string]model.Message{
+			"m_1": {ID: "m_1", Mailbox: "Projects/Alpha"},
+			"m_2": {ID: "m_2"},
+		},
+	}
+	if _, _, err := cmdMailboxAdminLocal(mailboxAdminRequest{action: "rename", name: "projects", to: "Clients"}, globalOptions{}, st); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := st.Folders["Clients/Alpha"]; !ok {
+		t.Fatalf("child folder not renamed: %v", st.Folders)
+	}
+	if st.Messages["m_1"].Mailbox != "Clients/Alpha" || st.Messages["m_2"].Mailbox != "" {
+		t.Fatalf("unexpected message mailboxes: %+v", st.Messages)
+	}
+	if _, _, err := cmdMailboxAdminLocal(mailboxAdminRequest{action: "unsubscribe", name: "Clients"}, globalOptions{}, st); err != nil {
+		t.Fatal(err)
+	}
+	if st.Folders["Clients"].Subscribed {
+		t.Fatal("Clients should be unsubscribed")
+	}
+}
@@ -5,11 +5,34 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+
+	"protonmailcli/internal/bridge"
 )
 
 var nonMailboxIDChars = regexp.MustCompile(`[^a-z0-9]+`)
 
-func classifyMailbox(name string) (string, string) {
+var specialUseMailboxIDs = map[string]string{
+	`\drafts`:  "drafts",
+	`\sent`:    "sent",
+	`\archive`: "archive",
+	`\junk`:    "spam",
+	`\trash`:   "trash",
+	`\all`:     "all_mail",
+}
+
+func mailboxSpecialUse(attrs []string) string {
+	for _, a := range attrs {
+		if _, ok := specialUseMailboxIDs[strings.ToLower(a)]; ok {
+			return a
+		}
+	}
+	return ""
+}
+
+func classifyMailbox(name string, attrs ...string) (string, string) {
+	if use := mailboxSpecialUse(attrs); use != "" {
+		return specialUseMailboxIDs[strings.ToLower(use)], "system"
+	}
 	n := strings.TrimSpace(name)
 	l := strings.ToLower(n)
 	switch l {
@@ -68,3 +91,72 @@ func resolveMailboxQuery(mailboxes []mailboxInfo, query string) (mailboxInfo, st
 	}
 	return mailboxInfo{}, "", nil, fmt.Errorf("mailbox not found: %q", q)
 }
+
+func mailboxInfosFromNames(names []string) []mailboxInfo {
+	boxes := make([]mailboxInfo, 0, len(names))
+	for _, n := range names {
+		id, kind := classifyMailbox(n)
+		boxes = append(boxes, mailboxInfo{ID: id, Name: n, Kind: kind})
+	}
+	return boxes
+}
+
+func mailboxInfosFromEntries(entries []bridge.MailboxEntry) []mailboxInfo {
+	claimed := map[string]bool{}
+	for _, e := range entries {
+		if use := mailboxSpecialUse(e.Attributes); use != "" {
+			claimed[specialUseMailboxIDs[strings.ToLower(use)]] = true
+		}
+	}
+	byName := map[string]int{}
+	boxes := make([]mailboxInfo, 0, len(entries))
+	for _, e := range entries {
+		id, kind := classifyMailbox(e.Name, e.Attributes...)
+		use := mailboxSpecialUse(e.Attributes)
+		if use == "" && kind == "system" && id != "inbox" && claimed[id] {
+			kind = "custom"
+			id = "mailbox_" + id
+		}
+		info := mailboxInfo{ID: id, Name: e.Name, Kind: kind, SpecialUse: use, Attributes: e.Attributes}
+		if e.Delimiter != "" {
+			if i := strings.LastIndex(e.Name, e.Delimiter); i > 0 {
+				info.Parent = e.Name[:i]
+			}
+		}
+		if e.StatusErr != nil {
+			addWarning("mailbox " + e.Name + ": STATUS failed, counts unknown: " + e.StatusErr.Error())
+		}
+		if e.Status != nil {
+			total, unread := e.Status.Messages, e.Status.Unseen
+			info.Count = total
+			info.Total = &total
+			info.Unread = &unread
+			info.UIDNext = e.Status.UIDNext
+			info.UIDValidity = e.Status.UIDValidity
+			info.Size = e.Status.Size
+		}
+		byName[e.Name] = len(boxes)
+		boxes = append(boxes, info)
+	}
+	for _, b := range boxes {
+		if i, ok := byName[b.Parent]; ok && b.Parent != "" {
+			boxes[i].Children = append(boxes[i].Children, b.Name)
+		}
+	}
+	return boxes
+}
+
+func resolveMailboxArg(boxes []mailboxInfo, query, flagName string) (mailboxInfo, error) {
+	mailbox, _, ambiguous, err := resolveMailboxQuery(boxes, query)
+	if err != nil {
+		if len(ambiguous) > 0 {
+			ids := make([]string, 0, len(ambiguous))
+			for _, m := range ambiguous {
+				ids = append(ids, m.ID)
+			}
+			return mailboxInfo{}, cliError{exit: 2, code: "validation_error", msg: err.Error(), hint: "Disambiguate " + flagName + " with one of: " + strings.Join(ids, ", ")}
+		}
+		return mailboxInfo{}, cliError{exit: 5, code: "not_found", msg: err.Error()}
+	}
+	return mailbox, nil
+}
@@ -1,6 +1,12 @@
 package app
 
-import "testing"
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"protonmailcli/internal/bridge"
+)
 
 func TestClassifyMailboxSystem(t *testing.T) {
 	cases := map[string]string{
@@ -69,3 +75,44 @@ func TestResolveMailboxQueryAmbiguousCasefold(t *testing.T) {
 		t.Fatalf("expected 2 ambiguous matches got %d", len(ambiguous))
 	}
 }
+
+func TestClassifyMailboxPrefersSpecialUse(t *testing.T) {
+	gotID, gotKind := classifyMailbox("Papierkorb", `\HasNoChildren`, `\Trash`)
+	if gotID != "trash" || gotKind != "system" {
+		t.Fatalf("special-use should win: id=%s kind=%s", gotID, gotKind)
+	}
+}
+
+func TestMailboxInfosFromEntriesHierarchyAndStatus(t *testing.T) {
+	entries := []bridge.MailboxEntry{
+		{Name: "INBOX", Delimiter: "/", Status: &bridge.MailboxStatus{Messages: 10, Unseen: 3, UIDNext: 42, UIDValidity: 7}},
+		{Name: "Folders", Delimiter: "/", Attributes: []string{`\Noselect`, `\HasChildren`}},
+		{Name: "Folders/Work", Delimiter: "/"},
+		{Name: "Sent", Delimiter: "/"},
+		{Name: "Sent Items", Delimiter: "/", Attributes: []string{`\Sent`}},
+		{Name: "Archive", Delimiter: "/", StatusErr: errors.New("NO mailbox busy")},
+	}
+	runtimeWarnings = nil
+	t.Cleanup(func() { runtimeWarnings = nil })
+	boxes := mailboxInfosFromEntries(entries)
+	byName := map[string]mailboxInfo{}
+	for _, b := range boxes {
+		byName[b.Name] = b
+	}
+	inbox := byName["INBOX"]
+	if inbox.Total == nil || *inbox.Total != 10 || inbox.Unread == nil || *inbox.Unread != 3 || inbox.UIDNext != 42 || inbox.UIDValidity != 7 {
+		t.Fatalf("unexpected inbox status: %+v", inbox)
+	}
+	if byName["Folders/Work"].Parent != "Folders" || len(byName["Folders"].Children) != 1 {
+		t.Fatalf("unexpected hierarchy: %+v %+v", byName["Folders"], byName["Folders/Work"])
+	}
+	if byName["Sent Items"].ID != "sent" || byName["Sent Items"].SpecialUse != `\Sent` {
+		t.Fatalf("special-use Sent should own the sent id: %+v", byName["Sent Items"])
+	}
+	if byName["Sent"].ID == "sent" || byName["Sent"].Kind != "custom" {
+		t.Fatalf("name-only Sent must not collide with special-use: %+v", byName["Sent"])
+	}
+	if archive := byName["Archive"]; archive.Total != nil || archive.Unread != nil || len(runtimeWarnings) != 1 || !strings.Contains(runtimeWarnings[0], "Archive") {
+		t.Fatalf("a failed STATUS should leave counts unset and warn: %+v %v", archive, runtimeWarnings)
+	}
+}
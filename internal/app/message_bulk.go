@@ -0,0 +1,321 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"protonmailcli/internal/bridge"
+	"protonmailcli/internal/model"
+)
+
+type imapBulkClient interface {
+	ListMailboxEntries() ([]bridge.MailboxEntry, error)
+	SearchUIDs(mailbox, criteria string) ([]string, error)
+	StoreFlags(mailbox string, uids []string, flags []string, add bool) error
+	MoveUIDs(srcMailbox string, uids []string, dstMailbox string) error
+}
+
+type bulkAction struct {
+	kind   string
+	arg    string
+	add    []string
+	remove []string
+}
+
+type messageBulkRequest struct {
+	action   bulkAction
+	raw      string
+	mailbox  string
+	query    string
+	from     string
+	to       string
+	subject  string
+	hasTag   string
+	unread   bool
+	sinceID  string
+	after    string
+	before   string
+	plan     bool
+	confirm  string
+	criteria string
+}
+
+func parseBulkAction(v string) (bulkAction, error) {
+	raw := strings.TrimSpace(v)
+	kind, arg, _ := strings.Cut(raw, ":")
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	arg = strings.TrimSpace(arg)
+	switch kind {
+	case "tag", "untag":
+		if arg == "" {
+			return bulkAction{}, fmt.Errorf("--action %s requires a tag name (%s:<name>)", kind, kind)
+		}
+		if strings.HasPrefix(arg, `\`) {
+			return bulkAction{}, fmt.Errorf("--action %s: %q is a system flag, not a tag; use mark:<state>, trash or message delete", kind, arg)
+		}
+		if kind == "tag" {
+			return bulkAction{kind: kind, arg: arg, add: []string{arg}}, nil
+		}
+		return bulkAction{kind: kind, arg: arg, remove: []string{arg}}, nil
+	case "move":
+		if arg == "" {
+			return bulkAction{}, fmt.Errorf("--action move requires a mailbox (move:<mailbox>)")
+		}
+		return bulkAction{kind: kind, arg: arg}, nil
+	case "archive", "trash":
+		if arg != "" {
+			return bulkAction{}, fmt.Errorf("--action %s takes no argument", kind)
+		}
+		return bulkAction{kind: "move", arg: kind}, nil
+	case "mark":
+		a := bulkAction{kind: kind, arg: strings.ToLower(arg)}
+		switch a.arg {
+		case "read":
+			a.add = []string{flagSeen}
+		case "unread":
+			a.remove = []string{flagSeen}
+		case "flagged":
+			a.add = []string{flagFlagged}
+		case "unflagged":
+			a.remove = []string{flagFlagged}
+		case "answered":
+			a.add = []string{flagAnswered}
+		default:
+			return bulkAction{}, fmt.Errorf("--action mark supports read|unread|flagged|unflagged|answered")
+		}
+		return a, nil
+	}
+	return bulkAction{}, fmt.Errorf("unsupported --action %q (expected tag:<name>|untag:<name>|move:<mailbox>|mark:<state>|archive|trash)", raw)
+}
+
+func parseMessageBulkFlags(args []string, g globalOptions) (messageBulkRequest, error) {
+	fs := newFlagSet("message bulk")
+	action := fs.String("action", "", "tag:<name>|untag:<name>|move:<mailbox>|mark:read|mark:unread|mark:flagged|mark:unflagged|archive|trash")
+	mailbox := fs.String("mailbox", "INBOX", "mailbox to select messages from")
+	query := fs.String("query", "", "query")
+	from := fs.String("from", "", "from filter")
+	to := fs.String("to", "", "to filter")
+	subject := fs.String("subject", "", "subject filter")
+	hasTag := fs.String("has-tag", "", "imap keyword/tag")
+	unread := fs.Bool("unread", false, "only unread messages")
+	sinceID := fs.String("since-id", "", "minimum UID (inclusive)")
+	after := fs.String("after", "", "date filter YYYY-MM-DD")
+	before := fs.String("before", "", "date filter YYYY-MM-DD")
+	plan := fs.Bool("plan", false, "list affected messages and the plan hash without applying")
+	confirm := fs.String("confirm", "", "plan hash from --plan (required to apply)")
+	if err := parseFlags(fs, args); err != nil {
+		return messageBulkRequest{}, err
+	}
+	if strings.TrimSpace(*action) == "" {
+		return messageBulkRequest{}, cliError{exit: 2, code: "validation_error", msg: "--action is required"}
+	}
+	a, err := parseBulkAction(*action)
+	if err != nil {
+		return messageBulkRequest{}, cliError{exit: 2, code: "validation_error", msg: err.Error()}
+	}
+	req := messageBulkRequest{
+		action:  a,
+		raw:     strings.TrimSpace(*action),
+		mailbox: strings.TrimSpace(*mailbox),
+		query:   *query,
+		from:    *from,
+		to:      *to,
+		subject: *subject,
+		hasTag:  *hasTag,
+		unread:  *unread,
+		sinceID: *sinceID,
+		after:   *after,
+		before:  *before,
+		plan:    *plan || g.dryRun,
+		confirm: strings.TrimSpace(*confirm),
+	}
+	if req.mailbox == "" {
+		req.mailbox = "INBOX"
+	}
+	criteria, err := buildIMAPCriteria(req.query, req.subject, req.from, req.to, req.hasTag, req.unread, req.sinceID, req.after, req.before)
+	if err != nil {
+		return req, cliError{exit: 2, code: "validation_error", msg: err.Error()}
+	}
+	req.criteria = criteria
+	return req, nil
+}
+
+func bulkPlanHash(action, mailbox string, ids []string) string {
+	sorted := append([]string{}, ids...)
+	sort.Strings(sorted)
+	sum := sha256.Sum256([]byte(action + "\n" + mailbox + "\n" + strings.Join(sorted, "\n")))
+	return "plan-" + hex.EncodeToString(sum[:])[:12]
+}
+
+func validateBulkConfirm(req messageBulkRequest, hash string) error {
+	if req.confirm == hash {
+		return nil
+	}
+	if req.confirm == "" {
+		return cliError{exit: 7, code: "confirmation_required", msg: "--confirm <plan-hash> is required to apply a bulk action", hint: "Run with --plan first, then pass --confirm " + hash}
+	}
+	return cliError{exit: 7, code: "confirmation_required", msg: "plan hash mismatch: the matching messages changed since the plan was made", hint: "Review with --plan again, then pass --confirm " + hash}
+}
+
+func cmdMessageBulkIMAP(c imapBulkClient, req messageBulkRequest) (any, bool, error) {
+	resp := messageBulkResponse{Action: req.raw, Mailbox: req.mailbox, Criteria: req.criteria, MessageIDs: []string{}, Source: "imap"}
+	var dest *mailboxInfo
+	if req.action.kind == "move" {
+		entries, err := c.ListMailboxEntries()
+		if err != nil {
+			return nil, false, cliError{exit: 4, code: "imap_list_failed", msg: err.Error()}
+		}
+		if dest, err = resolveFilingDestination(mailboxInfosFromEntries(entries), req.action.arg); err != nil {
+			return nil, false, err
+		}
+		resp.ToMailbox = dest
+	}
+	uids, err := c.SearchUIDs(req.mailbox, req.criteria)
+	if err != nil {
+		return nil, false, cliError{exit: 4, code: "imap_search_failed", msg: err.Error()}
+	}
+	sort.Slice(uids, func(i, j int) bool { return uidAsInt(uids[i]) < uidAsInt(uids[j]) })
+	for _, uid := range uids {
+		resp.MessageIDs = append(resp.MessageIDs, imapMessageIDForMailbox(req.mailbox, uid))
+	}
+	resp.Count = len(uids)
+	resp.UIDSet = bridge.UIDSet(uids)
+	resp.PlanHash = bulkPlanHash(req.raw, req.mailbox, uids)
+	if req.plan {
+		resp.Plan = true
+		return resp, false, nil
+	}
+	if len(uids) == 0 {
+		return resp, false, nil
+	}
+	if err := validateBulkConfirm(req, resp.PlanHash); err != nil {
+		return nil, false, err
+	}
+	switch req.action.kind {
+	case "move":
+		if dest.Name != req.mailbox {
+			if err := c.MoveUIDs(req.mailbox, uids, dest.Name); err != nil {
+				return nil, false, cliError{exit: 4, code: "imap_move_failed", msg: err.Error()}
+			}
+		}
+	default:
+		if len(req.action.add) > 0 {
+			if err := c.StoreFlags(req.mailbox, uids, req.action.add, true); err != nil {
+				return nil, false, cliError{exit: 4, code: "imap_flag_update_failed", msg: err.Error()}
+			}
+		}
+		if len(req.action.remove) > 0 {
+			if err := c.StoreFlags(req.mailbox, uids, req.action.remove, false); err != nil {
+				return nil, false, cliError{exit: 4, code: "imap_flag_update_failed", msg: err.Error()}
+			}
+		}
+	}
+	resp.Applied = true
+	return resp, true, nil
+}
+
+func localBulkMatches(req messageBulkRequest, m model.Message) (bool, error) {
+	if !strings.EqualFold(localMessageMailbox(m), req.mailbox) {
+		return false, nil
+	}
+	if q := strings.ToLower(strings.TrimSpace(req.query)); q != "" && !strings.Contains(strings.ToLower(m.Subject+" "+m.Body+" "+strings.Join(m.To, " ")), q) {
+		return false, nil
+	}
+	if v := strings.ToLower(strings.TrimSpace(req.subject)); v != "" && !strings.Contains(strings.ToLower(m.Subject), v) {
+		return false, nil
+	}
+	if v := strings.ToLower(strings.TrimSpace(req.from)); v != "" && !strings.Contains(strings.ToLower(m.From), v) {
+		return false, nil
+	}
+	if v := strings.ToLower(strings.TrimSpace(req.to)); v != "" && !strings.Contains(strings.ToLower(strings.Join(m.To, " ")), v) {
+		return false, nil
+	}
+	if v := strings.TrimSpace(req.hasTag); v != "" && !contains(m.Tags, v) {
+		return false, nil
+	}
+	if req.unread && contains(m.Flags, flagSeen) {
+		return false, nil
+	}
+	if d, ok, err := parseDateInput(req.after); err != nil {
+		return false, err
+	} else if ok && m.SentAt.Before(d) {
+		return false, nil
+	}
+	if d, ok, err := parseDateInput(req.before); err != nil {
+		return false, err
+	} else if ok && !m.SentAt.Before(d) {
+		return false, nil
+	}
+	return true, nil
+}
+
+func cmdMessageBulkLocal(req messageBulkRequest, st *model.State) (any, bool, error) {
+	if strings.TrimSpace(req.sinceID) != "" {
+		return nil, false, cliError{exit: 2, code: "validation_error", msg: "--since-id is only supported against IMAP"}
+	}
+	var dest *mailboxInfo
+	boxes := localMailboxes(st)
+	if mb, _, _, err := resolveMailboxQuery(boxes, req.mailbox); err == nil {
+		req.mailbox = mb.Name
+	}
+	resp := messageBulkResponse{Action: req.raw, Mailbox: req.mailbox, Criteria: req.criteria, MessageIDs: []string{}, Source: "local"}
+	if req.action.kind == "move" {
+		var err error
+		if dest, err = resolveFilingDestination(boxes, req.action.arg); err != nil {
+			return nil, false, err
+		}
+		if dest.ID == "drafts" {
+			return nil, false, cliError{exit: 2, code: "validation_error", msg: "messages cannot be filed into Drafts"}
+		}
+		resp.ToMailbox = dest
+	}
+	for id, m := range st.Messages {
+		ok, err := localBulkMatches(req, m)
+		if err != nil {
+			return nil, false, cliError{exit: 2, code: "validation_error", msg: err.Error()}
+		}
+		if ok {
+			resp.MessageIDs = append(resp.MessageIDs, id)
+		}
+	}
+	sort.Strings(resp.MessageIDs)
+	resp.Count = len(resp.MessageIDs)
+	resp.PlanHash = bulkPlanHash(req.raw, req.mailbox, resp.MessageIDs)
+	if req.plan {
+		resp.Plan = true
+		return resp, false, nil
+	}
+	if resp.Count == 0 {
+		return resp, false, nil
+	}
+	if err := validateBulkConfirm(req, resp.PlanHash); err != nil {
+		return nil, false, err
+	}
+	for _, id := range resp.MessageIDs {
+		m := st.Messages[id]
+		switch req.action.kind {
+		case "move":
+			m.Mailbox = dest.Name
+		case "tag":
+			if !contains(m.Tags, req.action.arg) {
+				m.Tags = append(m.Tags, req.action.arg)
+			}
+		case "untag":
+			next := make([]string, 0, len(m.Tags))
+			for _, t := range m.Tags {
+				if t != req.action.arg {
+					next = append(next, t)
+				}
+			}
+			m.Tags = next
+		default:
+			m.Flags = applyFlagChanges(m.Flags, req.action.add, req.action.remove)
+		}
+		st.Messages[id] = m
+	}
+	resp.Applied = true
+	return resp, true, nil
+}
@@ -0,0 +1,170 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"protonmailcli/internal/bridge"
+	"protonmailcli/internal/model"
+	"protonmailcli/internal/store"
+)
+
+type fakeBulkClient struct {
+	fakeFilingClient
+	uids     []string
+	criteria []string
+	stores   []string
+}
+
+func (f *fakeBulkClient) SearchUIDs(mailbox, criteria string) ([]string, error) {
+	f.criteria = append(f.criteria, mailbox+":"+criteria)
+	return f.uids, nil
+}
+
+func (f *fakeBulkClient) StoreFlags(mailbox string, uids []string, flags []string, add bool) error {
+	op := "-"
+	if add {
+		op = "+"
+	}
+	f.stores = append(f.stores, mailbox+":"+bridge.UIDSet(uids)+op+strings.Join(flags, " "))
+	return nil
+}
+
+func TestParseBulkAction(t *testing.T) {
+	a, err := parseBulkAction("tag:newsletter")
+	if err != nil || a.kind != "tag" || !slices.Equal(a.add, []string{"newsletter"}) {
+		t.Fatalf("unexpected tag action: %+v %v", a, err)
+	}
+	a, err = parseBulkAction("trash")
+	if err != nil || a.kind != "move" || a.arg != "trash" {
+		t.Fatalf("unexpected trash action: %+v %v", a, err)
+	}
+	a, err = parseBulkAction("mark:unread")
+	if err != nil || !slices.Equal(a.remove, []string{flagSeen}) {
+		t.Fatalf("unexpected mark action: %+v %v", a, err)
+	}
+	for _, bad := range []string{"tag:", `tag:\Deleted`, `untag:\Seen`, "move", "mark:loud", "explode", "archive:x"} {
+		if _, err := parseBulkAction(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestUIDSetCompressesRanges(t *testing.T) {
+	if got := bridge.UIDSet([]string{"7", "3", "4", "5", "9", "4"}); got != "3:5,7,9" {
+		t.Fatalf("unexpected uid set: %q", got)
+	}
+}
+
+func TestMessageBulkIMAPPlanThenApply(t *testing.T) {
+	c := &fakeBulkClient{uids: []string{"12", "10", "11"}}
+	req, err := parseMessageBulkFlags([]string{"--mailbox", "INBOX", "--from", "news@example.com", "--action", "mark:read", "--plan"}, globalOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, changed, err := cmdMessageBulkIMAP(c, req)
+	if err != nil || changed {
+		t.Fatalf("plan: changed=%v err=%v", changed, err)
+	}
+	plan := data.(messageBulkResponse)
+	if plan.Count != 3 || plan.UIDSet != "10:12" || plan.MessageIDs[0] != "imap:INBOX:10" || plan.PlanHash == "" {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+	if len(c.stores) != 0 {
+		t.Fatalf("plan must not store flags: %v", c.stores)
+	}
+	if c.criteria[0] != `INBOX:FROM "news@example.com"` {
+		t.Fatalf("unexpected criteria: %v", c.criteria)
+	}
+
+	req.plan = false
+	if _, _, err := cmdMessageBulkIMAP(c, req); errorCodeFromErr(err, "") != "confirmation_required" {
+		t.Fatalf("expected confirmation_required, got %v", err)
+	}
+	req.confirm = plan.PlanHash
+	if _, changed, err := cmdMessageBulkIMAP(c, req); err != nil || !changed {
+		t.Fatalf("apply: changed=%v err=%v", changed, err)
+	}
+	if strings.Join(c.stores, "|") != `INBOX:10:12+\Seen` {
+		t.Fatalf("unexpected stores: %v", c.stores)
+	}
+
+	c.uids = append(c.uids, "13")
+	if _, _, err := cmdMessageBulkIMAP(c, req); errorCodeFromErr(err, "") != "confirmation_required" {
+		t.Fatalf("stale plan hash should be rejected, got %v", err)
+	}
+}
+
+func TestMessageBulkIMAPMoveUsesSingleMove(t *testing.T) {
+	c := &fakeBulkClient{fakeFilingClient: fakeFilingClient{mailboxes: []string{"INBOX", "Archive"}}, uids: []string{"1", "2"}}
+	req, err := parseMessageBulkFlags([]string{"--query", "receipt", "--action", "move:archive"}, globalOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.confirm = bulkPlanHash(req.raw, req.mailbox, c.uids)
+	data, _, err := cmdMessageBulkIMAP(c, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp := data.(messageBulkResponse); !resp.Applied || resp.ToMailbox == nil || resp.ToMailbox.Name != "Archive" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if strings.Join(c.moves, "|") != "INBOX:1,2->Archive" {
+		t.Fatalf("unexpected moves: %v", c.moves)
+	}
+}
+
+func TestLocalMessageBulkTagPlanApply(t *testing.T) {
+	t.Setenv("PMAIL_USE_LOCAL_STATE", "1")
+	tmp := t.TempDir()
+	cfg := filepath.Join(tmp, "config.toml")
+	state := filepath.Join(tmp, "state.json")
+	if exit := Run([]string{"--json", "--config", cfg, "--state", state, "setup", "--non-interactive", "--username", "me@example.com"}, bytes.NewBuffer(nil), &bytes.Buffer{}, &bytes.Buffer{}); exit != 0 {
+		t.Fatalf("setup failed: %d", exit)
+	}
+	now := time.Now().UTC()
+	if err := store.New(state).Save(model.State{Messages: map[string]model.Message{
+		"m_1": {ID: "m_1", From: "news@example.com", Subject: "weekly digest", SentAt: now},
+		"m_2": {ID: "m_2", From: "news@example.com", Subject: "another digest", SentAt: now},
+		"m_3": {ID: "m_3", From: "boss@example.com", Subject: "digest review", SentAt: now},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	base := []string{"--json", "--config", cfg, "--state", state, "message", "bulk", "--from", "news@", "--action", "tag:newsletter"}
+	stdout := &bytes.Buffer{}
+	if exit := Run(append(base, "--plan"), bytes.NewBuffer(nil), stdout, &bytes.Buffer{}); exit != 0 {
+		t.Fatalf("plan failed: %d stdout=%s", exit, stdout.String())
+	}
+	var env struct {
+		Data messageBulkResponse `json:"data"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Data.Count != 2 || !slices.Equal(env.Data.MessageIDs, []string{"m_1", "m_2"}) {
+		t.Fatalf("unexpected plan: %+v", env.Data)
+	}
+	if exit := Run(base, bytes.NewBuffer(nil), &bytes.Buffer{}, &bytes.Buffer{}); exit != 7 {
+		t.Fatalf("apply without --confirm should exit 7, got %d", exit)
+	}
+	if exit := Run(append(base, "--confirm", env.Data.PlanHash), bytes.NewBuffer(nil), &bytes.Buffer{}, &bytes.Buffer{}); exit != 0 {
+		t.Fatalf("apply failed: %d", exit)
+	}
+	b, err := os.ReadFile(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var st model.State
+	if err := json.Unmarshal(b, &st); err != nil {
+		t.Fatal(err)
+	}
+	if !contains(st.Messages["m_1"].Tags, "newsletter") || !contains(st.Messages["m_2"].Tags, "newsletter") || contains(st.Messages["m_3"].Tags, "newsletter") {
+		t.Fatalf("unexpected tags: %+v", st.Messages)
+	}
+}
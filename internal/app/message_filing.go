@@ -0,0 +1,263 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"protonmailcli/internal/bridge"
+	"protonmailcli/internal/config"
+	"protonmailcli/internal/model"
+)
+
+type imapFilingClient interface {
+	ListMailboxEntries() ([]bridge.MailboxEntry, error)
+	MoveUIDs(srcMailbox string, uids []string, dstMailbox string) error
+	CopyUIDs(srcMailbox string, uids []string, dstMailbox string) error
+	ExpungeUIDs(mailbox string, uids []string) error
+}
+
+type messageFileRequest struct {
+	action         string
+	ids            []string
+	toMailbox      string
+	confirmDelete  string
+	idempotencyKey string
+}
+
+type mailboxUIDGroup struct {
+	Mailbox string
+	UIDs    []string
+}
+
+func isMessageFileAction(action string) bool {
+	switch action {
+	case "move", "copy", "archive", "trash", "delete":
+		return true
+	}
+	return false
+}
+
+func parseMessageFileFlags(action string, args []string) (messageFileRequest, error) {
+	fs := newFlagSet("message " + action)
+	var ids sliceFlag
+	fs.Var(&ids, "message-id", "message id (repeat)")
+	toMailbox := new(string)
+	if action == "move" || action == "copy" {
+		toMailbox = fs.String("to-mailbox", "", "destination mailbox id or name")
+	}
+	confirm := new(string)
+	if action == "delete" {
+		confirm = fs.String("confirm-delete", "", "confirmation token (message id, or token from --dry-run)")
+	}
+	idempotencyKey := fs.String("idempotency-key", "", "idempotency key")
+	if err := parseFlags(fs, args); err != nil {
+		return messageFileRequest{}, err
+	}
+	req := messageFileRequest{action: action, confirmDelete: strings.TrimSpace(*confirm), idempotencyKey: *idempotencyKey}
+	for _, id := range ids {
+		if strings.TrimSpace(id) != "" && !contains(req.ids, strings.TrimSpace(id)) {
+			req.ids = append(req.ids, strings.TrimSpace(id))
+		}
+	}
+	if len(req.ids) == 0 {
+		return req, cliError{exit: 2, code: "validation_error", msg: "at least one --message-id is required"}
+	}
+	switch action {
+	case "move", "copy":
+		req.toMailbox = strings.TrimSpace(*toMailbox)
+		if req.toMailbox == "" {
+			return req, cliError{exit: 2, code: "validation_error", msg: "--to-mailbox is required"}
+		}
+	case "archive":
+		req.toMailbox = "archive"
+	case "trash":
+		req.toMailbox = "trash"
+	}
+	return req, nil
+}
+
+func resolveFilingDestination(boxes []mailboxInfo, query string) (*mailboxInfo, error) {
+	if query == "" {
+		return nil, nil
+	}
+	mailbox, err := resolveMailboxArg(boxes, query, "--to-mailbox")
+	if err != nil {
+		return nil, err
+	}
+	return &mailbox, nil
+}
+
+func deleteConfirmToken(ids []string) string {
+	if len(ids) == 1 {
+		return ids[0]
+	}
+	sorted := append([]string{}, ids...)
+	sort.Strings(sorted)
+	sum := sha256.Sum256([]byte(strings.Join(sorted, "\n")))
+	return "del-" + hex.EncodeToString(sum[:])[:12]
+}
+
+func validateDeleteSafety(cfg config.Config, nonTTY bool, confirm string, ids []string) error {
+	if !cfg.Safety.RequireConfirmSendNonTTY || !nonTTY {
+		return nil
+	}
+	token := deleteConfirmToken(ids)
+	if confirm == token {
+		return nil
+	}
+	return cliError{exit: 7, code: "confirmation_required", msg: "--confirm-delete is required in non-interactive mode", hint: "Pass --confirm-delete " + token}
+}
+
+func groupByMailbox(ids []string) ([]mailboxUIDGroup, error) {
+	var groups []mailboxUIDGroup
+	index := map[string]int{}
+	for _, id := range ids {
+		mailbox, uid, err := parseMailboxUID(id, "INBOX")
+		if err != nil {
+			return nil, fmt.Errorf("invalid --message-id %q", id)
+		}
+		i, ok := index[mailbox]
+		if !ok {
+			i = len(groups)
+			index[mailbox] = i
+			groups = append(groups, mailboxUIDGroup{Mailbox: mailbox})
+		}
+		groups[i].UIDs = append(groups[i].UIDs, uid)
+	}
+	return groups, nil
+}
+
+func messageFilePayload(req messageFileRequest) map[string]any {
+	return map[string]any{"action": req.action, "messageIds": req.ids, "toMailbox": req.toMailbox}
+}
+
+func cmdMessageFileIMAP(c imapFilingClient, req messageFileRequest, g globalOptions, cfg config.Config, st *model.State) (any, bool, error) {
+	groups, err := groupByMailbox(req.ids)
+	if err != nil {
+		return nil, false, cliError{exit: 2, code: "validation_error", msg: err.Error()}
+	}
+	payload := messageFilePayload(req)
+	if found, cached, err := idempotencyLookup(st, req.idempotencyKey, "message."+req.action, payload); err != nil {
+		return nil, false, err
+	} else if found {
+		return cached, false, nil
+	}
+	var dest *mailboxInfo
+	if req.toMailbox != "" {
+		entries, err := c.ListMailboxEntries()
+		if err != nil {
+			return nil, false, cliError{exit: 4, code: "imap_list_failed", msg: err.Error()}
+		}
+		if dest, err = resolveFilingDestination(mailboxInfosFromEntries(entries), req.toMailbox); err != nil {
+			return nil, false, err
+		}
+	}
+	ids := make([]string, 0, len(req.ids))
+	for _, grp := range groups {
+		for _, uid := range grp.UIDs {
+			ids = append(ids, imapMessageIDForMailbox(grp.Mailbox, uid))
+		}
+	}
+	resp := messageFileResponse{Action: req.action, MessageIDs: ids, Count: len(ids), Mailbox: dest, Source: "imap"}
+	if g.dryRun {
+		resp.DryRun = true
+		if req.action == "delete" {
+			resp.ConfirmDelete = deleteConfirmToken(req.ids)
+		}
+		return resp, true, nil
+	}
+	if req.action == "delete" {
+		if err := validateDeleteSafety(cfg, isNonInteractiveSend(g, runtimeStdinIsTTY()), req.confirmDelete, req.ids); err != nil {
+			return nil, false, err
+		}
+	}
+	for _, grp := range groups {
+		switch req.action {
+		case "copy":
+			if err := c.CopyUIDs(grp.Mailbox, grp.UIDs, dest.Name); err != nil {
+				return nil, false, cliError{exit: 4, code: "imap_copy_failed", msg: err.Error()}
+			}
+		case "delete":
+			if err := c.ExpungeUIDs(grp.Mailbox, grp.UIDs); err != nil {
+				return nil, false, cliError{exit: 4, code: "imap_delete_failed", msg: err.Error()}
+			}
+		default:
+			if grp.Mailbox == dest.Name {
+				continue
+			}
+			if err := c.MoveUIDs(grp.Mailbox, grp.UIDs, dest.Name); err != nil {
+				return nil, false, cliError{exit: 4, code: "imap_move_failed", msg: err.Error()}
+			}
+		}
+	}
+	resp.Changed = true
+	_ = idempotencyStore(st, req.idempotencyKey, "message."+req.action, payload, resp)
+	return resp, true, nil
+}
+
+func cmdMessageFileLocal(req messageFileRequest, g globalOptions, cfg config.Config, st *model.State) (any, bool, error) {
+	payload := messageFilePayload(req)
+	if found, cached, err := idempotencyLookup(st, req.idempotencyKey, "message."+req.action, payload); err != nil {
+		return nil, false, err
+	} else if found {
+		return cached, false, nil
+	}
+	dest, err := resolveFilingDestination(localMailboxes(st), req.toMailbox)
+	if err != nil {
+		return nil, false, err
+	}
+	if dest != nil && dest.ID == "drafts" {
+		return nil, false, cliError{exit: 2, code: "validation_error", msg: "messages cannot be filed into Drafts"}
+	}
+	uids := make([]string, 0, len(req.ids))
+	for _, id := range req.ids {
+		uid, err := parseRequiredUID(id, "--message-id")
+		if err != nil {
+			return nil, false, cliError{exit: 2, code: "validation_error", msg: err.Error()}
+		}
+		if _, ok := st.Messages[uid]; !ok {
+			return nil, false, cliError{exit: 5, code: "not_found", msg: "message not found: " + id}
+		}
+		uids = append(uids, uid)
+	}
+	resp := messageFileResponse{Action: req.action, MessageIDs: uids, Count: len(uids), Mailbox: dest, Source: "local"}
+	if g.dryRun {
+		resp.DryRun = true
+		if req.action == "delete" {
+			resp.ConfirmDelete = deleteConfirmToken(req.ids)
+		}
+		return resp, true, nil
+	}
+	if req.action == "delete" {
+		if err := validateDeleteSafety(cfg, isNonInteractiveSend(g, runtimeStdinIsTTY()), req.confirmDelete, req.ids); err != nil {
+			return nil, false, err
+		}
+	}
+	for _, uid := range uids {
+		m := st.Messages[uid]
+		switch req.action {
+		case "delete":
+			delete(st.Messages, uid)
+		case "copy":
+			cp := m
+			cp.ID = fmt.Sprintf("m_%d", time.Now().UnixNano())
+			for _, exists := st.Messages[cp.ID]; exists; _, exists = st.Messages[cp.ID] {
+				cp.ID = fmt.Sprintf("m_%d", time.Now().UnixNano())
+			}
+			cp.Mailbox = dest.Name
+			cp.Tags = append([]string{}, m.Tags...)
+			st.Messages[cp.ID] = cp
+			resp.CopiedIDs = append(resp.CopiedIDs, cp.ID)
+		default:
+			m.Mailbox = dest.Name
+			st.Messages[uid] = m
+		}
+	}
+	resp.Changed = true
+	_ = idempotencyStore(st, req.idempotencyKey, "message."+req.action, payload, resp)
+	return resp, true, nil
+}
@@ -0,0 +1,182 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"protonmailcli/internal/bridge"
+	"protonmailcli/internal/config"
+	"protonmailcli/internal/model"
+	"protonmailcli/internal/store"
+)
+
+type fakeFilingClient struct {
+	mailboxes []string
+	moves     []string
+	copies    []string
+	expunged  []string
+}
+
+func (f *fakeFilingClient) ListMailboxEntries() ([]bridge.MailboxEntry, error) {
+	return fakeMailboxEntries(f.mailboxes), nil
+}
+
+func fakeMailboxEntries(names []string) []bridge.MailboxEntry {
+	out := make([]bridge.MailboxEntry, 0, len(names))
+	for _, n := range names {
+		out = append(out, bridge.MailboxEntry{Name: n, Delimiter: "/"})
+	}
+	return out
+}
+
+func (f *fakeFilingClient) MoveUIDs(srcMailbox string, uids []string, dstMailbox string) error {
+	f.moves = append(f.moves, srcMailbox+":"+strings.Join(uids, ",")+"->"+dstMailbox)
+	return nil
+}
+
+func (f *fakeFilingClient) CopyUIDs(srcMailbox string, uids []string, dstMailbox string) error {
+	f.copies = append(f.copies, srcMailbox+":"+strings.Join(uids, ",")+"->"+dstMailbox)
+	return nil
+}
+
+func (f *fakeFilingClient) ExpungeUIDs(mailbox string, uids []string) error {
+	f.expunged = append(f.expunged, mailbox+":"+strings.Join(uids, ","))
+	return nil
+}
+
+func TestMessageArchiveIMAPGroupsByMailbox(t *testing.T) {
+	c := &fakeFilingClient{mailboxes: []string{"INBOX", "Archive", "Folders/Work"}}
+	req := messageFileRequest{action: "archive", ids: []string{"imap:INBOX:1", "imap:INBOX:2", "imap:Folders/Work:9"}, toMailbox: "archive"}
+	data, changed, err := cmdMessageFileIMAP(c, req, globalOptions{}, config.Default(), &model.State{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !changed {
+		t.Fatal("archive should report a change")
+	}
+	resp := data.(messageFileResponse)
+	if resp.Mailbox == nil || resp.Mailbox.Name != "Archive" || resp.Count != 3 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	want := []string{"INBOX:1,2->Archive", "Folders/Work:9->Archive"}
+	if strings.Join(c.moves, "|") != strings.Join(want, "|") {
+		t.Fatalf("unexpected moves: %v", c.moves)
+	}
+}
+
+func TestMessageMoveIMAPUnknownDestination(t *testing.T) {
+	c := &fakeFilingClient{mailboxes: []string{"INBOX"}}
+	req := messageFileRequest{action: "move", ids: []string{"imap:INBOX:1"}, toMailbox: "Projects"}
+	_, _, err := cmdMessageFileIMAP(c, req, globalOptions{}, config.Default(), &model.State{})
+	if errorCodeFromErr(err, "") != "not_found" {
+		t.Fatalf("expected not_found, got %v", err)
+	}
+	if len(c.moves) != 0 {
+		t.Fatalf("no move expected: %v", c.moves)
+	}
+}
+
+func TestMessageDeleteIMAPRequiresConfirmToken(t *testing.T) {
+	c := &fakeFilingClient{}
+	ids := []string{"imap:INBOX:1", "imap:Trash:4"}
+	g := globalOptions{noInput: true}
+	_, _, err := cmdMessageFileIMAP(c, messageFileRequest{action: "delete", ids: ids}, g, config.Default(), &model.State{})
+	if errorCodeFromErr(err, "") != "confirmation_required" {
+		t.Fatalf("expected confirmation_required, got %v", err)
+	}
+
+	g.dryRun = true
+	data, _, err := cmdMessageFileIMAP(c, messageFileRequest{action: "delete", ids: ids}, g, config.Default(), &model.State{})
+	if err != nil {
+		t.Fatalf("dry-run: %v", err)
+	}
+	token := data.(messageFileResponse).ConfirmDelete
+	if token == "" || len(c.expunged) != 0 {
+		t.Fatalf("dry-run should return a token without expunging: token=%q expunged=%v", token, c.expunged)
+	}
+
+	g.dryRun = false
+	if _, _, err := cmdMessageFileIMAP(c, messageFileRequest{action: "delete", ids: ids, confirmDelete: token}, g, config.Default(), &model.State{}); err != nil {
+		t.Fatalf("confirmed delete: %v", err)
+	}
+	if strings.Join(c.expunged, "|") != "INBOX:1|Trash:4" {
+		t.Fatalf("unexpected expunges: %v", c.expunged)
+	}
+}
+
+func TestMessageFileIdempotencyReplay(t *testing.T) {
+	c := &fakeFilingClient{mailboxes: []string{"INBOX", "Trash"}}
+	st := &model.State{Idempotency: map[string]model.IdempotencyRecord{}}
+	req := messageFileRequest{action: "trash", ids: []string{"imap:INBOX:5"}, toMailbox: "trash", idempotencyKey: "k1"}
+	if _, _, err := cmdMessageFileIMAP(c, req, globalOptions{}, config.Default(), st); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := cmdMessageFileIMAP(c, req, globalOptions{}, config.Default(), st); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.moves) != 1 {
+		t.Fatalf("replay must not move again: %v", c.moves)
+	}
+}
+
+func TestLocalMessageArchiveUpdatesMailboxCounts(t *testing.T) {
+	t.Setenv("PMAIL_USE_LOCAL_STATE", "1")
+	tmp := t.TempDir()
+	cfg := filepath.Join(tmp, "config.toml")
+	state := filepath.Join(tmp, "state.json")
+	if exit := Run([]string{"--json", "--config", cfg, "--state", state, "setup", "--non-interactive", "--username", "me@example.com"}, bytes.NewBuffer(nil), &bytes.Buffer{}, &bytes.Buffer{}); exit != 0 {
+		t.Fatalf("setup failed: %d", exit)
+	}
+	now := time.Now().UTC()
+	if err := store.New(state).Save(model.State{Messages: map[string]model.Message{
+		"m_1": {ID: "m_1", From: "a@example.com", Subject: "one", SentAt: now},
+		"m_2": {ID: "m_2", From: "b@example.com", Subject: "two", SentAt: now},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	stdout := &bytes.Buffer{}
+	if exit := Run([]string{"--json", "--config", cfg, "--state", state, "message", "archive", "--message-id", "m_1"}, bytes.NewBuffer(nil), stdout, &bytes.Buffer{}); exit != 0 {
+		t.Fatalf("archive failed: %d stdout=%s", exit, stdout.String())
+	}
+	stdout.Reset()
+	if exit := Run([]string{"--json", "--config", cfg, "--state", state, "mailbox", "list"}, bytes.NewBuffer(nil), stdout, &bytes.Buffer{}); exit != 0 {
+		t.Fatalf("mailbox list failed: %d", exit)
+	}
+	var env struct {
+		Data mailboxListResponse `json:"data"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &env); err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]int{}
+	for _, m := range env.Data.Mailboxes {
+		counts[m.ID] = m.Count
+	}
+	if counts["inbox"] != 1 || counts["archive"] != 1 {
+		t.Fatalf("unexpected counts: %v", counts)
+	}
+
+	stdout.Reset()
+	if exit := Run([]string{"--json", "--no-input", "--config", cfg, "--state", state, "message", "delete", "--message-id", "m_2", "--confirm-delete", "m_2"}, bytes.NewBuffer(nil), stdout, &bytes.Buffer{}); exit != 0 {
+		t.Fatalf("delete failed: %d stdout=%s", exit, stdout.String())
+	}
+	b, err := os.ReadFile(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var st model.State
+	if err := json.Unmarshal(b, &st); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := st.Messages["m_2"]; ok {
+		t.Fatal("m_2 should be permanently deleted")
+	}
+	if st.Messages["m_1"].Mailbox != "Archive" {
+		t.Fatalf("m_1 should be in Archive, got %q", st.Messages["m_1"].Mailbox)
+	}
+}
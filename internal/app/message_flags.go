@@ -0,0 +1,190 @@
+package app
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"protonmailcli/internal/model"
+)
+
+const (
+	flagSeen     = `\Seen`
+	flagFlagged  = `\Flagged`
+	flagAnswered = `\Answered`
+)
+
+type imapFlagClient interface {
+	StoreFlags(mailbox string, uids []string, flags []string, add bool) error
+	FetchFlags(mailbox string, uids []string) (map[string][]string, error)
+}
+
+type imapAnsweredClient interface {
+	ArchiveMailboxName() (string, error)
+	SearchUIDs(mailbox, criteria string) ([]string, error)
+	StoreFlags(mailbox string, uids []string, flags []string, add bool) error
+}
+
+type messageMarkRequest struct {
+	ids    []string
+	add    []string
+	remove []string
+}
+
+func parseMessageMarkFlags(args []string) (messageMarkRequest, error) {
+	fs := newFlagSet("message mark")
+	var ids sliceFlag
+	fs.Var(&ids, "message-id", "message id (repeat)")
+	read := fs.Bool("read", false, "set \\Seen")
+	unread := fs.Bool("unread", false, "clear \\Seen")
+	flagged := fs.Bool("flagged", false, "set \\Flagged (starred)")
+	unflagged := fs.Bool("unflagged", false, "clear \\Flagged")
+	answered := fs.Bool("answered", false, "set \\Answered")
+	if err := parseFlags(fs, args); err != nil {
+		return messageMarkRequest{}, err
+	}
+	if *read && *unread {
+		return messageMarkRequest{}, cliError{exit: 2, code: "validation_error", msg: "--read and --unread are mutually exclusive"}
+	}
+	if *flagged && *unflagged {
+		return messageMarkRequest{}, cliError{exit: 2, code: "validation_error", msg: "--flagged and --unflagged are mutually exclusive"}
+	}
+	req := messageMarkRequest{}
+	for _, id := range ids {
+		if strings.TrimSpace(id) != "" && !contains(req.ids, strings.TrimSpace(id)) {
+			req.ids = append(req.ids, strings.TrimSpace(id))
+		}
+	}
+	if len(req.ids) == 0 {
+		return req, cliError{exit: 2, code: "validation_error", msg: "at least one --message-id is required"}
+	}
+	if *read {
+		req.add = append(req.add, flagSeen)
+	}
+	if *unread {
+		req.remove = append(req.remove, flagSeen)
+	}
+	if *flagged {
+		req.add = append(req.add, flagFlagged)
+	}
+	if *unflagged {
+		req.remove = append(req.remove, flagFlagged)
+	}
+	if *answered {
+		req.add = append(req.add, flagAnswered)
+	}
+	if len(req.add) == 0 && len(req.remove) == 0 {
+		return req, cliError{exit: 2, code: "validation_error", msg: "one of --read, --unread, --flagged, --unflagged or --answered is required"}
+	}
+	return req, nil
+}
+
+func applyFlagChanges(flags, add, remove []string) []string {
+	out := make([]string, 0, len(flags)+len(add))
+	for _, f := range flags {
+		if !slices.ContainsFunc(remove, func(r string) bool { return strings.EqualFold(r, f) }) {
+			out = append(out, f)
+		}
+	}
+	for _, a := range add {
+		if !slices.ContainsFunc(out, func(f string) bool { return strings.EqualFold(a, f) }) {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+func cmdMessageMarkIMAP(c imapFlagClient, req messageMarkRequest, g globalOptions) (any, bool, error) {
+	groups, err := groupByMailbox(req.ids)
+	if err != nil {
+		return nil, false, cliError{exit: 2, code: "validation_error", msg: err.Error()}
+	}
+	resp := messageMarkResponse{Added: req.add, Removed: req.remove, Source: "imap"}
+	for _, grp := range groups {
+		if !g.dryRun {
+			if len(req.add) > 0 {
+				if err := c.StoreFlags(grp.Mailbox, grp.UIDs, req.add, true); err != nil {
+					return nil, false, cliError{exit: 4, code: "imap_flag_update_failed", msg: err.Error()}
+				}
+			}
+			if len(req.remove) > 0 {
+				if err := c.StoreFlags(grp.Mailbox, grp.UIDs, req.remove, false); err != nil {
+					return nil, false, cliError{exit: 4, code: "imap_flag_update_failed", msg: err.Error()}
+				}
+			}
+		}
+		current, err := c.FetchFlags(grp.Mailbox, grp.UIDs)
+		if err != nil {
+			return nil, false, cliError{exit: 4, code: "imap_flag_update_failed", msg: err.Error()}
+		}
+		for _, uid := range grp.UIDs {
+			flags, ok := current[uid]
+			if !ok {
+				return nil, false, cliError{exit: 5, code: "not_found", msg: "message not found: " + imapMessageIDForMailbox(grp.Mailbox, uid)}
+			}
+			if g.dryRun {
+				flags = applyFlagChanges(flags, req.add, req.remove)
+			}
+			resp.Messages = append(resp.Messages, messageRecord{ID: imapMessageIDForMailbox(grp.Mailbox, uid), UID: uid, Flags: flags})
+		}
+	}
+	resp.Count = len(resp.Messages)
+	resp.DryRun = g.dryRun
+	return resp, !g.dryRun, nil
+}
+
+func cmdMessageMarkLocal(req messageMarkRequest, g globalOptions, st *model.State) (any, bool, error) {
+	resp := messageMarkResponse{Added: req.add, Removed: req.remove, DryRun: g.dryRun, Source: "local"}
+	for _, id := range req.ids {
+		uid, err := parseRequiredUID(id, "--message-id")
+		if err != nil {
+			return nil, false, cliError{exit: 2, code: "validation_error", msg: err.Error()}
+		}
+		m, ok := st.Messages[uid]
+		if !ok {
+			return nil, false, cliError{exit: 5, code: "not_found", msg: "message not found: " + id}
+		}
+		m.Flags = applyFlagChanges(m.Flags, req.add, req.remove)
+		if !g.dryRun {
+			st.Messages[uid] = m
+		}
+		resp.Messages = append(resp.Messages, messageRecord{ID: m.ID, UID: m.ID, Flags: m.Flags})
+	}
+	resp.Count = len(resp.Messages)
+	return resp, !g.dryRun, nil
+}
+
+func markOriginalAnswered(c imapAnsweredClient, inReplyTo string) string {
+	if strings.TrimSpace(inReplyTo) == "" {
+		return ""
+	}
+	mailboxes := []string{"INBOX"}
+	if archive, err := c.ArchiveMailboxName(); err == nil && archive != "" && archive != "INBOX" {
+		mailboxes = append(mailboxes, archive)
+	}
+	criteria := fmt.Sprintf(`HEADER Message-ID "%s"`, escapeSearch(inReplyTo))
+	for _, mb := range mailboxes {
+		uids, err := c.SearchUIDs(mb, criteria)
+		if err != nil || len(uids) == 0 {
+			continue
+		}
+		if err := c.StoreFlags(mb, uids, []string{flagAnswered}, true); err != nil {
+			return ""
+		}
+		return imapMessageIDForMailbox(mb, uids[len(uids)-1])
+	}
+	return ""
+}
+
+func markLocalOriginalAnswered(st *model.State, d model.Draft) string {
+	if strings.TrimSpace(d.InReplyTo) == "" {
+		return ""
+	}
+	orig, ok := st.Messages[d.InReplyTo]
+	if !ok {
+		return ""
+	}
+	orig.Flags = applyFlagChanges(orig.Flags, []string{flagAnswered}, nil)
+	st.Messages[orig.ID] = orig
+	return orig.ID
+}
@@ -0,0 +1,185 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"protonmailcli/internal/bridge"
+	"protonmailcli/internal/model"
+	"protonmailcli/internal/store"
+)
+
+type fakeFlagClient struct {
+	flags    map[string][]string
+	stores   []string
+	searches map[string][]string
+}
+
+func (f *fakeFlagClient) StoreFlags(mailbox string, uids []string, flags []string, add bool) error {
+	op := "-"
+	if add {
+		op = "+"
+	}
+	f.stores = append(f.stores, mailbox+":"+strings.Join(uids, ",")+op+strings.Join(flags, " "))
+	for _, uid := range uids {
+		if _, ok := f.flags[uid]; !ok {
+			continue
+		}
+		if add {
+			f.flags[uid] = applyFlagChanges(f.flags[uid], flags, nil)
+		} else {
+			f.flags[uid] = applyFlagChanges(f.flags[uid], nil, flags)
+		}
+	}
+	return nil
+}
+
+func (f *fakeFlagClient) FetchFlags(mailbox string, uids []string) (map[string][]string, error) {
+	out := map[string][]string{}
+	for _, uid := range uids {
+		if flags, ok := f.flags[uid]; ok {
+			out[uid] = flags
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeFlagClient) ArchiveMailboxName() (string, error) { return "Archive", nil }
+
+func (f *fakeFlagClient) SearchUIDs(mailbox, criteria string) ([]string, error) {
+	return f.searches[mailbox], nil
+}
+
+func TestParseMessageMarkFlagsValidation(t *testing.T) {
+	cases := [][]string{
+		{"--message-id", "imap:INBOX:1"},
+		{"--read"},
+		{"--message-id", "imap:INBOX:1", "--read", "--unread"},
+		{"--message-id", "imap:INBOX:1", "--flagged", "--unflagged"},
+	}
+	for _, args := range cases {
+		if _, err := parseMessageMarkFlags(args); errorCodeFromErr(err, "") != "validation_error" {
+			t.Fatalf("%v: expected validation_error, got %v", args, err)
+		}
+	}
+	req, err := parseMessageMarkFlags([]string{"--message-id", "imap:INBOX:1", "--read", "--unflagged"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(req.add, []string{flagSeen}) || !slices.Equal(req.remove, []string{flagFlagged}) {
+		t.Fatalf("unexpected request: %+v", req)
+	}
+}
+
+func TestMessageMarkIMAPReportsResultingFlags(t *testing.T) {
+	c := &fakeFlagClient{flags: map[string][]string{"1": {flagFlagged}, "2": nil}}
+	req := messageMarkRequest{ids: []string{"imap:INBOX:1", "imap:INBOX:2"}, add: []string{flagSeen}, remove: []string{flagFlagged}}
+	data, changed, err := cmdMessageMarkIMAP(c, req, globalOptions{})
+	if err != nil || !changed {
+		t.Fatalf("unexpected result: changed=%v err=%v", changed, err)
+	}
+	resp := data.(messageMarkResponse)
+	if resp.Count != 2 || !slices.Equal(resp.Messages[0].Flags, []string{flagSeen}) {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if strings.Join(c.stores, "|") != `INBOX:1,2+\Seen|INBOX:1,2-\Flagged` {
+		t.Fatalf("unexpected stores: %v", c.stores)
+	}
+}
+
+func TestMessageMarkIMAPDryRunDoesNotStore(t *testing.T) {
+	c := &fakeFlagClient{flags: map[string][]string{"1": nil}}
+	req := messageMarkRequest{ids: []string{"imap:INBOX:1"}, add: []string{flagFlagged}}
+	data, changed, err := cmdMessageMarkIMAP(c, req, globalOptions{dryRun: true})
+	if err != nil || changed {
+		t.Fatalf("unexpected result: changed=%v err=%v", changed, err)
+	}
+	if len(c.stores) != 0 {
+		t.Fatalf("dry-run stored flags: %v", c.stores)
+	}
+	if got := data.(messageMarkResponse).Messages[0].Flags; !slices.Equal(got, []string{flagFlagged}) {
+		t.Fatalf("dry-run should preview flags, got %v", got)
+	}
+}
+
+func TestMessageMarkIMAPUnknownMessage(t *testing.T) {
+	c := &fakeFlagClient{flags: map[string][]string{}}
+	req := messageMarkRequest{ids: []string{"imap:INBOX:9"}, add: []string{flagSeen}}
+	if _, _, err := cmdMessageMarkIMAP(c, req, globalOptions{}); errorCodeFromErr(err, "") != "not_found" {
+		t.Fatalf("expected not_found, got %v", err)
+	}
+}
+
+func TestMarkOriginalAnsweredFallsBackToArchive(t *testing.T) {
+	c := &fakeFlagClient{flags: map[string][]string{}, searches: map[string][]string{"Archive": {"7"}}}
+	if got := markOriginalAnswered(c, "<orig@example.com>"); got != "imap:Archive:7" {
+		t.Fatalf("unexpected answered id: %q", got)
+	}
+	if strings.Join(c.stores, "|") != `Archive:7+\Answered` {
+		t.Fatalf("unexpected stores: %v", c.stores)
+	}
+	if got := markOriginalAnswered(c, ""); got != "" {
+		t.Fatalf("empty In-Reply-To should be a no-op, got %q", got)
+	}
+}
+
+func TestSendHeadersForReplyDraft(t *testing.T) {
+	h := sendHeadersForDraft(bridge.DraftMessage{MessageID: "<d@x>", InReplyTo: "<o@x>", References: "<a@x>"}, "me@example.com")
+	if h["In-Reply-To"] != "<o@x>" || h["References"] != "<a@x> <o@x>" {
+		t.Fatalf("unexpected headers: %v", h)
+	}
+}
+
+func TestLocalMessageMarkAndGetMarkRead(t *testing.T) {
+	t.Setenv("PMAIL_USE_LOCAL_STATE", "1")
+	tmp := t.TempDir()
+	cfg := filepath.Join(tmp, "config.toml")
+	state := filepath.Join(tmp, "state.json")
+	if exit := Run([]string{"--json", "--config", cfg, "--state", state, "setup", "--non-interactive", "--username", "me@example.com"}, bytes.NewBuffer(nil), &bytes.Buffer{}, &bytes.Buffer{}); exit != 0 {
+		t.Fatalf("setup failed: %d", exit)
+	}
+	if err := store.New(state).Save(model.State{Messages: map[string]model.Message{
+		"m_1": {ID: "m_1", From: "a@example.com", Subject: "one", SentAt: time.Now().UTC()},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	run := func(args ...string) {
+		t.Helper()
+		stdout := &bytes.Buffer{}
+		if exit := Run(append([]string{"--json", "--config", cfg, "--state", state}, args...), bytes.NewBuffer(nil), stdout, &bytes.Buffer{}); exit != 0 {
+			t.Fatalf("%v failed: %d stdout=%s", args, exit, stdout.String())
+		}
+	}
+	load := func() model.State {
+		t.Helper()
+		b, err := os.ReadFile(state)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var st model.State
+		if err := json.Unmarshal(b, &st); err != nil {
+			t.Fatal(err)
+		}
+		return st
+	}
+
+	run("message", "get", "--message-id", "m_1")
+	if flags := load().Messages["m_1"].Flags; len(flags) != 0 {
+		t.Fatalf("plain get must not mark read: %v", flags)
+	}
+	run("message", "get", "--message-id", "m_1", "--mark-read")
+	run("message", "mark", "--message-id", "m_1", "--flagged")
+	if flags := load().Messages["m_1"].Flags; !slices.Equal(flags, []string{flagSeen, flagFlagged}) {
+		t.Fatalf("unexpected flags: %v", flags)
+	}
+	run("message", "mark", "--message-id", "m_1", "--unread")
+	if flags := load().Messages["m_1"].Flags; !slices.Equal(flags, []string{flagFlagged}) {
+		t.Fatalf("unexpected flags after --unread: %v", flags)
+	}
+}
@@ -0,0 +1,241 @@
+package app
+
+import (
+	"strings"
+	"time"
+
+	"protonmailcli/internal/bridge"
+	"protonmailcli/internal/model"
+)
+
+var authFailResults = map[string]bool{"fail": true, "softfail": true, "permerror": true}
+
+type messageGetRequest struct {
+	id         string
+	markRead   bool
+	allHeaders bool
+	headers    []string
+}
+
+func parseMessageGetFlags(args []string) (messageGetRequest, error) {
+	fs := newFlagSet("message get")
+	id := fs.String("message-id", "", "message id")
+	markRead := fs.Bool("mark-read", false, "set \\Seen after fetching (fetches never mark read otherwise)")
+	headers := fs.String("headers", "", "include raw headers: all or a comma-separated list of names")
+	if err := parseFlags(fs, args); err != nil {
+		return messageGetRequest{}, err
+	}
+	req := messageGetRequest{id: strings.TrimSpace(*id), markRead: *markRead}
+	if req.id == "" {
+		return req, cliError{exit: 2, code: "validation_error", msg: "--message-id required"}
+	}
+	spec := strings.TrimSpace(*headers)
+	if strings.EqualFold(spec, "all") {
+		req.allHeaders = true
+		return req, nil
+	}
+	for _, name := range strings.Split(spec, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			if strings.ContainsAny(name, " :") {
+				return req, cliError{exit: 2, code: "validation_error", msg: "invalid header name in --headers: " + name}
+			}
+			req.headers = append(req.headers, name)
+		}
+	}
+	return req, nil
+}
+
+func (r messageGetRequest) selectHeaders(fields []model.Header) []model.Header {
+	if r.allHeaders {
+		return fields
+	}
+	if len(r.headers) == 0 {
+		return nil
+	}
+	out := []model.Header{}
+	for _, f := range fields {
+		for _, name := range r.headers {
+			if strings.EqualFold(f.Name, name) {
+				out = append(out, f)
+				break
+			}
+		}
+	}
+	return out
+}
+
+func headersFromBridge(fields []bridge.HeaderField) []model.Header {
+	out := make([]model.Header, 0, len(fields))
+	for _, f := range fields {
+		out = append(out, model.Header{Name: f.Name, Value: f.Value})
+	}
+	return out
+}
+
+func localMessageHeaders(m model.Message) []model.Header {
+	has := func(name string) bool {
+		for _, h := range m.Headers {
+			if strings.EqualFold(h.Name, name) {
+				return true
+			}
+		}
+		return false
+	}
+	var out []model.Header
+	add := func(name, value string) {
+		if value != "" && !has(name) {
+			out = append(out, model.Header{Name: name, Value: value})
+		}
+	}
+	add("From", m.From)
+	add("To", strings.Join(m.To, ", "))
+	add("Subject", m.Subject)
+	if !m.SentAt.IsZero() {
+		add("Date", m.SentAt.UTC().Format(time.RFC1123Z))
+	}
+	add("Message-ID", localMessageIDHeader(m.ID))
+	if m.InReplyTo != "" {
+		add("In-Reply-To", localMessageIDHeader(m.InReplyTo))
+	}
+	return append(out, m.Headers...)
+}
+
+func parseAuthentication(fields []model.Header) *authenticationResult {
+	res := &authenticationResult{SPF: "none", DKIM: "none", DMARC: "none", Verdict: "none"}
+	seen := map[string]bool{}
+	var receivedSPF []authMethodResult
+	haveAuthResults, haveReceivedSPF := false, false
+	for _, f := range fields {
+		switch strings.ToLower(f.Name) {
+		case "authentication-results":
+			if haveAuthResults {
+				res.IgnoredHeaders++
+				continue
+			}
+			haveAuthResults = true
+			res.AuthServID, res.Results = parseAuthResultsHeader(f.Value)
+		case "received-spf":
+			if haveReceivedSPF {
+				res.IgnoredHeaders++
+				continue
+			}
+			haveReceivedSPF = true
+			if v := strings.ToLower(firstField(f.Value)); v != "" {
+				receivedSPF = append(receivedSPF, authMethodResult{Method: "spf", Result: v, Source: "Received-SPF"})
+			}
+		}
+	}
+	res.Results = append(res.Results, receivedSPF...)
+	for _, r := range res.Results {
+		var slot *string
+		switch r.Method {
+		case "spf":
+			slot = &res.SPF
+		case "dkim":
+			slot = &res.DKIM
+		case "dmarc":
+			slot = &res.DMARC
+		case "arc":
+			slot = &res.ARC
+		default:
+			continue
+		}
+		if !seen[r.Method] {
+			*slot = r.Result
+			seen[r.Method] = true
+		}
+	}
+	switch {
+	case authFailResults[res.SPF] || authFailResults[res.DKIM] || authFailResults[res.DMARC]:
+		res.Verdict = "fail"
+	case res.SPF == "pass" || res.DKIM == "pass" || res.DMARC == "pass":
+		res.Verdict = "pass"
+	}
+	return res
+}
+
+func parseAuthResultsHeader(v string) (string, []authMethodResult) {
+	segments := splitAuthResults(v)
+	if len(segments) == 0 {
+		return "", nil
+	}
+	servID := firstField(stripAuthComments(segments[0]))
+	var out []authMethodResult
+	for _, seg := range segments[1:] {
+		comment := authComment(seg)
+		tokens := strings.Fields(stripAuthComments(seg))
+		if len(tokens) == 0 {
+			continue
+		}
+		method, result, ok := strings.Cut(tokens[0], "=")
+		if !ok {
+			continue
+		}
+		method, _, _ = strings.Cut(strings.ToLower(method), "/")
+		r := authMethodResult{Method: method, Result: strings.ToLower(strings.Trim(result, `"`)), Comment: comment, Source: "Authentication-Results"}
+		for _, tok := range tokens[1:] {
+			if k, val, ok := strings.Cut(tok, "="); ok {
+				if r.Properties == nil {
+					r.Properties = map[string]string{}
+				}
+				r.Properties[strings.ToLower(k)] = strings.Trim(val, `"`)
+			}
+		}
+		out = append(out, r)
+	}
+	return servID, out
+}
+
+func splitAuthResults(v string) []string {
+	var out []string
+	var cur strings.Builder
+	depth, quoted := 0, false
+	for _, ch := range v {
+		switch {
+		case ch == '"' && depth == 0:
+			quoted = !quoted
+		case ch == '(' && !quoted:
+			depth++
+		case ch == ')' && !quoted && depth > 0:
+			depth--
+		case ch == ';' && depth == 0 && !quoted:
+			out = append(out, strings.TrimSpace(cur.String()))
+			cur.Reset()
+			continue
+		}
+		cur.WriteRune(ch)
+	}
+	if s := strings.TrimSpace(cur.String()); s != "" {
+		out = append(out, s)
+	}
+	return out
+}
+
+func stripAuthComments(v string) string {
+	var b strings.Builder
+	depth := 0
+	for _, ch := range v {
+		switch {
+		case ch == '(':
+			depth++
+		case ch == ')' && depth > 0:
+			depth--
+		case depth == 0:
+			b.WriteRune(ch)
+		}
+	}
+	return b.String()
+}
+
+func authComment(v string) string {
+	start := strings.Index(v, "(")
+	end := strings.LastIndex(v, ")")
+	if start < 0 || end <= start {
+		return ""
+	}
+	return strings.TrimSpace(v[start+1 : end])
+}
+
+func authFailed(a *authenticationResult) bool {
+	return a != nil && a.Verdict == "fail"
+}
@@ -0,0 +1,91 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"protonmailcli/internal/bridge"
+	"protonmailcli/internal/config"
+	"protonmailcli/internal/model"
+)
+
+const phishHeaders = "Return-Path: <bounce@evil.example>\r\n" +
+	"Authentication-Results: mail.protonmail.ch; dmarc=fail (p=reject dis=none)\r\n" +
+	" header.from=bank.example; spf=softfail smtp.mailfrom=evil.example;\r\n" +
+	" dkim=fail (signature \"invalid; bad\") header.d=bank.example; dkim=pass header.d=evil.example\r\n" +
+	"Authentication-Results: attacker.example; spf=pass; dkim=pass\r\n" +
+	"Received: from a.example by b.example\r\n" +
+	"Received: from c.example by a.example\r\n" +
+	"X-Pm-Spamscore: 4\r\n" +
+	"From: Bank <support@bank.example>\r\n" +
+	"Subject: Verify\r\n\r\nbody"
+
+func TestParseHeaderFieldsKeepsOrderAndUnfolds(t *testing.T) {
+	fields := bridge.ParseHeaderFields([]byte(phishHeaders))
+	if len(fields) != 8 || fields[1].Name != "Authentication-Results" || fields[4].Value != "from c.example by a.example" {
+		t.Fatalf("unexpected fields: %+v", fields)
+	}
+	if !strings.Contains(fields[1].Value, "header.from=bank.example; spf=softfail") {
+		t.Fatalf("continuation lines not unfolded: %q", fields[1].Value)
+	}
+	req := messageGetRequest{headers: []string{"received", "x-pm-spamscore"}}
+	got := req.selectHeaders(headersFromBridge(fields))
+	if len(got) != 3 || got[0].Value != "from a.example by b.example" || got[2].Name != "X-Pm-Spamscore" {
+		t.Fatalf("unexpected selection: %+v", got)
+	}
+}
+
+func TestParseAuthenticationPrefersTopmostResults(t *testing.T) {
+	a := parseAuthentication(headersFromBridge(bridge.ParseHeaderFields([]byte(phishHeaders))))
+	if a.AuthServID != "mail.protonmail.ch" || a.DMARC != "fail" || a.SPF != "softfail" || a.DKIM != "fail" || a.Verdict != "fail" || a.IgnoredHeaders != 1 || len(a.Results) != 4 {
+		t.Fatalf("unexpected verdicts: %+v", a)
+	}
+	if a.Results[0].Properties["header.from"] != "bank.example" || a.Results[0].Comment != "p=reject dis=none" {
+		t.Fatalf("unexpected dmarc result: %+v", a.Results[0])
+	}
+	if a.Results[2].Comment != `signature "invalid; bad"` {
+		t.Fatalf("semicolon inside comment should not split: %+v", a.Results[2])
+	}
+	forged := parseAuthentication([]model.Header{
+		{Name: "Authentication-Results", Value: "mx.example; spf=fail smtp.mailfrom=evil.example"},
+		{Name: "Authentication-Results", Value: "x; dkim=pass header.d=bank.example"},
+		{Name: "Received-SPF", Value: "fail"},
+		{Name: "Received-SPF", Value: "pass"},
+	})
+	if forged.DKIM != "none" || forged.SPF != "fail" || forged.Verdict != "fail" || forged.IgnoredHeaders != 2 {
+		t.Fatalf("lower headers added by the sender must not count: %+v", forged)
+	}
+	clean := parseAuthentication([]model.Header{{Name: "Received-SPF", Value: "pass (domain of x)"}})
+	if clean.SPF != "pass" || clean.Verdict != "pass" || authFailed(clean) {
+		t.Fatalf("unexpected Received-SPF fallback: %+v", clean)
+	}
+	if none := parseAuthentication(nil); none.Verdict != "none" || none.DKIM != "none" {
+		t.Fatalf("unexpected empty result: %+v", none)
+	}
+}
+
+func TestLocalMessageGetHeadersAndAuthFailSearch(t *testing.T) {
+	now := time.Now().UTC()
+	st := &model.State{Messages: map[string]model.Message{
+		"m_1": {ID: "m_1", From: "support@bank.example", Subject: "Verify", SentAt: now, Headers: []model.Header{
+			{Name: "Authentication-Results", Value: "mail.protonmail.ch; spf=fail smtp.mailfrom=evil.example"},
+		}},
+		"m_2": {ID: "m_2", From: "a@example.com", Subject: "Hi", SentAt: now},
+	}}
+	data, _, err := cmdMessage("get", []string{"--message-id", "m_1", "--headers", "Message-ID,authentication-results"}, globalOptions{}, config.Config{}, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := data.(localMessageGetResponse).Message
+	if len(msg.Headers) != 2 || msg.Headers[0].Value != "<m_1@local>" || msg.Authentication.SPF != "fail" {
+		t.Fatalf("unexpected local message: %+v", msg)
+	}
+	data, _, err = cmdSearch("messages", []string{"--auth-fail"}, globalOptions{}, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp := data.(localSearchMessagesResponse); resp.Count != 1 || resp.Messages[0].ID != "m_1" {
+		t.Fatalf("unexpected auth-fail search: %+v", resp)
+	}
+}
@@ -0,0 +1,369 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"protonmailcli/internal/bridge"
+	"protonmailcli/internal/config"
+	"protonmailcli/internal/model"
+)
+
+var errUnsubscribeTargetBlocked = errors.New("one-click target resolves to a loopback, private or link-local address")
+
+var unsubscribeHTTPClient = &http.Client{
+	Timeout: 15 * time.Second,
+	Transport: &http.Transport{
+		DialContext:         (&net.Dialer{Timeout: 10 * time.Second, Control: guardUnsubscribeDial}).DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+		ForceAttemptHTTP2:   true,
+	},
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+type imapUnsubscribeClient interface {
+	ListMessageHeaders(mailbox, criteria string) ([]bridge.DraftMessage, error)
+	AppendDraft(raw string) (string, error)
+}
+
+type messageUnsubscribeRequest struct {
+	id      string
+	mailto  string
+	confirm string
+}
+
+type unsubscribePlan struct {
+	sender   string
+	method   string
+	target   string
+	options  []string
+	oneClick bool
+	note     string
+	mailto   bridge.SendInput
+}
+
+type unsubscribeEnv struct {
+	from        string
+	smtp        bridge.SMTPConfig
+	createDraft func(in bridge.SendInput) (string, error)
+	source      string
+}
+
+func parseMessageUnsubscribeFlags(args []string) (messageUnsubscribeRequest, error) {
+	fs := newFlagSet("message unsubscribe")
+	id := fs.String("message-id", "", "message id")
+	mailto := fs.String("mailto", "draft", "mailto fallback: draft|send")
+	confirm := fs.String("confirm-send", "", "message id, required with --mailto send")
+	if err := parseFlags(fs, args); err != nil {
+		return messageUnsubscribeRequest{}, err
+	}
+	req := messageUnsubscribeRequest{id: strings.TrimSpace(*id), mailto: strings.ToLower(strings.TrimSpace(*mailto)), confirm: strings.TrimSpace(*confirm)}
+	if req.id == "" {
+		return req, cliError{exit: 2, code: "validation_error", msg: "--message-id required"}
+	}
+	if req.mailto != "draft" && req.mailto != "send" {
+		return req, cliError{exit: 2, code: "validation_error", msg: "--mailto must be draft or send"}
+	}
+	return req, nil
+}
+
+func parseListUnsubscribe(headers []model.Header) ([]string, bool) {
+	var options []string
+	oneClick := false
+	for _, h := range headers {
+		switch strings.ToLower(h.Name) {
+		case "list-unsubscribe":
+			for _, part := range strings.Split(h.Value, ",") {
+				part = strings.TrimSpace(part)
+				if strings.HasPrefix(part, "<") && strings.HasSuffix(part, ">") {
+					if uri := strings.TrimSpace(part[1 : len(part)-1]); uri != "" && !contains(options, uri) {
+						options = append(options, uri)
+					}
+				}
+			}
+		case "list-unsubscribe-post":
+			if strings.EqualFold(strings.ReplaceAll(h.Value, " ", ""), "List-Unsubscribe=One-Click") {
+				oneClick = true
+			}
+		}
+	}
+	return options, oneClick
+}
+
+func parseUnsubscribeMailto(uri string) (bridge.SendInput, error) {
+	u, err := url.Parse(uri)
+	if err != nil || !strings.EqualFold(u.Scheme, "mailto") {
+		return bridge.SendInput{}, fmt.Errorf("invalid mailto uri: %s", uri)
+	}
+	addrs, err := url.PathUnescape(u.Opaque)
+	if err != nil {
+		return bridge.SendInput{}, fmt.Errorf("invalid mailto uri: %s", uri)
+	}
+	in := bridge.SendInput{Subject: u.Query().Get("subject"), Body: u.Query().Get("body")}
+	for _, a := range strings.Split(addrs, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			if _, err := mail.ParseAddress(a); err != nil {
+				return bridge.SendInput{}, fmt.Errorf("invalid mailto address: %s", a)
+			}
+			in.To = append(in.To, a)
+		}
+	}
+	if len(in.To) == 0 {
+		return bridge.SendInput{}, fmt.Errorf("mailto uri has no recipient: %s", uri)
+	}
+	if in.Subject == "" {
+		in.Subject = "unsubscribe"
+	}
+	if in.Body == "" {
+		in.Body = "unsubscribe"
+	}
+	return in, nil
+}
+
+func planUnsubscribe(from string, headers []model.Header, mailtoMode string) (unsubscribePlan, error) {
+	plan := unsubscribePlan{sender: unsubscribeSenderKey(from), method: "none"}
+	plan.options, plan.oneClick = parseListUnsubscribe(headers)
+	var httpsURL, anyURL, mailtoURI string
+	for _, o := range plan.options {
+		l := strings.ToLower(o)
+		switch {
+		case strings.HasPrefix(l, "https://") && httpsURL == "":
+			httpsURL = o
+		case strings.HasPrefix(l, "mailto:") && mailtoURI == "":
+			mailtoURI = o
+		}
+		if (strings.HasPrefix(l, "https://") || strings.HasPrefix(l, "http://")) && anyURL == "" {
+			anyURL = o
+		}
+	}
+	switch {
+	case plan.oneClick && httpsURL != "" && parseAuthentication(headers).DKIM == "pass":
+		plan.method, plan.target = "one_click", httpsURL
+	case plan.oneClick && httpsURL != "":
+		plan.method, plan.target = "manual", httpsURL
+		plan.note = "one-click unsubscribe needs a DKIM pass; open the link manually if you trust the sender"
+	case mailtoURI != "":
+		in, err := parseUnsubscribeMailto(mailtoURI)
+		if err != nil {
+			return plan, cliError{exit: 2, code: "validation_error", msg: err.Error()}
+		}
+		plan.method, plan.target, plan.mailto = "mailto_"+mailtoMode, mailtoURI, in
+	case anyURL != "":
+		plan.method, plan.target = "manual", anyURL
+	}
+	return plan, nil
+}
+
+func unsubscribeSenderKey(from string) string {
+	if a, err := mail.ParseAddress(from); err == nil {
+		return strings.ToLower(a.Address)
+	}
+	return strings.ToLower(strings.Trim(strings.TrimSpace(from), "<>"))
+}
+
+func guardUnsubscribeDial(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return errUnsubscribeTargetBlocked
+	}
+	return nil
+}
+
+func postOneClick(target string) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, target, strings.NewReader("List-Unsubscribe=One-Click"))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := unsubscribeHTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("one-click unsubscribe returned HTTP %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func checkUnsubscribeSend(cfg config.Config, g globalOptions, st *model.State, messageID, confirm, from string, in bridge.SendInput) error {
+	if confirm != messageID {
+		return cliError{exit: 7, code: "confirmation_required", msg: "--mailto send needs --confirm-send " + messageID, hint: "The unsubscribe address comes from the sender; check it with --dry-run, or use --mailto draft"}
+	}
+	if err := validateSendSafety(cfg, isNonInteractiveSend(g, runtimeStdinIsTTY()), confirm, messageID, "", false, recipientCheck{addresses: in.To}); err != nil {
+		return err
+	}
+	if err := enforceDraftLint(cfg, lintInput{subject: in.Subject, body: in.Body}, messageID); err != nil {
+		return err
+	}
+	if _, err := enforceDLP(cfg, st, g, dlpContent{subject: in.Subject, body: in.Body}, nil); err != nil {
+		return err
+	}
+	return consumeSendQuota(st, cfg, g, from, false)
+}
+
+func runUnsubscribe(messageID, from string, headers []model.Header, req messageUnsubscribeRequest, env unsubscribeEnv, cfg config.Config, g globalOptions, st *model.State) (any, bool, error) {
+	plan, err := planUnsubscribe(from, headers, req.mailto)
+	if err != nil {
+		return nil, false, err
+	}
+	resp := messageUnsubscribeResponse{
+		MessageID: messageID,
+		Sender:    plan.sender,
+		Method:    plan.method,
+		Target:    plan.target,
+		Options:   plan.options,
+		OneClick:  plan.oneClick,
+		DryRun:    g.dryRun,
+		Source:    env.source,
+	}
+	if prev, ok := st.Unsubscribes[plan.sender]; ok && plan.sender != "" {
+		resp.Method, resp.Target, resp.DraftID = prev.Method, prev.Target, prev.DraftID
+		resp.Status = "already_processed"
+		resp.AlreadyProcessed = true
+		resp.ProcessedAt = prev.ProcessedAt.Format(time.RFC3339)
+		return resp, false, nil
+	}
+	switch {
+	case plan.method == "none":
+		return nil, false, cliError{exit: 5, code: "unsubscribe_unavailable", msg: "message has no usable List-Unsubscribe header"}
+	case plan.method == "manual":
+		if plan.note != "" {
+			addWarning(plan.note)
+		}
+		resp.Status = "manual_required"
+		return resp, false, nil
+	case g.dryRun:
+		resp.Status = "planned"
+		return resp, false, nil
+	}
+	switch plan.method {
+	case "one_click":
+		code, err := postOneClick(plan.target)
+		resp.HTTPStatus = code
+		if errors.Is(err, errUnsubscribeTargetBlocked) {
+			addWarning(err.Error() + "; open the link manually if you trust the sender")
+			resp.Method, resp.Status = "manual", "manual_required"
+			return resp, false, nil
+		}
+		if err != nil {
+			return nil, false, cliError{exit: 4, code: "unsubscribe_failed", msg: err.Error()}
+		}
+		resp.Status = "unsubscribed"
+	case "mailto_draft":
+		plan.mailto.From = env.from
+		id, err := env.createDraft(plan.mailto)
+		if err != nil {
+			return nil, false, cliError{exit: 4, code: "unsubscribe_failed", msg: err.Error()}
+		}
+		resp.DraftID = id
+		resp.Status = "draft_created"
+	case "mailto_send":
+		if env.from == "" {
+			return nil, false, cliError{exit: 3, code: "config_error", msg: "bridge username is missing", hint: "Run setup or auth login and set username"}
+		}
+		plan.mailto.From = env.from
+		if err := checkUnsubscribeSend(cfg, g, st, messageID, req.confirm, env.from, plan.mailto); err != nil {
+			return nil, false, err
+		}
+		if err := smtpSendFn(env.smtp, plan.mailto); err != nil {
+			return nil, false, cliError{exit: 4, code: "send_failed", msg: err.Error()}
+		}
+		resp.Status = "sent"
+	}
+	now := time.Now().UTC()
+	st.Unsubscribes[plan.sender] = model.Unsubscribe{
+		Sender:      plan.sender,
+		MessageID:   messageID,
+		Method:      plan.method,
+		Target:      plan.target,
+		DraftID:     resp.DraftID,
+		ProcessedAt: now,
+	}
+	resp.ProcessedAt = now.Format(time.RFC3339)
+	return resp, true, nil
+}
+
+func cmdMessageUnsubscribeIMAP(c imapUnsubscribeClient, req messageUnsubscribeRequest, g globalOptions, cfg config.Config, username, password string, st *model.State) (any, bool, error) {
+	mailbox, uid, err := parseMailboxUID(req.id, "INBOX")
+	if err != nil {
+		return nil, false, cliError{exit: 2, code: "validation_error", msg: err.Error()}
+	}
+	msgs, err := c.ListMessageHeaders(mailbox, "UID "+uid)
+	if err != nil || len(msgs) == 0 {
+		return nil, false, cliError{exit: 5, code: "not_found", msg: "message not found"}
+	}
+	env := unsubscribeEnv{
+		from:   username,
+		smtp:   bridge.SMTPConfig{Host: cfg.Bridge.Host, Port: cfg.Bridge.SMTPPort, Username: username, Password: password},
+		source: "imap",
+		createDraft: func(in bridge.SendInput) (string, error) {
+			uid, err := c.AppendDraft(bridge.BuildRawMessage(in.From, in.To, in.Subject, in.Body))
+			if err != nil {
+				return "", err
+			}
+			return imapDraftID(uid), nil
+		},
+	}
+	return runUnsubscribe(imapMessageIDForMailbox(mailbox, msgs[0].UID), msgs[0].From, headersFromBridge(msgs[0].Headers), req, env, cfg, g, st)
+}
+
+func cmdMessageUnsubscribeLocal(req messageUnsubscribeRequest, g globalOptions, cfg config.Config, st *model.State) (any, bool, error) {
+	uid, err := parseRequiredUID(req.id, "--message-id")
+	if err != nil {
+		return nil, false, cliError{exit: 2, code: "validation_error", msg: err.Error()}
+	}
+	m, ok := st.Messages[uid]
+	if !ok {
+		return nil, false, cliError{exit: 5, code: "not_found", msg: "message not found"}
+	}
+	from := firstNonEmpty(st.Auth.Username, cfg.Bridge.Username)
+	env := unsubscribeEnv{
+		from:   from,
+		source: "local",
+		createDraft: func(in bridge.SendInput) (string, error) {
+			now := time.Now().UTC()
+			id := fmt.Sprintf("d_%d", now.UnixNano())
+			st.Drafts[id] = model.Draft{ID: id, To: in.To, Subject: in.Subject, Body: in.Body, CreatedAt: now, UpdatedAt: now}
+			return id, nil
+		},
+	}
+	if req.mailto == "send" && !g.dryRun {
+		password, err := localSMTPPassword(cfg, st, "")
+		if err != nil {
+			return nil, false, err
+		}
+		env.smtp = bridge.SMTPConfig{Host: cfg.Bridge.Host, Port: cfg.Bridge.SMTPPort, Username: from, Password: password}
+	}
+	return runUnsubscribe(m.ID, m.From, localMessageHeaders(m), req, env, cfg, g, st)
+}
+
+func localSMTPPassword(cfg config.Config, st *model.State, passwordFile string) (string, error) {
+	password := strings.TrimSpace(os.Getenv("PMAIL_SMTP_PASSWORD"))
+	candidatePasswordFile := firstNonEmpty(passwordFile, st.Auth.PasswordFile, cfg.Bridge.PasswordFile)
+	if password == "" && candidatePasswordFile != "" {
+		b, err := os.ReadFile(filepath.Clean(config.Expand(candidatePasswordFile)))
+		if err != nil {
+			return "", cliError{exit: 2, code: "validation_error", msg: "cannot read smtp password file"}
+		}
+		password = strings.TrimSpace(string(b))
+	}
+	return password, nil
+}
@@ -0,0 +1,220 @@
+package app
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"protonmailcli/internal/bridge"
+	"protonmailcli/internal/config"
+	"protonmailcli/internal/model"
+)
+
+type fakeUnsubscribeClient struct {
+	msg      bridge.DraftMessage
+	appended []string
+}
+
+func (f *fakeUnsubscribeClient) ListMessageHeaders(mailbox, criteria string) ([]bridge.DraftMessage, error) {
+	if criteria != "UID "+f.msg.UID {
+		return nil, nil
+	}
+	return []bridge.DraftMessage{f.msg}, nil
+}
+
+func (f *fakeUnsubscribeClient) AppendDraft(raw string) (string, error) {
+	f.appended = append(f.appended, raw)
+	return "42", nil
+}
+
+func newsletterState(headers ...model.Header) *model.State {
+	return &model.State{
+		Drafts:       map[string]model.Draft{},
+		Unsubscribes: map[string]model.Unsubscribe{},
+		Messages: map[string]model.Message{
+			"m_1": {ID: "m_1", From: "News <news@shop.example>", Subject: "Deals", SentAt: time.Now().UTC(), Headers: headers},
+		},
+	}
+}
+
+var dkimPass = model.Header{Name: "Authentication-Results", Value: "mx.example; dkim=pass header.d=shop.example"}
+
+func TestUnsubscribeOneClickPostsOnceAndRecordsSender(t *testing.T) {
+	var posts []string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		posts = append(posts, r.Method+" "+r.URL.Path+" "+string(b)+" "+r.Header.Get("Content-Type"))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+	prev := unsubscribeHTTPClient
+	unsubscribeHTTPClient = srv.Client()
+	defer func() { unsubscribeHTTPClient = prev }()
+
+	st := newsletterState(
+		model.Header{Name: "List-Unsubscribe", Value: "<mailto:leave@shop.example?subject=stop>, <" + srv.URL + "/u/123>"},
+		model.Header{Name: "List-Unsubscribe-Post", Value: "List-Unsubscribe=One-Click"},
+		dkimPass,
+	)
+	req := messageUnsubscribeRequest{id: "m_1", mailto: "draft"}
+	data, changed, err := cmdMessageUnsubscribeLocal(req, globalOptions{dryRun: true}, config.Config{}, st)
+	if err != nil || changed || data.(messageUnsubscribeResponse).Status != "planned" || len(posts) != 0 {
+		t.Fatalf("dry-run must not act: %+v changed=%v err=%v posts=%v", data, changed, err, posts)
+	}
+	data, changed, err = cmdMessageUnsubscribeLocal(req, globalOptions{}, config.Config{}, st)
+	if err != nil || !changed {
+		t.Fatalf("unsubscribe: changed=%v err=%v", changed, err)
+	}
+	resp := data.(messageUnsubscribeResponse)
+	if resp.Method != "one_click" || resp.Status != "unsubscribed" || resp.HTTPStatus != http.StatusAccepted || resp.Sender != "news@shop.example" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if len(posts) != 1 || posts[0] != "POST /u/123 List-Unsubscribe=One-Click application/x-www-form-urlencoded" {
+		t.Fatalf("unexpected posts: %v", posts)
+	}
+	data, changed, err = cmdMessageUnsubscribeLocal(req, globalOptions{}, config.Config{}, st)
+	if err != nil || changed || !data.(messageUnsubscribeResponse).AlreadyProcessed || len(posts) != 1 {
+		t.Fatalf("same sender must not be processed twice: %+v posts=%v", data, posts)
+	}
+}
+
+func TestUnsubscribeOneClickFailureIsNotRecorded(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://elsewhere.example/", http.StatusFound)
+	}))
+	defer srv.Close()
+	prev := unsubscribeHTTPClient
+	unsubscribeHTTPClient = srv.Client()
+	unsubscribeHTTPClient.CheckRedirect = prev.CheckRedirect
+	defer func() { unsubscribeHTTPClient = prev }()
+
+	st := newsletterState(
+		model.Header{Name: "List-Unsubscribe", Value: "<" + srv.URL + "/u>"},
+		model.Header{Name: "List-Unsubscribe-Post", Value: "List-Unsubscribe=One-Click"},
+		dkimPass,
+	)
+	_, _, err := cmdMessageUnsubscribeLocal(messageUnsubscribeRequest{id: "m_1", mailto: "draft"}, globalOptions{}, config.Config{}, st)
+	if errorCodeFromErr(err, "") != "unsubscribe_failed" || len(st.Unsubscribes) != 0 {
+		t.Fatalf("expected unsubscribe_failed without state, got %v %v", err, st.Unsubscribes)
+	}
+}
+
+func TestUnsubscribeOneClickFallsBackToManual(t *testing.T) {
+	posts := 0
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posts++
+	}))
+	defer srv.Close()
+	cases := []struct {
+		name   string
+		client *http.Client
+		auth   model.Header
+	}{
+		{"no dkim pass", srv.Client(), model.Header{Name: "Authentication-Results", Value: "mx.example; dkim=fail header.d=shop.example"}},
+		{"loopback target", unsubscribeHTTPClient, dkimPass},
+	}
+	for _, tc := range cases {
+		prev := unsubscribeHTTPClient
+		unsubscribeHTTPClient = tc.client
+		st := newsletterState(
+			model.Header{Name: "List-Unsubscribe", Value: "<" + srv.URL + "/u>"},
+			model.Header{Name: "List-Unsubscribe-Post", Value: "List-Unsubscribe=One-Click"},
+			tc.auth,
+		)
+		data, changed, err := cmdMessageUnsubscribeLocal(messageUnsubscribeRequest{id: "m_1", mailto: "draft"}, globalOptions{}, config.Config{}, st)
+		unsubscribeHTTPClient = prev
+		if err != nil || changed || len(st.Unsubscribes) != 0 {
+			t.Fatalf("%s: changed=%v err=%v state=%v", tc.name, changed, err, st.Unsubscribes)
+		}
+		if resp := data.(messageUnsubscribeResponse); resp.Method != "manual" || resp.Status != "manual_required" || resp.Target != srv.URL+"/u" {
+			t.Fatalf("%s: unexpected response %+v", tc.name, resp)
+		}
+	}
+	if posts != 0 {
+		t.Fatalf("nothing may be posted, got %d", posts)
+	}
+}
+
+func TestGuardUnsubscribeDialBlocksInternalAddresses(t *testing.T) {
+	for _, addr := range []string{"127.0.0.1:443", "[::1]:443", "10.1.2.3:443", "192.168.0.10:443", "172.16.5.5:443", "169.254.169.254:80", "[fe80::1]:443", "[fd00::1]:443", "0.0.0.0:443", "[::ffff:127.0.0.1]:443"} {
+		if err := guardUnsubscribeDial("tcp", addr, nil); err != errUnsubscribeTargetBlocked {
+			t.Fatalf("%s should be blocked, got %v", addr, err)
+		}
+	}
+	for _, addr := range []string{"93.184.216.34:443", "[2606:2800:220:1:248:1893:25c8:1946]:443"} {
+		if err := guardUnsubscribeDial("tcp", addr, nil); err != nil {
+			t.Fatalf("%s should be allowed, got %v", addr, err)
+		}
+	}
+}
+
+func TestUnsubscribeMailtoFallbacks(t *testing.T) {
+	headers := []model.Header{{Name: "List-Unsubscribe", Value: "<http://shop.example/u>, <mailto:leave@shop.example?subject=remove%20me>"}}
+	st := newsletterState(headers...)
+	data, _, err := cmdMessageUnsubscribeLocal(messageUnsubscribeRequest{id: "m_1", mailto: "draft"}, globalOptions{}, config.Config{}, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := data.(messageUnsubscribeResponse)
+	d := st.Drafts[resp.DraftID]
+	if resp.Method != "mailto_draft" || d.Subject != "remove me" || strings.Join(d.To, ",") != "leave@shop.example" {
+		t.Fatalf("unexpected draft fallback: %+v %+v", resp, d)
+	}
+
+	prevSend := smtpSendFn
+	defer func() { smtpSendFn = prevSend }()
+	var sent bridge.SendInput
+	smtpSendFn = func(_ bridge.SMTPConfig, in bridge.SendInput) error {
+		sent = in
+		return nil
+	}
+	st = newsletterState(headers...)
+	st.Auth.Username = "me@example.com"
+	if _, _, err := cmdMessageUnsubscribeLocal(messageUnsubscribeRequest{id: "m_1", mailto: "send"}, globalOptions{}, config.Config{}, st); errorCodeFromErr(err, "") != "confirmation_required" || sent.From != "" || len(st.Unsubscribes) != 0 {
+		t.Fatalf("sending to a sender-supplied address needs --confirm-send: %v %+v", err, sent)
+	}
+	blocked := config.Config{}
+	blocked.Safety.Recipients.AllowDomains = []string{"example.com"}
+	if _, _, err := cmdMessageUnsubscribeLocal(messageUnsubscribeRequest{id: "m_1", mailto: "send", confirm: "m_1"}, globalOptions{}, blocked, st); errorCodeFromErr(err, "") != "policy_blocked" || sent.From != "" {
+		t.Fatalf("recipient policy must apply to unsubscribe sends: %v", err)
+	}
+	limited := config.Config{}
+	limited.RateLimit.MaxPerMinute = 5
+	data, _, err = cmdMessageUnsubscribeLocal(messageUnsubscribeRequest{id: "m_1", mailto: "send", confirm: "m_1"}, globalOptions{}, limited, st)
+	if err != nil || data.(messageUnsubscribeResponse).Status != "sent" || sent.From != "me@example.com" || sent.To[0] != "leave@shop.example" {
+		t.Fatalf("unexpected send fallback: %+v %+v %v", data, sent, err)
+	}
+	if q := st.SendQuotas[sendQuotaKey("", "me@example.com")]; q.Sent != 1 {
+		t.Fatalf("unsubscribe sends must spend send quota: %+v", st.SendQuotas)
+	}
+
+	st = newsletterState(model.Header{Name: "List-Unsubscribe", Value: "<https://shop.example/u>"})
+	data, changed, err := cmdMessageUnsubscribeLocal(messageUnsubscribeRequest{id: "m_1", mailto: "draft"}, globalOptions{}, config.Config{}, st)
+	if err != nil || changed || data.(messageUnsubscribeResponse).Status != "manual_required" {
+		t.Fatalf("https without one-click must not be fetched: %+v %v", data, err)
+	}
+	st = newsletterState()
+	if _, _, err := cmdMessageUnsubscribeLocal(messageUnsubscribeRequest{id: "m_1", mailto: "draft"}, globalOptions{}, config.Config{}, st); errorCodeFromErr(err, "") != "unsubscribe_unavailable" {
+		t.Fatalf("expected unsubscribe_unavailable, got %v", err)
+	}
+}
+
+func TestUnsubscribeIMAPCreatesMailtoDraft(t *testing.T) {
+	raw := "From: news@shop.example\r\nList-Unsubscribe: <mailto:leave@shop.example>\r\n\r\n"
+	c := &fakeUnsubscribeClient{msg: bridge.DraftMessage{UID: "9", From: "news@shop.example", Headers: bridge.ParseHeaderFields([]byte(raw))}}
+	st := newsletterState()
+	data, changed, err := cmdMessageUnsubscribeIMAP(c, messageUnsubscribeRequest{id: "imap:INBOX:9", mailto: "draft"}, globalOptions{}, config.Config{}, "me@example.com", "", st)
+	if err != nil || !changed {
+		t.Fatalf("changed=%v err=%v", changed, err)
+	}
+	resp := data.(messageUnsubscribeResponse)
+	if resp.DraftID != imapDraftID("42") || len(c.appended) != 1 || !strings.Contains(c.appended[0], "To: leave@shop.example") {
+		t.Fatalf("unexpected imap draft: %+v %v", resp, c.appended)
+	}
+	if st.Unsubscribes["news@shop.example"].MessageID != "imap:INBOX:9" {
+		t.Fatalf("state not recorded: %+v", st.Unsubscribes)
+	}
+}
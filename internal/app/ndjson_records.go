@@ -0,0 +1,25 @@
+package app
+
+func (r draftListResponse) Records() (string, any)           { return "drafts", r.Drafts }
+func (r localDraftListResponse) Records() (string, any)      { return "drafts", r.Drafts }
+func (r localSearchDraftsResponse) Records() (string, any)   { return "drafts", r.Drafts }
+func (r messageListResponse) Records() (string, any)         { return "messages", r.Messages }
+func (r localSearchMessagesResponse) Records() (string, any) { return "messages", r.Messages }
+func (r messageMarkResponse) Records() (string, any)         { return "messages", r.Messages }
+func (r mailboxListResponse) Records() (string, any)         { return "mailboxes", r.Mailboxes }
+func (r threadListResponse) Records() (string, any)          { return "threads", r.Threads }
+func (r threadGetResponse) Records() (string, any)           { return "messages", r.Messages }
+func (r batchResultResponse) Records() (string, any)         { return "results", r.Results }
+func (r outboxListResponse) Records() (string, any)          { return "items", r.Items }
+func (r outboxRunResponse) Records() (string, any)           { return "processed", r.Processed }
+func (r tagListResponse) Records() (string, any)             { return "tags", r.Tags }
+func (r filterListResponse) Records() (string, any)          { return "filters", r.Filters }
+func (r bridgeAccountListResponse) Records() (string, any)   { return "accounts", r.Accounts }
+func (r draftLintResponse) Records() (string, any)           { return "findings", r.Findings }
+func (r schemaListResponse) Records() (string, any)          { return "schemas", r.Schemas }
+func (r commandsResponse) Records() (string, any)            { return "commands", r.Commands }
+
+func appendBatchResult(results []batchItemResponse, item batchItemResponse) []batchItemResponse {
+	streamRecord(item)
+	return append(results, item)
+}
@@ -0,0 +1,108 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"protonmailcli/internal/bridge"
+)
+
+func TestStreamMessagesFetchesLazily(t *testing.T) {
+	uids := []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10"}
+	fetched, trips := 0, 0
+	fetch := func(batch []string) (map[string]bridge.DraftMessage, map[string]error, error) {
+		trips++
+		out := map[string]bridge.DraftMessage{}
+		for _, uid := range batch {
+			fetched++
+			out[uid] = bridge.DraftMessage{UID: uid}
+		}
+		return out, nil, nil
+	}
+	var got []string
+	emit := func(m bridge.DraftMessage) { got = append(got, m.UID) }
+	count, next, err := streamMessages(bridge.NewMessageCursor(uids, fetch), 2, 3, nil, emit)
+	if err != nil || count != 3 || next != "5" || strings.Join(got, ",") != "8,7,6" || fetched != 3 || trips != 1 {
+		t.Fatalf("unexpected page: count=%d next=%q got=%v fetched=%d trips=%d err=%v", count, next, got, fetched, trips, err)
+	}
+	got, fetched, trips = nil, 0, 0
+	even := func(m bridge.DraftMessage) bool { n, _ := strconv.Atoi(m.UID); return n%2 == 0 }
+	count, next, _ = streamMessages(bridge.NewMessageCursor(uids, fetch), 1, 2, even, emit)
+	if count != 2 || next != "3" || strings.Join(got, ",") != "8,6" || trips != 1 {
+		t.Fatalf("unexpected filtered page: count=%d next=%q got=%v trips=%d", count, next, got, trips)
+	}
+	got = nil
+	if count, next, _ = streamMessages(bridge.NewMessageCursor(uids, fetch), 0, 0, nil, emit); count != 10 || next != "" {
+		t.Fatalf("limit 0 should stream everything: count=%d next=%q", count, next)
+	}
+}
+
+func TestStreamMessagesReportsFetchErrors(t *testing.T) {
+	prev := runtimeWarnings
+	runtimeWarnings = nil
+	defer func() { runtimeWarnings = prev }()
+	uids := []string{"1", "2", "3", "4"}
+	fetch := func(batch []string) (map[string]bridge.DraftMessage, map[string]error, error) {
+		if batch[0] == "2" {
+			return nil, nil, errors.New("connection reset")
+		}
+		return map[string]bridge.DraftMessage{"4": {UID: "4"}}, map[string]error{"3": errors.New("empty message")}, nil
+	}
+	cur := bridge.NewMessageCursor(uids, fetch)
+	cur.SetBatch(2)
+	var got []string
+	count, next, err := streamMessages(cur, 0, 0, nil, func(m bridge.DraftMessage) { got = append(got, m.UID) })
+	if err == nil || !strings.Contains(err.Error(), "resume with --cursor 1") || count != 1 || next != "" || strings.Join(got, ",") != "4" {
+		t.Fatalf("a failed fetch must end the stream with an error: count=%d next=%q got=%v err=%v", count, next, got, err)
+	}
+	fetch = func(batch []string) (map[string]bridge.DraftMessage, map[string]error, error) {
+		return map[string]bridge.DraftMessage{"2": {UID: "2"}}, map[string]error{"1": errors.New("empty message")}, nil
+	}
+	if count, _, err := streamMessages(bridge.NewMessageCursor([]string{"1", "2"}, fetch), 0, 0, nil, func(bridge.DraftMessage) {}); err != nil || count != 1 || len(runtimeWarnings) != 1 || !strings.Contains(runtimeWarnings[0], "uid 1") {
+		t.Fatalf("unreadable messages must be reported as a warning: count=%d err=%v warnings=%v", count, err, runtimeWarnings)
+	}
+}
+
+func TestNDJSONModeStreamsListsAndBatches(t *testing.T) {
+	t.Setenv("PMAIL_USE_LOCAL_STATE", "1")
+	tmp := t.TempDir()
+	base := []string{"--config", filepath.Join(tmp, "config.toml"), "--state", filepath.Join(tmp, "state.json")}
+	run := func(args ...string) []map[string]any {
+		stdout := &bytes.Buffer{}
+		Run(append(append([]string{}, base...), args...), bytes.NewBuffer(nil), stdout, &bytes.Buffer{})
+		var lines []map[string]any
+		for _, line := range strings.Split(strings.TrimRight(stdout.String(), "\n"), "\n") {
+			var m map[string]any
+			if err := json.Unmarshal([]byte(line), &m); err != nil {
+				t.Fatalf("%v: invalid ndjson line %q", args, line)
+			}
+			lines = append(lines, m)
+		}
+		return lines
+	}
+	run("--ndjson", "setup", "--non-interactive", "--username", "me@example.com")
+	manifest := filepath.Join(tmp, "drafts.json")
+	if err := os.WriteFile(manifest, []byte(`[{"to":["a@example.com"],"subject":"one","body":"x"},{"subject":"missing to"}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	batch := run("--ndjson", "draft", "create-many", "--file", manifest)
+	if len(batch) != 3 || batch[0]["type"] != "record" || batch[1]["data"].(map[string]any)["errorCode"] != "validation_error" {
+		t.Fatalf("unexpected batch stream: %v", batch)
+	}
+	if s := batch[2]; s["type"] != "summary" || s["data"].(map[string]any)["success"] != float64(1) {
+		t.Fatalf("unexpected batch summary: %v", s)
+	}
+	list := run("--ndjson", "draft", "list")
+	if len(list) != 2 || list[0]["data"].(map[string]any)["subject"] != "one" || list[1]["data"].(map[string]any)["count"] != float64(1) {
+		t.Fatalf("unexpected list stream: %v", list)
+	}
+	if failed := run("--ndjson", "draft", "get", "--draft-id", "missing"); len(failed) != 1 || failed[0]["type"] != "error" {
+		t.Fatalf("expected a typed error line: %v", failed)
+	}
+}
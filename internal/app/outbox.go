@@ -0,0 +1,466 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"protonmailcli/internal/config"
+	"protonmailcli/internal/model"
+	"protonmailcli/internal/store"
+)
+
+const (
+	outboxMaxAttempts  = 3
+	outboxSendingLease = 10 * time.Minute
+)
+
+type scheduleRequest struct {
+	draftID        string
+	at             string
+	missedPolicy   string
+	confirm        string
+	force          bool
+	confirmBulk    int
+	allowFindings  []string
+	postSend       string
+	passwordFile   string
+	idempotencyKey string
+}
+
+func validateMissedPolicy(v string) (string, error) {
+	switch p := strings.ToLower(strings.TrimSpace(v)); p {
+	case "":
+		return "send-late", nil
+	case "send-late", "skip":
+		return p, nil
+	default:
+		return "", cliError{exit: 2, code: "validation_error", msg: "invalid missed policy " + v + " (expected send-late or skip)"}
+	}
+}
+
+func enqueueScheduledSend(st *model.State, g globalOptions, req scheduleRequest, source string) (any, bool, error) {
+	at, ok, err := parseDateInput(req.at)
+	if err != nil || !ok {
+		return nil, false, cliError{exit: 2, code: "validation_error", msg: "invalid --at (expected RFC3339, e.g. 2026-10-20T09:00:00+02:00)"}
+	}
+	if !at.After(time.Now()) {
+		return nil, false, cliError{exit: 2, code: "validation_error", msg: "--at must be in the future", hint: "Use message send without --at to send now"}
+	}
+	policy, err := validateMissedPolicy(req.missedPolicy)
+	if err != nil {
+		return nil, false, err
+	}
+	if req.idempotencyKey != "" {
+		for _, item := range st.Outbox {
+			if item.IdempotencyKey == req.idempotencyKey && item.Status != "canceled" {
+				if item.DraftID != req.draftID || !item.At.Equal(at.UTC()) {
+					return nil, false, cliError{exit: 6, code: "idempotency_conflict", msg: "idempotency key already used with different payload"}
+				}
+				return messageScheduleResponse{Scheduled: true, Item: item, Replayed: true, Source: source}, false, nil
+			}
+		}
+	}
+	item := newOutboxItem(req, at.UTC(), policy)
+	item.IdempotencyKey = req.idempotencyKey
+	if g.dryRun {
+		return messageScheduleResponse{Scheduled: false, Item: item, DryRun: true, Source: source}, false, nil
+	}
+	st.Outbox[item.ID] = item
+	return messageScheduleResponse{Scheduled: true, Item: item, Source: source}, true, nil
+}
+
+func newOutboxItem(req scheduleRequest, at time.Time, policy string) model.OutboxItem {
+	now := time.Now().UTC()
+	return model.OutboxItem{
+		ID:            fmt.Sprintf("o_%d", now.UnixNano()),
+		DraftID:       req.draftID,
+		At:            at,
+		MissedPolicy:  policy,
+		ConfirmSend:   req.confirm,
+		Force:         req.force,
+		ConfirmBulk:   req.confirmBulk,
+		AllowFindings: req.allowFindings,
+		PostSend:      req.postSend,
+		PasswordFile:  req.passwordFile,
+		Status:        "queued",
+		CreatedAt:     now,
+	}
+}
+
+func cmdOutbox(action string, args []string, g globalOptions, cfg config.Config, st *model.State) (any, bool, error) {
+	switch action {
+	case "list":
+		fs := newFlagSet("outbox list")
+		status := fs.String("status", "", "filter by status: queued|sending|sent|failed|skipped|canceled")
+		if err := parseFlags(fs, args); err != nil {
+			return nil, false, err
+		}
+		items := sortedOutbox(st, strings.TrimSpace(*status))
+		return outboxListResponse{Items: items, Count: len(items)}, false, nil
+	case "cancel":
+		fs := newFlagSet("outbox cancel")
+		id := fs.String("id", "", "outbox item id")
+		if err := parseFlags(fs, args); err != nil {
+			return nil, false, err
+		}
+		item, ok := st.Outbox[strings.TrimSpace(*id)]
+		if !ok {
+			return nil, false, cliError{exit: 5, code: "not_found", msg: "outbox item not found"}
+		}
+		if item.Status == "canceled" {
+			return outboxItemResponse{Item: item}, false, nil
+		}
+		if item.Status != "queued" {
+			return nil, false, cliError{exit: 6, code: "outbox_item_not_pending", msg: "outbox item is " + item.Status + ", only queued items can be canceled"}
+		}
+		if g.dryRun {
+			return outboxItemResponse{Item: item, DryRun: true}, false, nil
+		}
+		item.Status = "canceled"
+		item.UpdatedAt = time.Now().UTC()
+		st.Outbox[item.ID] = item
+		return outboxItemResponse{Item: item}, true, nil
+	case "requeue":
+		fs := newFlagSet("outbox requeue")
+		id := fs.String("id", "", "outbox item id")
+		if err := parseFlags(fs, args); err != nil {
+			return nil, false, err
+		}
+		item, ok := st.Outbox[strings.TrimSpace(*id)]
+		if !ok {
+			return nil, false, cliError{exit: 5, code: "not_found", msg: "outbox item not found"}
+		}
+		if item.Status != "failed" {
+			return nil, false, cliError{exit: 6, code: "outbox_item_not_pending", msg: "outbox item is " + item.Status + ", only failed items can be requeued"}
+		}
+		if g.dryRun {
+			return outboxItemResponse{Item: item, DryRun: true}, false, nil
+		}
+		now := time.Now().UTC()
+		item.Status = "queued"
+		item.Attempts = 0
+		item.LastError = ""
+		item.At = now
+		item.UpdatedAt = now
+		st.Outbox[item.ID] = item
+		return outboxItemResponse{Item: item}, true, nil
+	case "run":
+		defaultGrace, err := outboxGrace(cfg)
+		if err != nil {
+			return nil, false, err
+		}
+		fs := newFlagSet("outbox run")
+		loop := fs.Bool("loop", false, "keep running and process items as they become due")
+		interval := fs.Duration("interval", 30*time.Second, "poll interval with --loop")
+		iterations := fs.Int("iterations", 0, "stop --loop after n polls (0 = until interrupted)")
+		grace := fs.Duration("grace", defaultGrace, "how late an item may run before its missed policy applies (default: outbox.grace)")
+		missed := fs.String("missed-policy", "", "override missed policy for this run: send-late|skip")
+		if err := parseFlags(fs, args); err != nil {
+			return nil, false, err
+		}
+		if *missed != "" {
+			if _, err := validateMissedPolicy(*missed); err != nil {
+				return nil, false, err
+			}
+		}
+		if *interval <= 0 || *grace < 0 {
+			return nil, false, cliError{exit: 2, code: "validation_error", msg: "--interval must be positive and --grace must not be negative"}
+		}
+		unlock, err := lockOutboxRun(g)
+		if err != nil {
+			return nil, false, err
+		}
+		defer unlock()
+		opts := outboxRunOptions{grace: *grace, missedPolicy: strings.ToLower(*missed), file: stateFileFor(g, st)}
+		if !*loop {
+			resp := outboxRunResponse{Processed: []outboxRunResult{}, DryRun: g.dryRun, Iterations: 1}
+			changed := runOutboxOnce(st, g, cfg, opts, &resp)
+			resp.finish(st)
+			return resp, changed, nil
+		}
+		return runOutboxLoop(st, g, cfg, opts, *interval, *iterations)
+	case "flush":
+		fs := newFlagSet("outbox flush")
+		if err := parseFlags(fs, args); err != nil {
+			return nil, false, err
+		}
+		grace, err := outboxGrace(cfg)
+		if err != nil {
+			return nil, false, err
+		}
+		unlock, err := lockOutboxRun(g)
+		if err != nil {
+			return nil, false, err
+		}
+		defer unlock()
+		resp := outboxRunResponse{Processed: []outboxRunResult{}, DryRun: g.dryRun, Iterations: 1}
+		changed := runOutboxOnce(st, g, cfg, outboxRunOptions{grace: grace, file: stateFileFor(g, st)}, &resp)
+		resp.finish(st)
+		return resp, changed, nil
+	default:
+		return nil, false, cliError{exit: 2, code: "usage_error", msg: "unknown outbox action: " + action}
+	}
+}
+
+type outboxRunOptions struct {
+	grace        time.Duration
+	missedPolicy string
+	file         *stateFile
+}
+
+func outboxGrace(cfg config.Config) (time.Duration, error) {
+	d, err := time.ParseDuration(firstNonEmpty(strings.TrimSpace(cfg.Outbox.Grace), "15m"))
+	if err != nil || d < 0 {
+		return 0, cliError{exit: 3, code: "config_error", msg: fmt.Sprintf("invalid outbox.grace %q", cfg.Outbox.Grace), hint: "Use a duration such as 15m or 2h"}
+	}
+	return d, nil
+}
+
+func lockOutboxRun(g globalOptions) (func(), error) {
+	if g.statePath == "" || g.dryRun {
+		return func() {}, nil
+	}
+	unlock, err := store.New(g.statePath).TryLock("outbox")
+	if errors.Is(err, store.ErrLocked) {
+		return nil, cliError{exit: 6, code: "outbox_busy", msg: "another outbox run or flush is in progress", hint: "Only one process works the outbox at a time; retry when it has finished"}
+	}
+	if err != nil {
+		return nil, cliError{exit: 1, code: "state_error", msg: err.Error()}
+	}
+	return unlock, nil
+}
+
+func sortedOutbox(st *model.State, status string) []model.OutboxItem {
+	items := make([]model.OutboxItem, 0, len(st.Outbox))
+	for _, item := range st.Outbox {
+		if status == "" || strings.EqualFold(item.Status, status) {
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].At.Equal(items[j].At) {
+			return items[i].At.Before(items[j].At)
+		}
+		return items[i].ID < items[j].ID
+	})
+	return items
+}
+
+func updateOutbox(opts outboxRunOptions, st *model.State, update func(*model.State) error) error {
+	if opts.file == nil {
+		return update(st)
+	}
+	return opts.file.sync(st, true, update)
+}
+
+func persistOutbox(opts outboxRunOptions, st *model.State) {
+	if opts.file == nil {
+		return
+	}
+	if err := opts.file.sync(st, true, nil); err != nil {
+		fmt.Fprintln(runtimeStderr, "warning: cannot persist outbox state: "+err.Error())
+	}
+}
+
+func failStaleSends(st *model.State, now time.Time) bool {
+	changed := false
+	for id, item := range st.Outbox {
+		if item.Status != "sending" || now.Sub(item.UpdatedAt) < outboxSendingLease {
+			continue
+		}
+		item.Status = "failed"
+		item.LastError = "send was interrupted; it may or may not have gone out. Check Sent, then run outbox requeue --id " + id + " to send it again"
+		item.UpdatedAt = now
+		st.Outbox[id] = item
+		changed = true
+	}
+	return changed
+}
+
+func runOutboxOnce(st *model.State, g globalOptions, cfg config.Config, opts outboxRunOptions, resp *outboxRunResponse) bool {
+	now := time.Now().UTC()
+	changed := false
+	if !g.dryRun {
+		err := updateOutbox(opts, st, func(s *model.State) error {
+			changed = failStaleSends(s, now)
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintln(runtimeStderr, "warning: cannot persist outbox state: "+err.Error())
+		}
+	}
+	for _, item := range sortedOutbox(st, "queued") {
+		if item.At.After(now) {
+			continue
+		}
+		result := outboxRunResult{ID: item.ID, DraftID: item.DraftID, At: item.At.Format(time.RFC3339)}
+		policy := firstNonEmpty(opts.missedPolicy, item.MissedPolicy, "send-late")
+		late := now.Sub(item.At) > opts.grace
+		result.Late = late
+		if late && policy == "skip" {
+			result.Status = "skipped"
+			result.Error = fmt.Sprintf("missed schedule by %s", now.Sub(item.At).Round(time.Second))
+			if !g.dryRun {
+				item.Status = "skipped"
+				item.LastError = result.Error
+				item.UpdatedAt = now
+				st.Outbox[item.ID] = item
+				changed = true
+			}
+			resp.Processed = append(resp.Processed, result)
+			continue
+		}
+		if g.dryRun {
+			result.Status = "would_send"
+			resp.Processed = append(resp.Processed, result)
+			continue
+		}
+		key := firstNonEmpty(item.IdempotencyKey, "outbox:"+item.ID)
+		payload := map[string]any{"outboxId": item.ID, "draftId": item.DraftID}
+		if found, cached, err := idempotencyLookup(st, key, "outbox.send", payload); err == nil && found {
+			result.Status = "sent"
+			result.Replayed = true
+			result.Result = cached
+			item.Status = "sent"
+			item.UpdatedAt = now
+			st.Outbox[item.ID] = item
+			resp.Processed = append(resp.Processed, result)
+			changed = true
+			continue
+		}
+		claimed := false
+		err := updateOutbox(opts, st, func(s *model.State) error {
+			cur, ok := s.Outbox[item.ID]
+			if !ok || cur.Status != "queued" {
+				return nil
+			}
+			cur.Status = "sending"
+			cur.Attempts++
+			cur.UpdatedAt = now
+			s.Outbox[item.ID] = cur
+			claimed = true
+			return nil
+		})
+		if err != nil || !claimed {
+			continue
+		}
+		item = st.Outbox[item.ID]
+		sendOpts := g
+		sendOpts.fromOutbox = true
+		data, _, err := dispatchMessage("send", outboxSendArgs(item), sendOpts, cfg, st)
+		item.UpdatedAt = time.Now().UTC()
+		if err != nil {
+			item.LastError = err.Error()
+			class := classifyCLIError(errorCodeFromErr(err, ""), exitCodeFromErr(err))
+			if errorCodeFromErr(err, "") == "rate_limit" {
+				item.Status = "queued"
+				item.Attempts--
+				result.Status = "rate_limited"
+			} else if class.Retryable && item.Attempts < outboxMaxAttempts {
+				item.Status = "queued"
+				result.Status = "retrying"
+			} else {
+				item.Status = "failed"
+				result.Status = "failed"
+			}
+			result.Error = err.Error()
+			result.ErrorCode = errorCodeFromErr(err, "send_failed")
+		} else {
+			sentAt := item.UpdatedAt
+			item.Status = "sent"
+			item.SentAt = &sentAt
+			item.LastError = ""
+			result.Status = "sent"
+			result.Result = data
+			_ = idempotencyStore(st, key, "outbox.send", payload, data)
+		}
+		st.Outbox[item.ID] = item
+		persistOutbox(opts, st)
+		resp.Processed = append(resp.Processed, result)
+		changed = true
+	}
+	return changed
+}
+
+func runOutboxLoop(st *model.State, g globalOptions, cfg config.Config, opts outboxRunOptions, interval time.Duration, iterations int) (any, bool, error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	resp := outboxRunResponse{Processed: []outboxRunResult{}, DryRun: g.dryRun, Loop: true}
+	changed := false
+	for {
+		if opts.file != nil {
+			if err := opts.file.sync(st, false, nil); err != nil {
+				return nil, false, cliError{exit: 1, code: "state_error", msg: err.Error()}
+			}
+		}
+		before := len(resp.Processed)
+		if runOutboxOnce(st, g, cfg, opts, &resp) {
+			changed = true
+		}
+		for _, r := range resp.Processed[before:] {
+			fmt.Fprintf(runtimeStderr, "outbox: %s %s (draft %s)\n", r.ID, r.Status, r.DraftID)
+		}
+		resp.Iterations++
+		if iterations > 0 && resp.Iterations >= iterations {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			resp.finish(st)
+			return resp, changed, nil
+		case <-time.After(interval):
+		}
+	}
+	resp.finish(st)
+	return resp, changed, nil
+}
+
+func outboxSendArgs(item model.OutboxItem) []string {
+	args := []string{"--draft-id", item.DraftID}
+	if item.ConfirmSend != "" {
+		args = append(args, "--confirm-send", item.ConfirmSend)
+	}
+	if item.Force {
+		args = append(args, "--force")
+	}
+	if item.ConfirmBulk > 0 {
+		args = append(args, "--confirm-bulk", strconv.Itoa(item.ConfirmBulk))
+	}
+	for _, id := range item.AllowFindings {
+		args = append(args, "--allow-finding", id)
+	}
+	if item.PostSend != "" {
+		args = append(args, "--post-send", item.PostSend)
+	}
+	if item.PasswordFile != "" {
+		args = append(args, "--smtp-password-file", item.PasswordFile)
+	}
+	return args
+}
+
+func (r *outboxRunResponse) finish(st *model.State) {
+	for _, p := range r.Processed {
+		switch p.Status {
+		case "sent":
+			r.Sent++
+		case "skipped":
+			r.Skipped++
+		case "failed":
+			r.Failed++
+		}
+	}
+	r.Count = len(r.Processed)
+	for _, item := range st.Outbox {
+		if item.Status == "queued" {
+			r.Pending++
+		}
+	}
+}
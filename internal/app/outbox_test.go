@@ -0,0 +1,228 @@
+package app
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"protonmailcli/internal/bridge"
+	"protonmailcli/internal/config"
+	"protonmailcli/internal/model"
+	"protonmailcli/internal/store"
+)
+
+func outboxState() *model.State {
+	return &model.State{
+		Auth:        model.AuthState{Username: "me@example.com"},
+		Drafts:      map[string]model.Draft{"d_1": {ID: "d_1", To: []string{"a@example.com"}, Subject: "Later", Body: "b"}},
+		Messages:    map[string]model.Message{},
+		Idempotency: map[string]model.IdempotencyRecord{},
+		Outbox:      map[string]model.OutboxItem{},
+	}
+}
+
+func stubOutboxSend(t *testing.T, err error) *int {
+	t.Helper()
+	t.Setenv("PMAIL_USE_LOCAL_STATE", "1")
+	t.Setenv("PMAIL_SMTP_PASSWORD", "secret")
+	prev := smtpSendFn
+	t.Cleanup(func() { smtpSendFn = prev })
+	calls := 0
+	smtpSendFn = func(_ bridge.SMTPConfig, _ bridge.SendInput) error {
+		calls++
+		return err
+	}
+	return &calls
+}
+
+func dueItem(st *model.State, id string, at time.Time, policy string) {
+	st.Outbox[id] = model.OutboxItem{ID: id, DraftID: "d_1", At: at, MissedPolicy: policy, ConfirmSend: "d_1", Status: "queued", CreatedAt: at}
+}
+
+func TestScheduledSendEnqueuesAndValidates(t *testing.T) {
+	calls := stubOutboxSend(t, nil)
+	st := outboxState()
+	at := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	data, changed, err := cmdMessage("send", []string{"--draft-id", "d_1", "--confirm-send", "d_1", "--at", at, "--missed", "skip"}, globalOptions{}, config.Default(), st)
+	if err != nil || !changed {
+		t.Fatalf("enqueue: changed=%v err=%v", changed, err)
+	}
+	item := data.(messageScheduleResponse).Item
+	if item.Status != "queued" || item.MissedPolicy != "skip" || st.Outbox[item.ID].DraftID != "d_1" || *calls != 0 {
+		t.Fatalf("unexpected enqueue: %+v calls=%d", item, *calls)
+	}
+	past := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	if _, _, err := cmdMessage("send", []string{"--draft-id", "d_1", "--confirm-send", "d_1", "--at", past}, globalOptions{}, config.Default(), st); errorCodeFromErr(err, "") != "validation_error" {
+		t.Fatalf("expected validation_error for past --at, got %v", err)
+	}
+	if _, _, err := cmdMessage("send", []string{"--draft-id", "d_1", "--at", at}, globalOptions{noInput: true}, config.Default(), st); errorCodeFromErr(err, "") != "confirmation_required" {
+		t.Fatalf("confirmation must be checked at enqueue, got %v", err)
+	}
+
+	req := scheduleRequest{draftID: "d_1", at: at, idempotencyKey: "k1"}
+	first, _, err := enqueueScheduledSend(st, globalOptions{}, req, "local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, changed, err := enqueueScheduledSend(st, globalOptions{}, req, "local")
+	if err != nil || changed || !again.(messageScheduleResponse).Replayed || again.(messageScheduleResponse).Item.ID != first.(messageScheduleResponse).Item.ID {
+		t.Fatalf("expected idempotent replay: %+v changed=%v err=%v", again, changed, err)
+	}
+	req.at = time.Now().Add(2 * time.Hour).UTC().Format(time.RFC3339)
+	if _, _, err := enqueueScheduledSend(st, globalOptions{}, req, "local"); errorCodeFromErr(err, "") != "idempotency_conflict" {
+		t.Fatalf("expected idempotency_conflict, got %v", err)
+	}
+}
+
+func TestOutboxRunSendsDueItemsOnce(t *testing.T) {
+	calls := stubOutboxSend(t, nil)
+	st := outboxState()
+	dueItem(st, "o_1", time.Now().Add(-time.Minute).UTC(), "skip")
+	dueItem(st, "o_2", time.Now().Add(time.Hour).UTC(), "send-late")
+
+	data, changed, err := cmdOutbox("run", nil, globalOptions{dryRun: true}, config.Default(), st)
+	if err != nil || changed || data.(outboxRunResponse).Processed[0].Status != "would_send" || *calls != 0 {
+		t.Fatalf("dry-run must not send: %+v calls=%d", data, *calls)
+	}
+	data, changed, err = cmdOutbox("run", nil, globalOptions{}, config.Default(), st)
+	if err != nil || !changed {
+		t.Fatalf("run: changed=%v err=%v", changed, err)
+	}
+	resp := data.(outboxRunResponse)
+	if resp.Sent != 1 || resp.Pending != 1 || *calls != 1 || st.Outbox["o_1"].Status != "sent" || st.Outbox["o_1"].SentAt == nil {
+		t.Fatalf("unexpected run: %+v %+v calls=%d", resp, st.Outbox["o_1"], *calls)
+	}
+	if _, _, err := cmdOutbox("run", nil, globalOptions{}, config.Default(), st); err != nil || *calls != 1 {
+		t.Fatalf("sent items must not be resent: calls=%d err=%v", *calls, err)
+	}
+	if _, _, err := cmdOutbox("cancel", []string{"--id", "o_1"}, globalOptions{}, config.Default(), st); errorCodeFromErr(err, "") != "outbox_item_not_pending" {
+		t.Fatalf("expected outbox_item_not_pending, got %v", err)
+	}
+	if _, changed, err := cmdOutbox("cancel", []string{"--id", "o_2"}, globalOptions{}, config.Default(), st); err != nil || !changed || st.Outbox["o_2"].Status != "canceled" {
+		t.Fatalf("cancel: changed=%v err=%v %+v", changed, err, st.Outbox["o_2"])
+	}
+	if _, _, err := cmdOutbox("cancel", []string{"--id", "o_x"}, globalOptions{}, config.Default(), st); errorCodeFromErr(err, "") != "not_found" {
+		t.Fatalf("expected not_found, got %v", err)
+	}
+}
+
+func TestOutboxRunAppliesMissedPolicyAndRetries(t *testing.T) {
+	calls := stubOutboxSend(t, nil)
+	st := outboxState()
+	late := time.Now().Add(-2 * time.Hour).UTC()
+	dueItem(st, "o_skip", late, "skip")
+	dueItem(st, "o_late", late.Add(time.Second), "send-late")
+	data, _, err := cmdOutbox("run", []string{"--grace", "1h"}, globalOptions{}, config.Default(), st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := data.(outboxRunResponse)
+	if resp.Skipped != 1 || resp.Sent != 1 || *calls != 1 || !resp.Processed[1].Late || st.Outbox["o_skip"].Status != "skipped" {
+		t.Fatalf("unexpected missed handling: %+v calls=%d", resp, *calls)
+	}
+
+	stubOutboxSend(t, errors.New("connection reset"))
+	st = outboxState()
+	dueItem(st, "o_1", time.Now().Add(-time.Minute).UTC(), "send-late")
+	for i := 1; i <= outboxMaxAttempts; i++ {
+		data, _, err = cmdOutbox("run", nil, globalOptions{}, config.Default(), st)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	item := st.Outbox["o_1"]
+	if item.Status != "failed" || item.Attempts != outboxMaxAttempts || data.(outboxRunResponse).Failed != 1 {
+		t.Fatalf("expected failure after %d attempts: %+v", outboxMaxAttempts, item)
+	}
+}
+
+func TestOutboxRunLoopReloadsPersistedState(t *testing.T) {
+	calls := stubOutboxSend(t, nil)
+	path := filepath.Join(t.TempDir(), "state.json")
+	st := outboxState()
+	dueItem(st, "o_1", time.Now().Add(-time.Second).UTC(), "send-late")
+	if err := store.New(path).Save(*st); err != nil {
+		t.Fatal(err)
+	}
+	prevErr := runtimeStderr
+	runtimeStderr = io.Discard
+	defer func() { runtimeStderr = prevErr }()
+	var mem model.State
+	data, changed, err := cmdOutbox("run", []string{"--loop", "--iterations", "2", "--interval", "1ms"}, globalOptions{statePath: path}, config.Default(), &mem)
+	if err != nil || !changed {
+		t.Fatalf("loop: changed=%v err=%v", changed, err)
+	}
+	if resp := data.(outboxRunResponse); resp.Iterations != 2 || resp.Sent != 1 || *calls != 1 {
+		t.Fatalf("unexpected loop result: %+v calls=%d", resp, *calls)
+	}
+	saved, err := store.New(path).Load()
+	if err != nil || saved.Outbox["o_1"].Status != "sent" {
+		t.Fatalf("loop must persist progress: %+v err=%v", saved.Outbox["o_1"], err)
+	}
+}
+
+func TestOutboxFailsStaleSendingAndLocksRuns(t *testing.T) {
+	calls := stubOutboxSend(t, nil)
+	path := filepath.Join(t.TempDir(), "state.json")
+	st := outboxState()
+	dueItem(st, "o_1", time.Now().Add(-time.Hour).UTC(), "send-late")
+	stale := st.Outbox["o_1"]
+	stale.Status, stale.Attempts, stale.UpdatedAt = "sending", 1, time.Now().Add(-2*outboxSendingLease).UTC()
+	st.Outbox["o_1"] = stale
+	if err := store.New(path).Save(*st); err != nil {
+		t.Fatal(err)
+	}
+	unlock, err := store.New(path).TryLock("outbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := globalOptions{statePath: path}
+	if _, _, err := cmdOutbox("run", nil, g, config.Default(), st); errorCodeFromErr(err, "") != "outbox_busy" {
+		t.Fatalf("expected outbox_busy while another run holds the lock, got %v", err)
+	}
+	unlock()
+	data, _, err := cmdOutbox("run", nil, g, config.Default(), st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp := data.(outboxRunResponse); resp.Sent != 0 || *calls != 0 || st.Outbox["o_1"].Status != "failed" || !strings.Contains(st.Outbox["o_1"].LastError, "outbox requeue --id o_1") {
+		t.Fatalf("stale sending item must be failed, not resent: %+v item=%+v", resp, st.Outbox["o_1"])
+	}
+	if _, _, err := cmdOutbox("requeue", []string{"--id", "o_1"}, g, config.Default(), st); err != nil || st.Outbox["o_1"].Status != "queued" {
+		t.Fatalf("requeue: %+v err=%v", st.Outbox["o_1"], err)
+	}
+	if _, _, err := cmdOutbox("requeue", []string{"--id", "o_1"}, g, config.Default(), st); errorCodeFromErr(err, "") != "outbox_item_not_pending" {
+		t.Fatalf("only failed items can be requeued, got %v", err)
+	}
+	if err := store.New(path).Save(*st); err != nil {
+		t.Fatal(err)
+	}
+	if data, _, err = cmdOutbox("run", nil, g, config.Default(), st); err != nil || data.(outboxRunResponse).Sent != 1 || *calls != 1 {
+		t.Fatalf("requeued item should send: %+v err=%v calls=%d", data, err, *calls)
+	}
+}
+
+func TestOutboxKeepsOnlyTheGivenConfirmToken(t *testing.T) {
+	calls := stubOutboxSend(t, nil)
+	st := outboxState()
+	cfg := config.Default()
+	cfg.Safety.RequireConfirmSendNonTTY = false
+	at := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	data, _, err := cmdMessage("send", []string{"--draft-id", "d_1", "--at", at}, globalOptions{noInput: true}, cfg, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := data.(messageScheduleResponse).Item.ID
+	if st.Outbox[id].ConfirmSend != "" {
+		t.Fatalf("enqueue must not invent a confirm token: %+v", st.Outbox[id])
+	}
+	item := st.Outbox[id]
+	item.At = time.Now().Add(-time.Minute).UTC()
+	st.Outbox[id] = item
+	if _, _, err := cmdOutbox("run", nil, globalOptions{noInput: true}, config.Default(), st); err != nil || *calls != 1 || st.Outbox[id].Status != "sent" {
+		t.Fatalf("approved item should send from the outbox: %+v calls=%d err=%v", st.Outbox[id], *calls, err)
+	}
+}
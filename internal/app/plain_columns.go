@@ -0,0 +1,153 @@
+package app
+
+import (
+	"strings"
+	"time"
+
+	"protonmailcli/internal/model"
+)
+
+var (
+	plainDraftColumns   = []string{"id", "to", "subject", "date"}
+	plainMessageColumns = []string{"id", "from", "subject", "date"}
+	plainMailboxColumns = []string{"name", "kind", "total", "unread", "specialUse"}
+	plainThreadColumns  = []string{"id", "count", "unread", "subject", "lastActivity"}
+	plainThreadMessages = []string{"id", "depth", "from", "subject", "date"}
+	plainMarkColumns    = []string{"id", "flags"}
+	plainBatchColumns   = []string{"index", "ok", "draftId", "errorCode", "error"}
+	plainOutboxColumns  = []string{"id", "draftId", "at", "status", "attempts"}
+	plainOutboxRun      = []string{"id", "draftId", "status", "error"}
+	plainLintColumns    = []string{"rule", "severity", "message"}
+	plainTagColumns     = []string{"name"}
+	plainFilterColumns  = []string{"id", "name", "contains", "addTag"}
+	plainAccountColumns = []string{"username", "active"}
+	plainSchemaColumns  = []string{"command", "file"}
+	plainCommandColumns = []string{"command", "effect", "idempotencyKey", "summary"}
+)
+
+var plainColumnsByCommand = map[string][]string{
+	"draft list":          plainDraftColumns,
+	"draft get":           plainDraftColumns,
+	"search drafts":       plainDraftColumns,
+	"search messages":     plainMessageColumns,
+	"message get":         plainMessageColumns,
+	"message mark":        plainMarkColumns,
+	"mailbox list":        plainMailboxColumns,
+	"thread list":         plainThreadColumns,
+	"thread get":          plainThreadMessages,
+	"draft create-many":   plainBatchColumns,
+	"message send-many":   plainBatchColumns,
+	"outbox list":         plainOutboxColumns,
+	"outbox run":          plainOutboxRun,
+	"outbox flush":        plainOutboxRun,
+	"draft lint":          plainLintColumns,
+	"tag list":            plainTagColumns,
+	"filter list":         plainFilterColumns,
+	"bridge account list": plainAccountColumns,
+	"schema list":         plainSchemaColumns,
+	"commands":            plainCommandColumns,
+}
+
+func plainColumnsHelp(helpName string) string {
+	cols, ok := plainColumnsByCommand[helpName]
+	if !ok {
+		return ""
+	}
+	return "\n\nPlain columns (--plain, select with --fields): " + strings.Join(cols, ", ")
+}
+
+func plainDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func draftRecordFromModel(d model.Draft) draftRecord {
+	return draftRecord{ID: d.ID, UID: d.ID, To: d.To, Subject: d.Subject, Body: d.Body, Date: plainDate(d.UpdatedAt)}
+}
+
+func messageRecordFromModel(m model.Message) messageRecord {
+	return messageRecord{ID: m.ID, UID: m.ID, From: m.From, To: m.To, Subject: m.Subject, Body: m.Body, Flags: m.Flags, Date: plainDate(m.SentAt), Headers: m.Headers}
+}
+
+func draftRecordsFromModel(drafts []model.Draft) []draftRecord {
+	out := make([]draftRecord, 0, len(drafts))
+	for _, d := range drafts {
+		out = append(out, draftRecordFromModel(d))
+	}
+	return out
+}
+
+func (r draftListResponse) PlainList() (any, []string) { return r.Drafts, plainDraftColumns }
+
+func (r localDraftListResponse) PlainList() (any, []string) {
+	return draftRecordsFromModel(r.Drafts), plainDraftColumns
+}
+
+func (r localSearchDraftsResponse) PlainList() (any, []string) {
+	return draftRecordsFromModel(r.Drafts), plainDraftColumns
+}
+
+func (r draftResponse) PlainList() (any, []string) {
+	return []draftRecord{r.Draft}, plainDraftColumns
+}
+
+func (r localDraftResponse) PlainList() (any, []string) {
+	return []draftRecord{draftRecordFromModel(r.Draft)}, plainDraftColumns
+}
+
+func (r messageListResponse) PlainList() (any, []string) { return r.Messages, plainMessageColumns }
+
+func (r localSearchMessagesResponse) PlainList() (any, []string) {
+	out := make([]messageRecord, 0, len(r.Messages))
+	for _, m := range r.Messages {
+		out = append(out, messageRecordFromModel(m))
+	}
+	return out, plainMessageColumns
+}
+
+func (r messageGetResponse) PlainList() (any, []string) {
+	return []messageRecord{r.Message}, plainMessageColumns
+}
+
+func (r localMessageGetResponse) PlainList() (any, []string) {
+	rec := messageRecordFromModel(r.Message.Message)
+	rec.Headers = r.Message.Headers
+	rec.Authentication = r.Message.Authentication
+	return []messageRecord{rec}, plainMessageColumns
+}
+
+func (r messageMarkResponse) PlainList() (any, []string) { return r.Messages, plainMarkColumns }
+
+func (r mailboxListResponse) PlainList() (any, []string) { return r.Mailboxes, plainMailboxColumns }
+
+func (r threadListResponse) PlainList() (any, []string) { return r.Threads, plainThreadColumns }
+
+func (r threadGetResponse) PlainList() (any, []string) { return r.Messages, plainThreadMessages }
+
+func (r batchResultResponse) PlainList() (any, []string) { return r.Results, plainBatchColumns }
+
+func (r outboxListResponse) PlainList() (any, []string) { return r.Items, plainOutboxColumns }
+
+func (r outboxRunResponse) PlainList() (any, []string) { return r.Processed, plainOutboxRun }
+
+func (r draftLintResponse) PlainList() (any, []string) { return r.Findings, plainLintColumns }
+
+func (r tagListResponse) PlainList() (any, []string) {
+	out := make([]tagInfo, 0, len(r.Tags))
+	for _, t := range r.Tags {
+		out = append(out, tagInfo{Name: t})
+	}
+	return out, plainTagColumns
+}
+
+func (r filterListResponse) PlainList() (any, []string) { return r.Filters, plainFilterColumns }
+
+func (r bridgeAccountListResponse) PlainList() (any, []string) {
+	return r.Accounts, plainAccountColumns
+}
+
+func (r schemaListResponse) PlainList() (any, []string) { return r.Schemas, plainSchemaColumns }
+
+func (r commandsResponse) PlainList() (any, []string) { return r.Commands, plainCommandColumns }
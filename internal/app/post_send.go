@@ -0,0 +1,141 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"protonmailcli/internal/bridge"
+	"protonmailcli/internal/config"
+	"protonmailcli/internal/model"
+)
+
+const (
+	postSendDelete     = "delete"
+	postSendMoveToSent = "move-to-sent"
+	postSendKeep       = "keep"
+)
+
+type imapSentClient interface {
+	SentMailboxName() (string, error)
+	SearchUIDs(mailbox, criteria string) ([]string, error)
+	AppendMessage(mailbox, raw string, flags []string) (string, error)
+	MoveUID(srcMailbox, uid, dstMailbox string) error
+	DeleteUID(mailbox, uid string) error
+	SetKeyword(mailbox, uid, keyword string, add bool) error
+}
+
+type postSendResult struct {
+	Action            string `json:"action"`
+	SentMessageID     string `json:"sentMessageId,omitempty"`
+	SentAt            string `json:"sentAt,omitempty"`
+	SentCopy          string `json:"sentCopy"`
+	DraftRemoved      bool   `json:"draftRemoved"`
+	AnsweredMessageID string `json:"answeredMessageId,omitempty"`
+	Error             string `json:"error,omitempty"`
+}
+
+var (
+	sentCopyPollBudget   = 2 * time.Second
+	sentCopyPollInterval = 500 * time.Millisecond
+)
+
+func resolvePostSendAction(override string, cfg config.Config) (string, error) {
+	action := strings.TrimSpace(firstNonEmpty(override, cfg.Safety.PostSendAction, postSendDelete))
+	switch action {
+	case postSendDelete, postSendMoveToSent, postSendKeep:
+		return action, nil
+	default:
+		return "", fmt.Errorf("invalid post-send action %q (expected delete|move-to-sent|keep)", action)
+	}
+}
+
+func sendHeadersForDraft(d bridge.DraftMessage, from string) map[string]string {
+	headers := map[string]string{"Message-ID": normalizeMessageID(d.MessageID)}
+	if headers["Message-ID"] == "" {
+		headers["Message-ID"] = bridge.NewMessageID(from)
+	}
+	if inReplyTo := normalizeMessageID(d.InReplyTo); inReplyTo != "" {
+		headers["In-Reply-To"] = inReplyTo
+		_, refs := threadHeaders(inReplyTo, d.References)
+		headers["References"] = strings.Join(refs, " ")
+	}
+	return headers
+}
+
+func finalizeSentDraft(c imapSentClient, draftMailbox, draftUID, messageID, raw, action string, sentAt time.Time, pollBudget time.Duration) postSendResult {
+	res := postSendResult{Action: action, SentAt: sentAt.Format(time.RFC3339)}
+	sentMailbox, err := c.SentMailboxName()
+	if err != nil {
+		res.SentCopy = "missing"
+		res.Error = err.Error()
+		return res
+	}
+	criteria := fmt.Sprintf(`HEADER Message-ID "%s"`, escapeSearch(messageID))
+	sentUID := findSentCopy(c, sentMailbox, criteria, pollBudget)
+	switch {
+	case sentUID != "":
+		res.SentCopy = "bridge"
+	case action == postSendMoveToSent:
+		if err := c.MoveUID(draftMailbox, draftUID, sentMailbox); err != nil {
+			res.SentCopy = "missing"
+			res.Error = err.Error()
+			return res
+		}
+		res.SentCopy = "moved_draft"
+		res.DraftRemoved = true
+		if uids, err := c.SearchUIDs(sentMailbox, criteria); err == nil && len(uids) > 0 {
+			sentUID = uids[len(uids)-1]
+			_ = c.SetKeyword(sentMailbox, sentUID, `\Draft`, false)
+		}
+	default:
+		uid, err := c.AppendMessage(sentMailbox, raw, []string{`\Seen`})
+		if err != nil {
+			res.SentCopy = "missing"
+			res.Error = err.Error()
+		} else {
+			res.SentCopy = "imap_append"
+			sentUID = uid
+		}
+	}
+	if sentUID != "" {
+		res.SentMessageID = imapMessageIDForMailbox(sentMailbox, sentUID)
+	}
+	if action != postSendKeep && !res.DraftRemoved {
+		if err := c.DeleteUID(draftMailbox, draftUID); err != nil {
+			if res.Error == "" {
+				res.Error = err.Error()
+			}
+			return res
+		}
+		res.DraftRemoved = true
+	}
+	return res
+}
+
+func findSentCopy(c imapSentClient, sentMailbox, criteria string, budget time.Duration) string {
+	var waited time.Duration
+	for {
+		uids, err := c.SearchUIDs(sentMailbox, criteria)
+		if err == nil && len(uids) > 0 {
+			return uids[len(uids)-1]
+		}
+		if sentCopyPollInterval <= 0 || waited+sentCopyPollInterval > budget {
+			return ""
+		}
+		time.Sleep(sentCopyPollInterval)
+		waited += sentCopyPollInterval
+	}
+}
+
+func finalizeLocalSentDraft(st *model.State, d model.Draft, msgID, action string, sentAt time.Time) postSendResult {
+	res := postSendResult{Action: action, SentMessageID: msgID, SentAt: sentAt.Format(time.RFC3339), SentCopy: "local_state"}
+	if action == postSendKeep {
+		d.SentAt = &sentAt
+		st.Drafts[d.ID] = d
+		return res
+	}
+	delete(st.Drafts, d.ID)
+	res.DraftRemoved = true
+	return res
+}
@@ -0,0 +1,235 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"protonmailcli/internal/bridge"
+	"protonmailcli/internal/config"
+	"protonmailcli/internal/model"
+	"protonmailcli/internal/store"
+)
+
+type fakeSentClient struct {
+	searchUIDs map[string][]string
+	appendUID  string
+	appended   []string
+	moved      []string
+	deleted    []string
+	keywords   []string
+}
+
+func (f *fakeSentClient) SentMailboxName() (string, error) { return "Sent", nil }
+
+func (f *fakeSentClient) SearchUIDs(mailbox, criteria string) ([]string, error) {
+	_ = criteria
+	return f.searchUIDs[mailbox], nil
+}
+
+func (f *fakeSentClient) AppendMessage(mailbox, raw string, flags []string) (string, error) {
+	_ = raw
+	_ = flags
+	f.appended = append(f.appended, mailbox)
+	return f.appendUID, nil
+}
+
+func (f *fakeSentClient) MoveUID(srcMailbox, uid, dstMailbox string) error {
+	f.moved = append(f.moved, srcMailbox+":"+uid+"->"+dstMailbox)
+	return nil
+}
+
+func (f *fakeSentClient) DeleteUID(mailbox, uid string) error {
+	f.deleted = append(f.deleted, mailbox+":"+uid)
+	return nil
+}
+
+func (f *fakeSentClient) SetKeyword(mailbox, uid, keyword string, add bool) error {
+	_ = add
+	f.keywords = append(f.keywords, mailbox+":"+uid+":"+keyword)
+	return nil
+}
+
+func withFastSentPoll(t *testing.T) {
+	t.Helper()
+	prevBudget, prevInterval := sentCopyPollBudget, sentCopyPollInterval
+	sentCopyPollBudget, sentCopyPollInterval = 0, 0
+	t.Cleanup(func() {
+		sentCopyPollBudget, sentCopyPollInterval = prevBudget, prevInterval
+	})
+}
+
+func TestResolvePostSendAction(t *testing.T) {
+	cfg := config.Default()
+	if got, err := resolvePostSendAction("", cfg); err != nil || got != postSendDelete {
+		t.Fatalf("expected config default delete, got %q err=%v", got, err)
+	}
+	cfg.Safety.PostSendAction = postSendKeep
+	if got, err := resolvePostSendAction("", cfg); err != nil || got != postSendKeep {
+		t.Fatalf("expected config keep, got %q err=%v", got, err)
+	}
+	if got, err := resolvePostSendAction(postSendMoveToSent, cfg); err != nil || got != postSendMoveToSent {
+		t.Fatalf("expected flag override, got %q err=%v", got, err)
+	}
+	if _, err := resolvePostSendAction("archive", cfg); err == nil {
+		t.Fatal("expected invalid action error")
+	}
+}
+
+func TestFinalizeSentDraftUsesBridgeCopyAndDeletesDraft(t *testing.T) {
+	withFastSentPoll(t)
+	c := &fakeSentClient{searchUIDs: map[string][]string{"Sent": {"41"}}}
+	res := finalizeSentDraft(c, "Drafts", "7", "<id@example.com>", "raw", postSendDelete, time.Now().UTC(), 0)
+	if res.SentCopy != "bridge" || res.SentMessageID != "imap:Sent:41" || !res.DraftRemoved {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if len(c.appended) != 0 {
+		t.Fatalf("should not append when bridge created a copy: %v", c.appended)
+	}
+	if len(c.deleted) != 1 || c.deleted[0] != "Drafts:7" {
+		t.Fatalf("expected draft delete, got %v", c.deleted)
+	}
+}
+
+func TestFinalizeSentDraftAppendsMissingCopy(t *testing.T) {
+	withFastSentPoll(t)
+	c := &fakeSentClient{appendUID: "12"}
+	res := finalizeSentDraft(c, "Drafts", "7", "<id@example.com>", "raw", postSendKeep, time.Now().UTC(), 0)
+	if res.SentCopy != "imap_append" || res.SentMessageID != "imap:Sent:12" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if res.DraftRemoved || len(c.deleted) != 0 {
+		t.Fatalf("keep must not remove the draft: %+v %v", res, c.deleted)
+	}
+}
+
+func TestFinalizeSentDraftMovesDraftWhenNoCopy(t *testing.T) {
+	withFastSentPoll(t)
+	c := &fakeSentClient{}
+	res := finalizeSentDraft(c, "Drafts", "7", "<id@example.com>", "raw", postSendMoveToSent, time.Now().UTC(), 0)
+	if res.SentCopy != "moved_draft" || !res.DraftRemoved {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if len(c.moved) != 1 || c.moved[0] != "Drafts:7->Sent" {
+		t.Fatalf("expected draft move, got %v", c.moved)
+	}
+	if len(c.appended) != 0 || len(c.deleted) != 0 {
+		t.Fatalf("move-to-sent should neither append nor delete: appended=%v deleted=%v", c.appended, c.deleted)
+	}
+}
+
+func TestFindSentCopyGivesEachSendItsOwnBudget(t *testing.T) {
+	prevInterval := sentCopyPollInterval
+	sentCopyPollInterval = time.Millisecond
+	t.Cleanup(func() { sentCopyPollInterval = prevInterval })
+	c := &countingSentClient{}
+	for i := 0; i < 4; i++ {
+		if uid := findSentCopy(c, "Sent", "ALL", 3*time.Millisecond); uid != "" {
+			t.Fatalf("unexpected copy %q", uid)
+		}
+	}
+	if c.searches != 16 {
+		t.Fatalf("each send should poll within its own budget: %d searches", c.searches)
+	}
+}
+
+func TestFinalizeSentDraftReportsTheGivenSendTime(t *testing.T) {
+	withFastSentPoll(t)
+	sentAt := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	res := finalizeSentDraft(&fakeSentClient{appendUID: "12"}, "Drafts", "7", "<id@example.com>", "raw", postSendDelete, sentAt, 0)
+	if res.SentAt != "2026-03-01T09:30:00Z" {
+		t.Fatalf("sentAt should be the smtp send time, got %q", res.SentAt)
+	}
+}
+
+type countingSentClient struct {
+	fakeSentClient
+	searches int
+}
+
+func (c *countingSentClient) SearchUIDs(mailbox, criteria string) ([]string, error) {
+	c.searches++
+	return c.fakeSentClient.SearchUIDs(mailbox, criteria)
+}
+
+func TestSendHeadersForDraftPrefersDraftMessageID(t *testing.T) {
+	h := sendHeadersForDraft(bridge.DraftMessage{MessageID: "abc@example.com"}, "me@example.com")
+	if h["Message-ID"] != "<abc@example.com>" {
+		t.Fatalf("unexpected message id: %q", h["Message-ID"])
+	}
+	h = sendHeadersForDraft(bridge.DraftMessage{}, "me@example.com")
+	if !strings.HasSuffix(h["Message-ID"], "@example.com>") {
+		t.Fatalf("expected generated message id, got %q", h["Message-ID"])
+	}
+}
+
+func TestLocalSendPostSendAction(t *testing.T) {
+	t.Setenv("PMAIL_USE_LOCAL_STATE", "1")
+	prevSend := smtpSendFn
+	defer func() { smtpSendFn = prevSend }()
+	smtpSendFn = func(_ bridge.SMTPConfig, _ bridge.SendInput) error { return nil }
+
+	for _, tc := range []struct {
+		action    string
+		keepDraft bool
+	}{
+		{action: "", keepDraft: false},
+		{action: postSendKeep, keepDraft: true},
+	} {
+		t.Run("action="+tc.action, func(t *testing.T) {
+			tmp := t.TempDir()
+			cfg := filepath.Join(tmp, "config.toml")
+			state := filepath.Join(tmp, "state.json")
+			if exit := Run([]string{"--json", "--config", cfg, "--state", state, "setup", "--non-interactive", "--username", "me@example.com"}, bytes.NewBuffer(nil), &bytes.Buffer{}, &bytes.Buffer{}); exit != 0 {
+				t.Fatalf("setup failed: %d", exit)
+			}
+			now := time.Now().UTC()
+			if err := store.New(state).Save(model.State{
+				Drafts:   map[string]model.Draft{"d_1": {ID: "d_1", To: []string{"a@example.com"}, Subject: "s", Body: "b", CreatedAt: now, UpdatedAt: now}},
+				Messages: map[string]model.Message{},
+			}); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("PMAIL_SMTP_PASSWORD", "secret")
+			args := []string{"--json", "--no-input", "--config", cfg, "--state", state, "message", "send", "--draft-id", "d_1", "--confirm-send", "d_1"}
+			if tc.action != "" {
+				args = append(args, "--post-send", tc.action)
+			}
+			stdout := &bytes.Buffer{}
+			if exit := Run(args, bytes.NewBuffer(nil), stdout, &bytes.Buffer{}); exit != 0 {
+				t.Fatalf("send failed: %d stdout=%s", exit, stdout.String())
+			}
+			var env struct {
+				Data messageSendResponse `json:"data"`
+			}
+			if err := json.Unmarshal(stdout.Bytes(), &env); err != nil {
+				t.Fatal(err)
+			}
+			if env.Data.SentMessageID == "" || env.Data.PostSend == nil || env.Data.PostSend.SentAt == "" {
+				t.Fatalf("missing post-send details: %s", stdout.String())
+			}
+			b, err := os.ReadFile(state)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var st model.State
+			if err := json.Unmarshal(b, &st); err != nil {
+				t.Fatal(err)
+			}
+			d, kept := st.Drafts["d_1"]
+			if kept != tc.keepDraft {
+				t.Fatalf("draft kept=%v want %v", kept, tc.keepDraft)
+			}
+			if kept && d.SentAt == nil {
+				t.Fatalf("kept draft must record sentAt")
+			}
+			if _, ok := st.Messages[env.Data.SentMessageID]; !ok {
+				t.Fatalf("sent message %s not recorded", env.Data.SentMessageID)
+			}
+		})
+	}
+}
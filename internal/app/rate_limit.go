@@ -0,0 +1,167 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"protonmailcli/internal/config"
+	"protonmailcli/internal/model"
+)
+
+var rateLimitSleep = time.Sleep
+
+var errSendQuotaExhausted = errors.New("send quota exhausted")
+
+type sendQuotaStatus struct {
+	Profile           string `json:"profile"`
+	Identity          string `json:"identity"`
+	MaxPerMinute      int    `json:"maxPerMinute,omitempty"`
+	MaxPerDay         int    `json:"maxPerDay,omitempty"`
+	RemainingMinute   *int   `json:"remainingMinute,omitempty"`
+	RemainingDay      *int   `json:"remainingDay,omitempty"`
+	Sent              int    `json:"sent"`
+	RetryAfterSeconds int    `json:"retryAfterSeconds,omitempty"`
+}
+
+func rateLimitEnabled(cfg config.Config) bool {
+	return cfg.RateLimit.MaxPerMinute > 0 || cfg.RateLimit.MaxPerDay > 0
+}
+
+func sendQuotaKey(profile, identity string) string {
+	return firstNonEmpty(profile, "default") + "/" + strings.ToLower(strings.TrimSpace(identity))
+}
+
+func refillSendQuota(q model.SendQuota, limits config.RateLimit, now time.Time) model.SendQuota {
+	if q.UpdatedAt.IsZero() {
+		q.MinuteTokens = float64(limits.MaxPerMinute)
+		q.DayTokens = float64(limits.MaxPerDay)
+		q.UpdatedAt = now
+		return q
+	}
+	elapsed := now.Sub(q.UpdatedAt).Seconds()
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	q.MinuteTokens = math.Min(float64(limits.MaxPerMinute), q.MinuteTokens+elapsed*float64(limits.MaxPerMinute)/60)
+	q.DayTokens = math.Min(float64(limits.MaxPerDay), q.DayTokens+elapsed*float64(limits.MaxPerDay)/86400)
+	q.UpdatedAt = now
+	return q
+}
+
+func sendQuotaRetryAfter(q model.SendQuota, limits config.RateLimit) int {
+	wait := 0.0
+	if limits.MaxPerMinute > 0 && q.MinuteTokens < 1 {
+		wait = math.Max(wait, (1-q.MinuteTokens)*60/float64(limits.MaxPerMinute))
+	}
+	if limits.MaxPerDay > 0 && q.DayTokens < 1 {
+		wait = math.Max(wait, (1-q.DayTokens)*86400/float64(limits.MaxPerDay))
+	}
+	return int(math.Ceil(wait))
+}
+
+func consumeSendQuota(st *model.State, cfg config.Config, g globalOptions, identity string, wait bool) error {
+	if !rateLimitEnabled(cfg) {
+		return nil
+	}
+	var file *stateFile
+	if !g.dryRun {
+		file = stateFileFor(g, st)
+	}
+	who := strings.ToLower(strings.TrimSpace(identity))
+	for {
+		retry := 0
+		spend := func(s *model.State) error {
+			if retry = spendSendQuota(s, cfg.RateLimit, g.profile, identity, time.Now().UTC()); retry > 0 {
+				return errSendQuotaExhausted
+			}
+			return nil
+		}
+		var err error
+		if file != nil {
+			err = file.commit(st, spend)
+		} else {
+			err = spend(st)
+		}
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, errSendQuotaExhausted) {
+			return cliError{exit: 1, code: "state_error", msg: err.Error()}
+		}
+		if !wait {
+			return cliError{exit: 8, code: "rate_limit", msg: fmt.Sprintf("send quota exhausted for %s", who), hint: fmt.Sprintf("Retry in %ds or pass --wait-for-quota", retry), retryAfter: retry}
+		}
+		fmt.Fprintf(runtimeStderr, "waiting %ds for send quota (%s)\n", retry, who)
+		rateLimitSleep(time.Duration(retry) * time.Second)
+	}
+}
+
+func spendSendQuota(st *model.State, limits config.RateLimit, profile, identity string, now time.Time) int {
+	if st.SendQuotas == nil {
+		st.SendQuotas = map[string]model.SendQuota{}
+	}
+	key := sendQuotaKey(profile, identity)
+	q := st.SendQuotas[key]
+	q.Profile = firstNonEmpty(profile, "default")
+	q.Identity = strings.ToLower(strings.TrimSpace(identity))
+	q = refillSendQuota(q, limits, now)
+	if retry := sendQuotaRetryAfter(q, limits); retry > 0 {
+		return retry
+	}
+	if limits.MaxPerMinute > 0 {
+		q.MinuteTokens--
+	}
+	if limits.MaxPerDay > 0 {
+		q.DayTokens--
+	}
+	q.Sent++
+	st.SendQuotas[key] = q
+	return 0
+}
+
+func retryAfterFromErr(err error) int {
+	var ce cliError
+	if errors.As(err, &ce) {
+		return ce.retryAfter
+	}
+	return 0
+}
+
+func sendQuotaReport(cfg config.Config, st *model.State, profile, identity string) []sendQuotaStatus {
+	if !rateLimitEnabled(cfg) {
+		return []sendQuotaStatus{}
+	}
+	keys := []string{}
+	current := sendQuotaKey(profile, identity)
+	if _, ok := st.SendQuotas[current]; !ok && strings.TrimSpace(identity) != "" {
+		keys = append(keys, current)
+	}
+	for key := range st.SendQuotas {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	now := time.Now().UTC()
+	out := make([]sendQuotaStatus, 0, len(keys))
+	for _, key := range keys {
+		q, ok := st.SendQuotas[key]
+		if !ok {
+			q = model.SendQuota{Profile: firstNonEmpty(profile, "default"), Identity: strings.ToLower(strings.TrimSpace(identity))}
+		}
+		q = refillSendQuota(q, cfg.RateLimit, now)
+		status := sendQuotaStatus{Profile: q.Profile, Identity: q.Identity, MaxPerMinute: cfg.RateLimit.MaxPerMinute, MaxPerDay: cfg.RateLimit.MaxPerDay, Sent: q.Sent, RetryAfterSeconds: sendQuotaRetryAfter(q, cfg.RateLimit)}
+		if cfg.RateLimit.MaxPerMinute > 0 {
+			n := int(math.Floor(q.MinuteTokens))
+			status.RemainingMinute = &n
+		}
+		if cfg.RateLimit.MaxPerDay > 0 {
+			n := int(math.Floor(q.DayTokens))
+			status.RemainingDay = &n
+		}
+		out = append(out, status)
+	}
+	return out
+}
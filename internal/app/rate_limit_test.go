@@ -0,0 +1,137 @@
+package app
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"protonmailcli/internal/config"
+	"protonmailcli/internal/output"
+	"protonmailcli/internal/store"
+)
+
+func limitedConfig(perMinute, perDay int) config.Config {
+	cfg := config.Default()
+	cfg.RateLimit = config.RateLimit{MaxPerMinute: perMinute, MaxPerDay: perDay}
+	return cfg
+}
+
+func TestSendQuotaRejectsWhenBucketIsEmpty(t *testing.T) {
+	calls := stubOutboxSend(t, nil)
+	st := outboxState()
+	cfg := limitedConfig(2, 100)
+	args := []string{"--draft-id", "d_1", "--confirm-send", "d_1", "--post-send", "keep"}
+	for i := 0; i < 2; i++ {
+		if _, _, err := cmdMessage("send", args, globalOptions{profile: "work"}, cfg, st); err != nil {
+			t.Fatalf("send %d: %v", i, err)
+		}
+	}
+	_, _, err := cmdMessage("send", args, globalOptions{profile: "work"}, cfg, st)
+	if errorCodeFromErr(err, "") != "rate_limit" || exitCodeFromErr(err) != 8 || *calls != 2 {
+		t.Fatalf("expected rate_limit after two sends, got %v calls=%d", err, *calls)
+	}
+	if retry := retryAfterFromErr(err); retry < 1 || retry > 30 {
+		t.Fatalf("unexpected retryAfter %d", retry)
+	}
+	q := st.SendQuotas["work/me@example.com"]
+	if q.Sent != 2 || q.DayTokens > 98.01 {
+		t.Fatalf("quota not persisted in state: %+v", q)
+	}
+	if _, _, err := cmdMessage("send", args, globalOptions{profile: "personal"}, cfg, st); err != nil {
+		t.Fatalf("other profiles have their own bucket: %v", err)
+	}
+
+	report := sendQuotaReport(cfg, st, "work", "me@example.com")
+	if len(report) != 2 || report[1].Profile != "work" || *report[1].RemainingMinute != 0 || report[1].RetryAfterSeconds == 0 {
+		t.Fatalf("unexpected doctor report: %+v", report)
+	}
+}
+
+func TestSendQuotaWaitForQuota(t *testing.T) {
+	calls := stubOutboxSend(t, nil)
+	st := outboxState()
+	cfg := limitedConfig(1, 0)
+	prevSleep, prevErr := rateLimitSleep, runtimeStderr
+	defer func() { rateLimitSleep, runtimeStderr = prevSleep, prevErr }()
+	runtimeStderr = &bytes.Buffer{}
+	var waited time.Duration
+	rateLimitSleep = func(d time.Duration) {
+		waited += d
+		q := st.SendQuotas["default/me@example.com"]
+		q.UpdatedAt = q.UpdatedAt.Add(-d)
+		st.SendQuotas["default/me@example.com"] = q
+	}
+	args := []string{"--draft-id", "d_1", "--confirm-send", "d_1", "--post-send", "keep", "--wait-for-quota"}
+	for i := 0; i < 2; i++ {
+		if _, _, err := cmdMessage("send", args, globalOptions{}, cfg, st); err != nil {
+			t.Fatalf("send %d: %v", i, err)
+		}
+	}
+	if *calls != 2 || waited < 59*time.Second || waited > 60*time.Second {
+		t.Fatalf("expected one wait of about a minute, waited=%s calls=%d", waited, *calls)
+	}
+}
+
+func TestSendManyReportsRateLimitPerItem(t *testing.T) {
+	stubOutboxSend(t, nil)
+	st := outboxState()
+	manifest := filepath.Join(t.TempDir(), "send.json")
+	if err := os.WriteFile(manifest, []byte(`[{"draft_id":"d_1","confirm_send":"d_1"},{"draft_id":"d_1","confirm_send":"d_1"}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	data, _, err := cmdMessage("send-many", []string{"--file", manifest, "--post-send", "keep"}, globalOptions{}, limitedConfig(0, 1), st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := data.(batchResultResponse)
+	if resp.Success != 1 || resp.ExitCode() != 10 || resp.Results[1].ErrorCode != "rate_limit" || resp.Results[1].RetryAfterSeconds < 80000 {
+		t.Fatalf("unexpected batch result: %+v", resp)
+	}
+}
+
+func TestRateLimitErrorBodyCarriesRetryAfter(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	a := App{Stdout: &stdout, Stderr: &stderr}
+	exit := a.exitWithError(cliError{exit: 8, code: "rate_limit", msg: "send quota exhausted", retryAfter: 42}, output.ModeJSON, "default", "req_1", time.Now())
+	if exit != 8 || !strings.Contains(stdout.String(), `"retryAfterSeconds":42`) || !strings.Contains(stdout.String(), `"category":"rate_limit"`) {
+		t.Fatalf("unexpected error output exit=%d: %s", exit, stdout.String())
+	}
+}
+
+func TestSendQuotaIsSharedThroughTheStateFile(t *testing.T) {
+	stubOutboxSend(t, nil)
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := store.New(path).Save(*outboxState()); err != nil {
+		t.Fatal(err)
+	}
+	cfg := limitedConfig(1, 0)
+	g := globalOptions{statePath: path}
+	first, second := *outboxState(), *outboxState()
+	if err := consumeSendQuota(&first, cfg, g, "me@example.com", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := consumeSendQuota(&second, cfg, g, "me@example.com", false); errorCodeFromErr(err, "") != "rate_limit" {
+		t.Fatalf("a second process must see the spent quota on disk, got %v", err)
+	}
+
+	prevSleep, prevErr := rateLimitSleep, runtimeStderr
+	defer func() { rateLimitSleep, runtimeStderr = prevSleep, prevErr }()
+	runtimeStderr = &bytes.Buffer{}
+	rateLimitSleep = func(time.Duration) {
+		saved, _ := store.New(path).Load()
+		q := saved.SendQuotas[sendQuotaKey("", "me@example.com")]
+		q.MinuteTokens, q.UpdatedAt = 1, time.Now().UTC()
+		saved.SendQuotas[sendQuotaKey("", "me@example.com")] = q
+		_ = store.New(path).Save(saved)
+	}
+	if err := consumeSendQuota(&second, cfg, g, "me@example.com", true); err != nil {
+		t.Fatalf("--wait-for-quota must re-read the quota from disk: %v", err)
+	}
+	saved, _ := store.New(path).Load()
+	if q := saved.SendQuotas[sendQuotaKey("", "me@example.com")]; q.Sent != 2 || second.SendQuotas[sendQuotaKey("", "me@example.com")].Sent != 2 {
+		t.Fatalf("both spends must land on disk: %+v", q)
+	}
+}
@@ -0,0 +1,176 @@
+package app
+
+import (
+	"fmt"
+	"net/mail"
+	"sort"
+	"strings"
+
+	"protonmailcli/internal/bridge"
+	"protonmailcli/internal/config"
+	"protonmailcli/internal/model"
+)
+
+type recipientCheck struct {
+	addresses   []string
+	confirmBulk int
+}
+
+type policyViolation struct {
+	Rule      string   `json:"rule"`
+	Message   string   `json:"message"`
+	Addresses []string `json:"addresses,omitempty"`
+}
+
+type imapPolicyClient interface {
+	GetDraft(uid string) (bridge.DraftMessage, error)
+}
+
+func draftRecipients(d model.Draft) []string {
+	out := append([]string{}, d.To...)
+	out = append(out, d.CC...)
+	return append(out, d.BCC...)
+}
+
+func bridgeRecipients(d bridge.DraftMessage) []string {
+	out := append([]string{}, d.To...)
+	out = append(out, d.CC...)
+	return append(out, d.BCC...)
+}
+
+func recipientDomain(addr string) string {
+	if parsed, err := mail.ParseAddress(addr); err == nil {
+		addr = parsed.Address
+	}
+	at := strings.LastIndex(addr, "@")
+	if at < 0 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(addr[at+1:]))
+}
+
+func domainMatches(domain string, patterns []string) bool {
+	for _, p := range patterns {
+		p = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(p), "@"))
+		if p != "" && (domain == p || strings.HasSuffix(domain, "."+p)) {
+			return true
+		}
+	}
+	return false
+}
+
+func evaluateRecipientPolicy(policy config.RecipientPolicy, check recipientCheck) []policyViolation {
+	var denied, notAllowed, external []string
+	for _, addr := range check.addresses {
+		domain := recipientDomain(addr)
+		if domainMatches(domain, policy.DenyDomains) {
+			denied = append(denied, addr)
+			continue
+		}
+		if len(policy.AllowDomains) > 0 && !domainMatches(domain, policy.AllowDomains) {
+			notAllowed = append(notAllowed, addr)
+			continue
+		}
+		if strings.EqualFold(policy.External, "block") && !domainMatches(domain, policy.InternalDomains) {
+			external = append(external, addr)
+		}
+	}
+	violations := []policyViolation{}
+	if len(denied) > 0 {
+		violations = append(violations, policyViolation{Rule: "deny_domains", Message: "recipient domain is denied", Addresses: denied})
+	}
+	if len(notAllowed) > 0 {
+		violations = append(violations, policyViolation{Rule: "allow_domains", Message: "recipient domain is not allowed", Addresses: notAllowed})
+	}
+	if len(external) > 0 {
+		violations = append(violations, policyViolation{Rule: "external", Message: "external recipients are blocked", Addresses: external})
+	}
+	if n := len(check.addresses); policy.BulkThreshold > 0 && n > policy.BulkThreshold && check.confirmBulk != n {
+		violations = append(violations, policyViolation{Rule: "bulk_threshold", Message: fmt.Sprintf("%d recipients exceed bulk_threshold %d; pass --confirm-bulk %d", n, policy.BulkThreshold, n)})
+	}
+	return violations
+}
+
+func recipientPolicyError(violations []policyViolation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	parts := make([]string, 0, len(violations))
+	hint := "Check [safety.recipients] in config or run policy check --draft-id <id>"
+	for _, v := range violations {
+		if len(v.Addresses) > 0 {
+			parts = append(parts, v.Message+": "+strings.Join(v.Addresses, ", "))
+		} else {
+			parts = append(parts, v.Message)
+		}
+		if v.Rule == "bulk_threshold" {
+			hint = "Recount the recipients and pass --confirm-bulk <count>"
+		}
+	}
+	return cliError{exit: 7, code: "policy_blocked", msg: "recipient policy: " + strings.Join(parts, "; "), hint: hint}
+}
+
+func parsePolicyCheckFlags(action string, args []string) (string, int, error) {
+	if action != "check" {
+		return "", 0, cliError{exit: 2, code: "usage_error", msg: "unknown policy action: " + action}
+	}
+	fs := newFlagSet("policy check")
+	draftID := fs.String("draft-id", "", "draft id")
+	confirmBulk := fs.Int("confirm-bulk", 0, "recipient count confirmation for bulk sends")
+	if err := parseFlags(fs, args); err != nil {
+		return "", 0, err
+	}
+	if strings.TrimSpace(*draftID) == "" {
+		return "", 0, cliError{exit: 2, code: "validation_error", msg: "--draft-id required"}
+	}
+	return strings.TrimSpace(*draftID), *confirmBulk, nil
+}
+
+func policyCheckResult(cfg config.Config, draftID string, recipients []string, confirmBulk int, source string) policyCheckResponse {
+	domains := map[string]bool{}
+	for _, r := range recipients {
+		if d := recipientDomain(r); d != "" {
+			domains[d] = true
+		}
+	}
+	list := make([]string, 0, len(domains))
+	for d := range domains {
+		list = append(list, d)
+	}
+	sort.Strings(list)
+	violations := evaluateRecipientPolicy(cfg.Safety.Recipients, recipientCheck{addresses: recipients, confirmBulk: confirmBulk})
+	policy := cfg.Safety.Recipients
+	return policyCheckResponse{
+		DraftID:             draftID,
+		Allowed:             len(violations) == 0,
+		Recipients:          len(recipients),
+		Domains:             list,
+		RequiresConfirmBulk: policy.BulkThreshold > 0 && len(recipients) > policy.BulkThreshold,
+		Violations:          violations,
+		Source:              source,
+	}
+}
+
+func cmdPolicyCheckLocal(draftID string, confirmBulk int, cfg config.Config, st *model.State) (any, bool, error) {
+	uid, err := parseRequiredUID(draftID, "--draft-id")
+	if err != nil {
+		return nil, false, cliError{exit: 2, code: "validation_error", msg: err.Error()}
+	}
+	d, ok := st.Drafts[uid]
+	if !ok {
+		return nil, false, cliError{exit: 5, code: "not_found", msg: "draft not found"}
+	}
+	return policyCheckResult(cfg, d.ID, draftRecipients(d), confirmBulk, "local"), false, nil
+}
+
+func cmdPolicyCheckIMAP(c imapPolicyClient, draftID string, confirmBulk int, cfg config.Config) (any, bool, error) {
+	uid, err := parseRequiredUID(draftID, "--draft-id")
+	if err != nil {
+		return nil, false, cliError{exit: 2, code: "validation_error", msg: err.Error()}
+	}
+	d, err := c.GetDraft(uid)
+	if err != nil {
+		return nil, false, cliError{exit: 5, code: "not_found", msg: "draft not found"}
+	}
+	return policyCheckResult(cfg, imapDraftID(uid), bridgeRecipients(d), confirmBulk, "imap"), false, nil
+}
@@ -0,0 +1,138 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"protonmailcli/internal/bridge"
+	"protonmailcli/internal/config"
+	"protonmailcli/internal/model"
+)
+
+type fakePolicyClient struct {
+	draft bridge.DraftMessage
+}
+
+func (f fakePolicyClient) GetDraft(uid string) (bridge.DraftMessage, error) {
+	return f.draft, nil
+}
+
+func policyConfig() config.Config {
+	cfg := config.Default()
+	cfg.Safety.Recipients = config.RecipientPolicy{
+		AllowDomains:    []string{"example.com", "partner.org"},
+		DenyDomains:     []string{"gmail.com"},
+		InternalDomains: []string{"example.com"},
+		External:        "allow",
+		BulkThreshold:   2,
+	}
+	return cfg
+}
+
+func TestEvaluateRecipientPolicy(t *testing.T) {
+	policy := policyConfig().Safety.Recipients
+	got := evaluateRecipientPolicy(policy, recipientCheck{addresses: []string{"Bob <bob@gmail.com>", "a@eu.partner.org"}})
+	if len(got) != 1 || got[0].Rule != "deny_domains" || got[0].Addresses[0] != "Bob <bob@gmail.com>" {
+		t.Fatalf("unexpected violations: %+v", got)
+	}
+	got = evaluateRecipientPolicy(policy, recipientCheck{addresses: []string{"x@evil.example", "y@example.com.evil.example"}})
+	if len(got) != 1 || got[0].Rule != "allow_domains" || len(got[0].Addresses) != 2 {
+		t.Fatalf("suffix tricks must not pass the allowlist: %+v", got)
+	}
+	policy.External = "block"
+	got = evaluateRecipientPolicy(policy, recipientCheck{addresses: []string{"a@example.com", "b@partner.org", "c@sub.example.com"}})
+	if len(got) != 2 || got[0].Rule != "external" || strings.Join(got[0].Addresses, ",") != "b@partner.org" || got[1].Rule != "bulk_threshold" {
+		t.Fatalf("unexpected external/bulk violations: %+v", got)
+	}
+	if got := evaluateRecipientPolicy(policy, recipientCheck{addresses: []string{"a@example.com", "b@example.com", "c@example.com"}, confirmBulk: 3}); len(got) != 0 {
+		t.Fatalf("confirmed bulk send should pass: %+v", got)
+	}
+}
+
+func TestSendBlockedByRecipientPolicy(t *testing.T) {
+	calls := stubOutboxSend(t, nil)
+	st := outboxState()
+	st.Drafts["d_1"] = model.Draft{ID: "d_1", To: []string{"a@example.com"}, BCC: []string{"me@gmail.com"}, Subject: "Leak"}
+	cfg := policyConfig()
+	_, _, err := cmdMessage("send", []string{"--draft-id", "d_1", "--force"}, globalOptions{}, cfg, st)
+	if errorCodeFromErr(err, "") != "policy_blocked" || exitCodeFromErr(err) != 7 || !strings.Contains(err.Error(), "me@gmail.com") || *calls != 0 {
+		t.Fatalf("expected policy_blocked naming the address even with --force, got %v calls=%d", err, *calls)
+	}
+	at := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	if _, _, err := cmdMessage("send", []string{"--draft-id", "d_1", "--confirm-send", "d_1", "--at", at}, globalOptions{}, cfg, st); errorCodeFromErr(err, "") != "policy_blocked" || len(st.Outbox) != 0 {
+		t.Fatalf("scheduled sends must be checked at enqueue, got %v", err)
+	}
+
+	st.Drafts["d_1"] = model.Draft{ID: "d_1", To: []string{"a@example.com", "b@example.com", "c@partner.org"}, Subject: "Team"}
+	if _, _, err := cmdMessage("send", []string{"--draft-id", "d_1", "--confirm-send", "d_1"}, globalOptions{}, cfg, st); errorCodeFromErr(err, "") != "policy_blocked" {
+		t.Fatalf("expected bulk gate, got %v", err)
+	}
+	if _, _, err := cmdMessage("send", []string{"--draft-id", "d_1", "--confirm-send", "d_1", "--confirm-bulk", "3"}, globalOptions{}, cfg, st); err != nil || *calls != 1 {
+		t.Fatalf("confirmed bulk send failed: %v calls=%d", err, *calls)
+	}
+}
+
+func TestSendManyReportsPolicyViolationPerItem(t *testing.T) {
+	stubOutboxSend(t, nil)
+	st := outboxState()
+	st.Drafts["d_2"] = model.Draft{ID: "d_2", To: []string{"friend@gmail.com"}, Subject: "Hi"}
+	manifest := filepath.Join(t.TempDir(), "send.json")
+	if err := os.WriteFile(manifest, []byte(`[{"draft_id":"d_1","confirm_send":"d_1"},{"draft_id":"d_2","confirm_send":"d_2"}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := policyConfig()
+	st.Drafts["d_1"] = model.Draft{ID: "d_1", To: []string{"a@example.com"}, Subject: "Ok"}
+	data, _, err := cmdMessage("send-many", []string{"--file", manifest}, globalOptions{}, cfg, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := data.(batchResultResponse)
+	if resp.Success != 1 || resp.Results[1].ErrorCode != "policy_blocked" || !strings.Contains(resp.Results[1].Error, "friend@gmail.com") {
+		t.Fatalf("unexpected batch result: %+v", resp)
+	}
+}
+
+func TestPolicyCheckEvaluatesDraftAheadOfTime(t *testing.T) {
+	st := outboxState()
+	st.Drafts["d_1"] = model.Draft{ID: "d_1", To: []string{"a@example.com", "x@gmail.com"}, CC: []string{"b@partner.org"}}
+	data, changed, err := cmdPolicyCheckLocal("d_1", 0, policyConfig(), st)
+	if err != nil || changed {
+		t.Fatalf("check: changed=%v err=%v", changed, err)
+	}
+	resp := data.(policyCheckResponse)
+	if resp.Allowed || resp.ExitCode() != 7 || resp.Recipients != 3 || !resp.RequiresConfirmBulk || len(resp.Violations) != 2 || strings.Join(resp.Domains, ",") != "example.com,gmail.com,partner.org" {
+		t.Fatalf("unexpected check result: %+v", resp)
+	}
+	c := fakePolicyClient{draft: bridge.DraftMessage{UID: "7", To: []string{"a@example.com"}}}
+	data, _, err = cmdPolicyCheckIMAP(c, "imap:Drafts:7", 0, policyConfig())
+	if err != nil || !data.(policyCheckResponse).Allowed || data.(policyCheckResponse).DraftID != "imap:Drafts:7" {
+		t.Fatalf("unexpected imap check: %+v err=%v", data, err)
+	}
+	if _, _, err := cmdPolicyCheckLocal("d_9", 0, policyConfig(), st); errorCodeFromErr(err, "") != "not_found" {
+		t.Fatalf("expected not_found, got %v", err)
+	}
+}
+
+func TestIMAPPolicyChecksCcAndBcc(t *testing.T) {
+	d, err := bridge.ParseRawMessage([]byte("From: me@example.com\r\nTo: a@example.com\r\nCc: Friend <friend@gmail.com>\r\nBcc: b@partner.org\r\nSubject: s\r\n\r\nbody\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(bridgeRecipients(d), ",") != "a@example.com,friend@gmail.com,b@partner.org" {
+		t.Fatalf("unexpected recipients: %v", bridgeRecipients(d))
+	}
+	data, _, err := cmdPolicyCheckIMAP(fakePolicyClient{draft: d}, "imap:Drafts:7", 3, policyConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp := data.(policyCheckResponse); resp.Allowed || resp.Recipients != 3 || len(resp.Violations) != 1 || strings.Join(resp.Violations[0].Addresses, ",") != "friend@gmail.com" {
+		t.Fatalf("a denied Cc address must block: %+v", resp)
+	}
+	err = validateSendSafety(policyConfig(), false, "imap:Drafts:7", "imap:Drafts:7", "7", false, recipientCheck{addresses: bridgeRecipients(d), confirmBulk: 3})
+	if errorCodeFromErr(err, "") != "policy_blocked" || !strings.Contains(err.Error(), "friend@gmail.com") {
+		t.Fatalf("expected policy_blocked for the Cc address, got %v", err)
+	}
+}
@@ -3,10 +3,19 @@ package app
 import "protonmailcli/internal/model"
 
 type mailboxInfo struct {
-	ID    string `json:"id"`
-	Name  string `json:"name"`
-	Kind  string `json:"kind"`
-	Count int    `json:"count,omitempty"`
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Kind        string   `json:"kind"`
+	Count       int      `json:"count,omitempty"`
+	Total       *int     `json:"total,omitempty"`
+	Unread      *int     `json:"unread,omitempty"`
+	UIDNext     uint32   `json:"uidNext,omitempty"`
+	UIDValidity uint32   `json:"uidValidity,omitempty"`
+	Size        int64    `json:"size,omitempty"`
+	SpecialUse  string   `json:"specialUse,omitempty"`
+	Attributes  []string `json:"attributes,omitempty"`
+	Parent      string   `json:"parent,omitempty"`
+	Children    []string `json:"children,omitempty"`
 }
 
 type mailboxListResponse struct {
@@ -20,6 +29,20 @@ type mailboxResolveResponse struct {
 	Source    string      `json:"source"`
 }
 
+type mailboxChangeResponse struct {
+	Action          string      `json:"action"`
+	Mailbox         mailboxInfo `json:"mailbox"`
+	PreviousName    string      `json:"previousName,omitempty"`
+	Delimiter       string      `json:"delimiter,omitempty"`
+	Subscribed      *bool       `json:"subscribed,omitempty"`
+	MessageCount    int         `json:"messageCount,omitempty"`
+	DeletedChildren []string    `json:"deletedChildren,omitempty"`
+	ConfirmDelete   string      `json:"confirmDelete,omitempty"`
+	Changed         bool        `json:"changed"`
+	DryRun          bool        `json:"dryRun,omitempty"`
+	Source          string      `json:"source"`
+}
+
 type draftRecord struct {
 	ID      string   `json:"id"`
 	UID     string   `json:"uid"`
@@ -52,24 +75,57 @@ type localDraftResponse struct {
 }
 
 type localDraftListResponse struct {
-	Drafts []model.Draft `json:"drafts"`
-	Count  int           `json:"count"`
+	Drafts     []model.Draft `json:"drafts"`
+	Count      int           `json:"count"`
+	Total      int           `json:"total"`
+	NextCursor string        `json:"nextCursor,omitempty"`
 }
 
 type draftDeleteResponse struct {
 	Deleted bool   `json:"deleted"`
 	DraftID string `json:"draftId"`
+	Source  string `json:"source,omitempty"`
+}
+
+type draftPlanResponse struct {
+	Action      string `json:"action"`
+	DraftID     string `json:"draftId,omitempty"`
+	Source      string `json:"source"`
+	WouldCreate bool   `json:"wouldCreate,omitempty"`
+	WouldUpdate bool   `json:"wouldUpdate,omitempty"`
+	WouldDelete bool   `json:"wouldDelete,omitempty"`
 }
 
 type messageRecord struct {
-	ID      string   `json:"id"`
-	UID     string   `json:"uid"`
-	From    string   `json:"from,omitempty"`
-	To      []string `json:"to,omitempty"`
-	Subject string   `json:"subject,omitempty"`
-	Body    string   `json:"body,omitempty"`
-	Flags   []string `json:"flags,omitempty"`
-	Date    string   `json:"date,omitempty"`
+	ID             string                `json:"id"`
+	UID            string                `json:"uid"`
+	From           string                `json:"from,omitempty"`
+	To             []string              `json:"to,omitempty"`
+	Subject        string                `json:"subject,omitempty"`
+	Body           string                `json:"body,omitempty"`
+	Flags          []string              `json:"flags,omitempty"`
+	Date           string                `json:"date,omitempty"`
+	Headers        []model.Header        `json:"headers,omitempty"`
+	Authentication *authenticationResult `json:"authentication,omitempty"`
+}
+
+type authenticationResult struct {
+	SPF            string             `json:"spf"`
+	DKIM           string             `json:"dkim"`
+	DMARC          string             `json:"dmarc"`
+	ARC            string             `json:"arc,omitempty"`
+	Verdict        string             `json:"verdict"`
+	AuthServID     string             `json:"authservId,omitempty"`
+	Results        []authMethodResult `json:"results,omitempty"`
+	IgnoredHeaders int                `json:"ignoredHeaders,omitempty"`
+}
+
+type authMethodResult struct {
+	Method     string            `json:"method"`
+	Result     string            `json:"result"`
+	Properties map[string]string `json:"properties,omitempty"`
+	Comment    string            `json:"comment,omitempty"`
+	Source     string            `json:"source"`
 }
 
 type messageGetResponse struct {
@@ -78,14 +134,67 @@ type messageGetResponse struct {
 }
 
 type localMessageGetResponse struct {
-	Message model.Message `json:"message"`
+	Message localMessageRecord `json:"message"`
+}
+
+type localMessageRecord struct {
+	model.Message
+	Headers        []model.Header        `json:"headers,omitempty"`
+	Authentication *authenticationResult `json:"authentication,omitempty"`
 }
 
 type messageSendResponse struct {
-	Sent     bool          `json:"sent"`
-	Message  model.Message `json:"message"`
-	SendPath string        `json:"sendPath,omitempty"`
-	Source   string        `json:"source,omitempty"`
+	Sent          bool            `json:"sent"`
+	Message       model.Message   `json:"message"`
+	SendPath      string          `json:"sendPath,omitempty"`
+	Source        string          `json:"source,omitempty"`
+	SentMessageID string          `json:"sentMessageId,omitempty"`
+	PostSend      *postSendResult `json:"postSend,omitempty"`
+}
+
+type imapMessageSendResponse struct {
+	Sent          bool            `json:"sent"`
+	DraftID       string          `json:"draftId"`
+	SendPath      string          `json:"sendPath,omitempty"`
+	Source        string          `json:"source"`
+	SentAt        string          `json:"sentAt"`
+	SentMessageID string          `json:"sentMessageId,omitempty"`
+	PostSend      *postSendResult `json:"postSend,omitempty"`
+}
+
+type messageFileResponse struct {
+	Action        string       `json:"action"`
+	MessageIDs    []string     `json:"messageIds"`
+	Count         int          `json:"count"`
+	Mailbox       *mailboxInfo `json:"mailbox,omitempty"`
+	CopiedIDs     []string     `json:"copiedIds,omitempty"`
+	Changed       bool         `json:"changed"`
+	DryRun        bool         `json:"dryRun,omitempty"`
+	ConfirmDelete string       `json:"confirmDelete,omitempty"`
+	Source        string       `json:"source"`
+}
+
+type messageMarkResponse struct {
+	Messages []messageRecord `json:"messages"`
+	Count    int             `json:"count"`
+	Added    []string        `json:"added,omitempty"`
+	Removed  []string        `json:"removed,omitempty"`
+	DryRun   bool            `json:"dryRun,omitempty"`
+	Source   string          `json:"source"`
+}
+
+type messageBulkResponse struct {
+	Action     string       `json:"action"`
+	Mailbox    string       `json:"mailbox"`
+	ToMailbox  *mailboxInfo `json:"toMailbox,omitempty"`
+	Criteria   string       `json:"criteria"`
+	MessageIDs []string     `json:"messageIds"`
+	Count      int          `json:"count"`
+	UIDSet     string       `json:"uidSet,omitempty"`
+	PlanHash   string       `json:"planHash"`
+	Plan       bool         `json:"plan,omitempty"`
+	Applied    bool         `json:"applied"`
+	Source     string       `json:"source"`
 }
 
 type messageFollowUpPlanResponse struct {
@@ -115,12 +224,13 @@ type localMessageFollowUpResponse struct {
 }
 
 type sendPlanResponse struct {
-	Action    string `json:"action"`
-	DraftID   string `json:"draftId"`
-	WouldSend bool   `json:"wouldSend"`
-	DryRun    bool   `json:"dryRun"`
-	SendPath  string `json:"sendPath,omitempty"`
-	Source    string `json:"source,omitempty"`
+	Action         string `json:"action"`
+	DraftID        string `json:"draftId"`
+	WouldSend      bool   `json:"wouldSend"`
+	DryRun         bool   `json:"dryRun"`
+	SendPath       string `json:"sendPath,omitempty"`
+	PostSendAction string `json:"postSendAction,omitempty"`
+	Source         string `json:"source,omitempty"`
 }
 
 type messageListResponse struct {
@@ -133,18 +243,25 @@ type messageListResponse struct {
 }
 
 type batchItemResponse struct {
-	Index      int      `json:"index"`
-	OK         bool     `json:"ok"`
-	DryRun     bool     `json:"dryRun,omitempty"`
-	To         []string `json:"to,omitempty"`
-	Subject    string   `json:"subject,omitempty"`
-	DraftID    string   `json:"draftId,omitempty"`
-	UID        string   `json:"uid,omitempty"`
-	CreatePath string   `json:"createPath,omitempty"`
-	SendPath   string   `json:"sendPath,omitempty"`
-	SentAt     string   `json:"sentAt,omitempty"`
-	ErrorCode  string   `json:"errorCode,omitempty"`
-	Error      string   `json:"error,omitempty"`
+	Index             int             `json:"index"`
+	OK                bool            `json:"ok"`
+	DryRun            bool            `json:"dryRun,omitempty"`
+	To                []string        `json:"to,omitempty"`
+	Subject           string          `json:"subject,omitempty"`
+	DraftID           string          `json:"draftId,omitempty"`
+	UID               string          `json:"uid,omitempty"`
+	CreatePath        string          `json:"createPath,omitempty"`
+	SendPath          string          `json:"sendPath,omitempty"`
+	SentAt            string          `json:"sentAt,omitempty"`
+	SentMessageID     string          `json:"sentMessageId,omitempty"`
+	PostSend          *postSendResult `json:"postSend,omitempty"`
+	Pending           bool            `json:"pending,omitempty"`
+	PendingUntil      string          `json:"pendingUntil,omitempty"`
+	UndoToken         string          `json:"undoToken,omitempty"`
+	FlushWith         string          `json:"flushWith,omitempty"`
+	ErrorCode         string          `json:"errorCode,omitempty"`
+	Error             string          `json:"error,omitempty"`
+	RetryAfterSeconds int             `json:"retryAfterSeconds,omitempty"`
 }
 
 type batchResultResponse struct {
@@ -195,13 +312,17 @@ type bridgeAccountUseResponse struct {
 }
 
 type localSearchDraftsResponse struct {
-	Drafts []model.Draft `json:"drafts"`
-	Count  int           `json:"count"`
+	Drafts     []model.Draft `json:"drafts"`
+	Count      int           `json:"count"`
+	Total      int           `json:"total"`
+	NextCursor string        `json:"nextCursor,omitempty"`
 }
 
 type localSearchMessagesResponse struct {
-	Messages []model.Message `json:"messages"`
-	Count    int             `json:"count"`
+	Messages   []model.Message `json:"messages"`
+	Count      int             `json:"count"`
+	Total      int             `json:"total"`
+	NextCursor string          `json:"nextCursor,omitempty"`
 }
 
 type tagInfo struct {
@@ -248,3 +369,173 @@ type filterApplyResponse struct {
 	Matched  int    `json:"matched"`
 	Changed  int    `json:"changed"`
 }
+
+type threadSummary struct {
+	ID            string   `json:"id"`
+	Subject       string   `json:"subject"`
+	Count         int      `json:"count"`
+	Unread        int      `json:"unread"`
+	Participants  []string `json:"participants"`
+	FirstDate     string   `json:"firstDate,omitempty"`
+	LastActivity  string   `json:"lastActivity,omitempty"`
+	LastMessageID string   `json:"lastMessageId,omitempty"`
+	Mailboxes     []string `json:"mailboxes"`
+}
+
+type threadMessageRecord struct {
+	ID        string   `json:"id"`
+	MessageID string   `json:"messageId,omitempty"`
+	ParentID  string   `json:"parentId,omitempty"`
+	Depth     int      `json:"depth"`
+	Mailbox   string   `json:"mailbox"`
+	From      string   `json:"from,omitempty"`
+	To        []string `json:"to,omitempty"`
+	Subject   string   `json:"subject"`
+	Flags     []string `json:"flags,omitempty"`
+	Date      string   `json:"date,omitempty"`
+}
+
+type threadListResponse struct {
+	Threads    []threadSummary `json:"threads"`
+	Count      int             `json:"count"`
+	Total      int             `json:"total"`
+	NextCursor string          `json:"nextCursor,omitempty"`
+	Mailboxes  []string        `json:"mailboxes"`
+	Algorithm  string          `json:"algorithm"`
+	Source     string          `json:"source"`
+}
+
+type threadGetResponse struct {
+	Thread    threadSummary         `json:"thread"`
+	Messages  []threadMessageRecord `json:"messages"`
+	Count     int                   `json:"count"`
+	Algorithm string                `json:"algorithm"`
+	Source    string                `json:"source"`
+}
+
+type messageUnsubscribeResponse struct {
+	MessageID        string   `json:"messageId"`
+	Sender           string   `json:"sender"`
+	Method           string   `json:"method"`
+	Target           string   `json:"target,omitempty"`
+	Options          []string `json:"options"`
+	OneClick         bool     `json:"oneClick"`
+	Status           string   `json:"status"`
+	HTTPStatus       int      `json:"httpStatus,omitempty"`
+	DraftID          string   `json:"draftId,omitempty"`
+	AlreadyProcessed bool     `json:"alreadyProcessed,omitempty"`
+	ProcessedAt      string   `json:"processedAt,omitempty"`
+	DryRun           bool     `json:"dryRun,omitempty"`
+	Source           string   `json:"source"`
+}
+
+type messageScheduleResponse struct {
+	Scheduled bool             `json:"scheduled"`
+	Item      model.OutboxItem `json:"item"`
+	Replayed  bool             `json:"replayed,omitempty"`
+	DryRun    bool             `json:"dryRun,omitempty"`
+	Source    string           `json:"source"`
+}
+
+type messagePendingSendResponse struct {
+	Sent         bool   `json:"sent"`
+	Pending      bool   `json:"pending"`
+	DraftID      string `json:"draftId"`
+	OutboxID     string `json:"outboxId"`
+	PendingUntil string `json:"pendingUntil"`
+	UndoToken    string `json:"undoToken"`
+	FlushWith    string `json:"flushWith"`
+	Source       string `json:"source"`
+}
+
+type messageUndoSendResponse struct {
+	Undone       bool   `json:"undone"`
+	Token        string `json:"token"`
+	OutboxID     string `json:"outboxId"`
+	DraftID      string `json:"draftId"`
+	PendingUntil string `json:"pendingUntil"`
+	DryRun       bool   `json:"dryRun,omitempty"`
+}
+
+type outboxListResponse struct {
+	Items []model.OutboxItem `json:"items"`
+	Count int                `json:"count"`
+}
+
+type outboxItemResponse struct {
+	Item   model.OutboxItem `json:"item"`
+	DryRun bool             `json:"dryRun,omitempty"`
+}
+
+type outboxRunResult struct {
+	ID        string `json:"id"`
+	DraftID   string `json:"draftId"`
+	At        string `json:"at"`
+	Status    string `json:"status"`
+	Late      bool   `json:"late,omitempty"`
+	Replayed  bool   `json:"replayed,omitempty"`
+	Error     string `json:"error,omitempty"`
+	ErrorCode string `json:"errorCode,omitempty"`
+	Result    any    `json:"result,omitempty"`
+}
+
+type outboxRunResponse struct {
+	Processed  []outboxRunResult `json:"processed"`
+	Count      int               `json:"count"`
+	Sent       int               `json:"sent"`
+	Skipped    int               `json:"skipped"`
+	Failed     int               `json:"failed"`
+	Pending    int               `json:"pending"`
+	Iterations int               `json:"iterations"`
+	Loop       bool              `json:"loop,omitempty"`
+	DryRun     bool              `json:"dryRun,omitempty"`
+}
+
+type policyCheckResponse struct {
+	DraftID             string            `json:"draftId"`
+	Allowed             bool              `json:"allowed"`
+	Recipients          int               `json:"recipients"`
+	Domains             []string          `json:"domains"`
+	RequiresConfirmBulk bool              `json:"requiresConfirmBulk"`
+	Violations          []policyViolation `json:"violations"`
+	Source              string            `json:"source"`
+}
+
+func (r policyCheckResponse) ExitCode() int {
+	if !r.Allowed {
+		return 7
+	}
+	return 0
+}
+
+type draftLintResponse struct {
+	DraftID  string        `json:"draftId"`
+	OK       bool          `json:"ok"`
+	Errors   int           `json:"errors"`
+	Warnings int           `json:"warnings"`
+	Findings []lintFinding `json:"findings"`
+	Source   string        `json:"source"`
+}
+
+func (r draftLintResponse) ExitCode() int {
+	if !r.OK {
+		return 7
+	}
+	return 0
+}
+
+type schemaInfo struct {
+	Command string `json:"command"`
+	ID      string `json:"id"`
+	File    string `json:"file"`
+}
+
+type schemaListResponse struct {
+	Schemas []schemaInfo `json:"schemas"`
+	Count   int          `json:"count"`
+}
+
+type schemaGetResponse struct {
+	Command string         `json:"command"`
+	Schema  map[string]any `json:"schema"`
+}
@@ -0,0 +1,141 @@
+package app
+
+import (
+	"reflect"
+	"strings"
+
+	"protonmailcli/internal/jsonschema"
+	"protonmailcli/internal/output"
+)
+
+const schemaBaseID = "https://example.com/protonmailcli/responses/"
+
+type responseSchema struct {
+	command string
+	types   []any
+}
+
+var responseSchemas = []responseSchema{
+	{"setup", []any{setupResponse{}}},
+	{"doctor", []any{map[string]any{}}},
+	{"auth status", []any{authStatusResponse{}}},
+	{"auth login", []any{authLoginResponse{}}},
+	{"auth logout", []any{authLoginResponse{}}},
+	{"bridge account list", []any{bridgeAccountListResponse{}}},
+	{"bridge account use", []any{bridgeAccountUseResponse{}}},
+	{"draft create", []any{localDraftResponse{}, draftResponse{}, draftPlanResponse{}}},
+	{"draft create-many", []any{batchResultResponse{}}},
+	{"draft update", []any{localDraftResponse{}, draftResponse{}, draftPlanResponse{}}},
+	{"draft get", []any{localDraftResponse{}, draftResponse{}}},
+	{"draft list", []any{localDraftListResponse{}, draftListResponse{}}},
+	{"draft delete", []any{draftDeleteResponse{}, draftPlanResponse{}}},
+	{"draft lint", []any{draftLintResponse{}}},
+	{"message send", []any{messageSendResponse{}, imapMessageSendResponse{}, sendPlanResponse{}, messageScheduleResponse{}, messagePendingSendResponse{}}},
+	{"message send-many", []any{batchResultResponse{}}},
+	{"message get", []any{localMessageGetResponse{}, messageGetResponse{}}},
+	{"message follow-up", []any{localMessageFollowUpResponse{}, messageFollowUpResponse{}, messageFollowUpPlanResponse{}}},
+	{"message mark", []any{messageMarkResponse{}}},
+	{"message bulk", []any{messageBulkResponse{}}},
+	{"message move", []any{messageFileResponse{}}},
+	{"message copy", []any{messageFileResponse{}}},
+	{"message archive", []any{messageFileResponse{}}},
+	{"message trash", []any{messageFileResponse{}}},
+	{"message delete", []any{messageFileResponse{}}},
+	{"message unsubscribe", []any{messageUnsubscribeResponse{}}},
+	{"message undo-send", []any{messageUndoSendResponse{}}},
+	{"search messages", []any{localSearchMessagesResponse{}, messageListResponse{}}},
+	{"search drafts", []any{localSearchDraftsResponse{}, draftListResponse{}}},
+	{"mailbox list", []any{mailboxListResponse{}}},
+	{"mailbox resolve", []any{mailboxResolveResponse{}}},
+	{"mailbox create", []any{mailboxChangeResponse{}}},
+	{"mailbox rename", []any{mailboxChangeResponse{}}},
+	{"mailbox delete", []any{mailboxChangeResponse{}}},
+	{"mailbox subscribe", []any{mailboxChangeResponse{}}},
+	{"mailbox unsubscribe", []any{mailboxChangeResponse{}}},
+	{"tag list", []any{tagListResponse{}}},
+	{"tag create", []any{tagCreateResponse{}}},
+	{"tag add", []any{tagUpdateResponse{}}},
+	{"tag remove", []any{tagUpdateResponse{}}},
+	{"filter list", []any{filterListResponse{}}},
+	{"filter create", []any{filterCreateResponse{}}},
+	{"filter delete", []any{filterDeleteResponse{}}},
+	{"filter test", []any{filterApplyResponse{}}},
+	{"filter apply", []any{filterApplyResponse{}}},
+	{"thread list", []any{threadListResponse{}}},
+	{"thread get", []any{threadGetResponse{}}},
+	{"outbox list", []any{outboxListResponse{}}},
+	{"outbox cancel", []any{outboxItemResponse{}}},
+	{"outbox requeue", []any{outboxItemResponse{}}},
+	{"outbox run", []any{outboxRunResponse{}}},
+	{"outbox flush", []any{outboxRunResponse{}}},
+	{"policy check", []any{policyCheckResponse{}}},
+	{"commands", []any{commandsResponse{}}},
+	{"schema list", []any{schemaListResponse{}}},
+	{"schema get", []any{schemaGetResponse{}}},
+}
+
+func schemaFileName(command string) string {
+	return strings.ReplaceAll(command, " ", "-") + ".schema.json"
+}
+
+func findResponseSchema(command string) (responseSchema, bool) {
+	command = strings.Join(strings.Fields(strings.ReplaceAll(command, ".", " ")), " ")
+	for _, rs := range responseSchemas {
+		if rs.command == command {
+			return rs, true
+		}
+	}
+	return responseSchema{}, false
+}
+
+func buildResponseSchema(rs responseSchema) map[string]any {
+	g := jsonschema.NewGenerator()
+	envelope := g.Inline(reflect.TypeOf(output.Envelope{}))
+	variants := make([]any, 0, len(rs.types)+1)
+	for _, t := range append(append([]any{}, rs.types...), helpResponse{}) {
+		variants = append(variants, g.Schema(reflect.TypeOf(t)))
+	}
+	envelope["properties"].(map[string]any)["data"] = map[string]any{"anyOf": variants}
+	doc := map[string]any{
+		"$schema": jsonschema.Draft,
+		"$id":     schemaBaseID + schemaFileName(rs.command),
+		"title":   "protonmailcli " + rs.command + " response",
+		"$defs":   g.Defs(),
+	}
+	for k, v := range envelope {
+		doc[k] = v
+	}
+	return doc
+}
+
+func cmdSchema(action string, args []string, g globalOptions) (any, error) {
+	switch action {
+	case "list":
+		fs := newFlagSet("schema list")
+		if err := parseFlags(fs, args); err != nil {
+			return nil, err
+		}
+		out := make([]schemaInfo, 0, len(responseSchemas))
+		for _, rs := range responseSchemas {
+			out = append(out, schemaInfo{Command: rs.command, ID: schemaBaseID + schemaFileName(rs.command), File: schemaFileName(rs.command)})
+		}
+		return schemaListResponse{Schemas: out, Count: len(out)}, nil
+	case "get":
+		fs := newFlagSet("schema get <command>")
+		if err := parseFlags(fs, args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return nil, cliError{exit: 2, code: "usage_error", msg: "schema get requires a command, e.g. schema get draft list"}
+		}
+		rs, ok := findResponseSchema(strings.Join(fs.Args(), " "))
+		if !ok {
+			return nil, cliError{exit: 5, code: "not_found", msg: "no schema for command: " + strings.Join(fs.Args(), " "), hint: "Run protonmailcli schema list"}
+		}
+		return schemaGetResponse{Command: rs.command, Schema: buildResponseSchema(rs)}, nil
+	case "":
+		return nil, cliError{exit: 2, code: "usage_error", msg: "schema action required (list|get)"}
+	default:
+		return nil, cliError{exit: 2, code: "usage_error", msg: "unknown schema action: " + action}
+	}
+}
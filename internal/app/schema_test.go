@@ -0,0 +1,121 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"protonmailcli/internal/jsonschema"
+)
+
+func TestPublishedSchemasUpToDate(t *testing.T) {
+	_, here, _, _ := runtime.Caller(0)
+	dir := filepath.Join(filepath.Dir(here), "..", "..", "docs", "schemas", "responses")
+	published, err := filepath.Glob(filepath.Join(dir, "*.schema.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(published) != len(responseSchemas) {
+		t.Fatalf("published %d schemas, registry has %d; run scripts/update-schemas.sh", len(published), len(responseSchemas))
+	}
+	for _, rs := range responseSchemas {
+		want, err := json.MarshalIndent(buildResponseSchema(rs), "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(filepath.Join(dir, schemaFileName(rs.command)))
+		if err != nil {
+			t.Fatalf("%s: %v; run scripts/update-schemas.sh", rs.command, err)
+		}
+		if strings.TrimSpace(string(got)) != string(want) {
+			t.Fatalf("%s is stale; run scripts/update-schemas.sh", schemaFileName(rs.command))
+		}
+	}
+}
+
+func TestSchemaCommand(t *testing.T) {
+	tmp := t.TempDir()
+	run := func(args ...string) (int, map[string]any) {
+		stdout := &bytes.Buffer{}
+		code := Run(append([]string{"--json", "--config", filepath.Join(tmp, "missing.toml")}, args...), bytes.NewBuffer(nil), stdout, &bytes.Buffer{})
+		var env map[string]any
+		if err := json.Unmarshal(stdout.Bytes(), &env); err != nil {
+			t.Fatalf("%v: invalid json %q", args, stdout.String())
+		}
+		return code, env
+	}
+
+	code, env := run("schema", "list")
+	if code != 0 {
+		t.Fatalf("schema list exit=%d env=%v", code, env)
+	}
+	data := env["data"].(map[string]any)
+	if int(data["count"].(float64)) != len(responseSchemas) {
+		t.Fatalf("unexpected count: %v", data["count"])
+	}
+
+	for _, args := range [][]string{{"draft", "list"}, {"draft.list"}} {
+		code, env = run(append([]string{"schema", "get"}, args...)...)
+		if code != 0 {
+			t.Fatalf("schema get %v exit=%d", args, code)
+		}
+		data = env["data"].(map[string]any)
+		schema := data["schema"].(map[string]any)
+		if data["command"] != "draft list" || !strings.HasSuffix(schema["$id"].(string), "/draft-list.schema.json") {
+			t.Fatalf("unexpected schema get payload: %v", data["command"])
+		}
+	}
+
+	if code, env = run("schema", "get", "draft", "nope"); code != 5 || env["error"].(map[string]any)["code"] != "not_found" {
+		t.Fatalf("unknown command should be not_found: exit=%d env=%v", code, env)
+	}
+	if code, _ = run("schema", "get"); code != 2 {
+		t.Fatalf("missing command should be usage error: exit=%d", code)
+	}
+}
+
+func TestResponsesMatchSchemas(t *testing.T) {
+	t.Setenv("PMAIL_USE_LOCAL_STATE", "1")
+	tmp := t.TempDir()
+	base := []string{"--json", "--config", filepath.Join(tmp, "config.toml"), "--state", filepath.Join(tmp, "state.json")}
+	check := func(command string, args ...string) {
+		t.Helper()
+		stdout := &bytes.Buffer{}
+		if code := Run(append(append([]string{}, base...), args...), bytes.NewBuffer(nil), stdout, &bytes.Buffer{}); code != 0 {
+			t.Fatalf("%v: exit=%d stdout=%s", args, code, stdout.String())
+		}
+		var doc any
+		if err := json.Unmarshal(stdout.Bytes(), &doc); err != nil {
+			t.Fatalf("%v: invalid json %q", args, stdout.String())
+		}
+		rs, ok := findResponseSchema(command)
+		if !ok {
+			t.Fatalf("no schema for %s", command)
+		}
+		schema, err := json.Marshal(buildResponseSchema(rs))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded map[string]any
+		if err := json.Unmarshal(schema, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if errs := jsonschema.Validate(decoded, doc); len(errs) > 0 {
+			t.Fatalf("%s output does not match schema:\n%s\nstdout=%s", command, strings.Join(errs, "\n"), stdout.String())
+		}
+	}
+
+	check("setup", "setup", "--non-interactive", "--username", "me@example.com")
+	check("draft create", "draft", "create", "--to", "a@example.com", "--subject", "hi", "--body", "x")
+	check("draft list", "draft", "list")
+	check("search drafts", "search", "drafts", "--query", "hi")
+	check("tag create", "tag", "create", "--name", "work")
+	check("tag list", "tag", "list")
+	check("outbox list", "outbox", "list")
+	check("draft list", "draft", "list", "--help")
+	check("schema list", "schema", "list")
+}
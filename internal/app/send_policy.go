@@ -6,17 +6,20 @@ import (
 	"protonmailcli/internal/config"
 )
 
-func validateSendSafety(cfg config.Config, nonTTY bool, confirm, canonicalID, canonicalUID string, force bool) error {
+func validateSendSafety(cfg config.Config, nonTTY bool, confirm, canonicalID, canonicalUID string, force bool, recipients recipientCheck) error {
 	if cfg.Safety.RequireConfirmSendNonTTY && nonTTY && confirm != canonicalID && (canonicalUID == "" || confirm != canonicalUID) && !force {
 		return cliError{exit: 7, code: "confirmation_required", msg: "--confirm-send is required in non-interactive mode", hint: "Pass --confirm-send <draft-id> or --force"}
 	}
 	if force && !cfg.Safety.AllowForceSend {
 		return cliError{exit: 7, code: "safety_blocked", msg: "--force is disabled by policy"}
 	}
-	return nil
+	return recipientPolicyError(evaluateRecipientPolicy(cfg.Safety.Recipients, recipients))
 }
 
 func isNonInteractiveSend(g globalOptions, stdinIsTTY bool) bool {
+	if g.fromOutbox {
+		return false
+	}
 	return g.noInput || !stdinIsTTY
 }
 
@@ -27,3 +30,11 @@ func errorCodeFromErr(err error, fallback string) string {
 	}
 	return fallback
 }
+
+func exitCodeFromErr(err error) int {
+	var ce cliError
+	if errors.As(err, &ce) {
+		return ce.exit
+	}
+	return 1
+}
@@ -9,7 +9,7 @@ import (
 
 func TestValidateSendSafetyRequiresConfirmInNonTTY(t *testing.T) {
 	cfg := config.Default()
-	err := validateSendSafety(cfg, true, "", "d_1", "", false)
+	err := validateSendSafety(cfg, true, "", "d_1", "", false, recipientCheck{})
 	if err == nil {
 		t.Fatal("expected confirmation error")
 	}
@@ -24,7 +24,7 @@ func TestValidateSendSafetyRequiresConfirmInNonTTY(t *testing.T) {
 
 func TestValidateSendSafetyAcceptsUIDConfirmation(t *testing.T) {
 	cfg := config.Default()
-	if err := validateSendSafety(cfg, true, "123", "imap:Drafts:123", "123", false); err != nil {
+	if err := validateSendSafety(cfg, true, "123", "imap:Drafts:123", "123", false, recipientCheck{}); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 }
@@ -32,7 +32,7 @@ func TestValidateSendSafetyAcceptsUIDConfirmation(t *testing.T) {
 func TestValidateSendSafetyBlocksForceWhenPolicyDisabled(t *testing.T) {
 	cfg := config.Default()
 	cfg.Safety.AllowForceSend = false
-	err := validateSendSafety(cfg, true, "", "d_1", "", true)
+	err := validateSendSafety(cfg, true, "", "d_1", "", true, recipientCheck{})
 	if err == nil {
 		t.Fatal("expected safety_blocked")
 	}
@@ -0,0 +1,295 @@
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"protonmailcli/internal/config"
+	"protonmailcli/internal/model"
+	"protonmailcli/internal/output"
+	"protonmailcli/internal/store"
+)
+
+const shellHistoryLimit = 1000
+
+var shellBuiltins = []string{"use", "list", "get", ":json", "help", "exit", "quit"}
+
+var shellTerminal = func(in io.Reader) func() (func(), error) {
+	if f, ok := in.(*os.File); ok && isTTY(f) {
+		return sttyRaw(f)
+	}
+	return nil
+}
+
+type shellSession struct {
+	app         App
+	cfg         config.Config
+	base        globalOptions
+	mode        output.Mode
+	mailbox     string
+	exit        int
+	historyPath string
+}
+
+func (a App) cmdShell(g globalOptions, cfg config.Config) int {
+	s := &shellSession{app: a, cfg: cfg, base: g, mode: g.mode, historyPath: filepath.Join(filepath.Dir(g.statePath), "shell_history")}
+	if s.mode == output.ModeJSON {
+		s.mode = output.ModeHuman
+	}
+	editor := &lineEditor{in: bufio.NewReader(a.Stdin), out: a.Stderr, raw: shellTerminal(a.Stdin), complete: s.complete}
+	interactive := editor.raw != nil
+	if interactive {
+		editor.history = loadShellHistory(s.historyPath)
+		fmt.Fprintln(a.Stderr, "protonmailcli shell: type help for commands, exit to quit")
+	}
+	runtimeSession = &imapSession{}
+	defer func() {
+		runtimeSession.close()
+		runtimeSession = nil
+	}()
+	for {
+		prompt := ""
+		if interactive {
+			prompt = s.prompt()
+		}
+		line, err := editor.readLine(prompt)
+		if err != nil {
+			return s.exit
+		}
+		if interactive && editor.remember(line) {
+			if err := appendShellHistory(s.historyPath, strings.TrimSpace(line)); err != nil {
+				fmt.Fprintln(a.Stderr, "warning: shell history: "+err.Error())
+			}
+		}
+		if s.run(line) {
+			return s.exit
+		}
+	}
+}
+
+func (s *shellSession) prompt() string {
+	if s.mailbox != "" {
+		return "protonmailcli:" + s.mailbox + "> "
+	}
+	return "protonmailcli> "
+}
+
+func (s *shellSession) run(line string) bool {
+	runtimeWarnings, runtimeStream = nil, nil
+	words, err := splitShellWords(line)
+	if err != nil {
+		s.fail(cliError{exit: 2, code: "usage_error", msg: err.Error()})
+		return false
+	}
+	if len(words) == 0 || strings.HasPrefix(words[0], "#") {
+		return false
+	}
+	switch words[0] {
+	case "exit", "quit":
+		return true
+	case ":json":
+		if s.base.mode == output.ModeJSON {
+			s.base.mode = s.mode
+		} else {
+			s.base.mode = output.ModeJSON
+		}
+		fmt.Fprintf(s.app.Stderr, "output: %s\n", s.base.mode)
+		return false
+	case "use":
+		s.use(words[1:])
+		return false
+	case "help":
+		if len(words) == 1 {
+			g := s.base
+			g.mode = fallbackMode(g.mode)
+			s.exit = s.app.printResult(helpResponse{Help: "shell", Usage: shellUsage()}, g, fmt.Sprintf("req_%d", time.Now().UnixNano()), time.Now())
+			return false
+		}
+		words = append(words[1:], "--help")
+	}
+	s.exit = s.app.runCommand(s.expand(words), s.base)
+	return false
+}
+
+func (s *shellSession) fail(err error) {
+	s.exit = s.app.exitWithError(err, fallbackMode(s.base.mode), s.base.profile, fmt.Sprintf("req_%d", time.Now().UnixNano()), time.Now())
+}
+
+func (s *shellSession) use(args []string) {
+	if len(args) == 0 {
+		if s.mailbox == "" {
+			fmt.Fprintln(s.app.Stderr, "no mailbox selected")
+		} else {
+			fmt.Fprintln(s.app.Stderr, "mailbox: "+s.mailbox)
+		}
+		return
+	}
+	if args[0] != "mailbox" {
+		s.fail(cliError{exit: 2, code: "usage_error", msg: fmt.Sprintf("unknown context %q", args[0]) + didYouMean(args[0], []string{"mailbox"}), hint: "Use: use mailbox <name>"})
+		return
+	}
+	name := strings.Join(args[1:], " ")
+	if name == "" {
+		s.mailbox = ""
+		fmt.Fprintln(s.app.Stderr, "mailbox cleared")
+		return
+	}
+	data, err := s.app.apply([]string{"mailbox", "resolve", "--name=" + name}, s.base, s.cfg)
+	if err != nil {
+		s.fail(err)
+		return
+	}
+	if resolved, ok := data.(mailboxResolveResponse); ok {
+		s.mailbox = resolved.Mailbox.Name
+		s.exit = 0
+		fmt.Fprintln(s.app.Stderr, "using mailbox "+s.mailbox)
+	}
+}
+
+func (s *shellSession) expand(words []string) []string {
+	if len(words) == 0 {
+		return words
+	}
+	switch words[0] {
+	case "list":
+		out := []string{"search", "messages"}
+		if s.mailbox != "" {
+			out = append(out, "--mailbox", s.mailbox)
+		}
+		return append(out, words[1:]...)
+	case "get":
+		out := []string{"message", "get"}
+		rest := words[1:]
+		if len(rest) > 0 && !strings.HasPrefix(rest[0], "-") {
+			out = append(out, "--message-id", s.messageID(rest[0]))
+			rest = rest[1:]
+		}
+		return append(out, rest...)
+	}
+	return words
+}
+
+func (s *shellSession) messageID(id string) string {
+	if s.mailbox == "" || strings.Contains(id, ":") || useLocalStateMode() {
+		return id
+	}
+	return imapMessageIDForMailbox(s.mailbox, id)
+}
+
+func (s *shellSession) complete(head string) []string {
+	words, err := splitShellWords(head)
+	if err != nil {
+		return nil
+	}
+	cur := ""
+	if len(words) > 0 && !strings.HasSuffix(head, " ") {
+		cur, words = words[len(words)-1], words[:len(words)-1]
+	}
+	state := model.State{}
+	if _, err := os.Stat(s.base.statePath); err == nil {
+		state, _ = store.New(s.base.statePath).Load()
+	}
+	if len(words) == 0 {
+		return append(completeWords([]string{cur}, &state), matchPrefix(shellBuiltins, cur)...)
+	}
+	switch words[0] {
+	case "use":
+		if len(words) == 1 {
+			return matchPrefix([]string{"mailbox"}, cur)
+		}
+		return matchPrefix(flagValues("mailbox", &state), cur)
+	case "help":
+		words = words[1:]
+	}
+	return completeWords(append(s.expand(words), cur), &state)
+}
+
+func shellUsage() string {
+	return `Shell commands:
+  <resource> <action> [flags]  run any protonmailcli command without the binary name
+  use mailbox <name>           set the mailbox that list and get work in (no name clears it)
+  use                          show the current mailbox
+  list [flags]                 search messages in the current mailbox
+  get <id> [flags]             show a message; a bare UID is read from the current mailbox
+  :json                        toggle JSON output
+  help [command]               show this help or the help of a command
+  exit, quit                   leave the shell (Ctrl-D works too)
+
+Tab completes commands, flags and known values; Up/Down walk the history.`
+}
+
+func splitShellWords(line string) ([]string, error) {
+	var words []string
+	var cur strings.Builder
+	var quote rune
+	inWord, escaped := false, false
+	for _, r := range line {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case unicode.IsSpace(r):
+			if inWord {
+				words = append(words, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape")
+	}
+	if inWord {
+		words = append(words, cur.String())
+	}
+	return words, nil
+}
+
+func loadShellHistory(path string) []string {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var lines []string
+	for _, line := range strings.Split(string(b), "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > shellHistoryLimit {
+		lines = lines[len(lines)-shellHistoryLimit:]
+	}
+	return lines
+}
+
+func appendShellHistory(path, line string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintln(f, line)
+	return err
+}
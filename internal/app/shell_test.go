@@ -0,0 +1,170 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"protonmailcli/internal/model"
+	"protonmailcli/internal/store"
+)
+
+func setupShellState(t *testing.T) (string, string) {
+	t.Helper()
+	t.Setenv("PMAIL_USE_LOCAL_STATE", "1")
+	tmp := t.TempDir()
+	cfg := filepath.Join(tmp, "config.toml")
+	state := filepath.Join(tmp, "data", "state.json")
+	if exit := Run([]string{"--json", "--config", cfg, "--state", state, "setup", "--non-interactive", "--username", "me@example.com"}, bytes.NewBuffer(nil), &bytes.Buffer{}, &bytes.Buffer{}); exit != 0 {
+		t.Fatalf("setup failed: %d", exit)
+	}
+	now := time.Now().UTC()
+	if err := store.New(state).Save(model.State{Messages: map[string]model.Message{
+		"m_1": {ID: "m_1", From: "a@example.com", Subject: "inbox one", SentAt: now},
+		"m_2": {ID: "m_2", From: "b@example.com", Subject: "archived two", Mailbox: "Archive", SentAt: now},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	return cfg, state
+}
+
+func decodeEnvelopes(t *testing.T, r io.Reader) []map[string]any {
+	t.Helper()
+	var out []map[string]any
+	dec := json.NewDecoder(r)
+	for {
+		var env map[string]any
+		if err := dec.Decode(&env); err == io.EOF {
+			return out
+		} else if err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		out = append(out, env)
+	}
+}
+
+func TestShellRunsCommandsInMailboxContext(t *testing.T) {
+	cfg, state := setupShellState(t)
+	script := strings.Join([]string{
+		"# comments and blank lines are skipped",
+		"",
+		"use mailbox archive",
+		":json",
+		"list",
+		"get m_2",
+		"search messages --query 'inbox one'",
+		"message get --message-id m_404",
+		"use folder x",
+		"exit",
+		"draft list",
+	}, "\n")
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	exit := Run([]string{"--config", cfg, "--state", state, "shell"}, strings.NewReader(script), stdout, stderr)
+	if exit != 2 {
+		t.Fatalf("shell should exit with the last command's code 2, got %d stderr=%s", exit, stderr.String())
+	}
+	envs := decodeEnvelopes(t, stdout)
+	if len(envs) != 5 {
+		t.Fatalf("expected 5 JSON envelopes, got %d: %s", len(envs), stdout.String())
+	}
+	messageIDs := func(env map[string]any) []string {
+		var ids []string
+		for _, m := range env["data"].(map[string]any)["messages"].([]any) {
+			ids = append(ids, m.(map[string]any)["id"].(string))
+		}
+		return ids
+	}
+	if ids := messageIDs(envs[0]); !reflect.DeepEqual(ids, []string{"m_2"}) {
+		t.Fatalf("list should be scoped to Archive, got %v", ids)
+	}
+	if subject := envs[1]["data"].(map[string]any)["message"].(map[string]any)["subject"]; subject != "archived two" {
+		t.Fatalf("get returned %v", subject)
+	}
+	if ids := messageIDs(envs[2]); !reflect.DeepEqual(ids, []string{"m_1"}) {
+		t.Fatalf("quoted query should match m_1, got %v", ids)
+	}
+	if code := envs[3]["error"].(map[string]any)["code"]; code != "not_found" {
+		t.Fatalf("missing message should report not_found, got %v", code)
+	}
+	if code := envs[4]["error"].(map[string]any)["code"]; code != "usage_error" {
+		t.Fatalf("unknown context should report usage_error, got %v", code)
+	}
+	if !strings.Contains(stderr.String(), "using mailbox Archive") || !strings.Contains(stderr.String(), "output: json") {
+		t.Fatalf("missing status lines: %s", stderr.String())
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(state), "shell_history")); !os.IsNotExist(err) {
+		t.Fatalf("piped input must not be written to history: %v", err)
+	}
+}
+
+func TestShellHumanOutputAndHelp(t *testing.T) {
+	cfg, state := setupShellState(t)
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	exit := Run([]string{"--config", cfg, "--state", state, "shell"}, strings.NewReader("help\nhelp draft list\nmailbox list\nmesage list\n"), stdout, stderr)
+	if exit != 2 {
+		t.Fatalf("expected usage exit from the typo, got %d", exit)
+	}
+	out := stdout.String()
+	for _, want := range []string{"use mailbox <name>", "Usage: protonmailcli [global flags] draft list", "Archive"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("stdout missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, `"ok"`) {
+		t.Fatalf("shell should use the human renderers by default:\n%s", out)
+	}
+	if !strings.Contains(out+stderr.String(), `did you mean "message"?`) {
+		t.Fatalf("typo should get a suggestion: %s %s", out, stderr.String())
+	}
+}
+
+func TestShellInteractiveHistoryAndCompletion(t *testing.T) {
+	cfg, state := setupShellState(t)
+	prev := shellTerminal
+	shellTerminal = func(io.Reader) func() (func(), error) {
+		return func() (func(), error) { return func() {}, nil }
+	}
+	defer func() { shellTerminal = prev }()
+	historyPath := filepath.Join(filepath.Dir(state), "shell_history")
+	if err := os.WriteFile(historyPath, []byte("tag list\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	input := "use mail\tArch\t\r" + "\x1b[A\x1b[A\r" + "exit\r"
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if exit := Run([]string{"--config", cfg, "--state", state, "shell"}, strings.NewReader(input), stdout, stderr); exit != 0 {
+		t.Fatalf("shell failed: %d stderr=%q", exit, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "using mailbox Archive") || !strings.Contains(stderr.String(), "protonmailcli:Archive> ") {
+		t.Fatalf("completion should have produced use mailbox Archive: %q", stderr.String())
+	}
+	if strings.Count(stdout.String(), "0 tags") != 1 {
+		t.Fatalf("history recall should rerun tag list: %q", stdout.String())
+	}
+	b, err := os.ReadFile(historyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(b); got != "tag list\nuse mailbox Archive\ntag list\nexit\n" {
+		t.Fatalf("unexpected history file: %q", got)
+	}
+}
+
+func TestSplitShellWords(t *testing.T) {
+	words, err := splitShellWords(`draft create --subject "hello world" --body 'it''s' a\ b`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"draft", "create", "--subject", "hello world", "--body", "its", "a b"}
+	if !reflect.DeepEqual(words, want) {
+		t.Fatalf("got %q", words)
+	}
+	if _, err := splitShellWords(`draft create --subject "open`); err == nil {
+		t.Fatal("unterminated quote should fail")
+	}
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"protonmailcli/internal/model"
+	"protonmailcli/internal/store"
+)
+
+var runtimeState *stateFile
+
+type stateFile struct {
+	store *store.Store
+	base  model.State
+}
+
+func openStateFile(path string, st model.State) (*stateFile, error) {
+	base, err := store.Clone(st)
+	if err != nil {
+		return nil, err
+	}
+	return &stateFile{store: store.New(path), base: base}, nil
+}
+
+func stateFileFor(g globalOptions, st *model.State) *stateFile {
+	if runtimeState != nil || g.statePath == "" {
+		return runtimeState
+	}
+	f, err := openStateFile(g.statePath, *st)
+	if err != nil {
+		return nil
+	}
+	return f
+}
+
+func (f *stateFile) sync(st *model.State, save bool, update func(*model.State) error) error {
+	unlock, err := f.store.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	disk, err := f.store.Load()
+	if err != nil {
+		return err
+	}
+	merged := store.Merge(f.base, *st, disk)
+	if update != nil {
+		if err := update(&merged); err != nil {
+			return err
+		}
+	}
+	if save {
+		if err := f.store.Save(merged); err != nil {
+			return err
+		}
+		disk = merged
+	}
+	base, err := store.Clone(disk)
+	if err != nil {
+		return err
+	}
+	f.base, *st = base, merged
+	return nil
+}
+
+func (f *stateFile) commit(st *model.State, update func(*model.State) error) error {
+	unlock, err := f.store.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	disk, err := f.store.Load()
+	if err != nil {
+		return err
+	}
+	if err := update(&disk); err != nil {
+		return err
+	}
+	if err := f.store.Save(disk); err != nil {
+		return err
+	}
+	base, err := store.Clone(disk)
+	if err != nil {
+		return err
+	}
+	merged := store.Merge(f.base, *st, disk)
+	f.base, *st = base, merged
+	return nil
+}
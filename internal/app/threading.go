@@ -0,0 +1,615 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"protonmailcli/internal/bridge"
+	"protonmailcli/internal/model"
+)
+
+var threadSubjectPrefixRe = regexp.MustCompile(`(?i)^\s*((re|fwd?|aw|sv|wg)(\[\d+\])?\s*:\s*)+`)
+
+type threadMessage struct {
+	ID         string
+	MessageID  string
+	InReplyTo  string
+	References []string
+	Subject    string
+	From       string
+	To         []string
+	Date       time.Time
+	Mailbox    string
+	Flags      []string
+}
+
+type threadContainer struct {
+	key      string
+	msg      *threadMessage
+	parent   *threadContainer
+	children []*threadContainer
+}
+
+type threadEntry struct {
+	msg      *threadMessage
+	depth    int
+	parentID string
+}
+
+func normalizeThreadSubject(subject string) string {
+	s := threadSubjectPrefixRe.ReplaceAllString(strings.TrimSpace(subject), "")
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+func isReplySubject(subject string) bool {
+	return threadSubjectPrefixRe.MatchString(subject)
+}
+
+func threadMessageFromBridge(m bridge.DraftMessage, mailbox string) threadMessage {
+	return threadMessage{
+		ID:         imapMessageIDForMailbox(mailbox, m.UID),
+		MessageID:  normalizeMessageID(m.MessageID),
+		InReplyTo:  normalizeMessageID(firstField(m.InReplyTo)),
+		References: parseReferenceIDs(m.References),
+		Subject:    m.Subject,
+		From:       m.From,
+		To:         m.To,
+		Date:       m.Date,
+		Mailbox:    mailbox,
+		Flags:      m.Flags,
+	}
+}
+
+func firstField(v string) string {
+	fields := strings.Fields(v)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+func (c *threadContainer) hasAncestor(other *threadContainer) bool {
+	for p := c; p != nil; p = p.parent {
+		if p == other {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *threadContainer) addChild(child *threadContainer) {
+	if child.parent != nil {
+		child.parent.removeChild(child)
+	}
+	child.parent = c
+	c.children = append(c.children, child)
+}
+
+func (c *threadContainer) removeChild(child *threadContainer) {
+	for i, ch := range c.children {
+		if ch == child {
+			c.children = append(c.children[:i], c.children[i+1:]...)
+			break
+		}
+	}
+	child.parent = nil
+}
+
+func (c *threadContainer) subject() string {
+	if c.msg != nil {
+		return c.msg.Subject
+	}
+	for _, ch := range c.children {
+		if ch.msg != nil {
+			return ch.msg.Subject
+		}
+	}
+	return ""
+}
+
+func (c *threadContainer) latest() time.Time {
+	var t time.Time
+	if c.msg != nil {
+		t = c.msg.Date
+	}
+	for _, ch := range c.children {
+		if lt := ch.latest(); lt.After(t) {
+			t = lt
+		}
+	}
+	return t
+}
+
+func (c *threadContainer) earliest() time.Time {
+	var t time.Time
+	if c.msg != nil {
+		t = c.msg.Date
+	}
+	for _, ch := range c.children {
+		if et := ch.earliest(); !et.IsZero() && (t.IsZero() || et.Before(t)) {
+			t = et
+		}
+	}
+	return t
+}
+
+func buildThreads(msgs []threadMessage) []*threadContainer {
+	ids := map[string]*threadContainer{}
+	container := func(key string) *threadContainer {
+		c, ok := ids[key]
+		if !ok {
+			c = &threadContainer{key: key}
+			ids[key] = c
+		}
+		return c
+	}
+	for i := range msgs {
+		m := &msgs[i]
+		key := m.MessageID
+		if key == "" {
+			key = "local:" + m.ID
+		}
+		c := container(key)
+		if c.msg != nil {
+			continue
+		}
+		c.msg = m
+		refs := append([]string{}, m.References...)
+		if m.InReplyTo != "" && (len(refs) == 0 || refs[len(refs)-1] != m.InReplyTo) {
+			refs = append(refs, m.InReplyTo)
+		}
+		var prev *threadContainer
+		for _, ref := range refs {
+			if ref == key {
+				continue
+			}
+			rc := container(ref)
+			if prev != nil && rc.parent == nil && !prev.hasAncestor(rc) {
+				prev.addChild(rc)
+			}
+			prev = rc
+		}
+		if prev != nil && !prev.hasAncestor(c) {
+			prev.addChild(c)
+		} else if c.parent != nil && prev == nil {
+			c.parent.removeChild(c)
+		}
+	}
+	var roots []*threadContainer
+	for _, c := range ids {
+		if c.parent == nil {
+			roots = append(roots, c)
+		}
+	}
+	sort.Slice(roots, func(i, j int) bool { return roots[i].key < roots[j].key })
+	roots = pruneThreadContainers(roots, true)
+	return groupThreadsBySubject(roots)
+}
+
+func pruneThreadContainers(list []*threadContainer, root bool) []*threadContainer {
+	var out []*threadContainer
+	for _, c := range list {
+		c.children = pruneThreadContainers(c.children, false)
+		for _, ch := range c.children {
+			ch.parent = c
+		}
+		switch {
+		case c.msg == nil && len(c.children) == 0:
+			continue
+		case c.msg == nil && (!root || len(c.children) == 1):
+			for _, ch := range c.children {
+				ch.parent = c.parent
+				out = append(out, ch)
+			}
+		default:
+			out = append(out, c)
+		}
+	}
+	if root {
+		for _, c := range out {
+			c.parent = nil
+		}
+	}
+	return out
+}
+
+func groupThreadsBySubject(roots []*threadContainer) []*threadContainer {
+	table := map[string]*threadContainer{}
+	for _, c := range roots {
+		subj := normalizeThreadSubject(c.subject())
+		if subj == "" {
+			continue
+		}
+		old, ok := table[subj]
+		if !ok || (c.msg == nil && old.msg != nil) || (old.msg != nil && c.msg != nil && isReplySubject(old.msg.Subject) && !isReplySubject(c.msg.Subject)) {
+			table[subj] = c
+		}
+	}
+	var out []*threadContainer
+	for _, c := range roots {
+		subj := normalizeThreadSubject(c.subject())
+		target, ok := table[subj]
+		if subj == "" || !ok || target == c {
+			out = append(out, c)
+			continue
+		}
+		switch {
+		case target.msg == nil && c.msg == nil:
+			for _, ch := range append([]*threadContainer{}, c.children...) {
+				target.addChild(ch)
+			}
+		case target.msg == nil:
+			target.addChild(c)
+		case c.msg != nil && isReplySubject(c.msg.Subject) && !isReplySubject(target.msg.Subject):
+			target.addChild(c)
+		default:
+			merged := &threadContainer{key: "subject:" + subj}
+			merged.addChild(target)
+			merged.addChild(c)
+			table[subj] = merged
+			for i, r := range out {
+				if r == target {
+					out[i] = merged
+				}
+			}
+		}
+	}
+	return out
+}
+
+func threadsFromServer(tree []*bridge.ThreadNode, byUID map[string]*threadMessage) []*threadContainer {
+	var convert func(n *bridge.ThreadNode) *threadContainer
+	convert = func(n *bridge.ThreadNode) *threadContainer {
+		c := &threadContainer{}
+		if m, ok := byUID[n.UID]; ok {
+			c.msg = m
+			c.key = m.MessageID
+			if c.key == "" {
+				c.key = "local:" + m.ID
+			}
+		}
+		for _, ch := range n.Children {
+			c.addChild(convert(ch))
+		}
+		return c
+	}
+	roots := make([]*threadContainer, 0, len(tree))
+	for _, n := range tree {
+		c := convert(n)
+		if c.msg == nil {
+			c.key = "subject:" + normalizeThreadSubject(c.subject())
+		}
+		roots = append(roots, c)
+	}
+	return pruneThreadContainers(roots, true)
+}
+
+func threadKey(root *threadContainer) string {
+	var first *threadMessage
+	for _, e := range flattenThread(root) {
+		if first == nil || e.msg.Date.Before(first.Date) || (e.msg.Date.Equal(first.Date) && e.msg.ID < first.ID) {
+			first = e.msg
+		}
+	}
+	switch {
+	case first == nil:
+		return "subject:" + normalizeThreadSubject(root.subject())
+	case len(first.References) > 0:
+		return first.References[0]
+	case first.InReplyTo != "":
+		return first.InReplyTo
+	case first.MessageID != "":
+		return first.MessageID
+	}
+	return "local:" + first.ID
+}
+
+func threadIDFor(root *threadContainer) string {
+	sum := sha256.Sum256([]byte(threadKey(root)))
+	return "t_" + hex.EncodeToString(sum[:])[:16]
+}
+
+func flattenThread(root *threadContainer) []threadEntry {
+	var out []threadEntry
+	var walk func(c *threadContainer, depth int, parentID string)
+	walk = func(c *threadContainer, depth int, parentID string) {
+		next, nextDepth := parentID, depth
+		if c.msg != nil {
+			out = append(out, threadEntry{msg: c.msg, depth: depth, parentID: parentID})
+			next, nextDepth = c.msg.ID, depth+1
+		}
+		children := append([]*threadContainer{}, c.children...)
+		sort.SliceStable(children, func(i, j int) bool { return children[i].earliest().Before(children[j].earliest()) })
+		for _, ch := range children {
+			walk(ch, nextDepth, next)
+		}
+	}
+	walk(root, 0, "")
+	return out
+}
+
+func summarizeThread(root *threadContainer) (threadSummary, []threadEntry) {
+	entries := flattenThread(root)
+	s := threadSummary{ID: threadIDFor(root), Count: len(entries)}
+	seen := map[string]bool{}
+	mailboxes := map[string]bool{}
+	for _, e := range entries {
+		m := e.msg
+		if s.Subject == "" && e.depth == 0 {
+			s.Subject = m.Subject
+		}
+		for _, p := range append([]string{m.From}, m.To...) {
+			p = strings.TrimSpace(p)
+			if p != "" && !seen[strings.ToLower(p)] {
+				seen[strings.ToLower(p)] = true
+				s.Participants = append(s.Participants, p)
+			}
+		}
+		if !contains(m.Flags, flagSeen) {
+			s.Unread++
+		}
+		mailboxes[m.Mailbox] = true
+		if s.LastActivity == "" || !m.Date.Before(parseRFC3339(s.LastActivity)) {
+			s.LastActivity = m.Date.UTC().Format(time.RFC3339)
+			s.LastMessageID = m.ID
+		}
+		if first := m.Date.UTC().Format(time.RFC3339); s.FirstDate == "" || m.Date.Before(parseRFC3339(s.FirstDate)) {
+			s.FirstDate = first
+		}
+	}
+	if s.Subject == "" && len(entries) > 0 {
+		s.Subject = entries[0].msg.Subject
+	}
+	for mb := range mailboxes {
+		s.Mailboxes = append(s.Mailboxes, mb)
+	}
+	sort.Strings(s.Mailboxes)
+	return s, entries
+}
+
+func parseRFC3339(v string) time.Time {
+	t, _ := time.Parse(time.RFC3339, v)
+	return t
+}
+
+func threadRecords(entries []threadEntry) []threadMessageRecord {
+	out := make([]threadMessageRecord, 0, len(entries))
+	for _, e := range entries {
+		m := e.msg
+		out = append(out, threadMessageRecord{
+			ID:        m.ID,
+			MessageID: m.MessageID,
+			ParentID:  e.parentID,
+			Depth:     e.depth,
+			Mailbox:   m.Mailbox,
+			From:      m.From,
+			To:        m.To,
+			Subject:   m.Subject,
+			Flags:     m.Flags,
+			Date:      m.Date.UTC().Format(time.RFC3339),
+		})
+	}
+	return out
+}
+
+func sortThreadsByActivity(roots []*threadContainer) {
+	sort.SliceStable(roots, func(i, j int) bool {
+		li, lj := roots[i].latest(), roots[j].latest()
+		if !li.Equal(lj) {
+			return li.After(lj)
+		}
+		return threadKey(roots[i]) < threadKey(roots[j])
+	})
+}
+
+func findThread(roots []*threadContainer, threadID, messageID string) *threadContainer {
+	for _, r := range roots {
+		if threadID != "" && threadIDFor(r) == threadID {
+			return r
+		}
+		if messageID != "" {
+			for _, e := range flattenThread(r) {
+				if e.msg.ID == messageID || (e.msg.MessageID != "" && e.msg.MessageID == normalizeMessageID(messageID)) {
+					return r
+				}
+			}
+		}
+	}
+	return nil
+}
+
+type imapThreadClient interface {
+	SentMailboxName() (string, error)
+	ArchiveMailboxName() (string, error)
+	ListMessageHeaders(mailbox, criteria string) ([]bridge.DraftMessage, error)
+	HasCapability(name string) bool
+	ThreadUIDs(mailbox, algorithm, criteria string) ([]*bridge.ThreadNode, error)
+}
+
+type threadRequest struct {
+	action    string
+	mailbox   string
+	after     string
+	messageID string
+	threadID  string
+	limit     int
+	cursor    string
+}
+
+func parseThreadFlags(action string, args []string) (threadRequest, error) {
+	if action != "list" && action != "get" {
+		return threadRequest{}, cliError{exit: 2, code: "usage_error", msg: "unknown thread action: " + action}
+	}
+	fs := newFlagSet("thread " + action)
+	mailbox := fs.String("mailbox", "", "restrict to one mailbox (uses server THREAD when available)")
+	after := fs.String("after", "", "only messages on/after date (YYYY-MM-DD or RFC3339)")
+	req := threadRequest{action: action}
+	var messageID, threadID, cursor *string
+	var limit *int
+	if action == "get" {
+		messageID = fs.String("message-id", "", "any message id in the thread (imap:<mailbox>:<uid>, local id or Message-ID header)")
+		threadID = fs.String("thread-id", "", "thread id from thread list")
+	} else {
+		limit = fs.Int("limit", 50, "max threads")
+		cursor = fs.String("cursor", "", "pagination cursor")
+	}
+	if err := parseFlags(fs, args); err != nil {
+		return req, err
+	}
+	if _, _, err := parseDateInput(*after); err != nil {
+		return req, cliError{exit: 2, code: "validation_error", msg: "invalid --after: " + err.Error()}
+	}
+	req.mailbox = strings.TrimSpace(*mailbox)
+	req.after = strings.TrimSpace(*after)
+	if action == "get" {
+		req.messageID = strings.TrimSpace(*messageID)
+		req.threadID = strings.TrimSpace(*threadID)
+		if (req.messageID == "") == (req.threadID == "") {
+			return req, cliError{exit: 2, code: "validation_error", msg: "exactly one of --message-id or --thread-id is required"}
+		}
+		if req.threadID != "" && !strings.HasPrefix(req.threadID, "t_") {
+			return req, cliError{exit: 2, code: "validation_error", msg: "invalid --thread-id (expected t_<hex>)"}
+		}
+		return req, nil
+	}
+	req.limit = *limit
+	req.cursor = *cursor
+	return req, nil
+}
+
+func cmdThreadIMAP(c imapThreadClient, req threadRequest, g globalOptions) (any, bool, error) {
+	criteria, err := buildIMAPCriteria("", "", "", "", "", false, "", req.after, "")
+	if err != nil {
+		return nil, false, cliError{exit: 2, code: "validation_error", msg: err.Error()}
+	}
+	var roots []*threadContainer
+	var mailboxes []string
+	algorithm := "client_jwz"
+	if req.mailbox != "" {
+		mailboxes = []string{req.mailbox}
+		msgs, err := c.ListMessageHeaders(req.mailbox, criteria)
+		if err != nil {
+			return nil, false, cliError{exit: 4, code: "imap_thread_failed", msg: err.Error()}
+		}
+		tms := make([]threadMessage, 0, len(msgs))
+		for _, m := range msgs {
+			tms = append(tms, threadMessageFromBridge(m, req.mailbox))
+		}
+		if c.HasCapability("THREAD=REFERENCES") {
+			tree, err := c.ThreadUIDs(req.mailbox, "REFERENCES", criteria)
+			if err != nil {
+				return nil, false, cliError{exit: 4, code: "imap_thread_failed", msg: err.Error()}
+			}
+			byUID := map[string]*threadMessage{}
+			for i := range tms {
+				byUID[msgs[i].UID] = &tms[i]
+			}
+			roots = threadsFromServer(tree, byUID)
+			algorithm = "server_references"
+		} else {
+			roots = buildThreads(tms)
+		}
+	} else {
+		mailboxes = []string{"INBOX"}
+		for _, lookup := range []func() (string, error){c.SentMailboxName, c.ArchiveMailboxName} {
+			if name, err := lookup(); err == nil && name != "" && !contains(mailboxes, name) {
+				mailboxes = append(mailboxes, name)
+			}
+		}
+		var tms []threadMessage
+		for _, mb := range mailboxes {
+			msgs, err := c.ListMessageHeaders(mb, criteria)
+			if err != nil {
+				return nil, false, cliError{exit: 4, code: "imap_thread_failed", msg: err.Error()}
+			}
+			for _, m := range msgs {
+				tms = append(tms, threadMessageFromBridge(m, mb))
+			}
+		}
+		roots = buildThreads(tms)
+	}
+	if req.action == "get" {
+		messageID := req.messageID
+		if messageID != "" && !strings.Contains(messageID, "@") {
+			mb, uid, err := parseMailboxUID(messageID, "INBOX")
+			if err != nil {
+				return nil, false, cliError{exit: 2, code: "validation_error", msg: "invalid --message-id: " + err.Error()}
+			}
+			messageID = imapMessageIDForMailbox(mb, uid)
+		}
+		return threadGet(roots, req.threadID, messageID, algorithm, "imap")
+	}
+	return threadList(roots, req, mailboxes, algorithm, "imap")
+}
+
+func cmdThreadLocal(req threadRequest, st *model.State) (any, bool, error) {
+	after, hasAfter, _ := parseDateInput(req.after)
+	var tms []threadMessage
+	mailboxes := []string{}
+	for _, m := range st.Messages {
+		mb := localMessageMailbox(m)
+		if strings.EqualFold(mb, "Trash") || (req.mailbox != "" && !strings.EqualFold(mb, req.mailbox)) {
+			continue
+		}
+		if hasAfter && m.SentAt.Before(after) {
+			continue
+		}
+		tm := threadMessage{ID: m.ID, MessageID: localMessageIDHeader(m.ID), Subject: m.Subject, From: m.From, To: m.To, Date: m.SentAt, Mailbox: mb, Flags: m.Flags}
+		if m.InReplyTo != "" {
+			tm.InReplyTo = localMessageIDHeader(m.InReplyTo)
+		}
+		tms = append(tms, tm)
+		if !contains(mailboxes, mb) {
+			mailboxes = append(mailboxes, mb)
+		}
+	}
+	sort.Slice(tms, func(i, j int) bool {
+		if !tms[i].Date.Equal(tms[j].Date) {
+			return tms[i].Date.Before(tms[j].Date)
+		}
+		return tms[i].ID < tms[j].ID
+	})
+	sort.Strings(mailboxes)
+	roots := buildThreads(tms)
+	if req.action == "get" {
+		return threadGet(roots, req.threadID, req.messageID, "client_jwz", "local")
+	}
+	return threadList(roots, req, mailboxes, "client_jwz", "local")
+}
+
+func localMessageIDHeader(id string) string {
+	return "<" + id + "@local>"
+}
+
+func threadList(roots []*threadContainer, req threadRequest, mailboxes []string, algorithm, source string) (any, bool, error) {
+	sortThreadsByActivity(roots)
+	start, limit := parsePage(req.cursor, req.limit)
+	resp := threadListResponse{Threads: []threadSummary{}, Total: len(roots), Mailboxes: mailboxes, Algorithm: algorithm, Source: source}
+	for i := start; i < len(roots) && i < start+limit; i++ {
+		s, _ := summarizeThread(roots[i])
+		resp.Threads = append(resp.Threads, s)
+	}
+	if start+limit < len(roots) {
+		resp.NextCursor = strconv.Itoa(start + limit)
+	}
+	resp.Count = len(resp.Threads)
+	return resp, false, nil
+}
+
+func threadGet(roots []*threadContainer, threadID, messageID, algorithm, source string) (any, bool, error) {
+	root := findThread(roots, threadID, messageID)
+	if root == nil {
+		if threadID != "" {
+			return nil, false, cliError{exit: 5, code: "not_found", msg: "thread not found: " + threadID}
+		}
+		return nil, false, cliError{exit: 5, code: "not_found", msg: "message not found: " + messageID}
+	}
+	s, entries := summarizeThread(root)
+	records := threadRecords(entries)
+	return threadGetResponse{Thread: s, Messages: records, Count: len(records), Algorithm: algorithm, Source: source}, false, nil
+}
@@ -0,0 +1,153 @@
+package app
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"protonmailcli/internal/bridge"
+	"protonmailcli/internal/model"
+)
+
+type fakeThreadClient struct {
+	msgs      map[string][]bridge.DraftMessage
+	threadCap bool
+	tree      string
+	threaded  []string
+}
+
+func (f *fakeThreadClient) SentMailboxName() (string, error)    { return "Sent", nil }
+func (f *fakeThreadClient) ArchiveMailboxName() (string, error) { return "Archive", nil }
+func (f *fakeThreadClient) HasCapability(name string) bool      { return f.threadCap }
+
+func (f *fakeThreadClient) ListMessageHeaders(mailbox, criteria string) ([]bridge.DraftMessage, error) {
+	return f.msgs[mailbox], nil
+}
+
+func (f *fakeThreadClient) ThreadUIDs(mailbox, algorithm, criteria string) ([]*bridge.ThreadNode, error) {
+	f.threaded = append(f.threaded, mailbox+" "+algorithm)
+	return bridge.ParseThreadResponse(f.tree), nil
+}
+
+func threadTestMessages() map[string][]bridge.DraftMessage {
+	base := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	return map[string][]bridge.DraftMessage{
+		"INBOX": {
+			{UID: "1", From: "alice@example.com", To: []string{"me@example.com"}, Subject: "Plan", MessageID: "<a@x>", Date: base},
+			{UID: "3", From: "bob@example.com", To: []string{"me@example.com"}, Subject: "Re: Plan", MessageID: "<c@x>", InReplyTo: "<b@x>", References: "<a@x> <b@x>", Date: base.Add(2 * time.Hour), Flags: []string{flagSeen}},
+			{UID: "4", From: "carol@example.com", Subject: "Lunch?", MessageID: "<d@x>", Date: base.Add(time.Hour)},
+			{UID: "5", From: "dave@example.com", Subject: "RE: plan", MessageID: "<e@x>", Date: base.Add(3 * time.Hour)},
+		},
+		"Sent": {
+			{UID: "7", From: "me@example.com", To: []string{"alice@example.com"}, Subject: "Re: Plan", MessageID: "<b@x>", InReplyTo: "<a@x>", References: "<a@x>", Date: base.Add(time.Hour), Flags: []string{flagSeen}},
+			{UID: "8", From: "me@example.com", Subject: "Plan", MessageID: "<a@x>", Date: base},
+		},
+	}
+}
+
+func TestBuildThreadsLinksReferencesAndSubjects(t *testing.T) {
+	c := &fakeThreadClient{msgs: threadTestMessages()}
+	data, _, err := cmdThreadIMAP(c, threadRequest{action: "list"}, globalOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := data.(threadListResponse)
+	if resp.Total != 2 || resp.Algorithm != "client_jwz" || strings.Join(resp.Mailboxes, ",") != "INBOX,Sent,Archive" {
+		t.Fatalf("unexpected list: %+v", resp)
+	}
+	plan := resp.Threads[0]
+	if plan.Subject != "Plan" || plan.Count != 4 || plan.Unread != 2 || plan.LastMessageID != "imap:INBOX:5" {
+		t.Fatalf("unexpected plan thread: %+v", plan)
+	}
+	if strings.Join(plan.Participants, ",") != "alice@example.com,me@example.com,bob@example.com,dave@example.com" {
+		t.Fatalf("unexpected participants: %v", plan.Participants)
+	}
+
+	data, _, err = cmdThreadIMAP(c, threadRequest{action: "get", messageID: "imap:Sent:7"}, globalOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := data.(threadGetResponse)
+	if got.Thread.ID != plan.ID {
+		t.Fatalf("thread id not stable: %s vs %s", got.Thread.ID, plan.ID)
+	}
+	var order []string
+	for _, m := range got.Messages {
+		order = append(order, m.ID+"@"+strconv.Itoa(m.Depth))
+	}
+	if strings.Join(order, " ") != "imap:INBOX:1@0 imap:Sent:7@1 imap:INBOX:3@2 imap:INBOX:5@1" {
+		t.Fatalf("unexpected order: %v", order)
+	}
+	if got.Messages[2].ParentID != "imap:Sent:7" {
+		t.Fatalf("unexpected parent: %+v", got.Messages[2])
+	}
+}
+
+func TestThreadIDSurvivesMissingRoot(t *testing.T) {
+	msgs := threadTestMessages()
+	full := buildThreads([]threadMessage{threadMessageFromBridge(msgs["INBOX"][0], "INBOX"), threadMessageFromBridge(msgs["INBOX"][1], "INBOX")})
+	partial := buildThreads([]threadMessage{threadMessageFromBridge(msgs["INBOX"][1], "INBOX")})
+	if len(full) != 1 || len(partial) != 1 || threadIDFor(full[0]) != threadIDFor(partial[0]) {
+		t.Fatalf("thread id should be keyed on the conversation root: %d %d", len(full), len(partial))
+	}
+}
+
+func TestThreadIDMatchesForServerAndClientThreading(t *testing.T) {
+	inbox := threadTestMessages()["INBOX"]
+	reply, late := threadMessageFromBridge(inbox[1], "INBOX"), threadMessageFromBridge(inbox[3], "INBOX")
+	client := buildThreads([]threadMessage{reply, late})
+	server := threadsFromServer(bridge.ParseThreadResponse("((3)(5))"), map[string]*threadMessage{"3": &reply, "5": &late})
+	if len(client) != 1 || len(server) != 1 || threadIDFor(client[0]) != threadIDFor(server[0]) {
+		t.Fatalf("server THREAD and client threading must key a thread the same way: %d %d", len(client), len(server))
+	}
+	if threadKey(server[0]) != "<a@x>" {
+		t.Fatalf("a dummy root is keyed by its conversation root, got %q", threadKey(server[0]))
+	}
+}
+
+func TestThreadIMAPUsesServerThreadForMailbox(t *testing.T) {
+	c := &fakeThreadClient{msgs: threadTestMessages(), threadCap: true, tree: "(1 3)(4)(5)"}
+	data, _, err := cmdThreadIMAP(c, threadRequest{action: "list", mailbox: "INBOX"}, globalOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := data.(threadListResponse)
+	if resp.Algorithm != "server_references" || resp.Total != 3 || strings.Join(c.threaded, "|") != "INBOX REFERENCES" {
+		t.Fatalf("unexpected server threading: %+v %v", resp, c.threaded)
+	}
+}
+
+func TestParseThreadResponseNestsChains(t *testing.T) {
+	roots := bridge.ParseThreadResponse("(2)(3 6 (4 23)(44 7 96))((11)(12))")
+	if len(roots) != 3 {
+		t.Fatalf("expected 3 roots, got %d", len(roots))
+	}
+	six := roots[1].Children[0]
+	if roots[1].UID != "3" || six.UID != "6" || len(six.Children) != 2 || six.Children[1].Children[0].UID != "7" {
+		t.Fatalf("unexpected tree: %+v", six)
+	}
+	if roots[2].UID != "" || len(roots[2].Children) != 2 {
+		t.Fatalf("expected dummy root with two children: %+v", roots[2])
+	}
+}
+
+func TestThreadLocalFollowsInReplyTo(t *testing.T) {
+	now := time.Now().UTC()
+	st := &model.State{Messages: map[string]model.Message{
+		"m_1": {ID: "m_1", Subject: "Status", From: "a@example.com", SentAt: now},
+		"m_2": {ID: "m_2", Subject: "Status update", From: "me@example.com", InReplyTo: "m_1", SentAt: now.Add(time.Minute)},
+		"m_3": {ID: "m_3", Subject: "Other", Mailbox: "Trash", SentAt: now},
+	}}
+	data, _, err := cmdThreadLocal(threadRequest{action: "get", messageID: "m_2"}, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := data.(threadGetResponse)
+	if resp.Count != 2 || resp.Messages[1].ParentID != "m_1" || resp.Messages[1].Depth != 1 {
+		t.Fatalf("unexpected local thread: %+v", resp)
+	}
+	if _, _, err := cmdThreadLocal(threadRequest{action: "get", messageID: "m_3"}, st); errorCodeFromErr(err, "") != "not_found" {
+		t.Fatalf("trash should be excluded, got %v", err)
+	}
+}
@@ -0,0 +1,652 @@
+package app
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"protonmailcli/internal/config"
+	"protonmailcli/internal/model"
+	"protonmailcli/internal/output"
+	"protonmailcli/internal/store"
+)
+
+const (
+	tuiMessageLimit = 200
+	tuiKeysHelp     = "j/k move  tab pane  enter open  space/b scroll  r reply  c compose  t tag  m move  a archive  R refresh  q quit"
+)
+
+type tuiMessage struct {
+	ID      string
+	From    string
+	Subject string
+	Date    string
+	Unread  bool
+}
+
+type tuiPrompt struct {
+	label  string
+	value  string
+	values []string
+	hint   string
+	submit func(string)
+}
+
+type tuiModel struct {
+	app       App
+	cfg       config.Config
+	g         globalOptions
+	term      tuiTerminal
+	mailboxes []mailboxInfo
+	messages  []tuiMessage
+	box       int
+	cur       int
+	mailbox   string
+	sidebar   bool
+	open      *messageRecord
+	scroll    int
+	status    string
+	warnings  []string
+	prompt    *tuiPrompt
+	quit      bool
+}
+
+func (a App) cmdTUI(g globalOptions, cfg config.Config) error {
+	term, err := openTUITerminal(a)
+	if err != nil {
+		return err
+	}
+	defer term.close()
+	runtimeSession = &imapSession{}
+	defer func() {
+		runtimeSession.close()
+		runtimeSession = nil
+	}()
+	g.mode, g.noInput, g.shaper = output.ModeJSON, true, nil
+	m := &tuiModel{app: a, cfg: cfg, g: g, term: term}
+	m.loadMailboxes()
+	for i, b := range m.mailboxes {
+		if b.ID == "inbox" {
+			m.box = i
+		}
+	}
+	if len(m.mailboxes) > 0 {
+		m.loadMessages(m.mailboxes[m.box].Name)
+	}
+	for !m.quit {
+		term.draw(m.render())
+		key, err := term.readKey()
+		if err != nil {
+			break
+		}
+		m.handle(key)
+	}
+	return nil
+}
+
+func (m *tuiModel) call(words ...string) (any, bool) {
+	var stderr bytes.Buffer
+	prevErr, prevWarnings := runtimeStderr, runtimeWarnings
+	runtimeStderr, runtimeWarnings, runtimeStream = &stderr, nil, nil
+	defer func() { runtimeStderr = prevErr }()
+	app := m.app
+	app.Stderr = &stderr
+	data, err := app.apply(words, m.g, m.cfg)
+	m.warnings = runtimeWarnings
+	runtimeWarnings = prevWarnings
+	if err != nil {
+		m.status = "error: " + err.Error()
+		var ce cliError
+		if errors.As(err, &ce) && ce.hint != "" {
+			m.status += " (" + ce.hint + ")"
+		}
+		return nil, false
+	}
+	return data, true
+}
+
+func (m *tuiModel) report(msg string) {
+	msg = tuiLine(msg)
+	if m.g.dryRun {
+		msg = "dry-run: " + msg
+	}
+	for _, w := range m.warnings {
+		msg += "; warning: " + w
+	}
+	m.status = msg
+}
+
+func (m *tuiModel) selected() (tuiMessage, bool) {
+	if m.cur < 0 || m.cur >= len(m.messages) {
+		m.status = "no message selected"
+		return tuiMessage{}, false
+	}
+	return m.messages[m.cur], true
+}
+
+func (m *tuiModel) loadMailboxes() {
+	data, ok := m.call("mailbox", "list")
+	if !ok {
+		return
+	}
+	if list, isList := data.(mailboxListResponse); isList {
+		m.mailboxes = list.Mailboxes
+		m.box = min(m.box, max(0, len(m.mailboxes)-1))
+	}
+}
+
+func (m *tuiModel) loadMessages(mailbox string) {
+	data, ok := m.call("search", "messages", "--mailbox="+mailbox, fmt.Sprintf("--limit=%d", tuiMessageLimit))
+	if !ok {
+		return
+	}
+	var records []messageRecord
+	switch d := data.(type) {
+	case messageListResponse:
+		records = d.Messages
+	case localSearchMessagesResponse:
+		for _, msg := range d.Messages {
+			records = append(records, messageRecordFromModel(msg))
+		}
+	}
+	m.messages = m.messages[:0]
+	for _, r := range records {
+		m.messages = append(m.messages, tuiMessage{ID: r.ID, From: r.From, Subject: r.Subject, Date: r.Date, Unread: !contains(r.Flags, flagSeen)})
+	}
+	sort.SliceStable(m.messages, func(i, j int) bool {
+		if m.messages[i].Date != m.messages[j].Date {
+			return m.messages[i].Date > m.messages[j].Date
+		}
+		return m.messages[i].ID > m.messages[j].ID
+	})
+	if m.mailbox != mailbox {
+		m.cur, m.open, m.scroll = 0, nil, 0
+	}
+	m.mailbox = mailbox
+	m.cur = min(m.cur, max(0, len(m.messages)-1))
+	m.report(fmt.Sprintf("%s: %d messages", mailbox, len(m.messages)))
+}
+
+func (m *tuiModel) openSelected() bool {
+	msg, ok := m.selected()
+	if !ok {
+		return false
+	}
+	data, ok := m.call("message", "get", "--message-id="+msg.ID, "--mark-read")
+	if !ok {
+		return false
+	}
+	var rec messageRecord
+	switch d := data.(type) {
+	case messageGetResponse:
+		rec = d.Message
+	case localMessageGetResponse:
+		rec = messageRecordFromModel(d.Message.Message)
+	}
+	rec.ID = msg.ID
+	m.open, m.scroll = &rec, 0
+	m.messages[m.cur].Unread = false
+	m.status = ""
+	return true
+}
+
+func (m *tuiModel) remove(id string) {
+	for i, msg := range m.messages {
+		if msg.ID == id {
+			m.messages = append(m.messages[:i], m.messages[i+1:]...)
+			break
+		}
+	}
+	if m.open != nil && m.open.ID == id {
+		m.open = nil
+	}
+	m.cur = min(m.cur, max(0, len(m.messages)-1))
+}
+
+func (m *tuiModel) handle(key string) {
+	if m.prompt != nil {
+		m.handlePrompt(key)
+		return
+	}
+	m.status = ""
+	switch key {
+	case "q", "ctrl-c":
+		m.quit = true
+	case "tab":
+		m.sidebar = !m.sidebar
+	case "left", "h":
+		m.sidebar = true
+	case "right", "l":
+		m.sidebar = false
+	case "down", "j":
+		m.move(1)
+	case "up", "k":
+		m.move(-1)
+	case "home", "g":
+		m.move(-len(m.messages) - len(m.mailboxes))
+	case "end", "G":
+		m.move(len(m.messages) + len(m.mailboxes))
+	case "enter":
+		if m.sidebar {
+			if m.box < len(m.mailboxes) {
+				m.loadMessages(m.mailboxes[m.box].Name)
+				m.sidebar = false
+			}
+		} else {
+			m.openSelected()
+		}
+	case " ", "pgdn":
+		m.scroll += m.layout().reader - 1
+	case "b", "pgup":
+		m.scroll = max(0, m.scroll-m.layout().reader+1)
+	case "R", "ctrl-l":
+		m.loadMailboxes()
+		if m.mailbox != "" {
+			m.loadMessages(m.mailbox)
+		}
+	case "a":
+		m.archive()
+	case "m":
+		m.askMove()
+	case "t":
+		m.askTag()
+	case "r":
+		m.reply()
+	case "c":
+		m.compose()
+	}
+}
+
+func (m *tuiModel) move(delta int) {
+	if m.sidebar {
+		m.box = max(0, min(len(m.mailboxes)-1, m.box+delta))
+		return
+	}
+	next := max(0, min(len(m.messages)-1, m.cur+delta))
+	if next != m.cur {
+		m.cur, m.scroll = next, 0
+	}
+}
+
+func (m *tuiModel) handlePrompt(key string) {
+	p := m.prompt
+	switch key {
+	case "ctrl-c", "esc":
+		m.prompt, m.status = nil, "cancelled"
+	case "enter":
+		m.prompt = nil
+		if value := strings.TrimSpace(p.value); value != "" {
+			p.submit(value)
+		} else {
+			m.status = "cancelled"
+		}
+	case "backspace":
+		if r := []rune(p.value); len(r) > 0 {
+			p.value = string(r[:len(r)-1])
+		}
+		p.hint = ""
+	case "tab":
+		matches := matchPrefix(p.values, p.value)
+		switch len(matches) {
+		case 0:
+			p.hint = "no match"
+		case 1:
+			p.value, p.hint = matches[0], ""
+		default:
+			p.value, p.hint = commonPrefix(matches), strings.Join(matches, ", ")
+		}
+	default:
+		if r := []rune(key); len(r) == 1 && unicode.IsPrint(r[0]) {
+			p.value += key
+			p.hint = ""
+		}
+	}
+}
+
+func (m *tuiModel) archive() {
+	msg, ok := m.selected()
+	if !ok {
+		return
+	}
+	if _, ok := m.call("message", "archive", "--message-id="+msg.ID); !ok {
+		return
+	}
+	m.report("archived: " + msg.Subject)
+	if !m.g.dryRun {
+		m.remove(msg.ID)
+		m.loadMailboxes()
+	}
+}
+
+func (m *tuiModel) askMove() {
+	msg, ok := m.selected()
+	if !ok {
+		return
+	}
+	names := make([]string, 0, len(m.mailboxes))
+	for _, b := range m.mailboxes {
+		if b.Name != m.mailbox {
+			names = append(names, b.Name)
+		}
+	}
+	m.prompt = &tuiPrompt{label: "Move to: ", values: names, submit: func(dest string) {
+		if _, ok := m.call("message", "move", "--message-id="+msg.ID, "--to-mailbox="+dest); !ok {
+			return
+		}
+		m.report("moved to " + dest + ": " + msg.Subject)
+		if !m.g.dryRun {
+			m.remove(msg.ID)
+			m.loadMailboxes()
+		}
+	}}
+}
+
+func (m *tuiModel) askTag() {
+	msg, ok := m.selected()
+	if !ok {
+		return
+	}
+	state := m.loadState()
+	m.prompt = &tuiPrompt{label: "Tag: ", values: flagValues("tag", &state), submit: func(tag string) {
+		if _, ok := m.call("tag", "add", "--message-id="+msg.ID, "--tag="+tag); ok {
+			m.report("tagged " + tag + ": " + msg.Subject)
+		}
+	}}
+}
+
+func (m *tuiModel) reply() {
+	if _, ok := m.selected(); !ok {
+		return
+	}
+	if (m.open == nil || m.open.ID != m.messages[m.cur].ID) && !m.openSelected() {
+		return
+	}
+	orig := *m.open
+	var template strings.Builder
+	fmt.Fprintf(&template, "\n\nOn %s, %s wrote:\n", orig.Date, orig.From)
+	for _, line := range strings.Split(strings.TrimRight(tuiText(orig.Body), "\n"), "\n") {
+		template.WriteString(strings.TrimRight("> "+line, " ") + "\n")
+	}
+	body, ok := m.editText(template.String())
+	if !ok {
+		return
+	}
+	data, ok := m.call("message", "follow-up", "--message-id="+orig.ID, "--body="+body, "--idempotency-key="+tuiIdempotencyKey("follow-up", orig.ID, body))
+	if ok {
+		m.report("reply draft saved: " + tuiDraftSummary(data))
+	}
+}
+
+func (m *tuiModel) compose() {
+	text, ok := m.editText("To: \nSubject: \n\n")
+	if !ok {
+		return
+	}
+	to, subject, body := parseComposedMessage(text)
+	if len(to) == 0 {
+		m.status = "compose cancelled: no recipients"
+		return
+	}
+	words := []string{"draft", "create", "--subject=" + subject, "--body=" + body}
+	for _, addr := range to {
+		words = append(words, "--to="+addr)
+	}
+	words = append(words, "--idempotency-key="+tuiIdempotencyKey(append([]string{"draft-create", subject, body}, to...)...))
+	if data, ok := m.call(words...); ok {
+		m.report("draft saved: " + tuiDraftSummary(data))
+	}
+}
+
+func (m *tuiModel) editText(template string) (string, bool) {
+	f, err := os.CreateTemp("", "protonmailcli-*.txt")
+	if err != nil {
+		m.status = "error: " + err.Error()
+		return "", false
+	}
+	path := f.Name()
+	defer os.Remove(path)
+	_, err = f.WriteString(template)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = m.term.edit(path)
+	}
+	if err != nil {
+		m.status = "editor failed: " + err.Error()
+		return "", false
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		m.status = "error: " + err.Error()
+		return "", false
+	}
+	if strings.TrimSpace(string(b)) == strings.TrimSpace(template) {
+		m.status = "cancelled: nothing was written"
+		return "", false
+	}
+	return string(b), true
+}
+
+func (m *tuiModel) loadState() model.State {
+	state := model.State{}
+	if _, err := os.Stat(m.g.statePath); err == nil {
+		state, _ = store.New(m.g.statePath).Load()
+	}
+	return state
+}
+
+func parseComposedMessage(text string) ([]string, string, string) {
+	var to []string
+	subject := ""
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	i := 0
+	for ; i < len(lines) && strings.TrimSpace(lines[i]) != ""; i++ {
+		name, value, _ := strings.Cut(lines[i], ":")
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "to":
+			for _, addr := range strings.Split(value, ",") {
+				if addr = strings.TrimSpace(addr); addr != "" {
+					to = append(to, addr)
+				}
+			}
+		case "subject":
+			subject = strings.TrimSpace(value)
+		}
+	}
+	body := ""
+	if i < len(lines) {
+		body = strings.Join(lines[i+1:], "\n")
+	}
+	return to, subject, body
+}
+
+func tuiIdempotencyKey(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return "tui-" + hex.EncodeToString(sum[:8])
+}
+
+func tuiDraftSummary(data any) string {
+	var out struct {
+		Draft struct {
+			ID string `json:"id"`
+		} `json:"draft"`
+		Replayed bool `json:"replayed"`
+	}
+	if b, err := json.Marshal(data); err == nil {
+		_ = json.Unmarshal(b, &out)
+	}
+	switch {
+	case out.Draft.ID != "":
+		return out.Draft.ID + " (send with: protonmailcli message send --draft-id " + out.Draft.ID + ")"
+	case out.Replayed:
+		return "already saved"
+	}
+	return "ok"
+}
+
+type tuiLayout struct {
+	width, height int
+	side, right   int
+	body, list    int
+	reader        int
+}
+
+func (m *tuiModel) layout() tuiLayout {
+	w, h := m.term.size()
+	l := tuiLayout{width: max(w, 40), height: max(h, 10)}
+	l.side = min(24, l.width/4)
+	l.right = l.width - l.side - 1
+	l.body = l.height - 3
+	l.list = max(3, l.body/3)
+	l.reader = l.body - l.list - 1
+	return l
+}
+
+func (m *tuiModel) render() []string {
+	l := m.layout()
+	title := " protonmailcli"
+	if m.mailbox != "" {
+		title += "  " + m.mailbox + fmt.Sprintf("  %d messages", len(m.messages))
+	}
+	lines := []string{tuiFit(title, l.width)}
+	side := m.sidebarLines()
+	right := append(m.listLines(l.list), strings.Repeat("─", l.right))
+	right = append(right, m.readerLines(l.right, l.reader)...)
+	for i := 0; i < l.body; i++ {
+		left, r := "", ""
+		if i < len(side) {
+			left = side[i]
+		}
+		if i < len(right) {
+			r = right[i]
+		}
+		lines = append(lines, tuiFit(left, l.side)+"│"+tuiFit(r, l.right))
+	}
+	status := m.status
+	if p := m.prompt; p != nil {
+		status = p.label + p.value + "_"
+		if p.hint != "" {
+			status += "  [" + p.hint + "]"
+		}
+	}
+	return append(lines, tuiFit(status, l.width), tuiFit(tuiKeysHelp, l.width))
+}
+
+func (m *tuiModel) sidebarLines() []string {
+	lines := []string{" Mailboxes"}
+	for i, b := range m.mailboxes {
+		marker := " "
+		if i == m.box && m.sidebar {
+			marker = ">"
+		} else if b.Name == m.mailbox {
+			marker = "·"
+		}
+		label := b.Name
+		if b.Unread != nil && *b.Unread > 0 {
+			label += fmt.Sprintf(" (%d)", *b.Unread)
+		}
+		lines = append(lines, marker+" "+label)
+	}
+	return lines
+}
+
+func (m *tuiModel) listLines(height int) []string {
+	if len(m.messages) == 0 {
+		return []string{"  (no messages)"}
+	}
+	top := max(0, m.cur-height+1)
+	var lines []string
+	for i := top; i < len(m.messages) && len(lines) < height; i++ {
+		msg := m.messages[i]
+		marker, unread := " ", " "
+		if i == m.cur && !m.sidebar {
+			marker = ">"
+		}
+		if msg.Unread {
+			unread = "●"
+		}
+		date := msg.Date
+		if len(date) > 10 {
+			date = date[:10]
+		}
+		lines = append(lines, fmt.Sprintf("%s%s %s  %-10s  %s", marker, unread, tuiFit(msg.From, 22), tuiLine(date), tuiLine(msg.Subject)))
+	}
+	return lines
+}
+
+func (m *tuiModel) readerLines(width, height int) []string {
+	if m.open == nil {
+		return []string{"  press enter to read the selected message"}
+	}
+	text := fmt.Sprintf("From: %s\nTo: %s\nDate: %s\nSubject: %s\n\n%s", tuiLine(m.open.From), tuiLine(strings.Join(m.open.To, ", ")), tuiLine(m.open.Date), tuiLine(m.open.Subject), tuiText(m.open.Body))
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		lines = append(lines, tuiWrap(line, width)...)
+	}
+	m.scroll = max(0, min(m.scroll, len(lines)-height))
+	return lines[m.scroll:min(len(lines), m.scroll+height)]
+}
+
+func tuiText(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\t", "    ")
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || unicode.IsPrint(r) {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+func tuiLine(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return ' '
+		}
+		if unicode.IsPrint(r) {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+func tuiFit(s string, width int) string {
+	s = tuiLine(s)
+	r := []rune(s)
+	if len(r) > width {
+		if width <= 1 {
+			return string(r[:max(0, width)])
+		}
+		return string(r[:width-1]) + "…"
+	}
+	return s + strings.Repeat(" ", width-len(r))
+}
+
+func tuiWrap(line string, width int) []string {
+	if width <= 0 {
+		return []string{line}
+	}
+	var out []string
+	cur := []rune{}
+	for _, word := range strings.SplitAfter(line, " ") {
+		w := []rune(word)
+		if len(cur)+len(strings.TrimRight(word, " ")) > width && len(cur) > 0 {
+			out = append(out, strings.TrimRight(string(cur), " "))
+			cur = cur[:0]
+		}
+		for len(w) > width {
+			out = append(out, string(w[:width]))
+			w = w[width:]
+		}
+		cur = append(cur, w...)
+	}
+	return append(out, strings.TrimRight(string(cur), " "))
+}
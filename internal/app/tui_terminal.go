@@ -0,0 +1,120 @@
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+type tuiTerminal interface {
+	size() (int, int)
+	readKey() (string, error)
+	draw(lines []string)
+	edit(path string) error
+	close()
+}
+
+var openTUITerminal = func(a App) (tuiTerminal, error) {
+	in, inOK := a.Stdin.(*os.File)
+	out, outOK := a.Stdout.(*os.File)
+	if !inOK || !outOK || !isTTY(in) || !isTTY(out) {
+		return nil, cliError{exit: 2, code: "usage_error", msg: "tui needs an interactive terminal", hint: "Use protonmailcli shell or one-shot commands when stdin or stdout is not a terminal"}
+	}
+	t := &ttyTerminal{in: bufio.NewReader(in), file: in, out: out}
+	if err := t.enter(); err != nil {
+		return nil, cliError{exit: 1, code: "runtime_error", msg: "cannot switch the terminal to raw mode: " + err.Error()}
+	}
+	return t, nil
+}
+
+type ttyTerminal struct {
+	in      *bufio.Reader
+	file    *os.File
+	out     io.Writer
+	restore func()
+}
+
+func (t *ttyTerminal) enter() error {
+	restore, err := sttyRaw(t.file)()
+	if err != nil {
+		return err
+	}
+	t.restore = restore
+	fmt.Fprint(t.out, "\x1b[?1049h\x1b[?25l")
+	return nil
+}
+
+func (t *ttyTerminal) leave() {
+	fmt.Fprint(t.out, "\x1b[?25h\x1b[?1049l")
+	if t.restore != nil {
+		t.restore()
+		t.restore = nil
+	}
+}
+
+func (t *ttyTerminal) close() {
+	t.leave()
+}
+
+func (t *ttyTerminal) size() (int, int) {
+	var rows, cols int
+	if out, err := stty(t.file, "size"); err == nil {
+		fmt.Sscan(out, &rows, &cols)
+	}
+	if rows <= 0 || cols <= 0 {
+		return 80, 24
+	}
+	return cols, rows
+}
+
+func (t *ttyTerminal) readKey() (string, error) {
+	return readKey(t.in)
+}
+
+func (t *ttyTerminal) draw(lines []string) {
+	var b strings.Builder
+	b.WriteString("\x1b[H")
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString(line)
+		b.WriteString("\x1b[K")
+	}
+	b.WriteString("\x1b[J")
+	_, _ = io.WriteString(t.out, b.String())
+}
+
+func (t *ttyTerminal) edit(path string) error {
+	t.leave()
+	defer func() { _ = t.enter() }()
+	editor := strings.Fields(firstNonEmpty(os.Getenv("VISUAL"), os.Getenv("EDITOR"), "vi"))
+	cmd := exec.Command(editor[0], append(editor[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = t.file, t.out, t.out
+	return cmd.Run()
+}
+
+func readKey(in *bufio.Reader) (string, error) {
+	r, _, err := in.ReadRune()
+	if err != nil {
+		return "", err
+	}
+	switch r {
+	case '\r', '\n':
+		return "enter", nil
+	case keyTab:
+		return "tab", nil
+	case keyDelete, keyBackspace:
+		return "backspace", nil
+	case keyCtrlC:
+		return "ctrl-c", nil
+	case keyCtrlL:
+		return "ctrl-l", nil
+	case keyEscape:
+		return readEscapeKey(in), nil
+	}
+	return string(r), nil
+}
@@ -0,0 +1,259 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"protonmailcli/internal/model"
+	"protonmailcli/internal/store"
+)
+
+type simulatedTerminal struct {
+	width, height int
+	in            *bufio.Reader
+	frames        [][]string
+	editFn        func(path string) error
+	closed        bool
+}
+
+func (t *simulatedTerminal) size() (int, int)         { return t.width, t.height }
+func (t *simulatedTerminal) readKey() (string, error) { return readKey(t.in) }
+func (t *simulatedTerminal) draw(lines []string)      { t.frames = append(t.frames, lines) }
+func (t *simulatedTerminal) close()                   { t.closed = true }
+
+func (t *simulatedTerminal) edit(path string) error {
+	if t.editFn == nil {
+		return nil
+	}
+	return t.editFn(path)
+}
+
+func (t *simulatedTerminal) screen(i int) string {
+	if i < 0 {
+		i += len(t.frames)
+	}
+	return strings.Join(t.frames[i], "\n")
+}
+
+func setupTUIState(t *testing.T) (string, string) {
+	t.Helper()
+	t.Setenv("PMAIL_USE_LOCAL_STATE", "1")
+	tmp := t.TempDir()
+	cfg := filepath.Join(tmp, "config.toml")
+	state := filepath.Join(tmp, "state.json")
+	if exit := Run([]string{"--json", "--config", cfg, "--state", state, "setup", "--non-interactive", "--username", "me@example.com"}, bytes.NewBuffer(nil), &bytes.Buffer{}, &bytes.Buffer{}); exit != 0 {
+		t.Fatalf("setup failed: %d", exit)
+	}
+	now := time.Now().UTC()
+	if err := store.New(state).Save(model.State{
+		Messages: map[string]model.Message{
+			"m_1": {ID: "m_1", From: "alice@example.com", To: []string{"me@example.com"}, Subject: "Quarterly report", Body: "Numbers attached.\r\n\tThanks", SentAt: now.Add(-time.Hour)},
+			"m_2": {ID: "m_2", From: "bob@example.com", To: []string{"me@example.com"}, Subject: "Lunch?", Flags: []string{flagSeen}, SentAt: now.Add(-2 * time.Hour)},
+			"m_3": {ID: "m_3", From: "carol@example.com", Subject: "Old thread", Mailbox: "Archive", Flags: []string{flagSeen}, SentAt: now.Add(-48 * time.Hour)},
+		},
+		Tags:    map[string]string{"urgent": "t_1"},
+		Folders: map[string]model.Folder{"Clients": {Name: "Clients", Subscribed: true}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return cfg, state
+}
+
+func runTUI(t *testing.T, cfg, state, keys string, edit func(string) error, extra ...string) *simulatedTerminal {
+	t.Helper()
+	term := &simulatedTerminal{width: 100, height: 24, in: bufio.NewReader(strings.NewReader(keys)), editFn: edit}
+	prev := openTUITerminal
+	openTUITerminal = func(App) (tuiTerminal, error) { return term, nil }
+	defer func() { openTUITerminal = prev }()
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	args := append([]string{"--config", cfg, "--state", state}, extra...)
+	if exit := Run(append(args, "tui"), bytes.NewBuffer(nil), stdout, stderr); exit != 0 {
+		t.Fatalf("tui exited %d: %s %s", exit, stdout.String(), stderr.String())
+	}
+	if !term.closed {
+		t.Fatal("terminal was not restored")
+	}
+	if stdout.Len() > 0 || stderr.Len() > 0 {
+		t.Fatalf("tui must not write around the screen: %q %q", stdout.String(), stderr.String())
+	}
+	return term
+}
+
+func loadTestState(t *testing.T, path string) model.State {
+	t.Helper()
+	st, err := store.New(path).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+func TestTUIRendersSidebarListAndReadingPane(t *testing.T) {
+	cfg, state := setupTUIState(t)
+	term := runTUI(t, cfg, state, "\r"+"\t\x1b[B\x1b[B\x1b[B\r"+"q", nil)
+	first := term.screen(0)
+	for _, want := range []string{"· INBOX (1)", "Clients", "● alice@example.com", "Quarterly report", "bob@example.com", "press enter to read"} {
+		if !strings.Contains(first, want) {
+			t.Fatalf("first frame missing %q:\n%s", want, first)
+		}
+	}
+	if strings.Contains(first, "Old thread") {
+		t.Fatalf("archived message must not be listed in INBOX:\n%s", first)
+	}
+	for _, frame := range term.frames {
+		if len(frame) != 24 {
+			t.Fatalf("frame has %d lines, want 24", len(frame))
+		}
+		for _, line := range frame {
+			if n := len([]rune(line)); n != 100 {
+				t.Fatalf("line is %d columns, want 100: %q", n, line)
+			}
+		}
+	}
+	opened := term.screen(1)
+	for _, want := range []string{"From: alice@example.com", "Subject: Quarterly report", "Numbers attached.", "    Thanks", ">  alice@example.com"} {
+		if !strings.Contains(opened, want) {
+			t.Fatalf("reading pane missing %q:\n%s", want, opened)
+		}
+	}
+	if st := loadTestState(t, state); !contains(st.Messages["m_1"].Flags, flagSeen) {
+		t.Fatal("opening a message should mark it read through message get --mark-read")
+	}
+	archive := term.screen(-1)
+	if !strings.Contains(archive, "Old thread") || !strings.Contains(archive, "Archive: 1 messages") {
+		t.Fatalf("selecting Archive in the sidebar should load it:\n%s", archive)
+	}
+}
+
+func TestTUITagMoveAndArchive(t *testing.T) {
+	cfg, state := setupTUIState(t)
+	keys := "turg\t\r" + "a" + "mCl\t\r" + "q"
+	term := runTUI(t, cfg, state, keys, nil)
+	st := loadTestState(t, state)
+	if !contains(st.Messages["m_1"].Tags, "urgent") {
+		t.Fatalf("m_1 should be tagged: %+v", st.Messages["m_1"])
+	}
+	if st.Messages["m_1"].Mailbox != "Archive" || st.Messages["m_2"].Mailbox != "Clients" {
+		t.Fatalf("unexpected mailboxes: m_1=%q m_2=%q", st.Messages["m_1"].Mailbox, st.Messages["m_2"].Mailbox)
+	}
+	if prompt := term.screen(5); !strings.Contains(prompt, "Tag: urgent_") {
+		t.Fatalf("tag prompt should complete from known tags:\n%s", prompt)
+	}
+	last := term.screen(-1)
+	if !strings.Contains(last, "moved to Clients: Lunch?") || !strings.Contains(last, "(no messages)") || !strings.Contains(last, "Archive (1)") {
+		t.Fatalf("unexpected final frame:\n%s", last)
+	}
+}
+
+func TestTUIDryRunKeepsState(t *testing.T) {
+	cfg, state := setupTUIState(t)
+	term := runTUI(t, cfg, state, "aq", nil, "--dry-run")
+	if st := loadTestState(t, state); st.Messages["m_1"].Mailbox != "" {
+		t.Fatalf("dry-run archive must not move m_1: %q", st.Messages["m_1"].Mailbox)
+	}
+	if last := term.screen(-1); !strings.Contains(last, "dry-run: archived: Quarterly report") {
+		t.Fatalf("missing dry-run status:\n%s", last)
+	}
+}
+
+func TestTUIReplyAndComposeUseEditor(t *testing.T) {
+	cfg, state := setupTUIState(t)
+	var templates []string
+	edits := []string{
+		"Sounds good\n",
+		"To: dave@example.com, erin@example.com\nSubject: Hello\n\nHi both\n",
+		"To: dave@example.com, erin@example.com\nSubject: Hello\n\nHi both\n",
+		"",
+	}
+	edit := func(path string) error {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		templates = append(templates, string(b))
+		next := edits[0]
+		edits = edits[1:]
+		if next == "" {
+			return nil
+		}
+		if len(templates) == 1 {
+			next += string(b)
+		}
+		return os.WriteFile(path, []byte(next), 0o600)
+	}
+	term := runTUI(t, cfg, state, "rcccq", edit)
+	if len(templates) != 4 || !strings.Contains(templates[0], "alice@example.com wrote:\n> Numbers attached.\n>     Thanks\n") || templates[1] != "To: \nSubject: \n\n" {
+		t.Fatalf("unexpected editor templates: %q", templates)
+	}
+	st := loadTestState(t, state)
+	var reply, composed []model.Draft
+	for _, d := range st.Drafts {
+		if d.Subject == "Re: Quarterly report" {
+			reply = append(reply, d)
+		} else if d.Subject == "Hello" {
+			composed = append(composed, d)
+		}
+	}
+	if len(reply) != 1 || !strings.HasPrefix(reply[0].Body, "Sounds good") || strings.Join(reply[0].To, ",") != "alice@example.com" {
+		t.Fatalf("reply should go through follow-up: %+v", reply)
+	}
+	if len(composed) != 1 || strings.Join(composed[0].To, ",") != "dave@example.com,erin@example.com" || composed[0].Body != "Hi both\n" {
+		t.Fatalf("saving the same compose twice must create one draft: %+v", composed)
+	}
+	if len(st.Messages) != 3 {
+		t.Fatal("compose and reply must not send anything")
+	}
+	if !strings.Contains(term.screen(1), "reply draft saved: ") || !strings.Contains(term.screen(1), "message send --draft-id") {
+		t.Fatalf("missing reply status:\n%s", term.screen(1))
+	}
+	if !strings.Contains(term.screen(-1), "cancelled: nothing was written") {
+		t.Fatalf("an untouched template should cancel:\n%s", term.screen(-1))
+	}
+}
+
+func TestTUIStripsControlCharacters(t *testing.T) {
+	cfg, state := setupTUIState(t)
+	st := loadTestState(t, state)
+	st.Messages["m_4"] = model.Message{ID: "m_4", From: "evil\x1b]0;pwned\x07@example.com", Subject: "hi\x1b[2J\nFrom: ceo@example.com", Body: "body\x1b[1A", SentAt: time.Now().UTC()}
+	if err := store.New(state).Save(st); err != nil {
+		t.Fatal(err)
+	}
+	term := runTUI(t, cfg, state, "\r"+"aq", nil)
+	for i := range term.frames {
+		if screen := term.screen(i); strings.ContainsAny(screen, "\x1b\x07") {
+			t.Fatalf("frame %d leaks control characters: %q", i, screen)
+		}
+	}
+	if opened := term.screen(1); !strings.Contains(opened, "Subject: hi[2J From: ceo@example.com") || !strings.Contains(term.screen(-1), "archived: hi[2J From: ceo@example.com") {
+		t.Fatalf("header fields must stay on one line:\n%s\n%s", opened, term.screen(-1))
+	}
+}
+
+func TestTUIRequiresTerminal(t *testing.T) {
+	cfg, state := setupTUIState(t)
+	stdout := &bytes.Buffer{}
+	if exit := Run([]string{"--json", "--config", cfg, "--state", state, "tui"}, bytes.NewBuffer(nil), stdout, &bytes.Buffer{}); exit != 2 {
+		t.Fatalf("expected usage exit, got %d", exit)
+	}
+	if !strings.Contains(stdout.String(), "tui needs an interactive terminal") {
+		t.Fatalf("unexpected output: %s", stdout.String())
+	}
+}
+
+func TestParseComposedMessage(t *testing.T) {
+	to, subject, body := parseComposedMessage("To: a@example.com,\r\nsubject:  Hi \r\nX-Ignored: y\r\n\r\nline one\r\n\r\nline two")
+	if strings.Join(to, ",") != "a@example.com" || subject != "Hi" || body != "line one\n\nline two" {
+		t.Fatalf("got %q %q %q", to, subject, body)
+	}
+	if got := tuiWrap("the quick brown fox", 9); strings.Join(got, "|") != "the quick|brown fox" {
+		t.Fatalf("wrap: %q", got)
+	}
+	if got := tuiFit("abcdef", 4); got != "abc…" {
+		t.Fatalf("fit: %q", got)
+	}
+}
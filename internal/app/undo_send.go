@@ -0,0 +1,91 @@
+package app
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"protonmailcli/internal/config"
+	"protonmailcli/internal/model"
+)
+
+func undoSendWindow(cfg config.Config, g globalOptions) time.Duration {
+	if g.fromOutbox || g.dryRun || cfg.Safety.UndoSendSeconds <= 0 {
+		return 0
+	}
+	return time.Duration(cfg.Safety.UndoSendSeconds) * time.Second
+}
+
+const undoSendFlushCommand = "protonmailcli outbox flush"
+
+func newUndoToken() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("u_%d", time.Now().UnixNano())
+	}
+	return "u_" + hex.EncodeToString(b)
+}
+
+func enqueueUndoSend(st *model.State, req scheduleRequest, window time.Duration) model.OutboxItem {
+	item := newOutboxItem(req, time.Now().UTC().Add(window), "send-late")
+	item.UndoToken = newUndoToken()
+	st.Outbox[item.ID] = item
+	return item
+}
+
+func pendingSendResponse(item model.OutboxItem, draftID, source string) messagePendingSendResponse {
+	return messagePendingSendResponse{
+		Sent:         false,
+		Pending:      true,
+		DraftID:      draftID,
+		OutboxID:     item.ID,
+		PendingUntil: item.At.Format(time.RFC3339),
+		UndoToken:    item.UndoToken,
+		FlushWith:    undoSendFlushCommand,
+		Source:       source,
+	}
+}
+
+func parseMessageUndoSendFlags(args []string) (string, error) {
+	fs := newFlagSet("message undo-send")
+	token := fs.String("token", "", "undo token returned by message send")
+	if err := parseFlags(fs, args); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(*token) == "" {
+		return "", cliError{exit: 2, code: "validation_error", msg: "--token required"}
+	}
+	return strings.TrimSpace(*token), nil
+}
+
+func cmdMessageUndoSend(token string, g globalOptions, st *model.State) (any, bool, error) {
+	var item model.OutboxItem
+	found := false
+	for _, it := range st.Outbox {
+		if it.UndoToken != "" && it.UndoToken == token {
+			item, found = it, true
+			break
+		}
+	}
+	if !found {
+		return nil, false, cliError{exit: 5, code: "not_found", msg: "no pending send for undo token"}
+	}
+	resp := messageUndoSendResponse{Undone: true, Token: token, OutboxID: item.ID, DraftID: item.DraftID, PendingUntil: item.At.Format(time.RFC3339)}
+	if item.Status == "canceled" {
+		return resp, false, nil
+	}
+	if item.Status != "queued" || !time.Now().Before(item.At) {
+		return nil, false, cliError{exit: 6, code: "undo_window_expired", msg: "undo window closed at " + resp.PendingUntil + " (status " + item.Status + ")", hint: "Sent mail cannot be recalled; send a follow-up instead"}
+	}
+	if g.dryRun {
+		resp.Undone = false
+		resp.DryRun = true
+		return resp, false, nil
+	}
+	item.Status = "canceled"
+	item.UpdatedAt = time.Now().UTC()
+	st.Outbox[item.ID] = item
+	return resp, true, nil
+}
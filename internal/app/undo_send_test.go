@@ -0,0 +1,121 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"protonmailcli/internal/config"
+	"protonmailcli/internal/model"
+)
+
+func undoConfig() config.Config {
+	cfg := config.Default()
+	cfg.Safety.UndoSendSeconds = 30
+	return cfg
+}
+
+func TestUndoSendCancelsWithinWindow(t *testing.T) {
+	calls := stubOutboxSend(t, nil)
+	st := outboxState()
+	cfg := undoConfig()
+	data, changed, err := cmdMessage("send", []string{"--draft-id", "d_1", "--confirm-send", "d_1"}, globalOptions{}, cfg, st)
+	if err != nil || !changed {
+		t.Fatalf("send: changed=%v err=%v", changed, err)
+	}
+	pending := data.(messagePendingSendResponse)
+	if !pending.Pending || pending.Sent || pending.UndoToken == "" || pending.FlushWith != "protonmailcli outbox flush" || *calls != 0 || len(st.Messages) != 0 {
+		t.Fatalf("send must only be recorded as pending: %+v calls=%d", pending, *calls)
+	}
+	if until, err := time.Parse(time.RFC3339, pending.PendingUntil); err != nil || time.Until(until) < 20*time.Second {
+		t.Fatalf("unexpected pendingUntil %q: %v", pending.PendingUntil, err)
+	}
+
+	data, changed, err = cmdMessage("undo-send", []string{"--token", pending.UndoToken}, globalOptions{}, cfg, st)
+	if err != nil || !changed || !data.(messageUndoSendResponse).Undone || st.Outbox[pending.OutboxID].Status != "canceled" {
+		t.Fatalf("undo: %+v changed=%v err=%v", data, changed, err)
+	}
+	if _, changed, err := cmdMessage("undo-send", []string{"--token", pending.UndoToken}, globalOptions{}, cfg, st); err != nil || changed {
+		t.Fatalf("repeated undo must be a no-op: changed=%v err=%v", changed, err)
+	}
+	item := st.Outbox[pending.OutboxID]
+	item.At = time.Now().Add(-time.Second).UTC()
+	st.Outbox[item.ID] = item
+	if _, _, err := cmdOutbox("flush", nil, globalOptions{}, cfg, st); err != nil || *calls != 0 {
+		t.Fatalf("undone send must not be flushed: calls=%d err=%v", *calls, err)
+	}
+	if _, _, err := cmdMessage("undo-send", []string{"--token", "u_missing"}, globalOptions{}, cfg, st); errorCodeFromErr(err, "") != "not_found" {
+		t.Fatalf("expected not_found, got %v", err)
+	}
+}
+
+func TestUndoSendAfterWindowIsRejectedAndFlushSends(t *testing.T) {
+	calls := stubOutboxSend(t, nil)
+	st := outboxState()
+	cfg := undoConfig()
+	data, _, err := cmdMessage("send", []string{"--draft-id", "d_1", "--confirm-send", "d_1"}, globalOptions{}, cfg, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending := data.(messagePendingSendResponse)
+	item := st.Outbox[pending.OutboxID]
+	item.At = time.Now().Add(-time.Second).UTC()
+	st.Outbox[item.ID] = item
+
+	if _, _, err := cmdMessage("undo-send", []string{"--token", pending.UndoToken}, globalOptions{}, cfg, st); errorCodeFromErr(err, "") != "undo_window_expired" {
+		t.Fatalf("expected undo_window_expired, got %v", err)
+	}
+	data, _, err = cmdOutbox("flush", nil, globalOptions{}, cfg, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp := data.(outboxRunResponse); resp.Sent != 1 || *calls != 1 || len(st.Messages) != 1 || st.Outbox[item.ID].Status != "sent" {
+		t.Fatalf("flush must submit exactly once without a new window: %+v calls=%d outbox=%+v", resp, *calls, st.Outbox)
+	}
+}
+
+func TestOutboxFlushUsesConfiguredGrace(t *testing.T) {
+	calls := stubOutboxSend(t, nil)
+	st := outboxState()
+	dueItem(st, "o_1", time.Now().Add(-30*time.Minute).UTC(), "skip")
+	cfg := config.Default()
+	cfg.Outbox.Grace = "nonsense"
+	if _, _, err := cmdOutbox("flush", nil, globalOptions{}, cfg, st); errorCodeFromErr(err, "") != "config_error" {
+		t.Fatalf("expected config_error for a bad outbox.grace, got %v", err)
+	}
+	cfg.Outbox.Grace = "1h"
+	data, _, err := cmdOutbox("flush", nil, globalOptions{}, cfg, st)
+	if err != nil || data.(outboxRunResponse).Sent != 1 || *calls != 1 {
+		t.Fatalf("an item within outbox.grace must be sent: %+v err=%v", data, err)
+	}
+}
+
+func TestSendManyUsesUndoWindowPerItem(t *testing.T) {
+	calls := stubOutboxSend(t, nil)
+	st := outboxState()
+	st.Drafts["d_2"] = model.Draft{ID: "d_2", To: []string{"b@example.com"}, Subject: "Two"}
+	manifest := filepath.Join(t.TempDir(), "send.json")
+	if err := os.WriteFile(manifest, []byte(`[{"draft_id":"d_1","confirm_send":"d_1"},{"draft_id":"d_2","confirm_send":"d_2"}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	data, _, err := cmdMessage("send-many", []string{"--file", manifest}, globalOptions{}, undoConfig(), st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := data.(batchResultResponse)
+	if resp.Success != 2 || *calls != 0 || len(st.Outbox) != 2 {
+		t.Fatalf("expected two pending items: %+v calls=%d", resp, *calls)
+	}
+	first, second := resp.Results[0], resp.Results[1]
+	if !first.Pending || first.UndoToken == "" || first.UndoToken == second.UndoToken || first.PendingUntil == "" {
+		t.Fatalf("each item needs its own undo token: %+v %+v", first, second)
+	}
+	if _, _, err := cmdMessage("undo-send", []string{"--token", second.UndoToken}, globalOptions{}, undoConfig(), st); err != nil {
+		t.Fatal(err)
+	}
+	queued := sortedOutbox(st, "queued")
+	if len(queued) != 1 || queued[0].DraftID != "d_1" {
+		t.Fatalf("undo must only cancel its own item: %+v", queued)
+	}
+}
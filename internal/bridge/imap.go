@@ -28,17 +28,57 @@ type IMAPConfig struct {
 }
 
 type DraftMessage struct {
-	UID        string
-	Mailbox    string
-	From       string
-	To         []string
-	Subject    string
-	Body       string
-	Date       time.Time
-	Flags      []string
-	MessageID  string
-	InReplyTo  string
-	References string
+	UID         string
+	Mailbox     string
+	From        string
+	To          []string
+	CC          []string
+	BCC         []string
+	Subject     string
+	Body        string
+	Date        time.Time
+	Flags       []string
+	MessageID   string
+	InReplyTo   string
+	References  string
+	Headers     []HeaderField
+	Attachments []Attachment
+}
+
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+type HeaderField struct {
+	Name  string
+	Value string
+}
+
+type MailboxStatus struct {
+	Messages    int
+	Unseen      int
+	UIDNext     uint32
+	UIDValidity uint32
+	Size        int64
+}
+
+type MailboxEntry struct {
+	Name       string
+	Delimiter  string
+	Attributes []string
+	Status     *MailboxStatus
+	StatusErr  error
+}
+
+func (e MailboxEntry) HasAttribute(attr string) bool {
+	for _, a := range e.Attributes {
+		if strings.EqualFold(a, attr) {
+			return true
+		}
+	}
+	return false
 }
 
 type IMAPClient struct {
@@ -48,14 +88,18 @@ type IMAPClient struct {
 	tag     int
 	timeout time.Duration
 	debug   bool
+	caps    map[string]bool
+	held    bool
 }
 
 var (
-	literalRe   = regexp.MustCompile(`\{(\d+)\}\r?$`)
-	uidRe       = regexp.MustCompile(`UID\s+(\d+)`)
-	flagsRe     = regexp.MustCompile(`FLAGS\s+\(([^)]*)\)`)
-	nameRe      = regexp.MustCompile(`"([^"]+)"\s*$`)
-	listFlagsRe = regexp.MustCompile(`^\* LIST \(([^)]*)\)`)
+	literalRe    = regexp.MustCompile(`\{(\d+)\}\r?$`)
+	uidRe        = regexp.MustCompile(`UID\s+(\d+)`)
+	flagsRe      = regexp.MustCompile(`FLAGS\s+\(([^)]*)\)`)
+	nameRe       = regexp.MustCompile(`"([^"]+)"\s*$`)
+	listFlagsRe  = regexp.MustCompile(`^\* LIST \(([^)]*)\)`)
+	listLineRe   = regexp.MustCompile(`^\* LIST \(([^)]*)\) (NIL|"(?:\\.|[^"])*") (.+)$`)
+	statusLineRe = regexp.MustCompile(`^\* STATUS ("(?:\\.|[^"])*"|\S+) \(([^)]*)\)`)
 )
 
 func DialIMAP(cfg IMAPConfig, timeout time.Duration) (*IMAPClient, error) {
@@ -91,29 +135,191 @@ func DialIMAP(cfg IMAPConfig, timeout time.Duration) (*IMAPClient, error) {
 }
 
 func (c *IMAPClient) Close() error {
+	if c.held {
+		return nil
+	}
 	_ = c.conn.SetDeadline(time.Now().Add(500 * time.Millisecond))
 	_, _ = c.w.WriteString("ZZZZ LOGOUT\r\n")
 	_ = c.w.Flush()
 	return c.conn.Close()
 }
 
+func (c *IMAPClient) Hold() {
+	c.held = true
+}
+
+func (c *IMAPClient) Release() error {
+	c.held = false
+	return c.Close()
+}
+
+func (c *IMAPClient) Noop() error {
+	if err := c.conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+		return err
+	}
+	return c.simple("NOOP")
+}
+
 func (c *IMAPClient) ListMailboxes() ([]string, error) {
+	entries, err := c.ListMailboxEntries()
+	if err != nil {
+		return nil, err
+	}
+	boxes := make([]string, 0, len(entries))
+	for _, e := range entries {
+		boxes = append(boxes, e.Name)
+	}
+	return boxes, nil
+}
+
+func (c *IMAPClient) ListMailboxEntries() ([]MailboxEntry, error) {
 	lines, err := c.simpleLines(`LIST "" "*"`)
 	if err != nil {
 		return nil, err
 	}
-	boxes := make([]string, 0, len(lines))
+	entries := make([]MailboxEntry, 0, len(lines))
 	for _, line := range lines {
-		if !strings.HasPrefix(line, "* LIST") {
+		if e, ok := parseListLine(line); ok {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries, nil
+}
+
+func (c *IMAPClient) ListMailboxesWithStatus() ([]MailboxEntry, error) {
+	items := "MESSAGES UNSEEN UIDNEXT UIDVALIDITY"
+	if c.HasCapability("STATUS=SIZE") {
+		items += " SIZE"
+	}
+	if c.HasCapability("LIST-STATUS") {
+		lines, err := c.simpleLines(fmt.Sprintf(`LIST "" "*" RETURN (STATUS (%s))`, items))
+		if err != nil {
+			return nil, err
+		}
+		entries := []MailboxEntry{}
+		statuses := map[string]MailboxStatus{}
+		for _, line := range lines {
+			if e, ok := parseListLine(line); ok {
+				entries = append(entries, e)
+			} else if name, st, ok := parseStatusLine(line); ok {
+				statuses[name] = st
+			}
+		}
+		for i := range entries {
+			if st, ok := statuses[entries[i].Name]; ok {
+				entries[i].Status = &st
+			}
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+		return entries, nil
+	}
+	entries, err := c.ListMailboxEntries()
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if entries[i].HasAttribute(`\Noselect`) || entries[i].HasAttribute(`\NonExistent`) {
 			continue
 		}
-		m := nameRe.FindStringSubmatch(line)
-		if len(m) == 2 {
-			boxes = append(boxes, m[1])
+		lines, err := c.simpleLines(fmt.Sprintf(`STATUS "%s" (%s)`, escape(entries[i].Name), items))
+		if err != nil {
+			entries[i].StatusErr = err
+			continue
+		}
+		for _, line := range lines {
+			if _, st, ok := parseStatusLine(line); ok {
+				entries[i].Status = &st
+			}
 		}
 	}
-	sort.Strings(boxes)
-	return boxes, nil
+	return entries, nil
+}
+
+func parseListLine(line string) (MailboxEntry, bool) {
+	m := listLineRe.FindStringSubmatch(line)
+	if m == nil {
+		return MailboxEntry{}, false
+	}
+	e := MailboxEntry{Attributes: strings.Fields(m[1]), Name: unquoteIMAP(m[3])}
+	if m[2] != "NIL" {
+		e.Delimiter = unquoteIMAP(m[2])
+	}
+	return e, true
+}
+
+func parseStatusLine(line string) (string, MailboxStatus, bool) {
+	m := statusLineRe.FindStringSubmatch(line)
+	if m == nil {
+		return "", MailboxStatus{}, false
+	}
+	st := MailboxStatus{}
+	fields := strings.Fields(m[2])
+	for i := 0; i+1 < len(fields); i += 2 {
+		n, err := strconv.ParseInt(fields[i+1], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch strings.ToUpper(fields[i]) {
+		case "MESSAGES":
+			st.Messages = int(n)
+		case "UNSEEN":
+			st.Unseen = int(n)
+		case "UIDNEXT":
+			st.UIDNext = uint32(n)
+		case "UIDVALIDITY":
+			st.UIDValidity = uint32(n)
+		case "SIZE":
+			st.Size = n
+		}
+	}
+	return unquoteIMAP(m[1]), st, true
+}
+
+func unquoteIMAP(v string) string {
+	v = strings.TrimSpace(v)
+	if len(v) >= 2 && strings.HasPrefix(v, `"`) && strings.HasSuffix(v, `"`) {
+		v = v[1 : len(v)-1]
+		v = strings.ReplaceAll(v, `\"`, `"`)
+		v = strings.ReplaceAll(v, `\\`, `\`)
+	}
+	return v
+}
+
+func (c *IMAPClient) HierarchyDelimiter() (string, error) {
+	lines, err := c.simpleLines(`LIST "" ""`)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range lines {
+		if e, ok := parseListLine(line); ok {
+			return e.Delimiter, nil
+		}
+	}
+	return "", nil
+}
+
+func (c *IMAPClient) CreateMailbox(name string) error {
+	return c.simple(fmt.Sprintf(`CREATE "%s"`, escape(name)))
+}
+
+func (c *IMAPClient) RenameMailbox(oldName, newName string) error {
+	return c.simple(fmt.Sprintf(`RENAME "%s" "%s"`, escape(oldName), escape(newName)))
+}
+
+func (c *IMAPClient) DeleteMailbox(name string) error {
+	if !strings.EqualFold(name, "INBOX") {
+		_ = c.selectMailbox("INBOX")
+	}
+	return c.simple(fmt.Sprintf(`DELETE "%s"`, escape(name)))
+}
+
+func (c *IMAPClient) SubscribeMailbox(name string, subscribe bool) error {
+	cmd := "SUBSCRIBE"
+	if !subscribe {
+		cmd = "UNSUBSCRIBE"
+	}
+	return c.simple(fmt.Sprintf(`%s "%s"`, cmd, escape(name)))
 }
 
 func (c *IMAPClient) ListDrafts() ([]DraftMessage, error) {
@@ -125,6 +331,14 @@ func (c *IMAPClient) ListDrafts() ([]DraftMessage, error) {
 }
 
 func (c *IMAPClient) ListMessages(mailbox, criteria string) ([]DraftMessage, error) {
+	return c.listMessages(mailbox, criteria, "BODY.PEEK[]")
+}
+
+func (c *IMAPClient) ListMessageHeaders(mailbox, criteria string) ([]DraftMessage, error) {
+	return c.listMessages(mailbox, criteria, "BODY.PEEK[HEADER]")
+}
+
+func (c *IMAPClient) listMessages(mailbox, criteria, section string) ([]DraftMessage, error) {
 	if err := c.selectMailbox(mailbox); err != nil {
 		return nil, err
 	}
@@ -133,17 +347,102 @@ func (c *IMAPClient) ListMessages(mailbox, criteria string) ([]DraftMessage, err
 		return nil, err
 	}
 	msgs := make([]DraftMessage, 0, len(uids))
-	for _, uid := range uids {
-		m, err := c.fetchUID(mailbox, uid)
+	for start := 0; start < len(uids); start += messageCursorBatch {
+		fetched, _, err := c.fetchUIDs(mailbox, uids[start:min(start+messageCursorBatch, len(uids))], section)
 		if err != nil {
-			continue
+			return nil, err
+		}
+		for _, m := range fetched {
+			msgs = append(msgs, m)
 		}
-		msgs = append(msgs, m)
 	}
 	sort.Slice(msgs, func(i, j int) bool { return uidInt(msgs[i].UID) < uidInt(msgs[j].UID) })
 	return msgs, nil
 }
 
+const messageCursorBatch = 50
+
+type MessageCursor struct {
+	uids    []string
+	pos     int
+	batch   int
+	fetched map[string]DraftMessage
+	fetch   func(uids []string) (map[string]DraftMessage, map[string]error, error)
+	skipped []string
+	err     error
+}
+
+func NewMessageCursor(uids []string, fetch func(uids []string) (map[string]DraftMessage, map[string]error, error)) *MessageCursor {
+	sorted := append([]string{}, uids...)
+	sort.Slice(sorted, func(i, j int) bool { return uidInt(sorted[i]) > uidInt(sorted[j]) })
+	return &MessageCursor{uids: sorted, batch: messageCursorBatch, fetch: fetch}
+}
+
+func (c *IMAPClient) OpenMessages(mailbox, criteria string) (*MessageCursor, error) {
+	uids, err := c.SearchUIDs(mailbox, criteria)
+	if err != nil {
+		return nil, err
+	}
+	return NewMessageCursor(uids, func(batch []string) (map[string]DraftMessage, map[string]error, error) {
+		return c.fetchUIDs(mailbox, batch, "BODY.PEEK[]")
+	}), nil
+}
+
+func (m *MessageCursor) Total() int {
+	return len(m.uids)
+}
+
+func (m *MessageCursor) Remaining() int {
+	return len(m.uids) - m.pos
+}
+
+func (m *MessageCursor) Skip(n int) {
+	m.pos = min(m.pos+n, len(m.uids))
+}
+
+func (m *MessageCursor) SetBatch(n int) {
+	if n > 0 {
+		m.batch = n
+	}
+}
+
+func (m *MessageCursor) Next() (DraftMessage, bool) {
+	for m.err == nil && m.pos < len(m.uids) {
+		uid := m.uids[m.pos]
+		if _, ok := m.fetched[uid]; !ok {
+			batch := m.uids[m.pos:min(m.pos+m.batch, len(m.uids))]
+			msgs, bad, err := m.fetch(batch)
+			if err != nil {
+				m.err = err
+				return DraftMessage{}, false
+			}
+			m.fetched = map[string]DraftMessage{}
+			for _, id := range batch {
+				if _, failed := bad[id]; failed {
+					m.skipped = append(m.skipped, id)
+					continue
+				}
+				if msg, ok := msgs[id]; ok {
+					m.fetched[id] = msg
+				}
+			}
+		}
+		m.pos++
+		if msg, ok := m.fetched[uid]; ok {
+			return msg, true
+		}
+	}
+	return DraftMessage{}, false
+}
+
+func (m *MessageCursor) Err() error {
+	return m.err
+}
+
+func (m *MessageCursor) Skipped() []string {
+	return m.skipped
+}
+
 func (c *IMAPClient) GetDraft(uid string) (DraftMessage, error) {
 	mb, err := c.DraftMailboxName()
 	if err != nil {
@@ -160,12 +459,17 @@ func (c *IMAPClient) AppendDraft(raw string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	if err := c.selectMailbox(mb); err != nil {
+	return c.AppendMessage(mb, raw, nil)
+}
+
+func (c *IMAPClient) AppendMessage(mailbox, raw string, flags []string) (string, error) {
+	if err := c.selectMailbox(mailbox); err != nil {
 		return "", err
 	}
 	tag := c.nextTag()
-	cmd := fmt.Sprintf("%s APPEND \"%s\" () {%d}\r\n", tag, escape(mb), len(raw))
-	c.debugf("C: %s APPEND \"%s\" () {%d}", tag, mb, len(raw))
+	flagList := strings.Join(flags, " ")
+	cmd := fmt.Sprintf("%s APPEND \"%s\" (%s) {%d}\r\n", tag, escape(mailbox), flagList, len(raw))
+	c.debugf("C: %s APPEND \"%s\" (%s) {%d}", tag, mailbox, flagList, len(raw))
 	if _, err := c.w.WriteString(cmd); err != nil {
 		return "", err
 	}
@@ -194,7 +498,7 @@ func (c *IMAPClient) AppendDraft(raw string) (string, error) {
 			return "", err
 		}
 		if strings.HasPrefix(line, tag+" OK") {
-			if err := c.selectMailbox(mb); err != nil {
+			if err := c.selectMailbox(mailbox); err != nil {
 				return "", err
 			}
 			uids, err := c.searchUID("ALL")
@@ -214,16 +518,53 @@ func (c *IMAPClient) DeleteDraft(uid string) error {
 	if err != nil {
 		return err
 	}
-	if err := c.selectMailbox(mb); err != nil {
+	return c.DeleteUID(mb, uid)
+}
+
+func (c *IMAPClient) DeleteUID(mailbox, uid string) error {
+	return c.ExpungeUIDs(mailbox, []string{uid})
+}
+
+func (c *IMAPClient) ExpungeUIDs(mailbox string, uids []string) error {
+	if err := c.selectMailbox(mailbox); err != nil {
+		return err
+	}
+	return c.expungeSelected(uids)
+}
+
+func (c *IMAPClient) checkExpungeSafe(uids []string) error {
+	if c.HasCapability("UIDPLUS") {
+		return nil
+	}
+	others, err := c.searchUID("DELETED NOT UID " + UIDSet(uids))
+	if err != nil {
+		return err
+	}
+	if len(others) > 0 {
+		return fmt.Errorf("server lacks UIDPLUS and %d other message(s) in this mailbox are already marked \\Deleted; a plain EXPUNGE would remove them too", len(others))
+	}
+	return nil
+}
+
+func (c *IMAPClient) expungeSelected(uids []string) error {
+	if err := c.checkExpungeSafe(uids); err != nil {
 		return err
 	}
-	if err := c.simple(fmt.Sprintf("UID STORE %s +FLAGS.SILENT (\\\\Deleted)", uid)); err != nil {
+	set := UIDSet(uids)
+	if err := c.simple(fmt.Sprintf(`UID STORE %s +FLAGS.SILENT (\Deleted)`, set)); err != nil {
 		return err
 	}
+	if c.HasCapability("UIDPLUS") {
+		return c.simple("UID EXPUNGE " + set)
+	}
 	return c.simple("EXPUNGE")
 }
 
 func (c *IMAPClient) SetKeyword(mailbox, uid, keyword string, add bool) error {
+	return c.StoreFlags(mailbox, []string{uid}, []string{keyword}, add)
+}
+
+func (c *IMAPClient) StoreFlags(mailbox string, uids []string, flags []string, add bool) error {
 	if err := c.selectMailbox(mailbox); err != nil {
 		return err
 	}
@@ -231,7 +572,33 @@ func (c *IMAPClient) SetKeyword(mailbox, uid, keyword string, add bool) error {
 	if !add {
 		op = "-FLAGS.SILENT"
 	}
-	return c.simple(fmt.Sprintf("UID STORE %s %s (%s)", uid, op, keyword))
+	return c.simple(fmt.Sprintf("UID STORE %s %s (%s)", UIDSet(uids), op, strings.Join(flags, " ")))
+}
+
+func (c *IMAPClient) FetchFlags(mailbox string, uids []string) (map[string][]string, error) {
+	if err := c.selectMailbox(mailbox); err != nil {
+		return nil, err
+	}
+	lines, err := c.simpleLines(fmt.Sprintf("UID FETCH %s (UID FLAGS)", UIDSet(uids)))
+	if err != nil {
+		return nil, err
+	}
+	out := map[string][]string{}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "*") || !strings.Contains(line, "FETCH") {
+			continue
+		}
+		um := uidRe.FindStringSubmatch(line)
+		if len(um) != 2 {
+			continue
+		}
+		flags := []string{}
+		if fm := flagsRe.FindStringSubmatch(line); len(fm) == 2 {
+			flags = strings.Fields(strings.TrimSpace(fm[1]))
+		}
+		out[um[1]] = flags
+	}
+	return out, nil
 }
 
 func (c *IMAPClient) startTLS(serverName string) error {
@@ -280,53 +647,90 @@ func (c *IMAPClient) searchUID(criteria string) ([]string, error) {
 }
 
 func (c *IMAPClient) fetchUID(mailbox, uid string) (DraftMessage, error) {
+	return c.fetchUIDSection(mailbox, uid, "BODY.PEEK[]")
+}
+
+func (c *IMAPClient) fetchUIDSection(mailbox, uid, section string) (DraftMessage, error) {
+	msgs, bad, err := c.fetchUIDs(mailbox, []string{uid}, section)
+	if err != nil {
+		return DraftMessage{}, err
+	}
+	if err := bad[uid]; err != nil {
+		return DraftMessage{}, err
+	}
+	msg, ok := msgs[uid]
+	if !ok {
+		return DraftMessage{}, fmt.Errorf("empty message")
+	}
+	return msg, nil
+}
+
+func (c *IMAPClient) fetchUIDs(mailbox string, uids []string, section string) (map[string]DraftMessage, map[string]error, error) {
 	tag := c.nextTag()
-	cmd := fmt.Sprintf("%s UID FETCH %s (UID FLAGS RFC822)\r\n", tag, uid)
+	cmd := fmt.Sprintf("%s UID FETCH %s (UID FLAGS %s)\r\n", tag, strings.Join(uids, ","), section)
 	if _, err := c.w.WriteString(cmd); err != nil {
-		return DraftMessage{}, err
+		return nil, nil, err
 	}
 	if err := c.w.Flush(); err != nil {
-		return DraftMessage{}, err
+		return nil, nil, err
 	}
-	var raw []byte
-	var flags []string
+	raws := map[string][]byte{}
+	flags := map[string][]string{}
 	for {
 		line, err := c.readLine()
 		if err != nil {
-			return DraftMessage{}, err
+			return nil, nil, err
 		}
 		if strings.HasPrefix(line, "*") && strings.Contains(line, "FETCH") {
-			if fm := flagsRe.FindStringSubmatch(line); len(fm) == 2 {
-				flags = strings.Fields(strings.TrimSpace(fm[1]))
-			}
+			var raw []byte
 			if lm := literalRe.FindStringSubmatch(line); len(lm) == 2 {
 				n, _ := strconv.Atoi(lm[1])
-				buf := make([]byte, n)
-				if _, err := io.ReadFull(c.r, buf); err != nil {
-					return DraftMessage{}, err
+				raw = make([]byte, n)
+				if _, err := io.ReadFull(c.r, raw); err != nil {
+					return nil, nil, err
 				}
-				raw = buf
-				_, _ = c.readLine()
+				rest, err := c.readLine()
+				if err != nil {
+					return nil, nil, err
+				}
+				line += " " + rest
+			}
+			um := uidRe.FindStringSubmatch(line)
+			if len(um) != 2 {
+				continue
+			}
+			if fm := flagsRe.FindStringSubmatch(line); len(fm) == 2 {
+				flags[um[1]] = strings.Fields(strings.TrimSpace(fm[1]))
+			}
+			if raw != nil {
+				raws[um[1]] = raw
 			}
 			continue
 		}
 		if strings.HasPrefix(line, tag+" OK") {
-			msg, err := parseRawMessage(raw)
-			if err != nil {
-				return DraftMessage{}, err
-			}
-			msg.UID = uid
-			msg.Mailbox = mailbox
-			msg.Flags = flags
-			return msg, nil
+			break
 		}
 		if strings.HasPrefix(line, tag+" NO") || strings.HasPrefix(line, tag+" BAD") {
-			return DraftMessage{}, fmt.Errorf("imap fetch failed: %s", line)
+			return nil, nil, fmt.Errorf("imap fetch failed: %s", line)
+		}
+	}
+	msgs := map[string]DraftMessage{}
+	bad := map[string]error{}
+	for uid, raw := range raws {
+		msg, err := ParseRawMessage(raw)
+		if err != nil {
+			bad[uid] = err
+			continue
 		}
+		msg.UID = uid
+		msg.Mailbox = mailbox
+		msg.Flags = flags[uid]
+		msgs[uid] = msg
 	}
+	return msgs, bad, nil
 }
 
-func parseRawMessage(raw []byte) (DraftMessage, error) {
+func ParseRawMessage(raw []byte) (DraftMessage, error) {
 	if len(raw) == 0 {
 		return DraftMessage{}, fmt.Errorf("empty message")
 	}
@@ -334,29 +738,63 @@ func parseRawMessage(raw []byte) (DraftMessage, error) {
 	if err != nil {
 		return DraftMessage{}, err
 	}
-	to := []string{}
-	if v := m.Header.Get("To"); v != "" {
-		if list, err := mail.ParseAddressList(v); err == nil {
-			for _, a := range list {
-				to = append(to, a.Address)
-			}
-		}
-	}
 	bodyBytes, _ := io.ReadAll(m.Body)
 	body := decodeBestBody(m.Header, bodyBytes)
 	date, _ := mail.ParseDate(m.Header.Get("Date"))
 	return DraftMessage{
-		From:       m.Header.Get("From"),
-		To:         to,
-		Subject:    m.Header.Get("Subject"),
-		Body:       body,
-		Date:       date,
-		MessageID:  m.Header.Get("Message-ID"),
-		InReplyTo:  m.Header.Get("In-Reply-To"),
-		References: m.Header.Get("References"),
+		From:        m.Header.Get("From"),
+		To:          headerAddresses(m.Header, "To"),
+		CC:          headerAddresses(m.Header, "Cc"),
+		BCC:         headerAddresses(m.Header, "Bcc"),
+		Subject:     m.Header.Get("Subject"),
+		Body:        body,
+		Date:        date,
+		MessageID:   m.Header.Get("Message-ID"),
+		InReplyTo:   m.Header.Get("In-Reply-To"),
+		References:  m.Header.Get("References"),
+		Headers:     ParseHeaderFields(raw),
+		Attachments: extractAttachments(m.Header.Get("Content-Type"), decodeByTransferEncoding(m.Header.Get("Content-Transfer-Encoding"), bodyBytes)),
 	}, nil
 }
 
+func headerAddresses(h mail.Header, name string) []string {
+	out := []string{}
+	if v := h.Get(name); v != "" {
+		if list, err := mail.ParseAddressList(v); err == nil {
+			for _, a := range list {
+				out = append(out, a.Address)
+			}
+		}
+	}
+	return out
+}
+
+func ParseHeaderFields(raw []byte) []HeaderField {
+	block := raw
+	if i := bytes.Index(block, []byte("\r\n\r\n")); i >= 0 {
+		block = block[:i]
+	} else if i := bytes.Index(block, []byte("\n\n")); i >= 0 {
+		block = block[:i]
+	}
+	var fields []HeaderField
+	for _, line := range strings.Split(strings.ReplaceAll(string(block), "\r\n", "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(fields) > 0 {
+			last := &fields[len(fields)-1]
+			last.Value = strings.TrimSpace(last.Value + " " + strings.TrimSpace(line))
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			continue
+		}
+		fields = append(fields, HeaderField{Name: strings.TrimSpace(name), Value: strings.TrimSpace(value)})
+	}
+	return fields
+}
+
 func BuildRawMessage(from string, to []string, subject, body string) string {
 	return BuildRawMessageWithHeaders(from, to, subject, body, nil)
 }
@@ -442,6 +880,18 @@ func (c *IMAPClient) debugf(format string, args ...interface{}) {
 }
 
 func (c *IMAPClient) DraftMailboxName() (string, error) {
+	return c.specialUseMailbox(`\Drafts`, "Drafts")
+}
+
+func (c *IMAPClient) SentMailboxName() (string, error) {
+	return c.specialUseMailbox(`\Sent`, "Sent")
+}
+
+func (c *IMAPClient) ArchiveMailboxName() (string, error) {
+	return c.specialUseMailbox(`\Archive`, "Archive")
+}
+
+func (c *IMAPClient) specialUseMailbox(attr, fallback string) (string, error) {
 	lines, err := c.simpleLines(`LIST "" "*"`)
 	if err != nil {
 		return "", err
@@ -455,14 +905,14 @@ func (c *IMAPClient) DraftMailboxName() (string, error) {
 			continue
 		}
 		flags := strings.Fields(strings.TrimSpace(fm[1]))
-		isDraft := false
+		matched := false
 		for _, f := range flags {
-			if strings.EqualFold(f, `\Drafts`) {
-				isDraft = true
+			if strings.EqualFold(f, attr) {
+				matched = true
 				break
 			}
 		}
-		if !isDraft {
+		if !matched {
 			continue
 		}
 		m := nameRe.FindStringSubmatch(line)
@@ -470,7 +920,7 @@ func (c *IMAPClient) DraftMailboxName() (string, error) {
 			return m[1], nil
 		}
 	}
-	return "Drafts", nil
+	return fallback, nil
 }
 
 func (c *IMAPClient) SearchUIDs(mailbox, criteria string) ([]string, error) {
@@ -481,10 +931,146 @@ func (c *IMAPClient) SearchUIDs(mailbox, criteria string) ([]string, error) {
 }
 
 func (c *IMAPClient) MoveUID(srcMailbox, uid, dstMailbox string) error {
+	return c.MoveUIDs(srcMailbox, []string{uid}, dstMailbox)
+}
+
+func (c *IMAPClient) MoveUIDs(srcMailbox string, uids []string, dstMailbox string) error {
+	if err := c.selectMailbox(srcMailbox); err != nil {
+		return err
+	}
+	set := UIDSet(uids)
+	if c.HasCapability("MOVE") {
+		return c.simple(fmt.Sprintf(`UID MOVE %s "%s"`, set, escape(dstMailbox)))
+	}
+	if err := c.checkExpungeSafe(uids); err != nil {
+		return err
+	}
+	if err := c.simple(fmt.Sprintf(`UID COPY %s "%s"`, set, escape(dstMailbox))); err != nil {
+		return err
+	}
+	return c.expungeSelected(uids)
+}
+
+func (c *IMAPClient) CopyUIDs(srcMailbox string, uids []string, dstMailbox string) error {
 	if err := c.selectMailbox(srcMailbox); err != nil {
 		return err
 	}
-	return c.simple(fmt.Sprintf(`UID MOVE %s "%s"`, uid, escape(dstMailbox)))
+	return c.simple(fmt.Sprintf(`UID COPY %s "%s"`, UIDSet(uids), escape(dstMailbox)))
+}
+
+type ThreadNode struct {
+	UID      string
+	Children []*ThreadNode
+}
+
+func (c *IMAPClient) ThreadUIDs(mailbox, algorithm, criteria string) ([]*ThreadNode, error) {
+	if err := c.selectMailbox(mailbox); err != nil {
+		return nil, err
+	}
+	lines, err := c.simpleLines(fmt.Sprintf("UID THREAD %s UTF-8 %s", algorithm, criteria))
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range lines {
+		if strings.HasPrefix(line, "* THREAD") {
+			return ParseThreadResponse(strings.TrimSpace(strings.TrimPrefix(line, "* THREAD"))), nil
+		}
+	}
+	return nil, nil
+}
+
+func ParseThreadResponse(s string) []*ThreadNode {
+	var roots []*ThreadNode
+	for i := 0; i < len(s); {
+		if s[i] != '(' {
+			i++
+			continue
+		}
+		node, next := parseThreadList(s, i)
+		roots = append(roots, node)
+		i = next
+	}
+	return roots
+}
+
+func parseThreadList(s string, i int) (*ThreadNode, int) {
+	root := &ThreadNode{}
+	cur := root
+	first := true
+	for i++; i < len(s); {
+		switch ch := s[i]; {
+		case ch == ')':
+			return root, i + 1
+		case ch == '(':
+			child, next := parseThreadList(s, i)
+			cur.Children = append(cur.Children, child)
+			i = next
+		case ch >= '0' && ch <= '9':
+			j := i
+			for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+				j++
+			}
+			if first {
+				root.UID = s[i:j]
+			} else {
+				n := &ThreadNode{UID: s[i:j]}
+				cur.Children = append(cur.Children, n)
+				cur = n
+			}
+			first = false
+			i = j
+		default:
+			i++
+		}
+	}
+	return root, i
+}
+
+func (c *IMAPClient) HasCapability(name string) bool {
+	if c.caps == nil {
+		lines, err := c.simpleLines("CAPABILITY")
+		if err != nil {
+			return false
+		}
+		caps := map[string]bool{}
+		for _, line := range lines {
+			if !strings.HasPrefix(line, "* CAPABILITY") {
+				continue
+			}
+			for _, capName := range strings.Fields(line)[2:] {
+				caps[strings.ToUpper(capName)] = true
+			}
+		}
+		c.caps = caps
+	}
+	return c.caps[strings.ToUpper(name)]
+}
+
+func UIDSet(uids []string) string {
+	nums := make([]int, 0, len(uids))
+	for _, uid := range uids {
+		if n := uidInt(uid); n > 0 {
+			nums = append(nums, n)
+		}
+	}
+	if len(nums) != len(uids) {
+		return strings.Join(uids, ",")
+	}
+	sort.Ints(nums)
+	parts := []string{}
+	for i := 0; i < len(nums); {
+		j := i
+		for j+1 < len(nums) && nums[j+1] <= nums[j]+1 {
+			j++
+		}
+		if nums[i] == nums[j] {
+			parts = append(parts, strconv.Itoa(nums[i]))
+		} else {
+			parts = append(parts, strconv.Itoa(nums[i])+":"+strconv.Itoa(nums[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
 }
 
 func uidInt(uid string) int {
@@ -552,6 +1138,38 @@ func extractMultipartBody(boundary string, raw []byte) string {
 	return htmlFallback
 }
 
+func extractAttachments(contentType string, raw []byte) []Attachment {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || !strings.HasPrefix(strings.ToLower(mediaType), "multipart/") || params["boundary"] == "" {
+		return nil
+	}
+	var out []Attachment
+	r := multipart.NewReader(bytes.NewReader(raw), params["boundary"])
+	for {
+		p, err := r.NextPart()
+		if err != nil {
+			break
+		}
+		pb, _ := io.ReadAll(p)
+		data := decodeByTransferEncoding(p.Header.Get("Content-Transfer-Encoding"), pb)
+		ct := p.Header.Get("Content-Type")
+		if mt, _, err := mime.ParseMediaType(ct); err == nil && strings.HasPrefix(strings.ToLower(mt), "multipart/") {
+			out = append(out, extractAttachments(ct, data)...)
+			continue
+		}
+		disposition, dparams, _ := mime.ParseMediaType(p.Header.Get("Content-Disposition"))
+		_, cparams, _ := mime.ParseMediaType(ct)
+		name := dparams["filename"]
+		if name == "" {
+			name = cparams["name"]
+		}
+		if strings.EqualFold(disposition, "attachment") || name != "" {
+			out = append(out, Attachment{Filename: name, ContentType: ct, Data: data})
+		}
+	}
+	return out
+}
+
 func decodeByTransferEncoding(encoding string, data []byte) []byte {
 	switch strings.ToLower(strings.TrimSpace(encoding)) {
 	case "quoted-printable":
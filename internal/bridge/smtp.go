@@ -1,10 +1,13 @@
 package bridge
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"net/smtp"
 	"sort"
 	"strings"
+	"time"
 )
 
 type SMTPConfig struct {
@@ -48,3 +51,13 @@ func Send(cfg SMTPConfig, in SendInput) error {
 	}
 	return smtp.SendMail(addr, auth, in.From, in.To, []byte(msg))
 }
+
+func NewMessageID(from string) string {
+	domain := "protonmailcli.local"
+	if at := strings.LastIndex(from, "@"); at >= 0 && at < len(from)-1 {
+		domain = strings.Trim(from[at+1:], "<> ")
+	}
+	buf := make([]byte, 6)
+	_, _ = rand.Read(buf)
+	return fmt.Sprintf("<pmail.%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(buf), domain)
+}
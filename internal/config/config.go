@@ -5,16 +5,21 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 )
 
 type Config struct {
-	Profile string
-	Output  string
-	Timeout string
-	Bridge  Bridge
-	Safety  Safety
+	Profile   string
+	Output    string
+	Timeout   string
+	Bridge    Bridge
+	Safety    Safety
+	RateLimit RateLimit
+	Outbox    Outbox
+	Lint      Lint
+	DLP       DLP
 }
 
 type Bridge struct {
@@ -29,6 +34,51 @@ type Bridge struct {
 type Safety struct {
 	RequireConfirmSendNonTTY bool
 	AllowForceSend           bool
+	PostSendAction           string
+	UndoSendSeconds          int
+	Recipients               RecipientPolicy
+}
+
+type RecipientPolicy struct {
+	AllowDomains    []string
+	DenyDomains     []string
+	InternalDomains []string
+	External        string
+	BulkThreshold   int
+}
+
+type RateLimit struct {
+	MaxPerMinute int
+	MaxPerDay    int
+}
+
+type Outbox struct {
+	Grace string
+}
+
+type Lint struct {
+	Rules        map[string]string
+	MaxBodyBytes int
+}
+
+type DLP struct {
+	Enabled  bool
+	Patterns map[string]string
+}
+
+var LintRules = []string{"empty_subject", "attachment_mentioned", "unresolved_placeholder", "possible_placeholder", "missing_greeting", "missing_signature", "reply_without_thread", "oversized_body"}
+
+func defaultLintRules() map[string]string {
+	return map[string]string{
+		"empty_subject":          "warning",
+		"attachment_mentioned":   "warning",
+		"unresolved_placeholder": "error",
+		"possible_placeholder":   "warning",
+		"missing_greeting":       "off",
+		"missing_signature":      "off",
+		"reply_without_thread":   "warning",
+		"oversized_body":         "warning",
+	}
 }
 
 func Default() Config {
@@ -37,7 +87,10 @@ func Default() Config {
 		Output:  "human",
 		Timeout: "30s",
 		Bridge:  Bridge{Host: "127.0.0.1", IMAPPort: 1143, SMTPPort: 1025, TLS: true},
-		Safety:  Safety{RequireConfirmSendNonTTY: true, AllowForceSend: true},
+		Safety:  Safety{RequireConfirmSendNonTTY: true, AllowForceSend: true, PostSendAction: "delete", Recipients: RecipientPolicy{External: "allow"}},
+		Outbox:  Outbox{Grace: "15m"},
+		Lint:    Lint{Rules: defaultLintRules(), MaxBodyBytes: 100000},
+		DLP:     DLP{Enabled: true, Patterns: map[string]string{}},
 	}
 }
 
@@ -126,6 +179,51 @@ func Load(path string) (Config, error) {
 				cfg.Safety.RequireConfirmSendNonTTY = (v == "true")
 			case "allow_force_send":
 				cfg.Safety.AllowForceSend = (v == "true")
+			case "post_send_action":
+				cfg.Safety.PostSendAction = v
+			case "undo_send_seconds":
+				n, _ := strconv.Atoi(v)
+				cfg.Safety.UndoSendSeconds = n
+			}
+		case "safety.recipients":
+			switch k {
+			case "allow_domains":
+				cfg.Safety.Recipients.AllowDomains = parseList(v)
+			case "deny_domains":
+				cfg.Safety.Recipients.DenyDomains = parseList(v)
+			case "internal_domains":
+				cfg.Safety.Recipients.InternalDomains = parseList(v)
+			case "external":
+				cfg.Safety.Recipients.External = v
+			case "bulk_threshold":
+				n, _ := strconv.Atoi(v)
+				cfg.Safety.Recipients.BulkThreshold = n
+			}
+		case "outbox":
+			if k == "grace" {
+				cfg.Outbox.Grace = v
+			}
+		case "lint":
+			if k == "max_body_bytes" {
+				n, _ := strconv.Atoi(v)
+				cfg.Lint.MaxBodyBytes = n
+			} else {
+				cfg.Lint.Rules[k] = v
+			}
+		case "dlp":
+			if k == "enabled" {
+				cfg.DLP.Enabled = (v == "true")
+			}
+		case "dlp.patterns":
+			cfg.DLP.Patterns[k] = strings.Trim(v, "'")
+		case "rate_limit":
+			switch k {
+			case "max_per_minute":
+				n, _ := strconv.Atoi(v)
+				cfg.RateLimit.MaxPerMinute = n
+			case "max_per_day":
+				n, _ := strconv.Atoi(v)
+				cfg.RateLimit.MaxPerDay = n
 			}
 		}
 	}
@@ -153,6 +251,72 @@ password_file = "%s"
 [safety]
 require_confirm_send_non_tty = %t
 allow_force_send = %t
-`, cfg.Profile, cfg.Output, cfg.Timeout, cfg.Bridge.Host, cfg.Bridge.IMAPPort, cfg.Bridge.SMTPPort, cfg.Bridge.TLS, cfg.Bridge.Username, cfg.Bridge.PasswordFile, cfg.Safety.RequireConfirmSendNonTTY, cfg.Safety.AllowForceSend)
+post_send_action = "%s"
+undo_send_seconds = %d
+
+[safety.recipients]
+allow_domains = %s
+deny_domains = %s
+internal_domains = %s
+external = "%s"
+bulk_threshold = %d
+
+[rate_limit]
+max_per_minute = %d
+max_per_day = %d
+
+[outbox]
+grace = "%s"
+
+[lint]
+max_body_bytes = %d
+%s
+[dlp]
+enabled = %t
+
+[dlp.patterns]
+%s`, cfg.Profile, cfg.Output, cfg.Timeout, cfg.Bridge.Host, cfg.Bridge.IMAPPort, cfg.Bridge.SMTPPort, cfg.Bridge.TLS, cfg.Bridge.Username, cfg.Bridge.PasswordFile, cfg.Safety.RequireConfirmSendNonTTY, cfg.Safety.AllowForceSend, cfg.Safety.PostSendAction, cfg.Safety.UndoSendSeconds, formatList(cfg.Safety.Recipients.AllowDomains), formatList(cfg.Safety.Recipients.DenyDomains), formatList(cfg.Safety.Recipients.InternalDomains), cfg.Safety.Recipients.External, cfg.Safety.Recipients.BulkThreshold, cfg.RateLimit.MaxPerMinute, cfg.RateLimit.MaxPerDay, cfg.Outbox.Grace, cfg.Lint.MaxBodyBytes, formatLintRules(cfg.Lint.Rules), cfg.DLP.Enabled, formatPatterns(cfg.DLP.Patterns))
 	return os.WriteFile(path, []byte(content), 0o600)
 }
+
+func parseList(v string) []string {
+	v = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(v), "["), "]"))
+	out := []string{}
+	for _, part := range strings.Split(v, ",") {
+		if part = strings.Trim(strings.TrimSpace(part), "\""); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+func formatList(items []string) string {
+	quoted := make([]string, 0, len(items))
+	for _, item := range items {
+		quoted = append(quoted, strconv.Quote(item))
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+func formatLintRules(rules map[string]string) string {
+	var b strings.Builder
+	for _, name := range LintRules {
+		if v, ok := rules[name]; ok {
+			fmt.Fprintf(&b, "%s = %q\n", name, v)
+		}
+	}
+	return b.String()
+}
+
+func formatPatterns(patterns map[string]string) string {
+	names := make([]string, 0, len(patterns))
+	for name := range patterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s = '%s'\n", name, patterns[name])
+	}
+	return b.String()
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -18,7 +19,7 @@ func TestDefaultConfigValues(t *testing.T) {
 	if cfg.Bridge.Host != "127.0.0.1" || cfg.Bridge.IMAPPort != 1143 || cfg.Bridge.SMTPPort != 1025 {
 		t.Fatalf("unexpected bridge defaults: %+v", cfg.Bridge)
 	}
-	if !cfg.Safety.RequireConfirmSendNonTTY || !cfg.Safety.AllowForceSend {
+	if !cfg.Safety.RequireConfirmSendNonTTY || !cfg.Safety.AllowForceSend || cfg.Safety.PostSendAction != "delete" {
 		t.Fatalf("unexpected safety defaults: %+v", cfg.Safety)
 	}
 }
@@ -53,8 +54,22 @@ func TestSaveLoadRoundTrip(t *testing.T) {
 		Safety: Safety{
 			RequireConfirmSendNonTTY: false,
 			AllowForceSend:           true,
+			PostSendAction:           "keep",
+			UndoSendSeconds:          20,
+			Recipients: RecipientPolicy{
+				AllowDomains:    []string{"example.com", "partner.org"},
+				DenyDomains:     []string{"gmail.com"},
+				InternalDomains: []string{"example.com"},
+				External:        "block",
+				BulkThreshold:   20,
+			},
 		},
+		RateLimit: RateLimit{MaxPerMinute: 5, MaxPerDay: 200},
+		Outbox:    Outbox{Grace: "1h"},
+		Lint:      Lint{Rules: defaultLintRules(), MaxBodyBytes: 5000},
+		DLP:       DLP{Enabled: false, Patterns: map[string]string{"ticket": `TCK-\d{6}`, "internal_host": `[a-z]+\.corp\.example`}},
 	}
+	cfg.Lint.Rules["missing_signature"] = "error"
 	if err := Save(path, cfg); err != nil {
 		t.Fatalf("save: %v", err)
 	}
@@ -68,9 +83,21 @@ func TestSaveLoadRoundTrip(t *testing.T) {
 	if loaded.Bridge != cfg.Bridge {
 		t.Fatalf("unexpected bridge section: got=%+v want=%+v", loaded.Bridge, cfg.Bridge)
 	}
-	if loaded.Safety != cfg.Safety {
+	if !reflect.DeepEqual(loaded.Safety, cfg.Safety) {
 		t.Fatalf("unexpected safety section: got=%+v want=%+v", loaded.Safety, cfg.Safety)
 	}
+	if !reflect.DeepEqual(loaded.Lint, cfg.Lint) {
+		t.Fatalf("unexpected lint section: got=%+v want=%+v", loaded.Lint, cfg.Lint)
+	}
+	if !reflect.DeepEqual(loaded.DLP, cfg.DLP) {
+		t.Fatalf("unexpected dlp section: got=%+v want=%+v", loaded.DLP, cfg.DLP)
+	}
+	if loaded.RateLimit != cfg.RateLimit {
+		t.Fatalf("unexpected rate_limit section: got=%+v want=%+v", loaded.RateLimit, cfg.RateLimit)
+	}
+	if loaded.Outbox != cfg.Outbox {
+		t.Fatalf("unexpected outbox section: got=%+v want=%+v", loaded.Outbox, cfg.Outbox)
+	}
 }
 
 func TestDefaultPathsRespectXDG(t *testing.T) {
@@ -0,0 +1,319 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+type Generator struct {
+	defs  map[string]any
+	names map[reflect.Type]string
+}
+
+func NewGenerator() *Generator {
+	return &Generator{defs: map[string]any{}, names: map[reflect.Type]string{}}
+}
+
+func (g *Generator) Defs() map[string]any {
+	return g.defs
+}
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	rawType  = reflect.TypeOf(json.RawMessage{})
+)
+
+func (g *Generator) Inline(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Struct && t != timeType {
+		return g.object(t)
+	}
+	return g.Schema(t)
+}
+
+func (g *Generator) Schema(t reflect.Type) map[string]any {
+	if t == nil {
+		return map[string]any{}
+	}
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case rawType:
+		return map[string]any{}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.Schema(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": g.Schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.Schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		return map[string]any{"$ref": "#/$defs/" + g.define(t)}
+	}
+	return map[string]any{}
+}
+
+func (g *Generator) define(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+	for _, other := range g.names {
+		if other == name {
+			name = strings.ToUpper(path.Base(t.PkgPath())[:1]) + path.Base(t.PkgPath())[1:] + name
+			break
+		}
+	}
+	g.names[t] = name
+	g.defs[name] = map[string]any{}
+	g.defs[name] = g.object(t)
+	return name
+}
+
+func (g *Generator) object(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	required := map[string]bool{}
+	g.fields(t, props, required)
+	req := make([]string, 0, len(required))
+	for name := range required {
+		req = append(req, name)
+	}
+	sort.Strings(req)
+	out := map[string]any{"type": "object", "properties": props, "additionalProperties": false}
+	if len(req) > 0 {
+		out["required"] = req
+	}
+	return out
+}
+
+func (g *Generator) fields(t reflect.Type, props map[string]any, required map[string]bool) {
+	var embedded []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			embedded = append(embedded, f)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		omitempty := strings.Contains(opts, "omitempty")
+		s := g.Schema(f.Type)
+		if !omitempty && nullable(f.Type) {
+			s = map[string]any{"anyOf": []any{s, map[string]any{"type": "null"}}}
+		}
+		props[name] = s
+		if !omitempty {
+			required[name] = true
+		}
+	}
+	for _, f := range embedded {
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if ft.Kind() != reflect.Struct {
+			continue
+		}
+		inner := map[string]any{}
+		innerReq := map[string]bool{}
+		g.fields(ft, inner, innerReq)
+		for name, s := range inner {
+			if _, shadowed := props[name]; !shadowed {
+				props[name] = s
+				if innerReq[name] {
+					required[name] = true
+				}
+			}
+		}
+	}
+}
+
+func nullable(t reflect.Type) bool {
+	if t == rawType {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface:
+		return true
+	}
+	return false
+}
+
+func Validate(schema map[string]any, doc any) []string {
+	v := validator{root: schema}
+	v.check(schema, doc, "$")
+	return v.errs
+}
+
+type validator struct {
+	root map[string]any
+	errs []string
+}
+
+func (v *validator) fail(at, format string, args ...any) {
+	v.errs = append(v.errs, at+": "+fmt.Sprintf(format, args...))
+}
+
+func (v *validator) resolve(ref string) (map[string]any, bool) {
+	name, ok := strings.CutPrefix(ref, "#/$defs/")
+	if !ok {
+		return nil, false
+	}
+	defs, _ := v.root["$defs"].(map[string]any)
+	s, ok := defs[name].(map[string]any)
+	return s, ok
+}
+
+func (v *validator) check(s map[string]any, doc any, at string) {
+	if ref, ok := s["$ref"].(string); ok {
+		target, found := v.resolve(ref)
+		if !found {
+			v.fail(at, "unresolved $ref %s", ref)
+			return
+		}
+		v.check(target, doc, at)
+		return
+	}
+	if anyOf, ok := s["anyOf"].([]any); ok {
+		var best []string
+		bestMiss := true
+		for _, alt := range anyOf {
+			sub := validator{root: v.root}
+			sub.check(alt.(map[string]any), doc, at)
+			if len(sub.errs) == 0 {
+				return
+			}
+			miss := len(sub.errs) == 1 && strings.HasPrefix(sub.errs[0], at+": expected ")
+			if best == nil || (bestMiss && !miss) || (miss == bestMiss && len(sub.errs) < len(best)) {
+				best, bestMiss = sub.errs, miss
+			}
+		}
+		if len(anyOf) == 1 {
+			v.errs = append(v.errs, best...)
+			return
+		}
+		v.fail(at, "matches none of %d alternatives (closest: %s)", len(anyOf), strings.Join(best, "; "))
+		return
+	}
+	if typ, ok := s["type"].(string); ok && !hasType(typ, doc) {
+		v.fail(at, "expected %s, got %s", typ, typeName(doc))
+		return
+	}
+	switch d := doc.(type) {
+	case map[string]any:
+		props, _ := s["properties"].(map[string]any)
+		for _, name := range toStrings(s["required"]) {
+			if _, ok := d[name]; !ok {
+				v.fail(at, "missing required property %q", name)
+			}
+		}
+		keys := make([]string, 0, len(d))
+		for k := range d {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if ps, ok := props[k].(map[string]any); ok {
+				v.check(ps, d[k], at+"."+k)
+				continue
+			}
+			switch extra := s["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					v.fail(at, "unexpected property %q", k)
+				}
+			case map[string]any:
+				v.check(extra, d[k], at+"."+k)
+			}
+		}
+	case []any:
+		if items, ok := s["items"].(map[string]any); ok {
+			for i, item := range d {
+				v.check(items, item, fmt.Sprintf("%s[%d]", at, i))
+			}
+		}
+	}
+}
+
+func toStrings(v any) []string {
+	switch t := v.(type) {
+	case []string:
+		return t
+	case []any:
+		out := make([]string, 0, len(t))
+		for _, s := range t {
+			if str, ok := s.(string); ok {
+				out = append(out, str)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func hasType(typ string, doc any) bool {
+	switch typ {
+	case "integer":
+		switch n := doc.(type) {
+		case float64:
+			return n == float64(int64(n))
+		case json.Number:
+			_, err := n.Int64()
+			return err == nil
+		}
+		return false
+	case "number":
+		switch doc.(type) {
+		case float64, json.Number:
+			return true
+		}
+		return false
+	}
+	return typeName(doc) == typ
+}
+
+func typeName(doc any) string {
+	switch doc.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64, json.Number:
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", doc)
+}
@@ -0,0 +1,96 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type inner struct {
+	Name string `json:"name"`
+}
+
+type base struct {
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
+}
+
+type sample struct {
+	base
+	Kind    int             `json:"kind"`
+	When    time.Time       `json:"when"`
+	Tags    []string        `json:"tags"`
+	Extra   map[string]int  `json:"extra,omitempty"`
+	Child   *inner          `json:"child,omitempty"`
+	Items   []inner         `json:"items"`
+	Raw     json.RawMessage `json:"raw,omitempty"`
+	Skipped string          `json:"-"`
+	hidden  string
+}
+
+func roundTrip(t *testing.T, v any) map[string]any {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out map[string]any
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestGeneratorDescribesStructs(t *testing.T) {
+	g := NewGenerator()
+	s := g.Schema(reflect.TypeOf(sample{}))
+	if s["$ref"] != "#/$defs/Sample" {
+		t.Fatalf("named struct should be a ref: %v", s)
+	}
+	obj := g.Defs()["Sample"].(map[string]any)
+	props := obj["properties"].(map[string]any)
+	if props["kind"].(map[string]any)["type"] != "integer" {
+		t.Fatalf("outer field should shadow embedded one: %v", props["kind"])
+	}
+	if _, ok := props["id"]; !ok {
+		t.Fatal("embedded fields should be promoted")
+	}
+	for _, name := range []string{"Skipped", "hidden"} {
+		if _, ok := props[name]; ok {
+			t.Fatalf("%s should not be described", name)
+		}
+	}
+	if got := strings.Join(obj["required"].([]string), ","); got != "id,items,kind,tags,when" {
+		t.Fatalf("unexpected required: %s", got)
+	}
+	if _, ok := props["tags"].(map[string]any)["anyOf"]; !ok {
+		t.Fatal("non-omitempty slices should allow null")
+	}
+	if _, ok := g.Defs()["Inner"]; !ok {
+		t.Fatal("nested named structs should be defined once")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	g := NewGenerator()
+	schema := roundTrip(t, map[string]any{"$ref": g.Schema(reflect.TypeOf(sample{}))["$ref"], "$defs": g.Defs()})
+	good := roundTrip(t, sample{base: base{ID: "x"}, When: time.Now(), Items: []inner{{Name: "a"}}})
+	if errs := Validate(schema, good); len(errs) > 0 {
+		t.Fatalf("valid document rejected: %v", errs)
+	}
+	bad := roundTrip(t, map[string]any{"id": 1, "kind": 1.5, "when": "now", "tags": nil, "items": []any{map[string]any{"other": true}}, "unknown": 1})
+	errs := strings.Join(Validate(schema, bad), "\n")
+	for _, want := range []string{
+		"$.id: expected string, got number",
+		"$.kind: expected integer, got number",
+		`$: unexpected property "unknown"`,
+		`$.items[0]: missing required property "name"`,
+		`$.items[0]: unexpected property "other"`,
+	} {
+		if !strings.Contains(errs, want) {
+			t.Fatalf("missing %q in:\n%s", want, errs)
+		}
+	}
+}
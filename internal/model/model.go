@@ -13,20 +13,30 @@ type Draft struct {
 	Subject   string     `json:"subject"`
 	Body      string     `json:"body"`
 	Tags      []string   `json:"tags,omitempty"`
+	InReplyTo string     `json:"inReplyTo,omitempty"`
 	CreatedAt time.Time  `json:"createdAt"`
 	UpdatedAt time.Time  `json:"updatedAt"`
 	SentAt    *time.Time `json:"sentAt,omitempty"`
 }
 
 type Message struct {
-	ID      string    `json:"id"`
-	DraftID string    `json:"draftId,omitempty"`
-	From    string    `json:"from"`
-	To      []string  `json:"to"`
-	Subject string    `json:"subject"`
-	Body    string    `json:"body"`
-	Tags    []string  `json:"tags,omitempty"`
-	SentAt  time.Time `json:"sentAt"`
+	ID        string    `json:"id"`
+	DraftID   string    `json:"draftId,omitempty"`
+	Mailbox   string    `json:"mailbox,omitempty"`
+	From      string    `json:"from"`
+	To        []string  `json:"to"`
+	Subject   string    `json:"subject"`
+	Body      string    `json:"body"`
+	Tags      []string  `json:"tags,omitempty"`
+	Flags     []string  `json:"flags,omitempty"`
+	InReplyTo string    `json:"inReplyTo,omitempty"`
+	Headers   []Header  `json:"headers,omitempty"`
+	SentAt    time.Time `json:"sentAt"`
+}
+
+type Header struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
 }
 
 type Filter struct {
@@ -37,6 +47,61 @@ type Filter struct {
 	CreatedAt time.Time `json:"createdAt"`
 }
 
+type Folder struct {
+	Name       string    `json:"name"`
+	Subscribed bool      `json:"subscribed"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type Unsubscribe struct {
+	Sender      string    `json:"sender"`
+	MessageID   string    `json:"messageId"`
+	Method      string    `json:"method"`
+	Target      string    `json:"target"`
+	DraftID     string    `json:"draftId,omitempty"`
+	ProcessedAt time.Time `json:"processedAt"`
+}
+
+type OutboxItem struct {
+	ID             string     `json:"id"`
+	DraftID        string     `json:"draftId"`
+	At             time.Time  `json:"at"`
+	MissedPolicy   string     `json:"missedPolicy"`
+	ConfirmSend    string     `json:"confirmSend,omitempty"`
+	Force          bool       `json:"force,omitempty"`
+	ConfirmBulk    int        `json:"confirmBulk,omitempty"`
+	AllowFindings  []string   `json:"allowFindings,omitempty"`
+	PostSend       string     `json:"postSend,omitempty"`
+	PasswordFile   string     `json:"passwordFile,omitempty"`
+	IdempotencyKey string     `json:"idempotencyKey,omitempty"`
+	UndoToken      string     `json:"undoToken,omitempty"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts,omitempty"`
+	LastError      string     `json:"lastError,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt,omitempty"`
+	SentAt         *time.Time `json:"sentAt,omitempty"`
+}
+
+type SendQuota struct {
+	Profile      string    `json:"profile"`
+	Identity     string    `json:"identity"`
+	MinuteTokens float64   `json:"minuteTokens"`
+	DayTokens    float64   `json:"dayTokens"`
+	Sent         int       `json:"sent"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+type DLPOverride struct {
+	FindingID string    `json:"findingId"`
+	Type      string    `json:"type"`
+	Location  string    `json:"location"`
+	Redacted  string    `json:"redacted"`
+	DraftID   string    `json:"draftId"`
+	Profile   string    `json:"profile"`
+	AllowedAt time.Time `json:"allowedAt"`
+}
+
 type AuthState struct {
 	LoggedIn     bool       `json:"loggedIn"`
 	Username     string     `json:"username,omitempty"`
@@ -56,12 +121,27 @@ type BridgeState struct {
 	ActiveUsername string `json:"activeUsername,omitempty"`
 }
 
+type CompletionCache struct {
+	Mailboxes []string  `json:"mailboxes,omitempty"`
+	Drafts    []string  `json:"drafts,omitempty"`
+	Tags      []string  `json:"tags,omitempty"`
+	Contacts  []string  `json:"contacts,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt,omitempty"`
+}
+
 type State struct {
-	Drafts      map[string]Draft             `json:"drafts"`
-	Messages    map[string]Message           `json:"messages"`
-	Tags        map[string]string            `json:"tags"`
-	Filters     map[string]Filter            `json:"filters"`
-	Auth        AuthState                    `json:"auth"`
-	Bridge      BridgeState                  `json:"bridge"`
-	Idempotency map[string]IdempotencyRecord `json:"idempotency"`
+	Drafts       map[string]Draft             `json:"drafts"`
+	Messages     map[string]Message           `json:"messages"`
+	Tags         map[string]string            `json:"tags"`
+	Filters      map[string]Filter            `json:"filters"`
+	Folders      map[string]Folder            `json:"folders"`
+	Unsubscribes map[string]Unsubscribe       `json:"unsubscribes"`
+	Outbox       map[string]OutboxItem        `json:"outbox"`
+	SendQuotas   map[string]SendQuota         `json:"sendQuotas"`
+	DLPOverrides []DLPOverride                `json:"dlpOverrides"`
+	DLPKey       string                       `json:"dlpKey,omitempty"`
+	Auth         AuthState                    `json:"auth"`
+	Bridge       BridgeState                  `json:"bridge"`
+	Idempotency  map[string]IdempotencyRecord `json:"idempotency"`
+	Completion   CompletionCache              `json:"completion"`
 }
@@ -0,0 +1,381 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiDim    = "\x1b[2m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+)
+
+var ansiRe = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+var humanCodes = []string{ansiReset, ansiBold, ansiDim, ansiRed, ansiGreen, ansiYellow}
+
+type HumanOptions struct {
+	Width int
+	Color bool
+}
+
+type HumanRenderer interface {
+	RenderHuman(h *Human)
+}
+
+type Field struct {
+	Label string
+	Value string
+}
+
+type Human struct {
+	w    io.Writer
+	opts HumanOptions
+	err  error
+}
+
+func NewHuman(w io.Writer, opts HumanOptions) *Human {
+	return &Human{w: w, opts: opts}
+}
+
+func DetectHuman(w io.Writer) HumanOptions {
+	f, ok := w.(*os.File)
+	if !ok {
+		return HumanOptions{}
+	}
+	info, err := f.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return HumanOptions{}
+	}
+	width := 0
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		width = n
+	} else if width = terminalWidth(f); width <= 0 {
+		width = 80
+	}
+	return HumanOptions{Width: width, Color: os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb"}
+}
+
+func (h *Human) write(s string) {
+	if h.err == nil {
+		_, h.err = io.WriteString(h.w, s+"\n")
+	}
+}
+
+func (h *Human) paint(code, s string) string {
+	if !h.opts.Color || s == "" {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+func (h *Human) clean(s string, multiline bool) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == '\n' || r == '\t':
+			if multiline {
+				b.WriteRune(r)
+			} else {
+				b.WriteByte(' ')
+			}
+		case r == '\x1b' && h.opts.Color && ownCode(s[i:]):
+			b.WriteRune(r)
+		case unicode.IsControl(r):
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func ownCode(s string) bool {
+	for _, code := range humanCodes {
+		if strings.HasPrefix(s, code) {
+			return true
+		}
+	}
+	return false
+}
+
+func visibleLen(s string) int {
+	return utf8.RuneCountInString(ansiRe.ReplaceAllString(s, ""))
+}
+
+func (h *Human) fit(s string, width int) string {
+	if width <= 0 || visibleLen(s) <= width {
+		return s
+	}
+	if prefix := ansiRe.FindString(s); prefix != "" && strings.HasPrefix(s, prefix) {
+		return prefix + h.fit(ansiRe.ReplaceAllString(s, ""), width) + ansiReset
+	}
+	if width == 1 {
+		return "…"
+	}
+	return string([]rune(s)[:width-1]) + "…"
+}
+
+func (h *Human) Line(format string, args ...any) {
+	h.write(h.fit(h.clean(fmt.Sprintf(format, args...), false), h.opts.Width))
+}
+
+func (h *Human) Blank() {
+	h.write("")
+}
+
+func (h *Human) Title(s string) {
+	h.write(h.paint(ansiBold, h.fit(h.clean(s, false), h.opts.Width)))
+}
+
+func (h *Human) Text(s string) {
+	h.write(strings.TrimRight(h.clean(s, true), "\n"))
+}
+
+func (h *Human) OK(s string) string   { return h.paint(ansiGreen, s) }
+func (h *Human) Bad(s string) string  { return h.paint(ansiRed, s) }
+func (h *Human) Warn(s string) string { return h.paint(ansiYellow, s) }
+
+func (h *Human) Fields(fields ...Field) {
+	width := 0
+	for _, f := range fields {
+		if f.Value != "" && visibleLen(f.Label) > width {
+			width = visibleLen(f.Label)
+		}
+	}
+	for _, f := range fields {
+		if f.Value == "" {
+			continue
+		}
+		label := f.Label + ":" + strings.Repeat(" ", width-visibleLen(f.Label)+1)
+		value := h.clean(f.Value, false)
+		if h.opts.Width > 0 {
+			value = h.fit(value, max(h.opts.Width-width-2, 8))
+		}
+		h.write(h.paint(ansiDim, label) + value)
+	}
+}
+
+func (h *Human) Table(headers []string, rows [][]string) {
+	if len(rows) == 0 {
+		h.write(h.paint(ansiDim, "(none)"))
+		return
+	}
+	widths := make([]int, len(headers))
+	for i, hd := range headers {
+		widths[i] = visibleLen(hd)
+	}
+	for _, row := range rows {
+		for i := range headers {
+			if i < len(row) {
+				row[i] = h.clean(row[i], false)
+				widths[i] = max(widths[i], visibleLen(row[i]))
+			}
+		}
+	}
+	if h.opts.Width > 0 {
+		total := func() int {
+			n := 2 * (len(widths) - 1)
+			for _, w := range widths {
+				n += w
+			}
+			return n
+		}
+		for total() > h.opts.Width {
+			widest := 0
+			for i, w := range widths {
+				if w > widths[widest] {
+					widest = i
+				}
+			}
+			if widths[widest] <= 8 {
+				break
+			}
+			widths[widest]--
+		}
+	}
+	line := func(cells []string, code string) {
+		parts := make([]string, len(headers))
+		for i := range headers {
+			cell := ""
+			if i < len(cells) {
+				cell = h.fit(cells[i], widths[i])
+			}
+			if i < len(headers)-1 {
+				cell += strings.Repeat(" ", widths[i]-visibleLen(cell))
+			}
+			parts[i] = cell
+		}
+		h.write(h.paint(code, strings.TrimRight(strings.Join(parts, "  "), " ")))
+	}
+	line(headers, ansiBold)
+	for _, row := range rows {
+		line(row, "")
+	}
+}
+
+func renderHuman(w io.Writer, env Envelope) error {
+	h := NewHuman(w, DetectHuman(w))
+	if !env.OK {
+		h.write(h.Bad("error:") + " " + h.clean(env.Error.Message, false) + " (" + env.Error.Code + ")")
+		return h.err
+	}
+	if r, ok := env.Data.(HumanRenderer); ok {
+		r.RenderHuman(h)
+		return h.err
+	}
+	renderGeneric(h, env.Data)
+	return h.err
+}
+
+func renderGeneric(h *Human, data any) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		h.err = err
+		return
+	}
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		h.err = err
+		return
+	}
+	switch t := v.(type) {
+	case nil:
+		h.write("ok")
+	case map[string]any:
+		if len(t) == 0 {
+			h.write("ok")
+			return
+		}
+		renderGenericObject(h, t)
+	case []any:
+		renderGenericTable(h, t)
+	default:
+		h.Line("%s", scalarText(t))
+	}
+}
+
+func renderGenericObject(h *Human, obj map[string]any) {
+	var fields []Field
+	var tables []string
+	flattenFields(obj, "", &fields, &tables)
+	h.Fields(fields...)
+	for _, key := range tables {
+		h.Blank()
+		h.Title(key)
+		renderGenericTable(h, obj[key].([]any))
+	}
+}
+
+func flattenFields(obj map[string]any, prefix string, fields *[]Field, tables *[]string) {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		switch t := obj[k].(type) {
+		case map[string]any:
+			flattenFields(t, prefix+k+".", fields, tables)
+		case []any:
+			if isObjectList(t) {
+				if prefix == "" {
+					*tables = append(*tables, k)
+				}
+				continue
+			}
+			*fields = append(*fields, Field{Label: prefix + k, Value: scalarText(t)})
+		default:
+			*fields = append(*fields, Field{Label: prefix + k, Value: scalarText(t)})
+		}
+	}
+}
+
+func isObjectList(list []any) bool {
+	for _, item := range list {
+		if _, ok := item.(map[string]any); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func renderGenericTable(h *Human, list []any) {
+	seen := map[string]bool{}
+	var columns []string
+	for _, item := range list {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		for k, v := range obj {
+			if _, nested := v.(map[string]any); nested {
+				continue
+			}
+			if !seen[k] {
+				seen[k] = true
+				columns = append(columns, k)
+			}
+		}
+	}
+	sort.Slice(columns, func(i, j int) bool {
+		if (columns[i] == "id") != (columns[j] == "id") {
+			return columns[i] == "id"
+		}
+		return columns[i] < columns[j]
+	})
+	if len(columns) == 0 {
+		for _, item := range list {
+			h.Line("%s", scalarText(item))
+		}
+		return
+	}
+	rows := make([][]string, 0, len(list))
+	for _, item := range list {
+		obj, _ := item.(map[string]any)
+		row := make([]string, len(columns))
+		for i, c := range columns {
+			row[i] = scalarText(obj[c])
+		}
+		rows = append(rows, row)
+	}
+	headers := make([]string, len(columns))
+	for i, c := range columns {
+		headers[i] = strings.ToUpper(c)
+	}
+	h.Table(headers, rows)
+}
+
+func scalarText(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case []any:
+		parts := make([]string, 0, len(t))
+		for _, item := range t {
+			parts = append(parts, scalarText(item))
+		}
+		return strings.Join(parts, ", ")
+	case map[string]any:
+		b, _ := json.Marshal(t)
+		return string(b)
+	default:
+		return fmt.Sprint(t)
+	}
+}
@@ -0,0 +1,89 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHumanTableFitsWidth(t *testing.T) {
+	var out bytes.Buffer
+	h := NewHuman(&out, HumanOptions{Width: 40})
+	h.Table([]string{"ID", "SUBJECT", "DATE"}, [][]string{{"d_1", "A very long subject line that cannot fit on one row", "2026-01-02"}, {"d_22", "short", "2026-01-03"}})
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "ID    SUBJECT") {
+		t.Fatalf("unexpected table:\n%s", out.String())
+	}
+	for _, l := range lines {
+		if visibleLen(l) > 40 {
+			t.Fatalf("line exceeds width: %q", l)
+		}
+	}
+	if !strings.Contains(lines[1], "…") || !strings.HasSuffix(lines[2], "2026-01-03") {
+		t.Fatalf("expected truncated subject and intact date:\n%s", out.String())
+	}
+}
+
+func TestHumanColorOnlyWhenEnabled(t *testing.T) {
+	var plain, color bytes.Buffer
+	NewHuman(&plain, HumanOptions{}).Table([]string{"A"}, [][]string{{"x"}})
+	h := NewHuman(&color, HumanOptions{Width: 20, Color: true})
+	h.Table([]string{"STATUS", "ID"}, [][]string{{h.Bad("failed"), "1"}})
+	if strings.Contains(plain.String(), "\x1b[") {
+		t.Fatalf("unexpected escape codes without color: %q", plain.String())
+	}
+	if !strings.Contains(color.String(), ansiBold+"STATUS") || !strings.Contains(color.String(), ansiRed+"failed"+ansiReset+"  1") {
+		t.Fatalf("colored cells must keep alignment: %q", color.String())
+	}
+	if got := DetectHuman(&plain); got.Color || got.Width != 0 {
+		t.Fatalf("non-terminal writers must not get color or truncation: %+v", got)
+	}
+}
+
+func TestHumanStripsControlCharacters(t *testing.T) {
+	var out bytes.Buffer
+	h := NewHuman(&out, HumanOptions{Width: 60, Color: true})
+	h.Table([]string{"FROM", "SUBJECT"}, [][]string{{"evil\x1b]0;pwned\x07@example.com", "hi\x1b[2J\r\nthere"}})
+	h.Fields(Field{Label: "Subject", Value: "\x1b[8mhidden\x1b[0m"})
+	h.Text("line one\r\n\tline two\x1b[1A\x00")
+	got := out.String()
+	if strings.ContainsAny(strings.NewReplacer(ansiBold, "", ansiDim, "", ansiReset, "").Replace(got), "\x1b\x07\r\x00") {
+		t.Fatalf("untrusted control characters reached the terminal: %q", got)
+	}
+	for _, want := range []string{"evil]0;pwned@example.com", "hi[2J there", "[8mhidden", "line one\n\tline two[1A\n"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("missing %q in %q", want, got)
+		}
+	}
+}
+
+func TestPrintSuccessHumanGenericFallback(t *testing.T) {
+	var out bytes.Buffer
+	data := map[string]any{"deleted": true, "draftId": "d_1", "items": []map[string]any{{"id": "a", "n": 1}, {"id": "b", "n": 2}}}
+	if err := PrintSuccess(&out, ModeHuman, data, "", "req", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	want := "deleted: true\ndraftId: d_1\n\nitems\nID  N\na   1\nb   2\n"
+	if out.String() != want {
+		t.Fatalf("unexpected generic output:\n%q\nwant\n%q", out.String(), want)
+	}
+	out.Reset()
+	_ = PrintSuccess(&out, ModeHuman, nil, "", "req", time.Now())
+	if out.String() != "ok\n" {
+		t.Fatalf("nil data should print ok, got %q", out.String())
+	}
+}
+
+type renderedThing struct{ Name string }
+
+func (r renderedThing) RenderHuman(h *Human) { h.Line("thing %s", r.Name) }
+
+func TestPrintSuccessUsesHumanRenderer(t *testing.T) {
+	var human, js bytes.Buffer
+	_ = PrintSuccess(&human, ModeHuman, renderedThing{Name: "x"}, "", "req", time.Now())
+	_ = PrintSuccess(&js, ModeJSON, renderedThing{Name: "x"}, "", "req", time.Now())
+	if human.String() != "thing x\n" || !strings.Contains(js.String(), `"data":{"Name":"x"}`) {
+		t.Fatalf("unexpected output: human=%q json=%q", human.String(), js.String())
+	}
+}
@@ -0,0 +1,89 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+)
+
+type RecordLister interface {
+	Records() (field string, items any)
+}
+
+type NDJSON struct {
+	w       io.Writer
+	shaper  *Shaper
+	records int
+}
+
+type ndjsonLine struct {
+	Type     string   `json:"type"`
+	OK       *bool    `json:"ok,omitempty"`
+	Data     any      `json:"data,omitempty"`
+	Error    *ErrBody `json:"error,omitempty"`
+	Meta     *Meta    `json:"meta,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+func NewNDJSON(w io.Writer, shaper *Shaper) *NDJSON {
+	return &NDJSON{w: w, shaper: shaper}
+}
+
+func (n *NDJSON) Streamed() int {
+	return n.records
+}
+
+func (n *NDJSON) Record(v any) error {
+	n.records++
+	shaped, written, err := n.shaper.shapeRecord(n.w, v)
+	if written || err != nil {
+		return err
+	}
+	return n.line(ndjsonLine{Type: "record", Data: shaped})
+}
+
+func (n *NDJSON) line(l ndjsonLine) error {
+	b, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	_, err = n.w.Write(append(b, '\n'))
+	return err
+}
+
+func (n *NDJSON) finish(env Envelope) error {
+	ok := env.OK
+	if !env.OK {
+		return n.line(ndjsonLine{Type: "error", OK: &ok, Error: env.Error, Meta: &env.Meta})
+	}
+	data := env.Data
+	if lister, isList := data.(RecordLister); isList {
+		field, items := lister.Records()
+		summary, err := summaryWithout(data, field)
+		if err != nil {
+			return err
+		}
+		if v := reflect.ValueOf(items); n.records == 0 && v.Kind() == reflect.Slice {
+			for i := 0; i < v.Len(); i++ {
+				if err := n.Record(v.Index(i).Interface()); err != nil {
+					return err
+				}
+			}
+		}
+		data = summary
+	}
+	return n.line(ndjsonLine{Type: "summary", OK: &ok, Data: data, Meta: &env.Meta, Warnings: env.Warnings})
+}
+
+func summaryWithout(data any, field string) (map[string]json.RawMessage, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return nil, err
+	}
+	delete(obj, field)
+	return obj, nil
+}
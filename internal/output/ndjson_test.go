@@ -0,0 +1,75 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+type ndjsonList struct {
+	Items []plainItem `json:"items"`
+	Count int         `json:"count"`
+}
+
+func (l ndjsonList) Records() (string, any) { return "items", l.Items }
+
+func ndjsonLines(t *testing.T, s string) []map[string]any {
+	t.Helper()
+	var out []map[string]any
+	for _, line := range strings.Split(strings.TrimRight(s, "\n"), "\n") {
+		var m map[string]any
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("invalid ndjson line %q: %v", line, err)
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+func TestNDJSONEmitsRecordsThenSummary(t *testing.T) {
+	var out bytes.Buffer
+	list := ndjsonList{Items: []plainItem{{ID: "1"}, {ID: "2"}}, Count: 2}
+	if err := PrintSuccessWithOptions(&out, ModeNDJSON, list, Options{Warnings: []string{"w"}}, "", "req", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	lines := ndjsonLines(t, out.String())
+	if len(lines) != 3 || lines[0]["type"] != "record" || lines[1]["data"].(map[string]any)["id"] != "2" {
+		t.Fatalf("unexpected records:\n%s", out.String())
+	}
+	summary := lines[2]
+	if summary["type"] != "summary" || summary["ok"] != true || summary["data"].(map[string]any)["count"] != float64(2) || summary["meta"] == nil || summary["warnings"] == nil {
+		t.Fatalf("unexpected summary: %v", summary)
+	}
+	if _, ok := summary["data"].(map[string]any)["items"]; ok {
+		t.Fatalf("summary must not repeat streamed records: %v", summary)
+	}
+}
+
+func TestNDJSONSkipsRecordsAlreadyStreamed(t *testing.T) {
+	var out bytes.Buffer
+	stream := NewNDJSON(&out, nil)
+	if err := stream.Record(plainItem{ID: "early"}); err != nil {
+		t.Fatal(err)
+	}
+	list := ndjsonList{Items: []plainItem{}, Count: 1}
+	if err := PrintSuccessWithOptions(&out, ModeNDJSON, list, Options{Stream: stream}, "", "req", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	lines := ndjsonLines(t, out.String())
+	if len(lines) != 2 || lines[0]["data"].(map[string]any)["id"] != "early" || lines[1]["type"] != "summary" {
+		t.Fatalf("unexpected stream:\n%s", out.String())
+	}
+}
+
+func TestNDJSONErrorLine(t *testing.T) {
+	var out bytes.Buffer
+	if err := PrintError(&out, ModeNDJSON, "not_found", "missing", "", "not_found", false, "", "req", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	lines := ndjsonLines(t, out.String())
+	if len(lines) != 1 || lines[0]["type"] != "error" || lines[0]["ok"] != false || lines[0]["error"].(map[string]any)["code"] != "not_found" {
+		t.Fatalf("unexpected error line: %s", out.String())
+	}
+}
@@ -4,16 +4,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"strings"
 	"time"
 )
 
 type Mode string
 
 const (
-	ModeHuman Mode = "human"
-	ModeJSON  Mode = "json"
-	ModePlain Mode = "plain"
+	ModeHuman  Mode = "human"
+	ModeJSON   Mode = "json"
+	ModePlain  Mode = "plain"
+	ModeNDJSON Mode = "ndjson"
 )
 
 type Envelope struct {
@@ -32,20 +32,47 @@ type Meta struct {
 }
 
 type ErrBody struct {
-	Code      string `json:"code"`
-	Message   string `json:"message"`
-	Hint      string `json:"hint,omitempty"`
-	Category  string `json:"category,omitempty"`
-	Retryable bool   `json:"retryable"`
+	Code              string `json:"code"`
+	Message           string `json:"message"`
+	Hint              string `json:"hint,omitempty"`
+	Category          string `json:"category,omitempty"`
+	Retryable         bool   `json:"retryable"`
+	RetryAfterSeconds int    `json:"retryAfterSeconds,omitempty"`
+}
+
+type Options struct {
+	Shaper   *Shaper
+	Warnings []string
+	Stream   *NDJSON
 }
 
 func PrintSuccess(w io.Writer, mode Mode, data interface{}, profile, requestID string, start time.Time) error {
-	env := Envelope{OK: true, Data: data, Meta: meta(profile, requestID, start)}
+	return PrintSuccessWithOptions(w, mode, data, Options{}, profile, requestID, start)
+}
+
+func PrintSuccessWithOptions(w io.Writer, mode Mode, data interface{}, opts Options, profile, requestID string, start time.Time) error {
+	env := Envelope{OK: true, Data: data, Meta: meta(profile, requestID, start), Warnings: opts.Warnings}
+	if handled, err := opts.Shaper.printSuccess(w, mode, env); handled {
+		return err
+	}
+	switch mode {
+	case ModePlain:
+		return printPlain(w, env, opts.Shaper.Fields())
+	case ModeNDJSON:
+		if opts.Stream == nil {
+			opts.Stream = NewNDJSON(w, opts.Shaper)
+		}
+		return opts.Stream.finish(env)
+	}
 	return printEnvelope(w, mode, env)
 }
 
 func PrintError(w io.Writer, mode Mode, code, msg, hint, category string, retryable bool, profile, requestID string, start time.Time) error {
-	env := Envelope{OK: false, Error: &ErrBody{Code: code, Message: msg, Hint: hint, Category: category, Retryable: retryable}, Meta: meta(profile, requestID, start)}
+	return PrintErrorBody(w, mode, ErrBody{Code: code, Message: msg, Hint: hint, Category: category, Retryable: retryable}, profile, requestID, start)
+}
+
+func PrintErrorBody(w io.Writer, mode Mode, body ErrBody, profile, requestID string, start time.Time) error {
+	env := Envelope{OK: false, Error: &body, Meta: meta(profile, requestID, start)}
 	return printEnvelope(w, mode, env)
 }
 
@@ -59,31 +86,11 @@ func printEnvelope(w io.Writer, mode Mode, env Envelope) error {
 		_, err = fmt.Fprintln(w, string(b))
 		return err
 	case ModePlain:
-		if env.OK {
-			if env.Data == nil {
-				_, err := fmt.Fprintln(w, "ok\ttrue")
-				return err
-			}
-			b, err := json.Marshal(env.Data)
-			if err != nil {
-				return err
-			}
-			_, err = fmt.Fprintf(w, "ok\ttrue\tdata\t%s\n", string(b))
-			return err
-		}
-		hint := ""
-		if env.Error != nil {
-			hint = strings.ReplaceAll(env.Error.Hint, "\t", " ")
-		}
-		_, err := fmt.Fprintf(w, "ok\tfalse\terror\t%s\tmessage\t%s\thint\t%s\n", env.Error.Code, strings.ReplaceAll(env.Error.Message, "\t", " "), hint)
-		return err
+		return printPlain(w, env, nil)
+	case ModeNDJSON:
+		return NewNDJSON(w, nil).finish(env)
 	default:
-		if env.OK {
-			_, err := fmt.Fprintln(w, "ok")
-			return err
-		}
-		_, err := fmt.Fprintf(w, "error: %s (%s)\n", env.Error.Message, env.Error.Code)
-		return err
+		return renderHuman(w, env)
 	}
 }
 
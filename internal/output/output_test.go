@@ -37,13 +37,10 @@ func TestPrintErrorPlainMode(t *testing.T) {
 	if err != nil {
 		t.Fatalf("print error: %v", err)
 	}
-	line := strings.TrimSpace(out.String())
-	if !strings.Contains(line, "ok\tfalse\terror\tvalidation_error") {
+	line := strings.TrimSuffix(out.String(), "\n")
+	if line != `error	validation_error	bad\tvalue	hint\ttext` {
 		t.Fatalf("unexpected plain error line: %q", line)
 	}
-	if strings.Contains(line, "bad\tvalue") || strings.Contains(line, "hint\ttext") {
-		t.Fatalf("tabs should be sanitized in plain output: %q", line)
-	}
 }
 
 func TestPrintErrorHumanMode(t *testing.T) {
@@ -0,0 +1,208 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+type PlainLister interface {
+	PlainList() (items any, columns []string)
+}
+
+type UnknownFieldsError struct {
+	Fields    []string
+	Available []string
+}
+
+func (e UnknownFieldsError) Error() string {
+	return fmt.Sprintf("unknown field(s) %s; available: %s", strings.Join(e.Fields, ", "), strings.Join(e.Available, ", "))
+}
+
+var plainEscaper = strings.NewReplacer("\\", "\\\\", "\t", "\\t", "\n", "\\n", "\r", "\\r")
+
+func EscapePlain(s string) string {
+	return plainEscaper.Replace(s)
+}
+
+func printPlain(w io.Writer, env Envelope, fields []string) error {
+	if !env.OK {
+		_, err := fmt.Fprintf(w, "error\t%s\t%s\t%s\n", EscapePlain(env.Error.Code), EscapePlain(env.Error.Message), EscapePlain(env.Error.Hint))
+		return err
+	}
+	columns, records, available, err := plainRecords(env.Data)
+	if err != nil {
+		return err
+	}
+	var unknown []string
+	if len(fields) > 0 {
+		known := map[string]bool{}
+		for _, c := range available {
+			known[c] = true
+		}
+		for _, f := range fields {
+			if !known[f] {
+				unknown = append(unknown, f)
+			}
+		}
+		columns = fields
+	}
+	var b strings.Builder
+	b.WriteString(strings.Join(columns, "\t") + "\n")
+	for _, rec := range records {
+		cells := make([]string, len(columns))
+		for i, c := range columns {
+			cells[i] = EscapePlain(rec[c])
+		}
+		b.WriteString(strings.Join(cells, "\t") + "\n")
+	}
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return err
+	}
+	if len(unknown) > 0 {
+		return UnknownFieldsError{Fields: unknown, Available: available}
+	}
+	return nil
+}
+
+func plainRecords(data any) ([]string, []map[string]string, []string, error) {
+	if data == nil {
+		return []string{"ok"}, []map[string]string{{"ok": "true"}}, []string{"ok"}, nil
+	}
+	if lister, ok := data.(PlainLister); ok {
+		items, columns := lister.PlainList()
+		v := reflect.ValueOf(items)
+		if v.Kind() != reflect.Slice {
+			return nil, nil, nil, fmt.Errorf("plain list items must be a slice, got %T", items)
+		}
+		records := make([]map[string]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			rec, err := flattenRecord(v.Index(i).Interface())
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			records = append(records, rec)
+		}
+		return columns, records, structColumns(reflect.TypeOf(items).Elem(), ""), nil
+	}
+	rec, err := flattenRecord(data)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	columns := structColumns(reflect.TypeOf(data), "")
+	if columns == nil {
+		for k := range rec {
+			columns = append(columns, k)
+		}
+		sort.Strings(columns)
+	}
+	return columns, []map[string]string{rec}, columns, nil
+}
+
+func PlainColumns(item any) []string {
+	return structColumns(reflect.TypeOf(item), "")
+}
+
+func structColumns(t reflect.Type, prefix string) []string {
+	cols := collectColumns(t, prefix)
+	seen := map[string]bool{}
+	out := cols[:0]
+	for _, c := range cols {
+		if !seen[c] {
+			seen[c] = true
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func collectColumns(t reflect.Type, prefix string) []string {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct || t == reflect.TypeOf(time.Time{}) {
+		return nil
+	}
+	var out []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() && !f.Anonymous {
+			continue
+		}
+		name := f.Name
+		if tag := strings.Split(f.Tag.Get("json"), ",")[0]; tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		} else if f.Anonymous {
+			out = append(out, collectColumns(f.Type, prefix)...)
+			continue
+		}
+		if nested := collectColumns(f.Type, prefix+name+"."); nested != nil {
+			out = append(out, nested...)
+			continue
+		}
+		out = append(out, prefix+name)
+	}
+	return out
+}
+
+func flattenRecord(item any) (map[string]string, error) {
+	b, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	rec := map[string]string{}
+	obj, ok := v.(map[string]any)
+	if !ok {
+		rec["value"] = plainValue(v)
+		return rec, nil
+	}
+	flattenInto(rec, obj, "")
+	return rec, nil
+}
+
+func flattenInto(rec map[string]string, obj map[string]any, prefix string) {
+	for k, v := range obj {
+		if nested, ok := v.(map[string]any); ok {
+			flattenInto(rec, nested, prefix+k+".")
+			continue
+		}
+		rec[prefix+k] = plainValue(v)
+	}
+}
+
+func plainValue(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case []any:
+		if !isObjectList(t) {
+			parts := make([]string, 0, len(t))
+			for _, item := range t {
+				parts = append(parts, plainValue(item))
+			}
+			return strings.Join(parts, ",")
+		}
+		b, _ := json.Marshal(t)
+		return string(b)
+	case map[string]any:
+		b, _ := json.Marshal(t)
+		return string(b)
+	default:
+		return fmt.Sprint(t)
+	}
+}
@@ -0,0 +1,63 @@
+package output
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+type plainItem struct {
+	ID      string   `json:"id"`
+	Subject string   `json:"subject"`
+	Tags    []string `json:"tags,omitempty"`
+	Meta    struct {
+		Size int `json:"size"`
+	} `json:"meta"`
+}
+
+type plainList struct {
+	Items []plainItem `json:"items"`
+}
+
+func (l plainList) PlainList() (any, []string) { return l.Items, []string{"id", "subject"} }
+
+func TestPlainRecordsUseDefaultColumnsAndEscape(t *testing.T) {
+	var out bytes.Buffer
+	list := plainList{Items: []plainItem{{ID: "1", Subject: "tab\there", Tags: []string{"a", "b"}}, {ID: "2", Subject: "two\nlines\\"}}}
+	if err := PrintSuccess(&out, ModePlain, list, "", "req", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	want := "id\tsubject\n1\ttab\\there\n2\ttwo\\nlines\\\\\n"
+	if out.String() != want {
+		t.Fatalf("unexpected plain output:\n%q\nwant\n%q", out.String(), want)
+	}
+}
+
+func TestPlainFieldsSelectAndReportUnknown(t *testing.T) {
+	var out bytes.Buffer
+	list := plainList{Items: []plainItem{{ID: "1", Tags: []string{"a", "b"}}}}
+	list.Items[0].Meta.Size = 42
+	err := PrintSuccessWithOptions(&out, ModePlain, list, Options{Shaper: mustShaper(t, []string{"tags", "meta.size", "nope"}, "", "")}, "", "req", time.Now())
+	if out.String() != "tags\tmeta.size\tnope\na,b\t42\t\n" {
+		t.Fatalf("unexpected plain output: %q", out.String())
+	}
+	var unknown UnknownFieldsError
+	if !errors.As(err, &unknown) || len(unknown.Fields) != 1 || unknown.Fields[0] != "nope" || len(unknown.Available) != 4 {
+		t.Fatalf("expected unknown field error, got %v", err)
+	}
+}
+
+func TestPlainSingleObjectAndEmptyData(t *testing.T) {
+	var out bytes.Buffer
+	if err := PrintSuccess(&out, ModePlain, map[string]any{"b": 2, "a": "x"}, "", "req", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "a\tb\nx\t2\n" {
+		t.Fatalf("unexpected single record: %q", out.String())
+	}
+	out.Reset()
+	if err := PrintSuccess(&out, ModePlain, nil, "", "req", time.Now()); err != nil || out.String() != "ok\ntrue\n" {
+		t.Fatalf("unexpected empty record: %q %v", out.String(), err)
+	}
+}
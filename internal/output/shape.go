@@ -0,0 +1,485 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+type Shaper struct {
+	fields []string
+	query  *Query
+	tmpl   *template.Template
+}
+
+var templateFuncs = template.FuncMap{
+	"join": func(v any, sep string) string {
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			return fmt.Sprint(v)
+		}
+		parts := make([]string, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			parts = append(parts, fmt.Sprint(rv.Index(i).Interface()))
+		}
+		return strings.Join(parts, sep)
+	},
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+func NewShaper(fields []string, query, tmpl string) (*Shaper, error) {
+	set := 0
+	for _, on := range []bool{len(fields) > 0, query != "", tmpl != ""} {
+		if on {
+			set++
+		}
+	}
+	if set == 0 {
+		return nil, nil
+	}
+	if set > 1 {
+		return nil, fmt.Errorf("--fields, --jq and --template cannot be combined")
+	}
+	s := &Shaper{fields: fields}
+	if query != "" {
+		q, err := ParseQuery(query)
+		if err != nil {
+			return nil, err
+		}
+		s.query = q
+	}
+	if tmpl != "" {
+		t, err := template.New("record").Funcs(templateFuncs).Option("missingkey=zero").Parse(tmpl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --template: %w", err)
+		}
+		s.tmpl = t
+	}
+	return s, nil
+}
+
+func (s *Shaper) Fields() []string {
+	if s == nil {
+		return nil
+	}
+	return s.fields
+}
+
+func listItems(data any) (string, []any, bool) {
+	lister, ok := data.(RecordLister)
+	if !ok {
+		return "", nil, false
+	}
+	field, items := lister.Records()
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice {
+		return field, nil, true
+	}
+	out := make([]any, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		out = append(out, v.Index(i).Interface())
+	}
+	return field, out, true
+}
+
+func decodeJSON(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	err = dec.Decode(&out)
+	return out, err
+}
+
+func (s *Shaper) renderTemplate(w io.Writer, item any) error {
+	var b bytes.Buffer
+	if err := s.tmpl.Execute(&b, item); err != nil {
+		return fmt.Errorf("--template: %w", err)
+	}
+	b.WriteByte('\n')
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+func (s *Shaper) printTemplate(w io.Writer, data any) error {
+	_, items, ok := listItems(data)
+	if !ok {
+		items = []any{data}
+	}
+	var b bytes.Buffer
+	for _, item := range items {
+		if err := s.renderTemplate(&b, item); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+func (s *Shaper) project(item any) (map[string]any, []string, error) {
+	v, err := decodeJSON(item)
+	if err != nil {
+		return nil, nil, err
+	}
+	available := map[string]bool{}
+	for _, c := range PlainColumns(item) {
+		available[c] = true
+	}
+	obj, _ := v.(map[string]any)
+	out := make(map[string]any, len(s.fields))
+	var unknown []string
+	for _, f := range s.fields {
+		val, found := lookupPath(obj, f)
+		if !found && !available[f] {
+			unknown = append(unknown, f)
+		}
+		out[f] = val
+	}
+	return out, unknown, nil
+}
+
+func lookupPath(obj map[string]any, path string) (any, bool) {
+	var cur any = obj
+	for _, part := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+func (s *Shaper) projectData(data any) (any, error) {
+	field, items, ok := listItems(data)
+	var unknown []string
+	if !ok {
+		projected, missing, err := s.project(data)
+		if err != nil {
+			return nil, err
+		}
+		return projected, s.unknownErr(missing, data)
+	}
+	rest, err := summaryWithout(data, field)
+	if err != nil {
+		return nil, err
+	}
+	projected := make([]map[string]any, 0, len(items))
+	for _, item := range items {
+		p, missing, err := s.project(item)
+		if err != nil {
+			return nil, err
+		}
+		if unknown == nil {
+			unknown = missing
+		}
+		projected = append(projected, p)
+	}
+	b, err := json.Marshal(projected)
+	if err != nil {
+		return nil, err
+	}
+	rest[field] = b
+	var sample any
+	if len(items) > 0 {
+		sample = items[0]
+	}
+	return rest, s.unknownErr(unknown, sample)
+}
+
+func (s *Shaper) unknownErr(unknown []string, sample any) error {
+	if len(unknown) == 0 {
+		return nil
+	}
+	return UnknownFieldsError{Fields: unknown, Available: PlainColumns(sample)}
+}
+
+func (s *Shaper) printHumanFields(w io.Writer, data any) error {
+	h := NewHuman(w, DetectHuman(w))
+	_, items, ok := listItems(data)
+	if !ok {
+		projected, missing, err := s.project(data)
+		if err != nil {
+			return err
+		}
+		fields := make([]Field, 0, len(s.fields))
+		for _, f := range s.fields {
+			fields = append(fields, Field{Label: f, Value: scalarText(projected[f])})
+		}
+		h.Fields(fields...)
+		if h.err != nil {
+			return h.err
+		}
+		return s.unknownErr(missing, data)
+	}
+	headers := make([]string, len(s.fields))
+	for i, f := range s.fields {
+		headers[i] = strings.ToUpper(f)
+	}
+	rows := make([][]string, 0, len(items))
+	var unknown []string
+	for _, item := range items {
+		projected, missing, err := s.project(item)
+		if err != nil {
+			return err
+		}
+		if unknown == nil {
+			unknown = missing
+		}
+		row := make([]string, len(s.fields))
+		for i, f := range s.fields {
+			row[i] = scalarText(projected[f])
+		}
+		rows = append(rows, row)
+	}
+	h.Table(headers, rows)
+	if h.err != nil {
+		return h.err
+	}
+	var sample any
+	if len(items) > 0 {
+		sample = items[0]
+	}
+	return s.unknownErr(unknown, sample)
+}
+
+func printQueryValues(w io.Writer, values []any) error {
+	var b strings.Builder
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			b.WriteString(s + "\n")
+			continue
+		}
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		b.Write(raw)
+		b.WriteByte('\n')
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (s *Shaper) printSuccess(w io.Writer, mode Mode, env Envelope) (bool, error) {
+	switch {
+	case s == nil:
+		return false, nil
+	case s.tmpl != nil && mode != ModeNDJSON:
+		return true, s.printTemplate(w, env.Data)
+	case s.query != nil && mode != ModeNDJSON:
+		v, err := decodeJSON(env.Data)
+		if err != nil {
+			return true, err
+		}
+		values, err := s.query.Eval(v)
+		if err != nil {
+			return true, err
+		}
+		if mode != ModeJSON {
+			return true, printQueryValues(w, values)
+		}
+		if s.query.Single() && len(values) == 1 {
+			env.Data = values[0]
+		} else {
+			env.Data = values
+		}
+		return true, printEnvelope(w, mode, env)
+	case len(s.fields) > 0 && mode == ModeJSON:
+		projected, err := s.projectData(env.Data)
+		if projected == nil {
+			return true, err
+		}
+		env.Data = projected
+		if perr := printEnvelope(w, mode, env); perr != nil {
+			return true, perr
+		}
+		return true, err
+	case len(s.fields) > 0 && mode == ModeHuman:
+		return true, s.printHumanFields(w, env.Data)
+	}
+	return false, nil
+}
+
+func (s *Shaper) shapeRecord(w io.Writer, v any) (any, bool, error) {
+	switch {
+	case s == nil:
+		return v, false, nil
+	case s.tmpl != nil:
+		return nil, true, s.renderTemplate(w, v)
+	case s.query != nil:
+		decoded, err := decodeJSON(v)
+		if err != nil {
+			return nil, true, err
+		}
+		values, err := s.query.Eval(decoded)
+		if err != nil {
+			return nil, true, err
+		}
+		if s.query.Single() && len(values) == 1 {
+			return values[0], false, nil
+		}
+		return values, false, nil
+	case len(s.fields) > 0:
+		projected, _, err := s.project(v)
+		return projected, false, err
+	}
+	return v, false, nil
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type queryStep struct {
+	key     string
+	index   int
+	isIndex bool
+	iterate bool
+}
+
+type Query struct {
+	expr  string
+	steps []queryStep
+}
+
+func ParseQuery(expr string) (*Query, error) {
+	q := &Query{expr: expr}
+	s := strings.TrimSpace(expr)
+	s = strings.TrimPrefix(s, "$")
+	if s == "" || s == "." {
+		return q, nil
+	}
+	bad := func(msg string) (*Query, error) {
+		return nil, fmt.Errorf("invalid --jq %q: %s", expr, msg)
+	}
+	for s != "" {
+		switch {
+		case strings.HasPrefix(s, "["):
+			end := strings.Index(s, "]")
+			if end < 0 {
+				return bad("unterminated [")
+			}
+			inner := strings.TrimSpace(s[1:end])
+			s = s[end+1:]
+			switch {
+			case inner == "" || inner == "*":
+				q.steps = append(q.steps, queryStep{iterate: true})
+			case strings.HasPrefix(inner, `"`):
+				key, err := strconv.Unquote(inner)
+				if err != nil {
+					return bad("bad quoted key " + inner)
+				}
+				q.steps = append(q.steps, queryStep{key: key})
+			default:
+				n, err := strconv.Atoi(inner)
+				if err != nil {
+					return bad("index must be a number, \"key\" or empty")
+				}
+				q.steps = append(q.steps, queryStep{index: n, isIndex: true})
+			}
+		case strings.HasPrefix(s, "."):
+			s = s[1:]
+			if strings.HasPrefix(s, "[") {
+				continue
+			}
+			end := strings.IndexAny(s, ".[")
+			if end < 0 {
+				end = len(s)
+			}
+			key := s[:end]
+			if key == "" {
+				return bad("empty key")
+			}
+			s = s[end:]
+			if key == "*" {
+				q.steps = append(q.steps, queryStep{iterate: true})
+				continue
+			}
+			q.steps = append(q.steps, queryStep{key: key})
+		default:
+			return bad("expected . or [ at " + s)
+		}
+	}
+	return q, nil
+}
+
+func (q *Query) Single() bool {
+	for _, st := range q.steps {
+		if st.iterate {
+			return false
+		}
+	}
+	return true
+}
+
+func (q *Query) Eval(v any) ([]any, error) {
+	cur := []any{v}
+	for _, st := range q.steps {
+		next := make([]any, 0, len(cur))
+		for _, item := range cur {
+			switch {
+			case st.iterate:
+				switch t := item.(type) {
+				case []any:
+					next = append(next, t...)
+				case map[string]any:
+					for _, k := range sortedKeys(t) {
+						next = append(next, t[k])
+					}
+				case nil:
+				default:
+					return nil, fmt.Errorf("--jq %s: cannot iterate over %T", q.expr, item)
+				}
+			case st.isIndex:
+				arr, ok := item.([]any)
+				if !ok {
+					if item == nil {
+						next = append(next, nil)
+						continue
+					}
+					return nil, fmt.Errorf("--jq %s: cannot index %T", q.expr, item)
+				}
+				i := st.index
+				if i < 0 {
+					i += len(arr)
+				}
+				if i < 0 || i >= len(arr) {
+					next = append(next, nil)
+					continue
+				}
+				next = append(next, arr[i])
+			default:
+				switch t := item.(type) {
+				case map[string]any:
+					next = append(next, t[st.key])
+				case nil:
+					next = append(next, nil)
+				default:
+					return nil, fmt.Errorf("--jq %s: cannot read key %q of %T", q.expr, st.key, item)
+				}
+			}
+		}
+		cur = next
+	}
+	return cur, nil
+}
@@ -0,0 +1,105 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustShaper(t *testing.T, fields []string, query, tmpl string) *Shaper {
+	t.Helper()
+	s, err := NewShaper(fields, query, tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func shapeList() ndjsonList {
+	list := ndjsonList{Items: []plainItem{{ID: "1", Subject: "first", Tags: []string{"a", "b"}}, {ID: "2", Subject: "second"}}, Count: 2}
+	list.Items[0].Meta.Size = 7
+	return list
+}
+
+func TestQueryParseAndEval(t *testing.T) {
+	data := map[string]any{"count": 2, "drafts": []any{map[string]any{"id": "d1", "to": []any{"a", "b"}}, map[string]any{"id": "d2"}}}
+	cases := map[string]string{
+		".drafts[].id":        `["d1","d2"]`,
+		"$.drafts[0].id":      `["d1"]`,
+		".drafts[-1].id":      `["d2"]`,
+		`.["count"]`:          `[2]`,
+		".drafts[*].to[]":     `["a","b"]`,
+		".":                   `[{"count":2,"drafts":[{"id":"d1","to":["a","b"]},{"id":"d2"}]}]`,
+		".drafts[5].id":       `[null]`,
+		".drafts[].missing.x": `[null,null]`,
+	}
+	for expr, want := range cases {
+		q, err := ParseQuery(expr)
+		if err != nil {
+			t.Fatalf("%s: %v", expr, err)
+		}
+		got, err := q.Eval(data)
+		if err != nil {
+			t.Fatalf("%s: %v", expr, err)
+		}
+		if b, _ := json.Marshal(got); string(b) != want {
+			t.Fatalf("%s: got %s want %s", expr, b, want)
+		}
+	}
+	for _, bad := range []string{"drafts", ".a[x]", ".a[0", ".a..b"} {
+		if _, err := ParseQuery(bad); err == nil {
+			t.Fatalf("expected parse error for %q", bad)
+		}
+	}
+	if _, err := NewShaper([]string{"id"}, ".id", ""); err == nil {
+		t.Fatal("expected --fields/--jq conflict")
+	}
+	if _, err := NewShaper(nil, "", "{{.Subject"); err == nil {
+		t.Fatal("expected template parse error")
+	}
+}
+
+func TestShaperModes(t *testing.T) {
+	print := func(mode Mode, s *Shaper) string {
+		var out bytes.Buffer
+		if err := PrintSuccessWithOptions(&out, mode, shapeList(), Options{Shaper: s}, "", "req", time.Now()); err != nil {
+			t.Fatalf("%s: %v", mode, err)
+		}
+		return out.String()
+	}
+	var env struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(print(ModeJSON, mustShaper(t, []string{"id", "meta.size"}, "", ""))), &env); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := json.Marshal(env.Data); string(b) != `{"count":2,"items":[{"id":"1","meta.size":7},{"id":"2","meta.size":0}]}` {
+		t.Fatalf("unexpected projection: %s", b)
+	}
+	if got := print(ModeJSON, mustShaper(t, nil, ".items[].id", "")); !strings.Contains(got, `"data":["1","2"]`) {
+		t.Fatalf("unexpected json query: %s", got)
+	}
+	if got := print(ModeHuman, mustShaper(t, nil, ".count", "")); got != "2\n" {
+		t.Fatalf("unexpected human query: %q", got)
+	}
+	if got := print(ModePlain, mustShaper(t, nil, "", `{{.ID}}: {{.Subject}} [{{join .Tags ","}}]`)); got != "1: first [a,b]\n2: second []\n" {
+		t.Fatalf("unexpected template output: %q", got)
+	}
+	if got := print(ModeHuman, mustShaper(t, []string{"subject", "id"}, "", "")); got != "SUBJECT  ID\nfirst    1\nsecond   2\n" {
+		t.Fatalf("unexpected human projection: %q", got)
+	}
+	lines := strings.Split(strings.TrimRight(print(ModeNDJSON, mustShaper(t, nil, ".subject", "")), "\n"), "\n")
+	if len(lines) != 3 || lines[0] != `{"type":"record","data":"first"}` || !strings.HasPrefix(lines[2], `{"type":"summary"`) {
+		t.Fatalf("unexpected ndjson query stream: %v", lines)
+	}
+}
+
+func TestShaperTemplateErrorWritesNothing(t *testing.T) {
+	var out bytes.Buffer
+	err := PrintSuccessWithOptions(&out, ModeHuman, shapeList(), Options{Shaper: mustShaper(t, nil, "", "{{.Missing}}")}, "", "req", time.Now())
+	if err == nil || out.Len() != 0 {
+		t.Fatalf("expected template error and no output, got %v %q", err, out.String())
+	}
+}
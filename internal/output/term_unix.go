@@ -0,0 +1,17 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package output
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+func terminalWidth(f *os.File) int {
+	var ws struct{ Row, Col, X, Y uint16 }
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&ws))); errno != 0 {
+		return 0
+	}
+	return int(ws.Col)
+}
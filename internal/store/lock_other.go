@@ -0,0 +1,9 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package store
+
+import "os"
+
+func lockFile(f *os.File, wait bool) error {
+	return nil
+}
@@ -1,15 +1,19 @@
 package store
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 
 	"protonmailcli/internal/model"
 )
 
+var ErrLocked = errors.New("locked by another process")
+
 type Store struct {
 	path string
 }
@@ -20,12 +24,17 @@ func New(path string) *Store {
 
 func emptyState() model.State {
 	return model.State{
-		Drafts:      map[string]model.Draft{},
-		Messages:    map[string]model.Message{},
-		Tags:        map[string]string{},
-		Filters:     map[string]model.Filter{},
-		Bridge:      model.BridgeState{},
-		Idempotency: map[string]model.IdempotencyRecord{},
+		Drafts:       map[string]model.Draft{},
+		Messages:     map[string]model.Message{},
+		Tags:         map[string]string{},
+		Filters:      map[string]model.Filter{},
+		Folders:      map[string]model.Folder{},
+		Unsubscribes: map[string]model.Unsubscribe{},
+		Outbox:       map[string]model.OutboxItem{},
+		SendQuotas:   map[string]model.SendQuota{},
+		DLPOverrides: []model.DLPOverride{},
+		Bridge:       model.BridgeState{},
+		Idempotency:  map[string]model.IdempotencyRecord{},
 	}
 }
 
@@ -64,6 +73,21 @@ func (s *Store) Load() (model.State, error) {
 	if st.Filters == nil {
 		st.Filters = map[string]model.Filter{}
 	}
+	if st.Folders == nil {
+		st.Folders = map[string]model.Folder{}
+	}
+	if st.Unsubscribes == nil {
+		st.Unsubscribes = map[string]model.Unsubscribe{}
+	}
+	if st.Outbox == nil {
+		st.Outbox = map[string]model.OutboxItem{}
+	}
+	if st.SendQuotas == nil {
+		st.SendQuotas = map[string]model.SendQuota{}
+	}
+	if st.DLPOverrides == nil {
+		st.DLPOverrides = []model.DLPOverride{}
+	}
 	if st.Idempotency == nil {
 		st.Idempotency = map[string]model.IdempotencyRecord{}
 	}
@@ -80,3 +104,92 @@ func (s *Store) Save(st model.State) error {
 	}
 	return os.WriteFile(s.path, b, 0o600)
 }
+
+func (s *Store) Lock() (func(), error) {
+	return s.lock(s.path+".lock", true)
+}
+
+func (s *Store) TryLock(name string) (func(), error) {
+	return s.lock(s.path+"."+name+".lock", false)
+}
+
+func (s *Store) lock(path string, wait bool) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f, wait); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() { _ = f.Close() }, nil
+}
+
+func Clone(st model.State) (model.State, error) {
+	b, err := json.Marshal(st)
+	if err != nil {
+		return model.State{}, err
+	}
+	var out model.State
+	err = json.Unmarshal(b, &out)
+	return out, err
+}
+
+func Merge(base, ours, theirs model.State) model.State {
+	out := theirs
+	bv, ov, tv := reflect.ValueOf(base), reflect.ValueOf(ours), reflect.ValueOf(&out).Elem()
+	for i := 0; i < ov.NumField(); i++ {
+		b, o, t := bv.Field(i), ov.Field(i), tv.Field(i)
+		switch o.Kind() {
+		case reflect.Map:
+			t.Set(mergeMap(b, o, t))
+		case reflect.Slice:
+			t.Set(mergeSlice(b, o, t))
+		default:
+			if !sameJSON(b.Interface(), o.Interface()) {
+				t.Set(o)
+			}
+		}
+	}
+	return out
+}
+
+func mergeMap(base, ours, theirs reflect.Value) reflect.Value {
+	out := reflect.MakeMap(ours.Type())
+	for it := theirs.MapRange(); it.Next(); {
+		out.SetMapIndex(it.Key(), it.Value())
+	}
+	keys := append(base.MapKeys(), ours.MapKeys()...)
+	for _, k := range keys {
+		b, o := base.MapIndex(k), ours.MapIndex(k)
+		switch {
+		case b.IsValid() && o.IsValid() && sameJSON(b.Interface(), o.Interface()):
+		case o.IsValid():
+			out.SetMapIndex(k, o)
+		default:
+			out.SetMapIndex(k, reflect.Value{})
+		}
+	}
+	return out
+}
+
+func mergeSlice(base, ours, theirs reflect.Value) reflect.Value {
+	if sameJSON(base.Interface(), ours.Interface()) {
+		return theirs
+	}
+	if ours.Len() < base.Len() || (base.Len() > 0 && !sameJSON(base.Interface(), ours.Slice(0, base.Len()).Interface())) {
+		return ours
+	}
+	out := reflect.MakeSlice(ours.Type(), 0, theirs.Len()+ours.Len()-base.Len())
+	out = reflect.AppendSlice(out, theirs)
+	return reflect.AppendSlice(out, ours.Slice(base.Len(), ours.Len()))
+}
+
+func sameJSON(a, b any) bool {
+	x, errA := json.Marshal(a)
+	y, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(x, y)
+}
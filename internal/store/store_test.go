@@ -17,7 +17,7 @@ func TestLoadCreatesEmptyStateFile(t *testing.T) {
 	if err != nil {
 		t.Fatalf("load: %v", err)
 	}
-	if st.Drafts == nil || st.Messages == nil || st.Tags == nil || st.Filters == nil || st.Idempotency == nil {
+	if st.Drafts == nil || st.Messages == nil || st.Tags == nil || st.Filters == nil || st.Folders == nil || st.Unsubscribes == nil || st.Outbox == nil || st.SendQuotas == nil || st.DLPOverrides == nil || st.Idempotency == nil {
 		t.Fatalf("expected initialized maps: %+v", st)
 	}
 	if _, err := os.Stat(path); err != nil {
@@ -73,3 +73,53 @@ func TestLoadInvalidJSON(t *testing.T) {
 		t.Fatal("expected decode error")
 	}
 }
+
+func TestMergeKeepsOtherWritersChanges(t *testing.T) {
+	base := emptyState()
+	base.Drafts["d_1"] = model.Draft{ID: "d_1", Subject: "one"}
+	base.Drafts["d_2"] = model.Draft{ID: "d_2", Subject: "two"}
+	base.Outbox["o_1"] = model.OutboxItem{ID: "o_1", Status: "queued"}
+	base.DLPOverrides = []model.DLPOverride{{FindingID: "f_1"}}
+
+	ours, err := Clone(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ours.Drafts["d_1"] = model.Draft{ID: "d_1", Subject: "edited"}
+	delete(ours.Drafts, "d_2")
+	ours.DLPOverrides = append(ours.DLPOverrides, model.DLPOverride{FindingID: "f_ours"})
+	ours.Auth.Username = "me@example.com"
+
+	theirs, _ := Clone(base)
+	theirs.Outbox["o_1"] = model.OutboxItem{ID: "o_1", Status: "sent"}
+	theirs.Drafts["d_3"] = model.Draft{ID: "d_3", Subject: "new"}
+	theirs.DLPOverrides = append(theirs.DLPOverrides, model.DLPOverride{FindingID: "f_theirs"})
+
+	got := Merge(base, ours, theirs)
+	if got.Drafts["d_1"].Subject != "edited" || got.Drafts["d_3"].Subject != "new" || len(got.Drafts) != 2 {
+		t.Fatalf("unexpected drafts: %+v", got.Drafts)
+	}
+	if got.Outbox["o_1"].Status != "sent" || got.Auth.Username != "me@example.com" {
+		t.Fatalf("untouched entries must come from disk: %+v %+v", got.Outbox, got.Auth)
+	}
+	if len(got.DLPOverrides) != 3 || got.DLPOverrides[1].FindingID != "f_theirs" || got.DLPOverrides[2].FindingID != "f_ours" {
+		t.Fatalf("appended audit entries must be kept from both sides: %+v", got.DLPOverrides)
+	}
+}
+
+func TestTryLockIsExclusive(t *testing.T) {
+	s := New(filepath.Join(t.TempDir(), "state.json"))
+	unlock, err := s.TryLock("outbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.TryLock("outbox"); err != ErrLocked {
+		t.Fatalf("expected ErrLocked, got %v", err)
+	}
+	unlock()
+	again, err := s.TryLock("outbox")
+	if err != nil {
+		t.Fatalf("lock must be released: %v", err)
+	}
+	again()
+}
@@ -4,7 +4,6 @@ set -euo pipefail
 ROOT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")/.." && pwd)"
 OUT_DIR="${ROOT_DIR}/docs/help"
 BIN_PATH="${ROOT_DIR}/.tmp/protonmailcli-help"
-SNAPSHOT_LIST="${ROOT_DIR}/scripts/help-snapshots.txt"
 WORK_DIR="$(mktemp -d)"
 trap 'rm -rf "${WORK_DIR}"' EXIT
 
@@ -27,13 +26,16 @@ mkdir -p "${OUT_DIR}" "${ROOT_DIR}/.tmp"
 go build -o "${BIN_PATH}" ./cmd/protonmailcli
 
 CFG_PATH="${WORK_DIR}/config.toml"
-STATE_PATH="${WORK_DIR}/state.json"
-PMAIL_USE_LOCAL_STATE=1 "${BIN_PATH}" --config "${CFG_PATH}" --state "${STATE_PATH}" setup --non-interactive --username docs@example.com >/dev/null
-
-while IFS=$'\t' read -r out_file cmdline || [[ -n "${out_file:-}" ]]; do
-  [[ -z "${out_file:-}" || "${out_file}" =~ ^# ]] && continue
+help() {
+  "${BIN_PATH}" --config "${CFG_PATH}" "$@" --help </dev/null
+}
+
+rm -f "${OUT_DIR}"/*.txt
+help > "${OUT_DIR}/root.txt"
+"${BIN_PATH}" --config "${CFG_PATH}" --plain commands </dev/null | tail -n +2 | cut -f1 > "${WORK_DIR}/commands"
+cut -d' ' -f1 "${WORK_DIR}/commands" | sort -u | cat - "${WORK_DIR}/commands" | while read -r cmdline; do
   read -r -a cmd_args <<< "${cmdline}"
-  PMAIL_USE_LOCAL_STATE=1 "${BIN_PATH}" --config "${CFG_PATH}" --state "${STATE_PATH}" "${cmd_args[@]}" > "${OUT_DIR}/${out_file}"
-done < "${SNAPSHOT_LIST}"
+  help "${cmd_args[@]}" > "${OUT_DIR}/${cmdline// /-}.txt"
+done
 
 echo "Updated help snapshots in ${OUT_DIR}"
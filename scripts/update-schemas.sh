@@ -0,0 +1,27 @@
+#!/usr/bin/env bash
+set -euo pipefail
+
+ROOT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")/.." && pwd)"
+OUT_DIR="${ROOT_DIR}/docs/schemas/responses"
+BIN_PATH="${ROOT_DIR}/.tmp/protonmailcli-schemas"
+
+if [[ "${1:-}" == "--out-dir" ]]; then
+  if [[ -z "${2:-}" ]]; then
+    echo "usage: $0 [--out-dir <path>]" >&2
+    exit 2
+  fi
+  OUT_DIR="${2}"
+fi
+
+cd "${ROOT_DIR}"
+
+mkdir -p "${OUT_DIR}" "${ROOT_DIR}/.tmp"
+go build -o "${BIN_PATH}" ./cmd/protonmailcli
+
+rm -f "${OUT_DIR}"/*.schema.json
+while IFS=$'\t' read -r command file; do
+  read -r -a cmd_args <<< "${command}"
+  "${BIN_PATH}" schema get "${cmd_args[@]}" > "${OUT_DIR}/${file}"
+done < <("${BIN_PATH}" --plain schema list | tail -n +2)
+
+echo "Updated response schemas in ${OUT_DIR}"